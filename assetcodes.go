@@ -0,0 +1,110 @@
+package krakenapi
+
+// AssetCodes translates between Kraken's asset codes, which for the oldest
+// assets carry an X or Z prefix (e.g. "XXBT", "ZUSD"), and their altnames
+// (e.g. "XBT", "USD"). Codes it does not know are passed through unchanged.
+// The zero AssetCodes uses the built-in table of the assets listed when the
+// package was released.
+type AssetCodes struct {
+	altnames map[string]string // Altname by asset code
+	codes    map[string]string // Asset code by altname
+}
+
+// NewAssetCodes returns the AssetCodes of the assets of an Assets response,
+// falling back to the built-in table for assets the response does not list
+func NewAssetCodes(assets AssetsResponse) AssetCodes {
+	c := AssetCodes{
+		altnames: make(map[string]string, len(assets)),
+		codes:    make(map[string]string, len(assets)),
+	}
+	for code, info := range assets {
+		if info.Altname == "" || info.Altname == code {
+			continue
+		}
+		c.altnames[code] = info.Altname
+		c.codes[info.Altname] = code
+	}
+	return c
+}
+
+// Normalize returns the altname of an asset given either form, e.g. "XBT"
+// for "XXBT" or "XBT"
+func (c AssetCodes) Normalize(code string) string {
+	if altname, ok := c.altnames[code]; ok {
+		return altname
+	}
+	if altname, ok := fallbackAltnames[code]; ok {
+		return altname
+	}
+	return code
+}
+
+// Denormalize returns the asset code of an asset given either form, e.g.
+// "XXBT" for "XBT" or "XXBT"
+func (c AssetCodes) Denormalize(altname string) string {
+	if code, ok := c.codes[altname]; ok {
+		return code
+	}
+	if _, ok := c.altnames[altname]; ok {
+		return altname
+	}
+	if code, ok := fallbackCodes[altname]; ok {
+		return code
+	}
+	return altname
+}
+
+// Same reports whether a and b name the same asset, in either form
+func (c AssetCodes) Same(a, b string) bool {
+	return a == b || c.Normalize(a) == c.Normalize(b)
+}
+
+// NormalizeAssetCode returns the altname of an asset given either form, e.g.
+// "XBT" for "XXBT", using the built-in table
+func NormalizeAssetCode(code string) string {
+	return AssetCodes{}.Normalize(code)
+}
+
+// DenormalizeAssetCode returns the asset code of an asset given either form,
+// e.g. "ZUSD" for "USD", using the built-in table
+func DenormalizeAssetCode(altname string) string {
+	return AssetCodes{}.Denormalize(altname)
+}
+
+// fallbackCodes is the inverse of fallbackAltnames
+var fallbackCodes = func() map[string]string {
+	codes := make(map[string]string, len(fallbackAltnames))
+	for code, altname := range fallbackAltnames {
+		codes[altname] = code
+	}
+	return codes
+}()
+
+// Get returns the balance of asset given as asset code or altname, or an
+// empty string if the account holds none
+func (v BalanceResponse) Get(asset string) string {
+	if balance, ok := v[asset]; ok {
+		return balance
+	}
+	if balance, ok := v[DenormalizeAssetCode(asset)]; ok {
+		return balance
+	}
+	return v[NormalizeAssetCode(asset)]
+}
+
+// Get returns the balance of asset given as asset code or altname, or a
+// zero balance if the account holds none
+func (v BalanceExResponse) Get(asset string) ExtendedBalance {
+	if balance, ok := v[asset]; ok {
+		return balance
+	}
+	if balance, ok := v[DenormalizeAssetCode(asset)]; ok {
+		return balance
+	}
+	return v[NormalizeAssetCode(asset)]
+}
+
+// IsAsset reports whether the entry moved asset, given as asset code or altname
+func (l LedgerInfo) IsAsset(asset string) bool {
+	return AssetCodes{}.Same(l.Asset, asset)
+}
@@ -0,0 +1,26 @@
+// Code generated by go test -run TestAssetCodesFixture -update; DO NOT EDIT.
+
+package krakenapi
+
+// fallbackAltnames maps the asset codes differing from their altname to it,
+// from the Assets response recorded in testdata/assets.json
+var fallbackAltnames = map[string]string{
+	"KFEE": "FEE",
+	"XETC": "ETC",
+	"XETH": "ETH",
+	"XLTC": "LTC",
+	"XMLN": "MLN",
+	"XREP": "REP",
+	"XXBT": "XBT",
+	"XXDG": "XDG",
+	"XXLM": "XLM",
+	"XXMR": "XMR",
+	"XXRP": "XRP",
+	"XZEC": "ZEC",
+	"ZAUD": "AUD",
+	"ZCAD": "CAD",
+	"ZEUR": "EUR",
+	"ZGBP": "GBP",
+	"ZJPY": "JPY",
+	"ZUSD": "USD",
+}
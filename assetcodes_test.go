@@ -0,0 +1,127 @@
+package krakenapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"testing"
+)
+
+// loadAssetsFixture reads the Assets response recorded in testdata/assets.json.
+// To pick up newly listed assets, record it again with
+//
+//	curl https://api.kraken.com/0/public/Assets > testdata/assets.json
+//
+// and regenerate the built-in table with go test -run TestAssetCodesFixture -update.
+func loadAssetsFixture(t *testing.T) AssetsResponse {
+	data, err := os.ReadFile("testdata/assets.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Result AssetsResponse `json:"result"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp.Result
+}
+
+func TestAssetCodesFixture(t *testing.T) {
+	assets := loadAssetsFixture(t)
+	if *updateGolden {
+		writeAssetCodesTable(t, assets)
+		return
+	}
+
+	live := NewAssetCodes(assets)
+	for code, info := range assets {
+		for _, codes := range []AssetCodes{live, {}} {
+			if got := codes.Normalize(code); got != info.Altname {
+				t.Errorf("Normalize(%q) = %q, want %q", code, got, info.Altname)
+			}
+			if got := codes.Normalize(info.Altname); got != info.Altname {
+				t.Errorf("Normalize(%q) = %q, want it unchanged", info.Altname, got)
+			}
+			if got := codes.Denormalize(info.Altname); got != code {
+				t.Errorf("Denormalize(%q) = %q, want %q", info.Altname, got, code)
+			}
+			if got := codes.Denormalize(code); got != code {
+				t.Errorf("Denormalize(%q) = %q, want it unchanged", code, got)
+			}
+		}
+	}
+
+	for code := range fallbackAltnames {
+		if _, ok := assets[code]; !ok {
+			t.Errorf("The built-in table lists %s, which the fixture does not; run go test -run TestAssetCodesFixture -update", code)
+		}
+	}
+}
+
+// writeAssetCodesTable generates assetcodes_table.go from the fixture
+func writeAssetCodesTable(t *testing.T, assets AssetsResponse) {
+	var codes []string
+	for code, info := range assets {
+		if info.Altname != "" && info.Altname != code {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+
+	var src bytes.Buffer
+	src.WriteString("// Code generated by go test -run TestAssetCodesFixture -update; DO NOT EDIT.\n\n")
+	src.WriteString("package krakenapi\n\n")
+	src.WriteString("// fallbackAltnames maps the asset codes differing from their altname to it,\n")
+	src.WriteString("// from the Assets response recorded in testdata/assets.json\n")
+	src.WriteString("var fallbackAltnames = map[string]string{\n")
+	for _, code := range codes {
+		fmt.Fprintf(&src, "\t%q: %q,\n", code, assets[code].Altname)
+	}
+	src.WriteString("}\n")
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("assetcodes_table.go", formatted, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAssetCodesLive(t *testing.T) {
+	codes := NewAssetCodes(AssetsResponse{"XNEW": {Altname: "NEW"}, "DOT": {Altname: "DOT"}})
+	if codes.Normalize("XNEW") != "NEW" || codes.Denormalize("NEW") != "XNEW" {
+		t.Errorf("The response should drive the mapping")
+	}
+	if codes.Normalize("ZUSD") != "USD" || codes.Denormalize("XBT") != "XXBT" {
+		t.Errorf("Assets missing from the response should use the built-in table")
+	}
+	if NormalizeAssetCode("UNKNOWN") != "UNKNOWN" || DenormalizeAssetCode("UNKNOWN") != "UNKNOWN" {
+		t.Errorf("Unknown codes should pass through")
+	}
+	if !codes.Same("XXBT", "XBT") || codes.Same("XXBT", "XBT.M") {
+		t.Errorf("Same should compare normalized codes")
+	}
+}
+
+func TestBalanceGet(t *testing.T) {
+	balances := BalanceResponse{"XXBT": "0.5", "USDT": "100", "XBT.M": "0.1"}
+	for asset, want := range map[string]string{"XXBT": "0.5", "XBT": "0.5", "USDT": "100", "XBT.M": "0.1", "ZUSD": ""} {
+		if got := balances.Get(asset); got != want {
+			t.Errorf("Get(%q) = %q, want %q", asset, got, want)
+		}
+	}
+
+	extended := BalanceExResponse{"ZEUR": {Balance: 10}}
+	if extended.Get("EUR").Balance != 10 || extended.Get("ZEUR").Balance != 10 {
+		t.Errorf("Get should accept either form, got %+v", extended.Get("EUR"))
+	}
+
+	if entry := (LedgerInfo{Asset: "XETH"}); !entry.IsAsset("ETH") || !entry.IsAsset("XETH") || entry.IsAsset("ETH2") {
+		t.Errorf("IsAsset should accept either form")
+	}
+}
@@ -0,0 +1,227 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxIDsPerQuery is the number of ids Kraken accepts in QueryTrades and
+// QueryLedgers
+const maxIDsPerQuery = 20
+
+// missingErrorCodes are the errors Kraken answers for ids it cannot return,
+// e.g. trades and ledger entries too old to be queried. Other errors, such
+// as invalid keys or rate limits, fail the audit.
+var missingErrorCodes = []string{ErrCodeInvalidArguments, ErrCodeUnknownOrder}
+
+// isMissingError reports whether err tells that Kraken does not know the
+// requested ids
+func isMissingError(err error) bool {
+	for _, code := range missingErrorCodes {
+		if hasErrorCode(err, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Kinds of OrderAuditEvent
+const (
+	AuditOrderOpened  = "opened"
+	AuditOrderStarted = "started"
+	AuditOrderClosed  = "closed" // Closed, canceled or expired; see Order.Status and Order.Reason
+	AuditTrade        = "trade"
+	AuditLedger       = "ledger"
+)
+
+// OrderAuditEvent is a step in the life of an order
+type OrderAuditEvent struct {
+	Time   time.Time
+	Kind   string // One of the Audit constants
+	Ref    string // Txid of the order or trade, or id of the ledger entry
+	Detail string
+}
+
+// OrderAudit assembles everything Kraken knows about an order
+type OrderAudit struct {
+	TxID     string
+	Order    Order
+	Trades   map[string]TradeHistoryInfo // Fills of the order indexed by trade id
+	Ledgers  map[string]LedgerInfo       // Ledger entries of the fills indexed by ledger id
+	Timeline []OrderAuditEvent           // Every event in chronological order
+	Missing  []string                    // Pieces Kraken could not return, e.g. for very old orders
+	Fetched  ComponentTimes              // When the order, its trades and its ledger entries were fetched
+}
+
+// BuildOrderAudit returns the order with the given txid, its fills and the
+// ledger entries they caused, as a chronological timeline. Pieces Kraken no
+// longer returns are listed in Missing instead of failing the audit. With
+// WithMaxSkew the order is fetched again if it is too old compared to its
+// trades and ledger entries, and the audit fails with a *SkewError if the
+// order got new fills in the meantime.
+func (api *KrakenAPI) BuildOrderAudit(ctx context.Context, txid string) (*OrderAudit, error) {
+	orders, err := api.QueryOrdersWithContext(ctx, txid, map[string]string{"trades": "true"})
+	if err != nil {
+		return nil, err
+	}
+	order, ok := (*orders)[txid]
+	if !ok {
+		return nil, fmt.Errorf("unknown order %s", txid)
+	}
+
+	audit := &OrderAudit{
+		TxID:    txid,
+		Order:   order,
+		Trades:  make(map[string]TradeHistoryInfo),
+		Ledgers: make(map[string]LedgerInfo),
+		Fetched: ComponentTimes{"QueryOrders": time.Now()},
+	}
+
+	if len(order.Trades) > 0 {
+		trades, err := api.queryTrades(ctx, url.Values{"ledgers": {"true"}}, order.Trades, isMissingError)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range order.Trades {
+			trade, ok := trades[id]
+			if !ok {
+				audit.Missing = append(audit.Missing, "trade "+id)
+				continue
+			}
+			audit.Trades[id] = trade
+		}
+		audit.Fetched["QueryTrades"] = time.Now()
+	}
+
+	if err := api.auditLedgers(ctx, audit); err != nil {
+		return nil, err
+	}
+
+	if api.checkSkew(audit.Fetched) != nil {
+		orders, err := api.QueryOrdersWithContext(ctx, txid, map[string]string{"trades": "true"})
+		if err != nil {
+			return nil, err
+		}
+		audit.Fetched["QueryOrders"] = time.Now()
+		if len((*orders)[txid].Trades) != len(order.Trades) {
+			return nil, &SkewError{MaxSkew: api.maxSkew, Components: audit.Fetched}
+		}
+		audit.Order = (*orders)[txid]
+		if err := api.checkSkew(audit.Fetched); err != nil {
+			return nil, err
+		}
+	}
+
+	audit.Timeline = auditTimeline(audit)
+	return audit, nil
+}
+
+// auditLedgers fetches the ledger entries of the fills, by the ledger ids of
+// the trades when Kraken returned them, or else by searching the trade
+// entries around the time of the fills for the trade ids
+func (api *KrakenAPI) auditLedgers(ctx context.Context, audit *OrderAudit) error {
+	if len(audit.Trades) == 0 {
+		return nil
+	}
+
+	var ids []string
+	first, last := math.Inf(1), 0.0
+	for _, trade := range audit.Trades {
+		ids = append(ids, trade.Ledgers...)
+		first = math.Min(first, trade.Time)
+		last = math.Max(last, trade.Time)
+	}
+	sort.Strings(ids)
+
+	if len(ids) > 0 {
+		for _, chunk := range chunkStrings(ids, maxIDsPerQuery) {
+			var entries map[string]LedgerInfo
+			_, err := api.queryPrivateContext(ctx, "QueryLedgers", url.Values{"id": {strings.Join(chunk, ",")}}, &entries)
+			if err != nil && !isMissingError(err) {
+				return err
+			}
+			for _, id := range chunk {
+				entry, ok := entries[id]
+				if !ok {
+					audit.Missing = append(audit.Missing, "ledger "+id)
+					continue
+				}
+				audit.Ledgers[id] = entry
+			}
+		}
+		audit.Fetched["QueryLedgers"] = time.Now()
+		return nil
+	}
+
+	entries, err := api.allLedgers(ctx, map[string]string{
+		"type":  LedgerTypeTrade,
+		"start": strconv.FormatInt(int64(first)-1, 10),
+		"end":   strconv.FormatInt(int64(last)+1, 10),
+	})
+	audit.Fetched["Ledgers"] = time.Now()
+	if err != nil {
+		if !isMissingError(err) {
+			return err
+		}
+		audit.Missing = append(audit.Missing, "ledgers: "+err.Error())
+		return nil
+	}
+	for id, entry := range entries {
+		if _, ok := audit.Trades[entry.RefID]; ok {
+			audit.Ledgers[id] = entry
+		}
+	}
+	if len(audit.Ledgers) == 0 {
+		audit.Missing = append(audit.Missing, "ledgers of trades")
+	}
+	return nil
+}
+
+// auditTimeline orders the events of the audit chronologically
+func auditTimeline(audit *OrderAudit) []OrderAuditEvent {
+	order := audit.Order
+	events := []OrderAuditEvent{{
+		Time: order.OpenTimestamp(), Kind: AuditOrderOpened, Ref: audit.TxID, Detail: order.Description.Order,
+	}}
+	if order.StartTime > 0 {
+		events = append(events, OrderAuditEvent{Time: order.StartTimestamp(), Kind: AuditOrderStarted, Ref: audit.TxID})
+	}
+	for id, trade := range audit.Trades {
+		events = append(events, OrderAuditEvent{
+			Time: trade.Timestamp(), Kind: AuditTrade, Ref: id,
+			Detail: fmt.Sprintf("%s %s @ %s", trade.Type, FormatDecimal(trade.Volume, -1), FormatDecimal(trade.Price, -1)),
+		})
+	}
+	for id, entry := range audit.Ledgers {
+		events = append(events, OrderAuditEvent{
+			Time: entry.Timestamp(), Kind: AuditLedger, Ref: id,
+			Detail: fmt.Sprintf("%s %s fee %s", entry.Asset, entry.Amount.Text('f', -1), entry.Fee.Text('f', -1)),
+		})
+	}
+	if order.CloseTime > 0 {
+		detail := order.Status
+		if order.Reason != "" {
+			detail += ": " + order.Reason
+		}
+		events = append(events, OrderAuditEvent{Time: order.CloseTimestamp(), Kind: AuditOrderClosed, Ref: audit.TxID, Detail: detail})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Time.Equal(events[j].Time) {
+			return auditRank(events[i].Kind) < auditRank(events[j].Kind)
+		}
+		return events[i].Time.Before(events[j].Time)
+	})
+	return events
+}
+
+// auditRank orders events sharing a timestamp by causality
+func auditRank(kind string) int {
+	return map[string]int{AuditOrderOpened: 0, AuditOrderStarted: 1, AuditTrade: 2, AuditLedger: 3, AuditOrderClosed: 4}[kind]
+}
@@ -0,0 +1,115 @@
+package krakenapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestBuildOrderAudit(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(body))
+		switch method {
+		case "QueryOrders":
+			return `{"error":[],"result":{"O1":{"status":"closed","opentm":100,"closetm":300,
+				"descr":{"order":"buy 2 XBTUSD @ market"},"trades":["T1","T2"]}}}`
+		case "QueryTrades":
+			if params.Get("ledgers") != "true" {
+				t.Errorf("QueryTrades should request the ledger ids")
+			}
+			// T2 is too old for Kraken to still know
+			return `{"error":[],"result":{"T1":{"ordertxid":"O1","time":200,"type":"buy","price":"50000","vol":"1","ledgers":["L1","L2"]}}}`
+		case "QueryLedgers":
+			if params.Get("id") != "L1,L2" {
+				t.Errorf("QueryLedgers should be called with the trade's ledger ids, got %s", params.Get("id"))
+			}
+			return `{"error":[],"result":{
+				"L1":{"refid":"T1","time":200,"type":"trade","asset":"XXBT","amount":"1","fee":"0"},
+				"L2":{"refid":"T1","time":200,"type":"trade","asset":"ZUSD","amount":"-50000","fee":"80"}}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	audit, err := api.BuildOrderAudit(context.Background(), "O1")
+	if err != nil {
+		t.Fatalf("BuildOrderAudit() should not return an error, got %s", err)
+	}
+	if len(audit.Trades) != 1 || len(audit.Ledgers) != 2 {
+		t.Errorf("Unexpected audit %+v", audit)
+	}
+	if len(audit.Missing) != 1 || audit.Missing[0] != "trade T2" {
+		t.Errorf("The unknown trade should be reported as missing, got %v", audit.Missing)
+	}
+
+	kinds := []string{AuditOrderOpened, AuditTrade, AuditLedger, AuditLedger, AuditOrderClosed}
+	if len(audit.Timeline) != len(kinds) {
+		t.Fatalf("Expected %d events, got %+v", len(kinds), audit.Timeline)
+	}
+	for i, kind := range kinds {
+		if audit.Timeline[i].Kind != kind {
+			t.Errorf("Event %d should be %s, got %s", i, kind, audit.Timeline[i].Kind)
+		}
+	}
+	if audit.Timeline[4].Detail != "closed" {
+		t.Errorf("Unexpected close detail %q", audit.Timeline[4].Detail)
+	}
+}
+
+func TestBuildOrderAuditFallsBackToLedgers(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "QueryOrders":
+			return `{"error":[],"result":{"O1":{"status":"closed","opentm":100,"closetm":300,"trades":["T1"]}}}`
+		case "QueryTrades":
+			return `{"error":[],"result":{"T1":{"ordertxid":"O1","time":200,"type":"sell","price":"1","vol":"1"}}}`
+		case "Ledgers":
+			return `{"error":[],"result":{"count":2,"ledger":{
+				"L1":{"refid":"T1","time":200,"type":"trade","asset":"XXBT","amount":"-1","fee":"0"},
+				"L9":{"refid":"T9","time":200,"type":"trade","asset":"XXBT","amount":"-1","fee":"0"}}}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	audit, err := api.BuildOrderAudit(context.Background(), "O1")
+	if err != nil {
+		t.Fatalf("BuildOrderAudit() should not return an error, got %s", err)
+	}
+	if _, ok := audit.Ledgers["L1"]; !ok || len(audit.Ledgers) != 1 {
+		t.Errorf("Only the ledger entries of the order's trades should be kept, got %v", audit.Ledgers)
+	}
+	if len(audit.Missing) != 0 {
+		t.Errorf("Nothing should be missing, got %v", audit.Missing)
+	}
+}
+
+func TestBuildOrderAuditErrors(t *testing.T) {
+	var tradesError string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "QueryOrders":
+			return `{"error":[],"result":{"O1":{"status":"closed","opentm":100,"closetm":300,"trades":["T1"]}}}`
+		case "QueryTrades":
+			return `{"error":["` + tradesError + `"]}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	tradesError = "EGeneral:Invalid arguments:txid"
+	audit, err := api.BuildOrderAudit(context.Background(), "O1")
+	if err != nil || len(audit.Missing) != 1 || audit.Missing[0] != "trade T1" {
+		t.Errorf("Unknown trade ids should be reported as missing, got %v", err)
+	}
+
+	for _, code := range []string{"EAPI:Invalid key", ErrCodePermissionDenied, "EAPI:Rate limit exceeded"} {
+		tradesError = code
+		if _, err := api.BuildOrderAudit(context.Background(), "O1"); !hasErrorCode(err, code) {
+			t.Errorf("%s should fail the audit, got %v", code, err)
+		}
+	}
+}
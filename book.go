@@ -0,0 +1,168 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxQuoteDeviation is the relative deviation of the best quotes of a
+// book from the ticker tolerated by ValidateBook when none is given
+const DefaultMaxQuoteDeviation = 0.05
+
+// Issues reported by ValidateBook
+const (
+	BookCrossed      = "crossed"       // Best bid at or above best ask
+	BookNoBids       = "no_bids"       // Empty bid side
+	BookNoAsks       = "no_asks"       // Empty ask side
+	BookBidDeviates  = "bid_deviates"  // Best bid too far from the ticker's bid
+	BookAskDeviates  = "ask_deviates"  // Best ask too far from the ticker's ask
+	BookTickerNoData = "ticker_nodata" // Ticker without usable quotes, deviation not checked
+)
+
+// BookReport is the outcome of ValidateBook
+type BookReport struct {
+	BestBid      float64
+	BestAsk      float64
+	TickerBid    float64
+	TickerAsk    float64
+	BidDeviation float64        // Relative deviation of BestBid from TickerBid
+	AskDeviation float64        // Relative deviation of BestAsk from TickerAsk
+	Issues       []string       // Book issues found, empty if the book looks sane
+	Fetched      ComponentTimes // When the book and the ticker were fetched, set by DepthForPair
+}
+
+// OK reports whether no issue was found
+func (r BookReport) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// ValidateBook checks book for crossed quotes and empty sides, and compares
+// its best quotes to ticker. A relative deviation above maxDeviation is
+// reported; a maxDeviation of zero uses DefaultMaxQuoteDeviation.
+func ValidateBook(book OrderBook, ticker PairTickerInfo, maxDeviation float64) BookReport {
+	if maxDeviation <= 0 {
+		maxDeviation = DefaultMaxQuoteDeviation
+	}
+
+	var report BookReport
+	if len(book.Bids) == 0 {
+		report.Issues = append(report.Issues, BookNoBids)
+	} else {
+		report.BestBid = book.Bids[0].Price
+	}
+	if len(book.Asks) == 0 {
+		report.Issues = append(report.Issues, BookNoAsks)
+	} else {
+		report.BestAsk = book.Asks[0].Price
+	}
+	if len(book.Bids) > 0 && len(book.Asks) > 0 && report.BestBid >= report.BestAsk {
+		report.Issues = append(report.Issues, BookCrossed)
+	}
+
+	var bidErr, askErr error
+	report.TickerBid, bidErr = firstPrice(ticker.Bid)
+	report.TickerAsk, askErr = firstPrice(ticker.Ask)
+	if bidErr != nil || askErr != nil {
+		report.Issues = append(report.Issues, BookTickerNoData)
+		return report
+	}
+	if len(book.Bids) > 0 {
+		report.BidDeviation = math.Abs(report.BestBid-report.TickerBid) / report.TickerBid
+		if report.BidDeviation > maxDeviation {
+			report.Issues = append(report.Issues, BookBidDeviates)
+		}
+	}
+	if len(book.Asks) > 0 {
+		report.AskDeviation = math.Abs(report.BestAsk-report.TickerAsk) / report.TickerAsk
+		if report.AskDeviation > maxDeviation {
+			report.Issues = append(report.Issues, BookAskDeviates)
+		}
+	}
+	return report
+}
+
+// firstPrice parses the price of a ticker quote array
+func firstPrice(quote []string) (float64, error) {
+	if len(quote) == 0 {
+		return 0, errors.New("missing quote")
+	}
+	price, err := strconv.ParseFloat(quote[0], 64)
+	if err == nil && price <= 0 {
+		err = fmt.Errorf("invalid quote %s", quote[0])
+	}
+	return price, err
+}
+
+// ErrSuspectData is matched by every *SuspectDataError
+var ErrSuspectData = errors.New("suspect data")
+
+// SuspectDataError is returned by DepthForPair instead of a book failing validation
+type SuspectDataError struct {
+	Pair   string
+	Report BookReport
+}
+
+func (e *SuspectDataError) Error() string {
+	return fmt.Sprintf("suspect order book of %s: %s", e.Pair, strings.Join(e.Report.Issues, ", "))
+}
+
+// Unwrap makes errors.Is(err, ErrSuspectData) hold
+func (e *SuspectDataError) Unwrap() error {
+	return ErrSuspectData
+}
+
+// WithBookValidation makes DepthForPair validate every book against the
+// ticker, tolerating a relative deviation of maxDeviation. A maxDeviation
+// below zero disables validation, zero uses DefaultMaxQuoteDeviation.
+func (api *KrakenAPI) WithBookValidation(maxDeviation float64) *KrakenAPI {
+	api.validateBooks = maxDeviation >= 0
+	api.bookDeviation = maxDeviation
+	return api
+}
+
+// DepthForPair returns the order book of pair. With WithBookValidation the
+// ticker is fetched as well and a book failing ValidateBook is returned as a
+// *SuspectDataError instead. With WithMaxSkew a book fetched too long before
+// the ticker is fetched again.
+func (api *KrakenAPI) DepthForPair(ctx context.Context, pair string, count int) (*OrderBook, error) {
+	book, err := api.depth(ctx, pair, count)
+	if err != nil || !api.validateBooks {
+		return book, err
+	}
+	fetched := time.Now()
+
+	tickers, err := api.TickerWithContext(ctx, pair)
+	if err != nil {
+		return nil, err
+	}
+	components := ComponentTimes{"Depth": fetched, "Ticker": time.Now()}
+	if api.checkSkew(components) != nil {
+		if book, err = api.depth(ctx, pair, count); err != nil {
+			return nil, err
+		}
+		components["Depth"] = time.Now()
+		if err := api.checkSkew(components); err != nil {
+			return nil, err
+		}
+	}
+
+	ticker, ok := (*tickers)[pair]
+	if !ok && len(*tickers) == 1 {
+		// Kraken answers with the canonical pair name, which may differ from the requested one
+		for _, info := range *tickers {
+			ticker = info
+		}
+	}
+
+	report := ValidateBook(*book, ticker, api.bookDeviation)
+	report.Fetched = components
+	if !report.OK() {
+		return nil, &SuspectDataError{Pair: pair, Report: report}
+	}
+	return book, nil
+}
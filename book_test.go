@@ -0,0 +1,87 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestValidateBook(t *testing.T) {
+	ticker := PairTickerInfo{Bid: []string{"100", "1", "1"}, Ask: []string{"101", "1", "1"}}
+	book := func(bid, ask float64) OrderBook {
+		return OrderBook{Bids: []OrderBookItem{{Price: bid}}, Asks: []OrderBookItem{{Price: ask}}}
+	}
+
+	tests := []struct {
+		name   string
+		book   OrderBook
+		ticker PairTickerInfo
+		issues []string
+	}{
+		{"sane", book(100, 101), ticker, nil},
+		{"crossed", book(101, 100.5), ticker, []string{BookCrossed}},
+		{"empty", OrderBook{}, ticker, []string{BookNoBids, BookNoAsks}},
+		{"deviating", book(90, 101), ticker, []string{BookBidDeviates}},
+		{"no ticker", book(100, 101), PairTickerInfo{}, []string{BookTickerNoData}},
+	}
+	for _, test := range tests {
+		report := ValidateBook(test.book, test.ticker, 0)
+		if !reflect.DeepEqual(report.Issues, test.issues) {
+			t.Errorf("%s: expected issues %v, got %v", test.name, test.issues, report.Issues)
+		}
+	}
+}
+
+func TestDepthForPairValidation(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "Depth":
+			return `{"error":[],"result":{"XXBTZUSD":{"asks":[["100","1",1]],"bids":[["102","1",1]]}}}`
+		case "Ticker":
+			return `{"error":[],"result":{"XXBTZUSD":{"a":["101","1","1"],"b":["100","1","1"]}}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	if _, err := api.DepthForPair(context.Background(), "XBTUSD", 10); err != nil {
+		t.Errorf("DepthForPair() should not validate by default, got %s", err)
+	}
+
+	_, err := api.WithBookValidation(0).DepthForPair(context.Background(), "XBTUSD", 10)
+	var suspect *SuspectDataError
+	if !errors.Is(err, ErrSuspectData) || !errors.As(err, &suspect) || suspect.Report.OK() {
+		t.Errorf("DepthForPair() should reject the crossed book, got %v", err)
+	}
+}
+
+func TestDepthCount(t *testing.T) {
+	var count []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		count = req.URL.Query()["count"]
+		return `{"error":[],"result":{"XXBTZUSD":{"asks":[["30300.0","1.0",1688671200]],"bids":[["30290.0","2.0",1688671200]]}}}`
+	})
+
+	for _, n := range []int{1, 500} {
+		book, err := api.Depth("XBTUSD", n)
+		if err != nil || len(book.Asks) != 1 {
+			t.Fatalf("Depth with count %d failed: %v", n, err)
+		}
+		if len(count) != 1 || count[0] != strconv.Itoa(n) {
+			t.Errorf("Expected count %d to be sent, got %v", n, count)
+		}
+	}
+	if _, err := api.Depth("XBTUSD", 0); err != nil || count != nil {
+		t.Errorf("A zero count should leave the depth to Kraken, got %v and %v", count, err)
+	}
+
+	count = []string{"untouched"}
+	for _, n := range []int{-1, 501} {
+		if _, err := api.Depth("XBTUSD", n); err == nil || count[0] != "untouched" {
+			t.Errorf("Count %d should be refused before sending, got %v", n, err)
+		}
+	}
+}
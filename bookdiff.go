@@ -0,0 +1,184 @@
+package krakenapi
+
+import (
+	"math"
+	"sort"
+)
+
+// LevelChange is a price level that appeared, disappeared or changed its
+// amount between two order book snapshots
+type LevelChange struct {
+	Price     float64 // Price of the level in the newer snapshot, or in the older one if removed
+	OldAmount float64 // Zero if the level was added
+	NewAmount float64 // Zero if the level was removed
+	Ts        int64   // Timestamp of the level in the newer snapshot
+}
+
+// SideDiff holds the level changes of one side of the book
+type SideDiff struct {
+	Added   []LevelChange
+	Removed []LevelChange
+	Changed []LevelChange
+}
+
+// Empty reports whether the side did not change
+func (d SideDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// BookDiff is the difference between two order book snapshots
+type BookDiff struct {
+	TickSize float64 // Levels closer than half a tick are the same level
+	Bids     SideDiff
+	Asks     SideDiff
+}
+
+// Empty reports whether the book did not change
+func (d BookDiff) Empty() bool {
+	return d.Bids.Empty() && d.Asks.Empty()
+}
+
+// DiffBooks compares two snapshots of the same book. Levels are matched by
+// price, tolerating half of tickSize (AssetPairInfo.TickSize) so that float
+// rounding does not turn a level into a removal and an addition; a zero
+// tickSize matches prices exactly.
+func DiffBooks(old, new OrderBook, tickSize float64) BookDiff {
+	return BookDiff{
+		TickSize: tickSize,
+		Bids:     diffSide(old.Bids, new.Bids, tickSize/2),
+		Asks:     diffSide(old.Asks, new.Asks, tickSize/2),
+	}
+}
+
+func diffSide(old, new []OrderBookItem, tolerance float64) SideDiff {
+	old, new = sortedLevels(old), sortedLevels(new)
+
+	var diff SideDiff
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case j == len(new) || i < len(old) && old[i].Price < new[j].Price-tolerance:
+			diff.Removed = append(diff.Removed, LevelChange{Price: old[i].Price, OldAmount: old[i].Amount, Ts: old[i].Ts})
+			i++
+		case i == len(old) || new[j].Price < old[i].Price-tolerance:
+			diff.Added = append(diff.Added, LevelChange{Price: new[j].Price, NewAmount: new[j].Amount, Ts: new[j].Ts})
+			j++
+		default:
+			if old[i].Amount != new[j].Amount || old[i].Price != new[j].Price {
+				diff.Changed = append(diff.Changed, LevelChange{Price: new[j].Price, OldAmount: old[i].Amount, NewAmount: new[j].Amount, Ts: new[j].Ts})
+			}
+			i++
+			j++
+		}
+	}
+	return diff
+}
+
+// sortedLevels returns a copy of levels sorted by ascending price
+func sortedLevels(levels []OrderBookItem) []OrderBookItem {
+	sorted := append([]OrderBookItem(nil), levels...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Price < sorted[j].Price })
+	return sorted
+}
+
+// Apply returns book with the changes of the diff applied, bids sorted by
+// descending and asks by ascending price
+func (d BookDiff) Apply(book OrderBook) OrderBook {
+	bids := applySide(book.Bids, d.Bids, d.TickSize/2)
+	asks := applySide(book.Asks, d.Asks, d.TickSize/2)
+	sort.SliceStable(bids, func(i, j int) bool { return bids[i].Price > bids[j].Price })
+	return OrderBook{Bids: bids, Asks: asks}
+}
+
+func applySide(levels []OrderBookItem, diff SideDiff, tolerance float64) []OrderBookItem {
+	result := make([]OrderBookItem, 0, len(levels)+len(diff.Added))
+	for _, level := range levels {
+		if findLevel(diff.Removed, level.Price, tolerance) >= 0 {
+			continue
+		}
+		if i := findLevel(diff.Changed, level.Price, tolerance); i >= 0 {
+			change := diff.Changed[i]
+			level = OrderBookItem{Price: change.Price, Amount: change.NewAmount, Ts: change.Ts}
+		}
+		result = append(result, level)
+	}
+	for _, added := range diff.Added {
+		result = append(result, OrderBookItem{Price: added.Price, Amount: added.NewAmount, Ts: added.Ts})
+	}
+	return sortedLevels(result)
+}
+
+// findLevel returns the index of the change at price, or -1
+func findLevel(changes []LevelChange, price, tolerance float64) int {
+	i := sort.Search(len(changes), func(i int) bool { return changes[i].Price >= price-tolerance })
+	if i < len(changes) && changes[i].Price <= price+tolerance {
+		return i
+	}
+	return -1
+}
+
+// DepthChange compares the volume resting near the mid price in two snapshots
+type DepthChange struct {
+	Bps       float64 // Distance from the mid price covered, in basis points
+	BidBefore float64
+	BidAfter  float64
+	AskBefore float64
+	AskAfter  float64
+}
+
+// BidChange returns the relative change of the bid depth, e.g. -0.8 when it dropped by 80%
+func (c DepthChange) BidChange() float64 {
+	return relativeChange(c.BidBefore, c.BidAfter)
+}
+
+// AskChange returns the relative change of the ask depth
+func (c DepthChange) AskChange() float64 {
+	return relativeChange(c.AskBefore, c.AskAfter)
+}
+
+func relativeChange(before, after float64) float64 {
+	if before == 0 {
+		if after == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return (after - before) / before
+}
+
+// CompareDepth sums the volume within bps of the mid price of each snapshot,
+// e.g. to alert when the bid depth within 10 bps dropped by 80% in one poll
+func CompareDepth(old, new OrderBook, bps float64) DepthChange {
+	change := DepthChange{Bps: bps}
+	change.BidBefore, change.AskBefore = depthNearMid(old, bps)
+	change.BidAfter, change.AskAfter = depthNearMid(new, bps)
+	return change
+}
+
+// depthNearMid returns the bid and ask volume within bps of the mid price
+func depthNearMid(book OrderBook, bps float64) (bid, ask float64) {
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return 0, 0
+	}
+	bestBid, bestAsk := book.Bids[0].Price, book.Asks[0].Price
+	for _, level := range book.Bids {
+		bestBid = math.Max(bestBid, level.Price)
+	}
+	for _, level := range book.Asks {
+		bestAsk = math.Min(bestAsk, level.Price)
+	}
+	mid := (bestBid + bestAsk) / 2
+	band := mid * bps / 10000
+
+	for _, level := range book.Bids {
+		if level.Price >= mid-band {
+			bid += level.Amount
+		}
+	}
+	for _, level := range book.Asks {
+		if level.Price <= mid+band {
+			ask += level.Amount
+		}
+	}
+	return bid, ask
+}
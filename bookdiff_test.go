@@ -0,0 +1,88 @@
+package krakenapi
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomBook returns a book of up to 20 levels per side on a 0.1 tick grid
+func randomBook(r *rand.Rand) OrderBook {
+	side := func(from, step float64) []OrderBookItem {
+		var levels []OrderBookItem
+		for i := 0; i < 20; i++ {
+			if r.Intn(3) == 0 {
+				continue
+			}
+			amount := float64(r.Intn(3) + 1)
+			levels = append(levels, OrderBookItem{Price: from + step*float64(i)*0.1, Amount: amount})
+		}
+		return levels
+	}
+	return OrderBook{Bids: side(99.9, -1), Asks: side(100.1, 1)}
+}
+
+// roundBook rounds prices to the tick grid so that books can be compared
+func roundBook(book OrderBook) OrderBook {
+	round := func(levels []OrderBookItem) []OrderBookItem {
+		rounded := []OrderBookItem{}
+		for _, level := range levels {
+			rounded = append(rounded, OrderBookItem{Price: math.Round(level.Price*10) / 10, Amount: level.Amount})
+		}
+		return rounded
+	}
+	return OrderBook{Bids: round(book.Bids), Asks: round(book.Asks)}
+}
+
+func TestDiffBooksProperties(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		old, new := randomBook(r), randomBook(r)
+
+		if diff := DiffBooks(old, old, 0.1); !diff.Empty() {
+			t.Fatalf("Diffing a book against itself should be empty, got %+v", diff)
+		}
+
+		diff := DiffBooks(old, new, 0.1)
+		if applied := diff.Apply(old); !reflect.DeepEqual(roundBook(applied), roundBook(new)) {
+			t.Fatalf("Applying the diff should reconstruct the new book\nold: %v\nnew: %v\ngot: %v", old, new, applied)
+		}
+	}
+}
+
+func TestDiffBooksTolerance(t *testing.T) {
+	old := OrderBook{Bids: []OrderBookItem{{Price: 100.1, Amount: 1}}}
+	new := OrderBook{Bids: []OrderBookItem{{Price: 100.10000000001, Amount: 2}}}
+
+	diff := DiffBooks(old, new, 0.1)
+	if len(diff.Bids.Changed) != 1 || len(diff.Bids.Added) != 0 || len(diff.Bids.Removed) != 0 {
+		t.Errorf("Prices within half a tick should match, got %+v", diff.Bids)
+	}
+	diff = DiffBooks(old, new, 0)
+	if len(diff.Bids.Added) != 1 || len(diff.Bids.Removed) != 1 {
+		t.Errorf("Prices should match exactly without a tick size, got %+v", diff.Bids)
+	}
+}
+
+func TestCompareDepth(t *testing.T) {
+	old := OrderBook{
+		Bids: []OrderBookItem{{Price: 99.95, Amount: 5}, {Price: 99, Amount: 100}},
+		Asks: []OrderBookItem{{Price: 100.05, Amount: 4}},
+	}
+	new := OrderBook{
+		Bids: []OrderBookItem{{Price: 99.95, Amount: 1}, {Price: 99, Amount: 100}},
+		Asks: []OrderBookItem{{Price: 100.05, Amount: 4}, {Price: 100.06, Amount: 4}},
+	}
+
+	change := CompareDepth(old, new, 10)
+	if change.BidBefore != 5 || change.BidAfter != 1 {
+		t.Errorf("Only levels within 10 bps of the mid should count, got %+v", change)
+	}
+	if math.Abs(change.BidChange()+0.8) > 1e-9 {
+		t.Errorf("Expected the bid depth to drop by 80%%, got %v", change.BidChange())
+	}
+	if change.AskChange() != 1 {
+		t.Errorf("Expected the ask depth to double, got %v", change.AskChange())
+	}
+}
@@ -0,0 +1,412 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BracketState is the stage of a Bracket
+type BracketState string
+
+// States of a Bracket
+const (
+	BracketPending   BracketState = "pending"   // Entry placed, not filled yet
+	BracketProtected BracketState = "protected" // Entry filled, protective orders live
+	BracketStopped   BracketState = "stopped"   // Stop-loss filled, take-profit cancelled
+	BracketTarget    BracketState = "target"    // Take-profit filled, stop-loss cancelled
+	BracketCancelled BracketState = "cancelled" // Torn down by Cancel, or an order was cancelled on Kraken
+	BracketFailed    BracketState = "failed"    // A protective order could not be placed, see Err
+	BracketFlattened BracketState = "flattened" // Failed, and the filled entry was closed at market
+)
+
+// BracketSpec describes an entry order protected by a stop-loss and a take-profit
+type BracketSpec struct {
+	Pair       string
+	Direction  string            // Direction of the entry, "buy" or "sell"
+	OrderType  string            // Type of the entry, e.g. OTLimit
+	Volume     string            // Volume of the entry
+	Args       map[string]string // Further AddOrder arguments of the entry, e.g. "price" or "leverage"
+	StopLoss   string            // Stop price closing the position at a loss, empty for none
+	TakeProfit string            // Limit price closing the position at a profit, empty for none
+
+	// FlattenOnFailure closes a filled entry at market when its protection
+	// cannot be placed, instead of leaving the position open
+	FlattenOnFailure bool
+	// PollInterval is the delay between QueryOrders polls. Defaults to one second.
+	PollInterval time.Duration
+}
+
+// Bracket is a handle on an entry order and its protective orders
+type Bracket struct {
+	api  *KrakenAPI
+	spec BracketSpec
+	stop context.CancelFunc
+	done chan struct{}
+
+	mu         sync.Mutex
+	entry      string
+	stopLoss   string
+	takeProfit string
+	state      BracketState
+	err        error
+}
+
+// PlaceBracket places the entry of spec and protects it once filled. The
+// stop-loss is attached to the entry as its conditional close, so Kraken
+// places it as soon as the entry fills. The take-profit, which Kraken cannot
+// attach as a second close, is placed as a limit order when the entry is
+// seen filled, and whichever protective order fills first cancels the other.
+// Without a stop-loss the take-profit is the conditional close.
+//
+// On spot both protective orders reserve the position, so trade on margin
+// (set "leverage" in Args, which the take-profit inherits) when using both.
+// The bracket is monitored until it completes or ctx is done.
+func (api *KrakenAPI) PlaceBracket(ctx context.Context, spec BracketSpec) (*Bracket, error) {
+	if spec.Direction != "buy" && spec.Direction != "sell" {
+		return nil, fmt.Errorf("invalid direction %q", spec.Direction)
+	}
+	if spec.StopLoss == "" && spec.TakeProfit == "" {
+		return nil, errors.New("a bracket needs a stop-loss or a take-profit")
+	}
+
+	args := make(map[string]string, len(spec.Args)+2)
+	for k, v := range spec.Args {
+		args[k] = v
+	}
+	if spec.StopLoss != "" {
+		args["close_order_type"] = OTStopLoss
+		args["close_price"] = spec.StopLoss
+	} else {
+		args["close_order_type"] = OTLimit
+		args["close_price"] = spec.TakeProfit
+	}
+
+	resp, err := api.AddOrderWithContext(ctx, spec.Pair, spec.Direction, spec.OrderType, spec.Volume, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.TxId) == 0 {
+		return nil, errors.New("AddOrder returned no txid")
+	}
+
+	ctx, stop := context.WithCancel(ctx)
+	b := &Bracket{
+		api:   api,
+		spec:  spec,
+		stop:  stop,
+		done:  make(chan struct{}),
+		entry: resp.TxId[0],
+		state: BracketPending,
+	}
+	go b.run(ctx)
+	return b, nil
+}
+
+// TxIDs returns the txids of the entry and of the protective orders placed so far
+func (b *Bracket) TxIDs() (entry, stopLoss, takeProfit string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.entry, b.stopLoss, b.takeProfit
+}
+
+// State returns the current stage of the bracket
+func (b *Bracket) State() BracketState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Err returns why the bracket failed, or why its monitoring stopped early
+func (b *Bracket) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// Done is closed when the bracket is no longer monitored
+func (b *Bracket) Done() <-chan struct{} {
+	return b.done
+}
+
+// Cancel stops monitoring and cancels the live orders of the bracket with a
+// single batch call, including the protection left by a failed bracket. A
+// position opened by the entry is left open. Close orders Kraken creates for
+// an entry filling during the cancellation are cancelled as well.
+func (b *Bracket) Cancel(ctx context.Context) error {
+	b.stop()
+	<-b.done
+
+	b.mu.Lock()
+	var live []string
+	switch b.state {
+	case BracketPending:
+		live = []string{b.entry}
+	case BracketProtected, BracketFailed:
+		for _, txid := range []string{b.stopLoss, b.takeProfit} {
+			if txid != "" {
+				live = append(live, txid)
+			}
+		}
+	default:
+		b.mu.Unlock()
+		return nil
+	}
+	b.mu.Unlock()
+
+	if err := b.api.cancelChunk(ctx, live); err != nil {
+		return err
+	}
+	closes, err := b.closeOrders(ctx)
+	if err != nil {
+		return err
+	}
+	if len(closes) > 0 {
+		if err := b.api.cancelChunk(ctx, closes); err != nil {
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	b.state = BracketCancelled
+	b.mu.Unlock()
+	return nil
+}
+
+// closeOrders returns the open orders Kraken created as conditional close of the entry
+func (b *Bracket) closeOrders(ctx context.Context) ([]string, error) {
+	open, err := b.api.OpenOrdersWithContext(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	var txids []string
+	for txid, order := range open.Open {
+		if order.ReferenceID == b.entry {
+			txids = append(txids, txid)
+		}
+	}
+	return txids, nil
+}
+
+// closedProtection finishes the bracket if the conditional close of the
+// entry, placed after openTime, is already closed. It reports whether the
+// bracket is finished.
+func (b *Bracket) closedProtection(ctx context.Context, openTime float64) bool {
+	args := map[string]string{}
+	if openTime > 0 {
+		args["start"] = strconv.FormatInt(int64(openTime)-1, 10)
+	}
+	closed, err := b.api.ClosedOrdersWithContext(ctx, args)
+	if err != nil {
+		return false
+	}
+	for txid, order := range closed.Closed {
+		if order.ReferenceID != b.entry {
+			continue
+		}
+		b.mu.Lock()
+		if b.spec.StopLoss != "" {
+			b.stopLoss = txid
+		} else {
+			b.takeProfit = txid
+		}
+		b.mu.Unlock()
+
+		switch {
+		case order.Status != "closed":
+			b.finish(BracketCancelled, nil)
+		case b.spec.StopLoss != "":
+			b.finish(BracketStopped, nil)
+		default:
+			b.finish(BracketTarget, nil)
+		}
+		return true
+	}
+	return false
+}
+
+func (b *Bracket) run(ctx context.Context) {
+	defer close(b.done)
+	interval := b.spec.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		var finished bool
+		switch b.State() {
+		case BracketPending:
+			finished = b.pollEntry(ctx)
+		case BracketProtected:
+			finished = b.pollProtection(ctx)
+		default:
+			finished = true
+		}
+		if finished {
+			return
+		}
+	}
+}
+
+// pollEntry protects the entry once it is filled. It reports whether the
+// bracket is finished.
+func (b *Bracket) pollEntry(ctx context.Context) bool {
+	orders, err := b.api.QueryOrdersWithContext(ctx, b.entry, nil)
+	if err != nil {
+		return false
+	}
+	entry := (*orders)[b.entry]
+	switch entry.Status {
+	case "closed":
+	case "canceled", "expired":
+		if entry.VolumeExecuted == 0 {
+			b.finish(BracketCancelled, nil)
+			return true
+		}
+	default:
+		return false
+	}
+
+	closes, err := b.closeOrders(ctx)
+	if err != nil {
+		return false
+	}
+	if len(closes) == 0 {
+		// Kraken creates the conditional close shortly after the fill, and
+		// it may have been filled or cancelled already
+		return b.closedProtection(ctx, entry.OpenTime)
+	}
+
+	b.mu.Lock()
+	if b.spec.StopLoss != "" {
+		b.stopLoss = closes[0]
+	} else {
+		b.takeProfit = closes[0]
+	}
+	b.mu.Unlock()
+
+	if b.spec.StopLoss != "" && b.spec.TakeProfit != "" {
+		args := map[string]string{"price": b.spec.TakeProfit}
+		if leverage, ok := b.spec.Args["leverage"]; ok {
+			args["leverage"] = leverage
+		}
+		volume := FormatDecimal(entry.VolumeExecuted, -1)
+		resp, err := b.api.AddOrderWithContext(ctx, b.spec.Pair, b.closingDirection(), OTLimit, volume, args)
+		if err == nil && len(resp.TxId) == 0 {
+			err = errors.New("AddOrder returned no txid")
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				b.fail(ctx, volume, fmt.Errorf("placing take-profit of filled entry %s: %w", b.entry, err))
+			}
+			return true
+		}
+		b.mu.Lock()
+		b.takeProfit = resp.TxId[0]
+		b.mu.Unlock()
+	}
+
+	b.finish(BracketProtected, nil)
+	return false
+}
+
+// pollProtection cancels the other protective order once one of them is
+// filled or cancelled. It reports whether the bracket is finished.
+func (b *Bracket) pollProtection(ctx context.Context) bool {
+	entry, stopLoss, takeProfit := b.TxIDs()
+	legs := []string{}
+	for _, txid := range []string{stopLoss, takeProfit} {
+		if txid != "" {
+			legs = append(legs, txid)
+		}
+	}
+	orders, err := b.api.QueryOrdersWithContext(ctx, strings.Join(legs, ","), nil)
+	if err != nil {
+		return false
+	}
+
+	for _, txid := range legs {
+		order := (*orders)[txid]
+		if order.Status != "closed" && order.Status != "canceled" && order.Status != "expired" {
+			continue
+		}
+
+		var others []string
+		for _, other := range legs {
+			if other != txid {
+				others = append(others, other)
+			}
+		}
+		if len(others) > 0 {
+			if err := b.api.cancelChunk(ctx, others); err != nil {
+				b.finish(BracketFailed, fmt.Errorf("cancelling the remaining protection of %s: %w", entry, err))
+				return true
+			}
+		}
+
+		switch {
+		case order.Status != "closed":
+			b.finish(BracketCancelled, nil)
+		case txid == stopLoss:
+			b.finish(BracketStopped, nil)
+		default:
+			b.finish(BracketTarget, nil)
+		}
+		return true
+	}
+	return false
+}
+
+// fail records that the filled entry could not be protected and, with
+// FlattenOnFailure, closes it at market after cancelling the protection
+// already in place
+func (b *Bracket) fail(ctx context.Context, volume string, err error) {
+	if !b.spec.FlattenOnFailure {
+		b.finish(BracketFailed, err)
+		return
+	}
+
+	_, stopLoss, _ := b.TxIDs()
+	if stopLoss != "" {
+		if cancelErr := b.api.cancelChunk(ctx, []string{stopLoss}); cancelErr != nil {
+			b.finish(BracketFailed, fmt.Errorf("%w; cancelling stop-loss %s before flattening: %v", err, stopLoss, cancelErr))
+			return
+		}
+	}
+	args := make(map[string]string)
+	if leverage, ok := b.spec.Args["leverage"]; ok {
+		args["leverage"] = leverage
+	}
+	if _, flattenErr := b.api.AddOrderWithContext(ctx, b.spec.Pair, b.closingDirection(), OTMarket, volume, args); flattenErr != nil {
+		b.finish(BracketFailed, fmt.Errorf("%w; flattening: %v", err, flattenErr))
+		return
+	}
+	b.finish(BracketFlattened, err)
+}
+
+func (b *Bracket) finish(state BracketState, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = state
+	b.err = err
+}
+
+// closingDirection returns the direction of the protective orders
+func (b *Bracket) closingDirection() string {
+	if b.spec.Direction == "buy" {
+		return "sell"
+	}
+	return "buy"
+}
@@ -0,0 +1,274 @@
+package krakenapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeExchange keeps the orders placed through a test client and creates the
+// conditional close of an entry when the entry is filled
+type fakeExchange struct {
+	mu           sync.Mutex
+	orders       map[string]Order
+	closes       map[string]url.Values // Close parameters of the entries
+	placed       []url.Values
+	rejectLimits bool
+}
+
+func newFakeExchange(t *testing.T) (*fakeExchange, *KrakenAPI) {
+	f := &fakeExchange{orders: make(map[string]Order), closes: make(map[string]url.Values)}
+	api := newTestAPI(func(method string, req *http.Request) string {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		body, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(body))
+		switch method {
+		case "AddOrder":
+			if f.rejectLimits && params.Get("ordertype") == OTLimit {
+				return `{"error":["EOrder:Insufficient funds"]}`
+			}
+			txid := f.add(Order{Status: "open", Description: OrderDescription{Type: params.Get("type"), OrderType: params.Get("ordertype")}}, params.Get("volume"))
+			if params.Get("close[ordertype]") != "" {
+				f.closes[txid] = params
+			}
+			f.placed = append(f.placed, params)
+			return `{"error":[],"result":{"txid":["` + txid + `"]}}`
+		case "QueryOrders":
+			result := make(map[string]Order)
+			for _, txid := range strings.Split(params.Get("txid"), ",") {
+				result[txid] = f.orders[txid]
+			}
+			data, _ := json.Marshal(result)
+			return `{"error":[],"result":` + string(data) + `}`
+		case "OpenOrders":
+			open := make(map[string]Order)
+			for txid, order := range f.orders {
+				if order.Status == "open" {
+					open[txid] = order
+				}
+			}
+			data, _ := json.Marshal(open)
+			return `{"error":[],"result":{"open":` + string(data) + `}}`
+		case "ClosedOrders":
+			closed := make(map[string]Order)
+			for txid, order := range f.orders {
+				if order.Status != "open" {
+					closed[txid] = order
+				}
+			}
+			data, _ := json.Marshal(closed)
+			return `{"error":[],"result":{"closed":` + string(data) + `,"count":` + strconv.Itoa(len(closed)) + `}}`
+		case "CancelOrderBatch":
+			var batch struct {
+				Orders []string `json:"orders"`
+			}
+			json.Unmarshal(body, &batch)
+			for _, txid := range batch.Orders {
+				f.setStatus(txid, "canceled")
+			}
+			return `{"error":[],"result":{"count":` + strconv.Itoa(len(batch.Orders)) + `}}`
+		}
+		t.Errorf("Unexpected call to %s", method)
+		return `{"error":["EGeneral:Invalid arguments"]}`
+	})
+	return f, api
+}
+
+// add stores a new order and returns its txid. Must hold f.mu.
+func (f *fakeExchange) add(order Order, volume string) string {
+	order.Volume, _ = strconv.ParseFloat(volume, 64)
+	txid := fmt.Sprintf("T%d", len(f.orders)+1)
+	f.orders[txid] = order
+	return txid
+}
+
+// setStatus changes the status of an order. Must hold f.mu.
+func (f *fakeExchange) setStatus(txid, status string) {
+	order := f.orders[txid]
+	order.Status = status
+	f.orders[txid] = order
+}
+
+// fill closes the order with its whole volume, and places its conditional close
+func (f *fakeExchange) fill(txid string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fillLocked(txid)
+}
+
+// fillLocked fills the order and returns the txid of its conditional close,
+// if any. Must hold f.mu.
+func (f *fakeExchange) fillLocked(txid string) string {
+	order := f.orders[txid]
+	order.Status = "closed"
+	order.VolumeExecuted = order.Volume
+	f.orders[txid] = order
+
+	params, ok := f.closes[txid]
+	if !ok {
+		return ""
+	}
+	direction := "sell"
+	if params.Get("type") == "sell" {
+		direction = "buy"
+	}
+	return f.add(Order{
+		Status:      "open",
+		ReferenceID: txid,
+		Description: OrderDescription{Type: direction, OrderType: params.Get("close[ordertype]")},
+	}, params.Get("volume"))
+}
+
+func (f *fakeExchange) status(txid string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.orders[txid].Status
+}
+
+// waitForState waits until the bracket reaches state
+func waitForState(t *testing.T, b *Bracket, state BracketState) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for b.State() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected bracket to be %s, got %s (err %v)", state, b.State(), b.Err())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func testBracketSpec() BracketSpec {
+	return BracketSpec{
+		Pair: "XBTUSD", Direction: "buy", OrderType: OTLimit, Volume: "1",
+		Args:     map[string]string{"price": "30000", "leverage": "2"},
+		StopLoss: "29000", TakeProfit: "32000", PollInterval: time.Millisecond,
+	}
+}
+
+func TestBracketTakeProfit(t *testing.T) {
+	f, api := newFakeExchange(t)
+	b, err := api.PlaceBracket(context.Background(), testBracketSpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, _, _ := b.TxIDs()
+	if p := f.placed[0]; p.Get("close[ordertype]") != OTStopLoss || p.Get("close[price]") != "29000" {
+		t.Errorf("The stop-loss should be the conditional close of the entry, got %v", p)
+	}
+
+	f.fill(entry)
+	waitForState(t, b, BracketProtected)
+	_, stopLoss, takeProfit := b.TxIDs()
+	if stopLoss == "" || takeProfit == "" {
+		t.Fatalf("Expected both protective orders, got %q and %q", stopLoss, takeProfit)
+	}
+	if p := f.placed[1]; p.Get("type") != "sell" || p.Get("price") != "32000" || p.Get("leverage") != "2" {
+		t.Errorf("Unexpected take-profit %v", p)
+	}
+
+	f.fill(takeProfit)
+	waitForState(t, b, BracketTarget)
+	<-b.Done()
+	if f.status(stopLoss) != "canceled" {
+		t.Errorf("The stop-loss should be cancelled once the take-profit filled")
+	}
+}
+
+func TestBracketClosedBetweenPolls(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		spec     func(*BracketSpec)
+		status   string
+		expected BracketState
+	}{
+		{"Stop-loss filled", func(*BracketSpec) {}, "closed", BracketStopped},
+		{"Take-profit filled", func(spec *BracketSpec) { spec.StopLoss = "" }, "closed", BracketTarget},
+		{"Close cancelled", func(*BracketSpec) {}, "canceled", BracketCancelled},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			f, api := newFakeExchange(t)
+			spec := testBracketSpec()
+			test.spec(&spec)
+			b, err := api.PlaceBracket(context.Background(), spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry, _, _ := b.TxIDs()
+
+			// The entry and its conditional close complete before the next poll
+			f.mu.Lock()
+			closing := f.fillLocked(entry)
+			if test.status == "closed" {
+				f.fillLocked(closing)
+			} else {
+				f.setStatus(closing, test.status)
+			}
+			f.mu.Unlock()
+
+			waitForState(t, b, test.expected)
+			<-b.Done()
+			if _, stopLoss, takeProfit := b.TxIDs(); stopLoss != closing && takeProfit != closing {
+				t.Errorf("The conditional close %s should be recorded, got %q and %q", closing, stopLoss, takeProfit)
+			}
+		})
+	}
+}
+
+func TestBracketFlattenOnFailure(t *testing.T) {
+	f, api := newFakeExchange(t)
+	spec := testBracketSpec()
+	spec.OrderType = OTMarket
+	spec.FlattenOnFailure = true
+	b, err := api.PlaceBracket(context.Background(), spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	f.rejectLimits = true
+	f.mu.Unlock()
+	entry, _, _ := b.TxIDs()
+	f.fill(entry)
+
+	<-b.Done()
+	if b.State() != BracketFlattened || b.Err() == nil || !strings.Contains(b.Err().Error(), "Insufficient funds") {
+		t.Fatalf("Expected a flattened bracket reporting the rejection, got %s: %v", b.State(), b.Err())
+	}
+	_, stopLoss, _ := b.TxIDs()
+	if f.status(stopLoss) != "canceled" {
+		t.Errorf("The stop-loss should be cancelled before flattening")
+	}
+	if p := f.placed[len(f.placed)-1]; p.Get("ordertype") != OTMarket || p.Get("type") != "sell" || p.Get("volume") != FormatDecimal(1, -1) {
+		t.Errorf("The entry should be closed at market, got %v", p)
+	}
+}
+
+func TestBracketCancel(t *testing.T) {
+	f, api := newFakeExchange(t)
+	b, err := api.PlaceBracket(context.Background(), testBracketSpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, _, _ := b.TxIDs()
+
+	if err := b.Cancel(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if b.State() != BracketCancelled || f.status(entry) != "canceled" {
+		t.Errorf("Cancel should cancel the pending entry, got %s and %s", b.State(), f.status(entry))
+	}
+
+	if _, err := api.PlaceBracket(context.Background(), BracketSpec{Pair: "XBTUSD", Direction: "buy"}); err == nil {
+		t.Errorf("A bracket without protection should be rejected")
+	}
+}
@@ -0,0 +1,231 @@
+package krakenapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// snapshotVersion is the version of the format written by MetadataCache.Snapshot
+const snapshotVersion = 1
+
+// sharedCallTimeout bounds the calls shared by concurrent callers. Such a
+// call is not bound to the context of any of them, so a hung request would
+// otherwise block every later lookup.
+const sharedCallTimeout = 30 * time.Second
+
+// sharedCallContext returns the context of a shared call, which times out
+// after timeout, sharedCallTimeout if zero
+func sharedCallContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = sharedCallTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// maxRestoreJitter bounds the delay after which a stale restored entry is
+// refreshed, so that clients restarted together do not refresh together
+const maxRestoreJitter = time.Minute
+
+// MetadataCache caches account independent metadata: AssetPairs and Assets.
+// A single cache can be shared by clients of different accounts and is safe
+// for concurrent use.
+type MetadataCache struct {
+	ttl         time.Duration
+	callTimeout time.Duration // Of the shared fetches, sharedCallTimeout if zero
+
+	mu         sync.Mutex
+	assetPairs metadataEntry[AssetPairsResponse]
+	assets     metadataEntry[AssetsResponse]
+}
+
+// metadataEntry is a cached metadata response
+type metadataEntry[T any] struct {
+	value     *T
+	fetched   time.Time
+	stale     bool      // Restored after its TTL, usable until refreshAt
+	refreshAt time.Time // When a stale entry is refreshed
+	call      *metadataCall[T]
+}
+
+// metadataCall is a fetch of an entry shared by every waiting caller
+type metadataCall[T any] struct {
+	done  chan struct{}
+	value *T
+	err   error
+}
+
+// get returns the cached value, calling fetch when it is missing or expired.
+// Concurrent callers share a single fetch and wait for it until their ctx
+// is done; the fetch itself gives up after timeout. A stale entry restored
+// from a snapshot is served until its refresh time, and kept if the refresh
+// fails. mu guards the entry.
+func (e *metadataEntry[T]) get(ctx context.Context, mu *sync.Mutex, ttl, timeout time.Duration, api *KrakenAPI, fetch func(context.Context) (*T, error)) (*T, error) {
+	mu.Lock()
+	if e.value != nil && (time.Since(e.fetched) < ttl || e.stale && time.Now().Before(e.refreshAt)) {
+		value := e.value
+		mu.Unlock()
+		api.stats.cacheLookup("metadata", true)
+		return value, nil
+	}
+	call := e.call
+	if call == nil {
+		call = &metadataCall[T]{done: make(chan struct{})}
+		e.call = call
+		go e.refresh(mu, call, timeout, fetch)
+	}
+	mu.Unlock()
+	api.stats.cacheLookup("metadata", false)
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// refresh performs the shared fetch. It is not bound to the context of the
+// caller that started it, so that one cancelled caller does not fail the
+// others, but times out so that a hung request does not block the entry.
+func (e *metadataEntry[T]) refresh(mu *sync.Mutex, call *metadataCall[T], timeout time.Duration, fetch func(context.Context) (*T, error)) {
+	ctx, cancel := sharedCallContext(timeout)
+	value, err := fetch(ctx)
+	cancel()
+
+	mu.Lock()
+	switch {
+	case err == nil:
+		e.value = value
+		e.fetched = time.Now()
+		e.stale = false
+	case e.value != nil && e.stale:
+		value, err = e.value, nil
+	}
+	e.call = nil
+	call.value, call.err = value, err
+	mu.Unlock()
+	close(call.done)
+}
+
+// restore replaces the entry with a snapshot of it fetched at the given time
+func (e *metadataEntry[T]) restore(value *T, fetched time.Time, ttl time.Duration) {
+	e.value = value
+	e.fetched = fetched
+	e.stale = value != nil && time.Since(fetched) >= ttl
+	if e.stale {
+		e.refreshAt = time.Now().Add(restoreJitter(ttl))
+	}
+}
+
+// NewMetadataCache creates a cache whose entries are refreshed after ttl
+func NewMetadataCache(ttl time.Duration) *MetadataCache {
+	return &MetadataCache{ttl: ttl}
+}
+
+// AssetPairs returns the cached asset pairs, fetching them through api when
+// the cache is empty or expired. Concurrent callers share a single fetch,
+// each waiting for it until its ctx is done. A stale entry restored from a
+// snapshot is served until its refresh time, and kept if the refresh fails.
+func (c *MetadataCache) AssetPairs(ctx context.Context, api *KrakenAPI) (*AssetPairsResponse, error) {
+	return c.assetPairs.get(ctx, &c.mu, c.ttl, c.callTimeout, api, func(ctx context.Context) (*AssetPairsResponse, error) {
+		return api.AssetPairsWithContext(ctx)
+	})
+}
+
+// Assets returns the cached assets like AssetPairs
+func (c *MetadataCache) Assets(ctx context.Context, api *KrakenAPI) (*AssetsResponse, error) {
+	return c.assets.get(ctx, &c.mu, c.ttl, c.callTimeout, api, func(ctx context.Context) (*AssetsResponse, error) {
+		return api.AssetsWithContext(ctx)
+	})
+}
+
+// Stale reports whether the cache holds entries restored after their TTL
+// which have not been refreshed yet
+func (c *MetadataCache) Stale() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.assetPairs.stale || c.assets.stale
+}
+
+// metadataSnapshot is the serialized form of a MetadataCache
+type metadataSnapshot struct {
+	Version    int                 `json:"version"`
+	Taken      time.Time           `json:"taken"`
+	TTL        time.Duration       `json:"ttl"`
+	AssetPairs *AssetPairsResponse `json:"asset_pairs,omitempty"`
+	Fetched    time.Time           `json:"asset_pairs_fetched"`
+
+	Assets        *AssetsResponse `json:"assets,omitempty"`
+	AssetsFetched time.Time       `json:"assets_fetched"`
+}
+
+// Snapshot writes the cache contents to w, to be restored by Restore after a
+// restart instead of fetching everything again
+func (c *MetadataCache) Snapshot(w io.Writer) error {
+	c.mu.Lock()
+	snapshot := metadataSnapshot{
+		Version:       snapshotVersion,
+		Taken:         time.Now(),
+		TTL:           c.ttl,
+		AssetPairs:    c.assetPairs.value,
+		Fetched:       c.assetPairs.fetched,
+		Assets:        c.assets.value,
+		AssetsFetched: c.assets.fetched,
+	}
+	c.mu.Unlock()
+
+	return json.NewEncoder(w).Encode(snapshot)
+}
+
+// Restore loads a snapshot written by Snapshot, replacing the cache contents.
+// Entries older than the TTL of the cache are kept as stale and refreshed
+// after a random delay of up to a minute, spreading the refreshes of clients
+// restarted at the same time.
+func (c *MetadataCache) Restore(r io.Reader) error {
+	var snapshot metadataSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return fmt.Errorf("invalid metadata snapshot: %w", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return fmt.Errorf("unsupported metadata snapshot version %d", snapshot.Version)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.assetPairs.restore(snapshot.AssetPairs, snapshot.Fetched, c.ttl)
+	c.assets.restore(snapshot.Assets, snapshot.AssetsFetched, c.ttl)
+	return nil
+}
+
+// restoreJitter returns the random delay before refreshing a stale entry
+func restoreJitter(ttl time.Duration) time.Duration {
+	max := maxRestoreJitter
+	if ttl < max {
+		max = ttl
+	}
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(max)))
+}
+
+// assetPairs returns the asset pairs through the metadata cache if the client has one
+func (api *KrakenAPI) assetPairs(ctx context.Context) (*AssetPairsResponse, error) {
+	if api.metadata != nil {
+		return api.metadata.AssetPairs(ctx, api)
+	}
+	return api.AssetPairsWithContext(ctx)
+}
+
+// assets returns the assets through the metadata cache if the client has one
+func (api *KrakenAPI) assets(ctx context.Context) (*AssetsResponse, error) {
+	if api.metadata != nil {
+		return api.metadata.Assets(ctx, api)
+	}
+	return api.AssetsWithContext(ctx)
+}
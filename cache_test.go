@@ -0,0 +1,121 @@
+package krakenapi
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMetadataCacheSnapshot(t *testing.T) {
+	calls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls++
+		return testAssetPairs
+	})
+
+	cache := NewMetadataCache(time.Hour)
+	if _, err := cache.AssetPairs(context.Background(), api); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := cache.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewMetadataCache(time.Hour)
+	if err := restored.Restore(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	pairs, err := restored.AssetPairs(context.Background(), api)
+	if err != nil || len(*pairs) != 2 {
+		t.Fatalf("Restored cache should serve the asset pairs, got %v, %v", pairs, err)
+	}
+	if calls != 1 || restored.Stale() {
+		t.Errorf("A fresh snapshot should be served without fetching, got %d calls", calls)
+	}
+
+	// The snapshot outlived the TTL of the new cache
+	expired := NewMetadataCache(time.Nanosecond)
+	if err := expired.Restore(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !expired.Stale() {
+		t.Errorf("An expired snapshot should be restored as stale")
+	}
+	failing := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":["EService:Unavailable"]}`
+	})
+	if _, err := expired.AssetPairs(context.Background(), failing); err != nil {
+		t.Errorf("Stale entries should be served when the refresh fails, got %s", err)
+	}
+
+	if err := expired.Restore(strings.NewReader(`{"version":99}`)); err == nil {
+		t.Errorf("Restore should reject unknown snapshot versions")
+	}
+}
+
+func TestMetadataCacheSingleFlight(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	api := newTestAPI(func(method string, req *http.Request) string {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return testAssetPairs
+	})
+	cache := NewMetadataCache(time.Hour)
+
+	results := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := cache.AssetPairs(context.Background(), api)
+			results <- err
+		}()
+	}
+
+	// A caller giving up does not wait for the shared fetch, nor fail it
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := cache.AssetPairs(ctx, api); err != context.DeadlineExceeded {
+		t.Errorf("Expected the caller to give up with its context, got %v", err)
+	}
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Concurrent callers should share one fetch, got %d", n)
+	}
+}
+
+// hangFirst returns a handler hanging the first call of method until its
+// request is cancelled, and answering every other call with respond
+func hangFirst(method string, respond func(method string, req *http.Request) string) func(string, *http.Request) string {
+	var hung int32
+	return func(m string, req *http.Request) string {
+		if m == method && atomic.CompareAndSwapInt32(&hung, 0, 1) {
+			<-req.Context().Done()
+			return `{"error":["EGeneral:Internal error"]}`
+		}
+		return respond(m, req)
+	}
+}
+
+func TestMetadataCacheHungFetch(t *testing.T) {
+	api := newTestAPI(hangFirst("AssetPairs", func(string, *http.Request) string { return testAssetPairs }))
+	cache := NewMetadataCache(time.Hour)
+	cache.callTimeout = 20 * time.Millisecond
+
+	if _, err := cache.AssetPairs(context.Background(), api); err == nil {
+		t.Fatal("The hung fetch should time out")
+	}
+	if pairs, err := cache.AssetPairs(context.Background(), api); err != nil || len(*pairs) != 2 {
+		t.Errorf("A new fetch should follow the hung one, got %v %v", pairs, err)
+	}
+}
@@ -0,0 +1,170 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Credentials identify one Kraken account of a ClientSet
+type Credentials struct {
+	Name   string // Name the account is registered under, e.g. "personal"
+	Key    string
+	Secret string
+	// Limiter is the rate limiter of the account's API key, optional
+	Limiter *RateLimiter
+}
+
+// ClientSet manages clients for several Kraken accounts. The clients share
+// the HTTP client, the metadata cache and the hooks, while nonces and rate
+// limiters are kept per account so calls of different accounts never interfere.
+// It is safe for concurrent use.
+type ClientSet struct {
+	clients  map[string]*KrakenAPI
+	names    []string
+	metadata *MetadataCache
+}
+
+// NewClientSet creates one client per credentials. Account names and API
+// keys must be unique, since Kraken tracks nonces per key.
+func NewClientSet(httpClient *http.Client, hooks Hooks, credentials ...Credentials) (*ClientSet, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	set := &ClientSet{
+		clients:  make(map[string]*KrakenAPI, len(credentials)),
+		metadata: NewMetadataCache(time.Hour),
+	}
+	keys := make(map[string]string, len(credentials))
+	for _, c := range credentials {
+		if _, found := set.clients[c.Name]; found {
+			return nil, fmt.Errorf("duplicate account name %q", c.Name)
+		}
+		if other, found := keys[c.Key]; found {
+			return nil, fmt.Errorf("accounts %q and %q use the same API key", other, c.Name)
+		}
+		keys[c.Key] = c.Name
+
+		set.clients[c.Name] = NewWithClient(c.Key, c.Secret, httpClient).
+			WithMetadataCache(set.metadata).
+			WithHooks(hooks)
+		if c.Limiter != nil {
+			set.clients[c.Name].WithRateLimiter(c.Limiter)
+		}
+		set.names = append(set.names, c.Name)
+	}
+	sort.Strings(set.names)
+
+	return set, nil
+}
+
+// Client returns the client of the named account
+func (s *ClientSet) Client(name string) (*KrakenAPI, bool) {
+	api, ok := s.clients[name]
+	return api, ok
+}
+
+// Names returns the sorted account names
+func (s *ClientSet) Names() []string {
+	return append([]string(nil), s.names...)
+}
+
+// MetadataCache returns the cache shared by all clients of the set
+func (s *ClientSet) MetadataCache() *MetadataCache {
+	return s.metadata
+}
+
+// ClientSetBalances holds the balances of every account and their sum
+type ClientSetBalances struct {
+	Accounts map[string]*BalanceResponse // Balances indexed by account name
+	Combined BalanceResponse             // Sum of the balances of all accounts that answered
+	Errors   map[string]error            // Errors indexed by account name
+	Fetched  ComponentTimes              // When the balance of each account was received
+}
+
+// Balances fetches the balances of all accounts concurrently and sums them per asset
+func (s *ClientSet) Balances(ctx context.Context) *ClientSetBalances {
+	result := &ClientSetBalances{
+		Accounts: make(map[string]*BalanceResponse),
+		Combined: make(BalanceResponse),
+		Errors:   make(map[string]error),
+		Fetched:  make(ComponentTimes),
+	}
+
+	var mu sync.Mutex
+	s.each(func(name string, api *KrakenAPI) {
+		balance, err := api.BalanceWithContext(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			result.Errors[name] = err
+			return
+		}
+		result.Accounts[name] = balance
+		result.Fetched[name] = time.Now()
+	})
+
+	totals := make(map[string]*big.Float)
+	for _, name := range s.names {
+		balance, ok := result.Accounts[name]
+		if !ok {
+			continue
+		}
+		for asset, amount := range *balance {
+			value, ok := new(big.Float).SetString(amount)
+			if !ok {
+				result.Errors[name] = fmt.Errorf("invalid %s balance %q", asset, amount)
+				continue
+			}
+			if total, found := totals[asset]; found {
+				total.Add(total, value)
+			} else {
+				totals[asset] = value
+			}
+		}
+	}
+	for asset, total := range totals {
+		result.Combined[asset] = total.Text('f', -1)
+	}
+
+	return result
+}
+
+// CancelAll cancels the open orders of every account concurrently. Errors are
+// indexed by account name; accounts missing from both maps were not reached.
+func (s *ClientSet) CancelAll(ctx context.Context) (map[string]*CancelOrderResponse, map[string]error) {
+	results := make(map[string]*CancelOrderResponse)
+	errs := make(map[string]error)
+
+	var mu sync.Mutex
+	s.each(func(name string, api *KrakenAPI) {
+		resp, err := api.CancelAllWithContext(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs[name] = err
+			return
+		}
+		results[name] = resp
+	})
+
+	return results, errs
+}
+
+// each runs fn for every account concurrently and waits for all of them
+func (s *ClientSet) each(fn func(name string, api *KrakenAPI)) {
+	var wg sync.WaitGroup
+	for _, name := range s.names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			fn(name, s.clients[name])
+		}(name)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,99 @@
+package krakenapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestClientSet(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	base := newTestAPI(func(method string, req *http.Request) string {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[method+" "+req.Header.Get("API-Key")]++
+		switch method {
+		case "Balance":
+			if req.Header.Get("API-Key") == "k1" {
+				return `{"error":[],"result":{"XXBT":"0.1000000000","ZEUR":"10.50"}}`
+			}
+			return `{"error":[],"result":{"XXBT":"0.2500000000"}}`
+		case "CancelAll":
+			return `{"error":[],"result":{"count":2}}`
+		}
+		return `{"error":["EGeneral:Unknown method"]}`
+	})
+
+	var events []RequestEvent
+	hooks := Hooks{OnRequest: func(e RequestEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}}
+	set, err := NewClientSet(base.client, hooks,
+		Credentials{Name: "personal", Key: "k1", Secret: "c2VjcmV0"},
+		Credentials{Name: "corporate", Key: "k2", Secret: "c2VjcmV0"},
+	)
+	if err != nil {
+		t.Fatalf("NewClientSet() should not return an error, got %s", err)
+	}
+
+	if strings.Join(set.Names(), ",") != "corporate,personal" {
+		t.Errorf("Names() should be sorted, got %v", set.Names())
+	}
+
+	balances := set.Balances(context.Background())
+	if len(balances.Errors) != 0 {
+		t.Fatalf("Balances() should not return errors, got %v", balances.Errors)
+	}
+	if balances.Combined["XXBT"] != "0.35" || balances.Combined["ZEUR"] != "10.5" {
+		t.Errorf("Balances() should sum per asset, got %v", balances.Combined)
+	}
+
+	results, errs := set.CancelAll(context.Background())
+	if len(errs) != 0 || len(results) != 2 {
+		t.Errorf("CancelAll() should reach every account, got %v %v", results, errs)
+	}
+	if calls["CancelAll k1"] != 1 || calls["CancelAll k2"] != 1 {
+		t.Errorf("CancelAll() should call each account once, got %v", calls)
+	}
+	if len(events) != 4 || !events[0].Private {
+		t.Errorf("Hooks should see every private request, got %+v", events)
+	}
+}
+
+func TestClientSetDuplicateKey(t *testing.T) {
+	_, err := NewClientSet(nil, Hooks{},
+		Credentials{Name: "a", Key: "k"},
+		Credentials{Name: "b", Key: "k"},
+	)
+	if err == nil {
+		t.Errorf("NewClientSet() should reject accounts sharing an API key")
+	}
+}
+
+func TestNonceIsMonotonic(t *testing.T) {
+	api := New("key", "secret")
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				nonce := api.nextNonce()
+				mu.Lock()
+				if seen[nonce] {
+					t.Errorf("Nonce %s issued twice", nonce)
+				}
+				seen[nonce] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,104 @@
+package krakenapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sergey-lipin/kraken-go-api-client/krakentest"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the conformance snapshots in testdata/conformance and the built-in asset code table")
+
+// conformanceCalls decodes every fixture of the corpus through the public API
+var conformanceCalls = map[string]func(api *KrakenAPI) (interface{}, error){
+	"AddOrder": func(api *KrakenAPI) (interface{}, error) {
+		return api.AddOrder("XBTUSD", "buy", OTLimit, "1.25", map[string]string{"price": "27500.0"})
+	},
+	"AddOrder.validate": func(api *KrakenAPI) (interface{}, error) {
+		return api.AddOrder("XBTUSD", "buy", OTLimit, "1.25", map[string]string{"price": "27500.0", "validate": "true"})
+	},
+	"AssetPairs":          func(api *KrakenAPI) (interface{}, error) { return api.AssetPairs() },
+	"AssetPairs.darkpool": func(api *KrakenAPI) (interface{}, error) { return api.AssetPairs() },
+	"Assets":              func(api *KrakenAPI) (interface{}, error) { return api.Assets() },
+	"Balance":             func(api *KrakenAPI) (interface{}, error) { return api.Balance() },
+	"CancelOrder":         func(api *KrakenAPI) (interface{}, error) { return api.CancelOrder("OU22CG-KLAF2-FWUDD7") },
+	"ClosedOrders":        func(api *KrakenAPI) (interface{}, error) { return api.ClosedOrders(nil) },
+	"DepositAddresses":    func(api *KrakenAPI) (interface{}, error) { return api.DepositAddresses("XBT", "Bitcoin") },
+	"Depth":               func(api *KrakenAPI) (interface{}, error) { return api.Depth("XXBTZEUR", 2) },
+	"Error.unknownOrder":  func(api *KrakenAPI) (interface{}, error) { return api.CancelOrder("OU22CG-KLAF2-FWUDD7") },
+	"Error.withResult":    func(api *KrakenAPI) (interface{}, error) { return api.CancelOrder("OU22CG-KLAF2-FWUDD7") },
+	"Ledgers.staking":     func(api *KrakenAPI) (interface{}, error) { return api.Ledgers(map[string]string{"asset": "DOT.S"}) },
+	"OHLC":                func(api *KrakenAPI) (interface{}, error) { return api.OHLC("XXBTZEUR") },
+	"OpenOrders":          func(api *KrakenAPI) (interface{}, error) { return api.OpenOrders(nil) },
+	"OpenOrders.empty":    func(api *KrakenAPI) (interface{}, error) { return api.OpenOrders(nil) },
+	"QueryOrders":         func(api *KrakenAPI) (interface{}, error) { return api.QueryOrders("OBCMZD-JIEE7-77TH3F", nil) },
+	"QueryOrders.trigger": func(api *KrakenAPI) (interface{}, error) { return api.QueryOrders("OQCLML-BW3P3-BUCMWZ", nil) },
+	"SystemStatus":        func(api *KrakenAPI) (interface{}, error) { return api.SystemStatus() },
+	"Ticker":              func(api *KrakenAPI) (interface{}, error) { return api.Ticker("XXBTZEUR") },
+	"Time":                func(api *KrakenAPI) (interface{}, error) { return api.Time() },
+	"TradeBalance":        func(api *KrakenAPI) (interface{}, error) { return api.TradeBalance(nil) },
+	"TradeVolume":         func(api *KrakenAPI) (interface{}, error) { return api.TradeVolume(nil) },
+	"Trades":              func(api *KrakenAPI) (interface{}, error) { return api.Trades("XXBTZEUR", 0) },
+	"TradesHistory":       func(api *KrakenAPI) (interface{}, error) { return api.TradesHistory(0, 0, nil) },
+	"Withdraw": func(api *KrakenAPI) (interface{}, error) {
+		return api.Withdraw("XBT", "my wallet", big.NewFloat(0.725))
+	},
+	"WithdrawInfo": func(api *KrakenAPI) (interface{}, error) {
+		return api.WithdrawInfo("XBT", "my wallet", big.NewFloat(0.725))
+	},
+}
+
+func TestConformance(t *testing.T) {
+	for _, name := range krakentest.Fixtures() {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			call, ok := conformanceCalls[name]
+			if !ok {
+				t.Fatalf("Fixture %s has no conformance call", name)
+			}
+
+			api := newTestAPI(func(method string, req *http.Request) string {
+				return string(krakentest.MustFixture(name))
+			})
+			result, err := call(api)
+
+			snapshot := map[string]interface{}{"result": result}
+			if err != nil {
+				snapshot = map[string]interface{}{"error": err.Error()}
+			}
+			got, err := json.MarshalIndent(snapshot, "", "  ")
+			if err != nil {
+				t.Fatalf("Result of %s cannot be snapshotted: %s", name, err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", "conformance", name+".golden")
+			if *updateGolden {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Missing snapshot, run go test -run TestConformance -update: %s", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Decoded %s differs from %s:\n%s", name, golden, got)
+			}
+		})
+	}
+}
+
+func TestConformanceCallsHaveFixtures(t *testing.T) {
+	for name := range conformanceCalls {
+		if _, err := krakentest.Fixture(name); err != nil {
+			t.Errorf("Conformance call %s has no fixture", name)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DeadMansSwitch keeps the CancelAllOrdersAfter timer armed while the
+// process is alive. Its methods are safe for concurrent use.
+type DeadMansSwitch struct {
+	api  *KrakenAPI
+	stop context.CancelFunc
+	done chan struct{}
+
+	mu          sync.Mutex
+	triggerTime time.Time
+	err         error
+}
+
+// minDeadMansSwitchInterval is the shortest interval between two refreshes
+// of a DeadMansSwitch
+const minDeadMansSwitchInterval = 100 * time.Millisecond
+
+// KeepDeadMansSwitch arms CancelAllOrdersAfter with timeout and re-arms it
+// every interval until ctx is done or Disarm is called. An interval of zero
+// defaults to a quarter of timeout, and intervals are at least 100ms. When
+// ctx is done the timer is left armed, so the open orders get cancelled once
+// timeout passes, as when the process dies; call Disarm to stop without
+// cancelling them. A timeout that is not positive, which would disarm the
+// timer, or an interval that is not shorter than timeout, which would let
+// it fire between refreshes, stops the switch at once with Err reporting it.
+func (api *KrakenAPI) KeepDeadMansSwitch(ctx context.Context, timeout, interval time.Duration) *DeadMansSwitch {
+	ctx, stop := context.WithCancel(ctx)
+	s := &DeadMansSwitch{api: api, stop: stop, done: make(chan struct{})}
+	if timeout <= 0 {
+		s.err = fmt.Errorf("dead man's switch timeout must be positive, got %v", timeout)
+		close(s.done)
+		return s
+	}
+	if interval >= timeout {
+		s.err = fmt.Errorf("dead man's switch interval %v must be shorter than its timeout %v", interval, timeout)
+		close(s.done)
+		return s
+	}
+	if interval <= 0 {
+		interval = timeout / 4
+	}
+	if interval < minDeadMansSwitchInterval {
+		interval = minDeadMansSwitchInterval
+	}
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			resp, err := api.CancelAllOrdersAfterWithContext(ctx, timeout)
+			if ctx.Err() != nil {
+				return
+			}
+			s.mu.Lock()
+			s.err = err
+			if err == nil {
+				s.triggerTime = resp.TriggerTime
+			}
+			s.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return s
+}
+
+// TriggerTime returns when the open orders get cancelled unless the timer is
+// refreshed, as reported by the last successful refresh
+func (s *DeadMansSwitch) TriggerTime() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.triggerTime
+}
+
+// Err returns the error of the last refresh, nil if it succeeded
+func (s *DeadMansSwitch) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Disarm stops refreshing the timer and disarms it
+func (s *DeadMansSwitch) Disarm(ctx context.Context) error {
+	s.stop()
+	<-s.done
+
+	if _, err := s.api.CancelAllOrdersAfterWithContext(ctx, 0); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.triggerTime = time.Time{}
+	s.mu.Unlock()
+	return nil
+}
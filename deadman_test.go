@@ -0,0 +1,95 @@
+package krakenapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeepDeadMansSwitch(t *testing.T) {
+	var mu sync.Mutex
+	var timeouts []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(body))
+		mu.Lock()
+		timeouts = append(timeouts, params.Get("timeout"))
+		mu.Unlock()
+		if params.Get("timeout") == "0" {
+			return `{"error":[],"result":{"currentTime":"2023-03-24T17:41:56Z","triggerTime":"0"}}`
+		}
+		return `{"error":[],"result":{"currentTime":"2023-03-24T17:41:56Z","triggerTime":"2023-03-24T17:42:56Z"}}`
+	})
+
+	s := api.KeepDeadMansSwitch(context.Background(), 1500*time.Millisecond, time.Millisecond)
+	for {
+		mu.Lock()
+		refreshed := len(timeouts)
+		mu.Unlock()
+		if refreshed >= 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if s.Err() != nil || s.TriggerTime().IsZero() {
+		t.Errorf("Expected an armed switch, got %s (err %v)", s.TriggerTime(), s.Err())
+	}
+
+	if err := s.Disarm(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !s.TriggerTime().IsZero() {
+		t.Errorf("Disarm should clear the trigger time")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, timeout := range timeouts[:len(timeouts)-1] {
+		if timeout != "2" {
+			t.Errorf("Timeouts should be rounded up to whole seconds, got %s", timeout)
+		}
+	}
+	if timeouts[len(timeouts)-1] != "0" {
+		t.Errorf("Disarm should send a zero timeout, got %v", timeouts)
+	}
+}
+
+func TestKeepDeadMansSwitchInvalidTimeout(t *testing.T) {
+	calls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls++
+		return `{"error":[],"result":{"currentTime":"2023-03-24T17:41:56Z","triggerTime":"0"}}`
+	})
+
+	for _, timeout := range []time.Duration{0, -time.Second} {
+		s := api.KeepDeadMansSwitch(context.Background(), timeout, 0)
+		if s.Err() == nil || calls != 0 {
+			t.Errorf("A timeout of %v should stop the switch with an error, got %v after %d calls", timeout, s.Err(), calls)
+		}
+		if err := s.Disarm(context.Background()); err != nil {
+			t.Errorf("Disarm should still work, got %v", err)
+		}
+		calls = 0
+	}
+
+	for _, interval := range []time.Duration{30 * time.Second, 10 * time.Second} {
+		s := api.KeepDeadMansSwitch(context.Background(), 10*time.Second, interval)
+		if s.Err() == nil || calls != 0 {
+			t.Errorf("An interval of %v should stop the switch with an error, got %v after %d calls", interval, s.Err(), calls)
+		}
+	}
+
+	if _, err := api.CancelAllOrdersAfter(-time.Second); err == nil || calls != 0 {
+		t.Errorf("A negative timeout should be rejected before calling Kraken, got %v after %d calls", err, calls)
+	}
+
+	s := api.KeepDeadMansSwitch(context.Background(), 3*time.Nanosecond, 0)
+	defer s.Disarm(context.Background())
+	if s.Err() != nil {
+		t.Errorf("Tiny timeouts should be rounded up rather than fail, got %v", s.Err())
+	}
+}
@@ -0,0 +1,98 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// minDiagnosticsTime is the time that must be left before the context
+// deadline for the diagnostic calls to be made
+const minDiagnosticsTime = 2 * time.Second
+
+// InsufficientFundsError explains an "EOrder:Insufficient funds" rejection
+// of AddOrder. It wraps the original APIError.
+type InsufficientFundsError struct {
+	Err       error
+	Asset     string  // Asset the order spends, e.g. "ZUSD" for a buy on XXBTZUSD
+	Required  float64 // Amount the order needs
+	Available float64 // Balance plus credit minus credit used and holds of open orders
+}
+
+// Shortfall returns how much of Asset is missing
+func (e *InsufficientFundsError) Shortfall() float64 {
+	if e == nil {
+		return 0
+	}
+	return e.Required - e.Available
+}
+
+// Error appends the computed shortfall to the Kraken error
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("%s: order needs %s %s, %s available, short by %s",
+		e.Err,
+		strconv.FormatFloat(e.Required, 'f', -1, 64), e.Asset,
+		strconv.FormatFloat(e.Available, 'f', -1, 64),
+		strconv.FormatFloat(e.Shortfall(), 'f', -1, 64))
+}
+
+// Unwrap returns the original Kraken error
+func (e *InsufficientFundsError) Unwrap() error {
+	return e.Err
+}
+
+// WithInsufficientFundsDiagnostics makes AddOrder explain "EOrder:Insufficient
+// funds" errors with an InsufficientFundsError. This costs a BalanceEx and an
+// AssetPairs call per rejection, which are skipped when less than two seconds
+// are left before the context deadline.
+func (api *KrakenAPI) WithInsufficientFundsDiagnostics(enabled bool) *KrakenAPI {
+	api.diagnoseFunds = enabled
+	return api
+}
+
+// diagnoseInsufficientFunds returns err enriched with the shortfall of the
+// order, or err itself when the shortfall cannot be computed
+func (api *KrakenAPI) diagnoseInsufficientFunds(ctx context.Context, err error, pair, direction, volume string, args map[string]string) error {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < minDiagnosticsTime {
+		return err
+	}
+	if leverage, parseErr := parseLeverageArg(args["leverage"]); parseErr != nil || !leverage.IsNone() {
+		// Margin orders are limited by the margin available, not the balance
+		return err
+	}
+
+	vol, parseErr := strconv.ParseFloat(volume, 64)
+	if parseErr != nil {
+		return err
+	}
+
+	pairs, pairErr := api.assetPairs(ctx)
+	if pairErr != nil {
+		return err
+	}
+	names, ok := pairs.FindPair(pair)
+	if !ok {
+		return err
+	}
+	info := (*pairs)[names.Name]
+
+	diag := &InsufficientFundsError{Err: err, Asset: info.Base, Required: vol}
+	if direction == "buy" {
+		price, parseErr := strconv.ParseFloat(args["price"], 64)
+		if parseErr != nil {
+			// Market orders have no price to compute the cost from
+			return err
+		}
+		diag.Asset = info.Quote
+		diag.Required = vol * price
+	}
+
+	balances, balanceErr := api.BalanceExWithContext(ctx)
+	if balanceErr != nil {
+		return err
+	}
+	diag.Available = balances.Get(diag.Asset).Available()
+
+	return diag
+}
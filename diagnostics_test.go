@@ -0,0 +1,73 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func insufficientFundsAPI(calls map[string]int) *KrakenAPI {
+	return newTestAPI(func(method string, req *http.Request) string {
+		calls[method]++
+		switch method {
+		case "AddOrder":
+			return `{"error":["EOrder:Insufficient funds"]}`
+		case "AssetPairs":
+			return testAssetPairs
+		case "BalanceEx":
+			return `{"error":[],"result":{"ZUSD":{"balance":"1000.00","hold_trade":"400.00"},"XXBT":{"balance":"0.5","hold_trade":"0"}}}`
+		}
+		return `{"error":["EGeneral:Unknown method"]}`
+	})
+}
+
+func TestInsufficientFundsDiagnostics(t *testing.T) {
+	calls := make(map[string]int)
+	api := insufficientFundsAPI(calls).WithInsufficientFundsDiagnostics(true)
+
+	_, err := api.AddOrder("XBTUSD", "buy", OTLimit, "0.03", map[string]string{"price": "30000"})
+	var diag *InsufficientFundsError
+	if !errors.As(err, &diag) {
+		t.Fatalf("AddOrder() should explain the error, got %v", err)
+	}
+	if diag.Asset != "ZUSD" || diag.Required != 900 || diag.Available != 600 || diag.Shortfall() != 300 {
+		t.Errorf("Unexpected diagnostics %+v", diag)
+	}
+	if !hasErrorCode(err, ErrCodeInsufficientFunds) {
+		t.Errorf("Diagnostics should wrap the Kraken error")
+	}
+	if !strings.Contains(err.Error(), "short by 300") {
+		t.Errorf("Error should state the shortfall, got %s", err)
+	}
+
+	calls["BalanceEx"] = 0
+	api.AddOrder("XBTUSD", "buy", OTLimit, "0.03", map[string]string{"price": "30000", "leverage": "2:1"})
+	if calls["BalanceEx"] != 0 {
+		t.Errorf("Margin orders should not be checked against the balance")
+	}
+
+	_, err = api.AddOrder("XBTUSD", "sell", OTMarket, "0.75", map[string]string{"leverage": "none"})
+	if !errors.As(err, &diag) || diag.Asset != "XXBT" || diag.Shortfall() != 0.25 {
+		t.Errorf("Sell orders should be checked against the base asset, got %v", err)
+	}
+}
+
+func TestInsufficientFundsDiagnosticsOptIn(t *testing.T) {
+	calls := make(map[string]int)
+	api := insufficientFundsAPI(calls)
+	api.AddOrder("XBTUSD", "buy", OTLimit, "0.03", map[string]string{"price": "30000"})
+	if calls["BalanceEx"] != 0 {
+		t.Errorf("Diagnostics should be opt-in")
+	}
+
+	api.WithInsufficientFundsDiagnostics(true)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	api.AddOrderWithContext(ctx, "XBTUSD", "buy", OTLimit, "0.03", map[string]string{"price": "30000"})
+	if calls["BalanceEx"] != 0 {
+		t.Errorf("Diagnostics should be skipped close to the deadline")
+	}
+}
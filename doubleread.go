@@ -0,0 +1,198 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// doubleReadGap is the pause between the two reads of WithDoubleRead
+const doubleReadGap = 250 * time.Millisecond
+
+// ErrInconsistentRead is matched by every *InconsistentReadError
+var ErrInconsistentRead = errors.New("inconsistent read")
+
+// InconsistentReadError is returned by reads made with WithDoubleRead whose
+// two results disagree. First and Second hold both results, of the type the
+// read returns.
+type InconsistentReadError struct {
+	Method string
+	Path   string // First value found to differ, e.g. "[ZUSD]" or ".Open[OQCLML-BW3P3-BUCMWZ].VolumeExecuted"
+	First  interface{}
+	Second interface{}
+}
+
+func (e *InconsistentReadError) Error() string {
+	return fmt.Sprintf("inconsistent read of %s: %s differs between two reads", e.Method, e.Path)
+}
+
+// Unwrap returns ErrInconsistentRead
+func (e *InconsistentReadError) Unwrap() error {
+	return ErrInconsistentRead
+}
+
+type doubleReadKey struct{}
+
+// WithDoubleRead makes the reads made with ctx fetch twice, a short gap
+// apart, and fail with an *InconsistentReadError unless both results agree,
+// e.g. for the balance before a large withdrawal. Numbers may differ by at
+// most tolerance, compared exactly for decimal strings; everything else must
+// be equal. Only idempotent endpoints are read twice, the others ignore the
+// option. Each read costs its rate limit budget.
+func WithDoubleRead(ctx context.Context, tolerance float64) context.Context {
+	return context.WithValue(ctx, doubleReadKey{}, tolerance)
+}
+
+// doubleRead runs query into typ and, if ctx asks for it and method is a
+// read, once more into a fresh value, checking that both results agree
+func (api *KrakenAPI) doubleRead(ctx context.Context, method string, typ interface{}, query func(typ interface{}) (interface{}, error)) (interface{}, error) {
+	first, err := query(typ)
+	tolerance, ok := ctx.Value(doubleReadKey{}).(float64)
+	if err != nil || !ok {
+		return first, err
+	}
+	if endpoint, known := LookupEndpoint(method); !known || endpoint.Idempotency != Idempotent {
+		return first, nil
+	}
+	if _, streamed := typ.(*resultStream); streamed {
+		return first, nil
+	}
+
+	timer := time.NewTimer(doubleReadGap)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+		return nil, ctx.Err()
+	case <-timer.C:
+	}
+
+	var fresh interface{}
+	if typ != nil {
+		fresh = reflect.New(reflect.TypeOf(typ).Elem()).Interface()
+	}
+	second, err := query(fresh)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := new(big.Rat)
+	if !math.IsInf(tolerance, 0) && !math.IsNaN(tolerance) {
+		limit.SetFloat64(math.Abs(tolerance))
+	}
+	if path, same := sameRead(reflect.ValueOf(first), reflect.ValueOf(second), limit, ""); !same {
+		if path == "" {
+			path = "result"
+		}
+		return nil, &InconsistentReadError{Method: method, Path: path, First: first, Second: second}
+	}
+	return first, nil
+}
+
+var (
+	bigFloatType = reflect.TypeOf(big.Float{})
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// sameRead compares two decoded results, returning the path of the first
+// value differing by more than tolerance
+func sameRead(a, b reflect.Value, tolerance *big.Rat, path string) (string, bool) {
+	if a.IsValid() != b.IsValid() {
+		return path, false
+	}
+	if !a.IsValid() {
+		return "", true
+	}
+	if a.Type() != b.Type() {
+		return path, false
+	}
+
+	switch a.Type() {
+	case bigFloatType:
+		x, _ := copyValue(a).Interface().(*big.Float).Rat(nil)
+		y, _ := copyValue(b).Interface().(*big.Float).Rat(nil)
+		return path, x != nil && y != nil && withinTolerance(x, y, tolerance)
+	case timeType:
+		return path, a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+	}
+
+	switch a.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if a.IsNil() || b.IsNil() {
+			return path, a.IsNil() == b.IsNil()
+		}
+		return sameRead(a.Elem(), b.Elem(), tolerance, path)
+	case reflect.Struct:
+		for i := 0; i < a.NumField(); i++ {
+			if !a.Type().Field(i).IsExported() {
+				continue
+			}
+			if p, same := sameRead(a.Field(i), b.Field(i), tolerance, path+"."+a.Type().Field(i).Name); !same {
+				return p, false
+			}
+		}
+		return "", true
+	case reflect.Slice, reflect.Array:
+		if a.Len() != b.Len() {
+			return path, false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if p, same := sameRead(a.Index(i), b.Index(i), tolerance, fmt.Sprintf("%s[%d]", path, i)); !same {
+				return p, false
+			}
+		}
+		return "", true
+	case reflect.Map:
+		if a.Len() != b.Len() {
+			return path, false
+		}
+		keys := a.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			keyPath := fmt.Sprintf("%s[%v]", path, key)
+			other := b.MapIndex(key)
+			if !other.IsValid() {
+				return keyPath, false
+			}
+			if p, same := sameRead(a.MapIndex(key), other, tolerance, keyPath); !same {
+				return p, false
+			}
+		}
+		return "", true
+	case reflect.String:
+		if a.String() == b.String() {
+			return "", true
+		}
+		x, okX := new(big.Rat).SetString(a.String())
+		y, okY := new(big.Rat).SetString(b.String())
+		return path, okX && okY && withinTolerance(x, y, tolerance)
+	case reflect.Float32, reflect.Float64:
+		tol, _ := tolerance.Float64()
+		return path, math.Abs(a.Float()-b.Float()) <= tol
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return path, withinTolerance(new(big.Rat).SetInt64(a.Int()), new(big.Rat).SetInt64(b.Int()), tolerance)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x := new(big.Rat).SetInt(new(big.Int).SetUint64(a.Uint()))
+		y := new(big.Rat).SetInt(new(big.Int).SetUint64(b.Uint()))
+		return path, withinTolerance(x, y, tolerance)
+	case reflect.Bool:
+		return path, a.Bool() == b.Bool()
+	}
+	return path, reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// copyValue returns a pointer to a copy of v, which need not be addressable
+func copyValue(v reflect.Value) reflect.Value {
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p
+}
+
+func withinTolerance(x, y, tolerance *big.Rat) bool {
+	diff := new(big.Rat).Sub(x, y)
+	return diff.Abs(diff).Cmp(tolerance) <= 0
+}
@@ -0,0 +1,74 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestDoubleRead(t *testing.T) {
+	var balances []string
+	calls := make(map[string]int)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls[method]++
+		switch method {
+		case "Balance":
+			balance := balances[0]
+			balances = balances[1:]
+			return `{"error":[],"result":{"ZUSD":"` + balance + `","XXBT":"0.5"}}`
+		case "Earn/Allocations":
+			return `{"error":[],"result":{"converted_asset":"USD","total_allocated":"` + fmt.Sprint(calls[method]*100) + `","total_rewarded":"0","items":[]}}`
+		case "AddOrder":
+			return `{"error":[],"result":{"descr":{"order":"buy 1 XBTUSD @ market"},"txid":["OUF4EM-FRGI2-MQMWZD"]}}`
+		}
+		executed := "0"
+		if calls[method] > 1 {
+			executed = "0.4"
+		}
+		return `{"error":[],"result":{"open":{"OQCLML-BW3P3-BUCMWZ":{"status":"open","vol":"1","vol_exec":"` + executed + `"}}}}`
+	})
+	ctx := WithDoubleRead(context.Background(), 0.01)
+
+	balances = []string{"1000.0000", "1000.00"}
+	resp, err := api.BalanceWithContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls["Balance"] != 2 || (*resp)["ZUSD"] != "1000.0000" {
+		t.Errorf("Expected two reads returning the first, got %d and %v", calls["Balance"], resp)
+	}
+
+	balances = []string{"1000.00", "1000.005", "1000.00", "900.00"}
+	if _, err := api.BalanceWithContext(ctx); err != nil {
+		t.Errorf("Differences within the tolerance should pass, got %s", err)
+	}
+	_, err = api.BalanceWithContext(ctx)
+	var inconsistent *InconsistentReadError
+	if !errors.As(err, &inconsistent) || !errors.Is(err, ErrInconsistentRead) {
+		t.Fatalf("Expected an inconsistent read, got %v", err)
+	}
+	if inconsistent.Method != "Balance" || inconsistent.Path != "[ZUSD]" || (*inconsistent.Second.(*BalanceResponse))["ZUSD"] != "900.00" {
+		t.Errorf("Unexpected error %+v", inconsistent)
+	}
+
+	_, err = api.OpenOrdersWithContext(ctx, nil)
+	if !errors.As(err, &inconsistent) || inconsistent.Path != ".Open[OQCLML-BW3P3-BUCMWZ].VolumeExecuted" {
+		t.Errorf("A fill between the reads should be reported, got %v", err)
+	}
+
+	if _, err := api.AddOrderWithContext(ctx, "XBTUSD", "buy", OTMarket, "1", nil); err != nil || calls["AddOrder"] != 1 {
+		t.Errorf("Orders should never be sent twice, got %d calls and %v", calls["AddOrder"], err)
+	}
+
+	_, err = api.EarnAllocationsWithContext(ctx, "USD", false)
+	if !errors.As(err, &inconsistent) || inconsistent.Path != ".TotalAllocated" || calls["Earn/Allocations"] != 2 {
+		t.Errorf("JSON bodied reads should be read twice too, got %d calls and %v", calls["Earn/Allocations"], err)
+	}
+
+	balances = []string{"1000.00"}
+	if _, err := api.Balance(); err != nil || calls["Balance"] != 7 {
+		t.Errorf("Reads without the option should be made once, got %d calls and %v", calls["Balance"], err)
+	}
+}
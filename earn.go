@@ -0,0 +1,63 @@
+package krakenapi
+
+import (
+	"context"
+	"time"
+)
+
+// EarnLockKind is the kind of lock of an Earn strategy
+type EarnLockKind string
+
+// Earn lock kinds
+const (
+	EarnLockFlex    EarnLockKind = "flex"    // Funds can be deallocated at any time
+	EarnLockBonded  EarnLockKind = "bonded"  // Funds go through bonding and unbonding periods
+	EarnLockTimed   EarnLockKind = "timed"   // Funds are locked for a fixed duration
+	EarnLockInstant EarnLockKind = "instant" // Funds are allocated and deallocated instantly
+)
+
+// DefaultEarnPollInterval is the interval WaitForAllocation and
+// WaitForDeallocation poll at when given no positive interval
+const DefaultEarnPollInterval = 5 * time.Second
+
+// WaitForAllocation polls EarnAllocateStatus every interval until the last
+// allocation to the strategy is no longer pending, or ctx is done. An
+// interval that is not positive defaults to DefaultEarnPollInterval.
+func (api *KrakenAPI) WaitForAllocation(ctx context.Context, strategyID string, interval time.Duration) error {
+	return waitForEarn(ctx, interval, func() (*EarnOperationStatus, error) {
+		return api.EarnAllocateStatusWithContext(ctx, strategyID)
+	})
+}
+
+// WaitForDeallocation polls EarnDeallocateStatus every interval until the
+// last deallocation from the strategy is no longer pending, or ctx is done.
+// This may last until the end of the unbonding period of the strategy. An
+// interval that is not positive defaults to DefaultEarnPollInterval.
+func (api *KrakenAPI) WaitForDeallocation(ctx context.Context, strategyID string, interval time.Duration) error {
+	return waitForEarn(ctx, interval, func() (*EarnOperationStatus, error) {
+		return api.EarnDeallocateStatusWithContext(ctx, strategyID)
+	})
+}
+
+// waitForEarn polls status every interval until it is no longer pending
+func waitForEarn(ctx context.Context, interval time.Duration, status func() (*EarnOperationStatus, error)) error {
+	if interval <= 0 {
+		interval = DefaultEarnPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		resp, err := status()
+		if err != nil {
+			return err
+		}
+		if !resp.Pending {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
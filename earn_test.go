@@ -0,0 +1,191 @@
+package krakenapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestEarnAllocate(t *testing.T) {
+	var body map[string]interface{}
+	statusCalls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body = nil
+		json.NewDecoder(req.Body).Decode(&body)
+		switch method {
+		case "Earn/Allocate":
+			return `{"error":[],"result":true}`
+		case "Earn/AllocateStatus":
+			statusCalls++
+			if statusCalls < 3 {
+				return `{"error":[],"result":{"pending":true}}`
+			}
+			return `{"error":[],"result":{"pending":false}}`
+		}
+		t.Errorf("Unexpected call to %s", method)
+		return ""
+	})
+
+	accepted, err := api.EarnAllocate("ESRFUO3-Q62XD-WIOIL7", "12.5")
+	if err != nil || !accepted {
+		t.Fatalf("Expected the allocation to be accepted, got %v, %v", accepted, err)
+	}
+	if body["strategy_id"] != "ESRFUO3-Q62XD-WIOIL7" || body["amount"] != "12.5" {
+		t.Errorf("Unexpected request %v", body)
+	}
+
+	status, err := api.EarnAllocateStatus("ESRFUO3-Q62XD-WIOIL7")
+	if err != nil || !status.Pending {
+		t.Errorf("Expected a pending allocation, got %+v, %v", status, err)
+	}
+
+	if err := api.WaitForAllocation(context.Background(), "ESRFUO3-Q62XD-WIOIL7", time.Millisecond); err != nil || statusCalls != 3 {
+		t.Errorf("Expected to poll until the allocation completed, got %d calls and %v", statusCalls, err)
+	}
+
+	statusCalls = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := api.WaitForAllocation(ctx, "ESRFUO3-Q62XD-WIOIL7", time.Hour); err != context.DeadlineExceeded {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+
+	statusCalls = 0
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := api.WaitForAllocation(ctx, "ESRFUO3-Q62XD-WIOIL7", 0); err != context.DeadlineExceeded || statusCalls != 1 {
+		t.Errorf("A zero interval should poll at the default interval, got %d calls and %v", statusCalls, err)
+	}
+}
+
+func TestEarnDeallocate(t *testing.T) {
+	var body map[string]interface{}
+	statusCalls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body = nil
+		json.NewDecoder(req.Body).Decode(&body)
+		switch method {
+		case "Earn/Deallocate":
+			if body["amount"] == "0.0001" {
+				return `{"error":["EEarn:Below min:(De)allocation operation amount less than minimum"]}`
+			}
+			return `{"error":[],"result":true}`
+		case "Earn/DeallocateStatus":
+			statusCalls++
+			return `{"error":[],"result":{"pending":` + strconv.FormatBool(statusCalls < 2) + `}}`
+		}
+		t.Errorf("Unexpected call to %s", method)
+		return ""
+	})
+
+	accepted, err := api.EarnDeallocate("ESRFUO3-Q62XD-WIOIL7", "2.5000000000")
+	if err != nil || !accepted || body["amount"] != "2.5000000000" {
+		t.Fatalf("Expected the deallocation to be accepted as sent, got %v, %v with %v", accepted, err, body)
+	}
+	if err := api.WaitForDeallocation(context.Background(), "ESRFUO3-Q62XD-WIOIL7", time.Millisecond); err != nil || statusCalls != 2 {
+		t.Errorf("Expected to poll until the deallocation completed, got %d calls and %v", statusCalls, err)
+	}
+
+	_, err = api.EarnDeallocate("ESRFUO3-Q62XD-WIOIL7", "0.0001")
+	if !errors.Is(err, ErrEarnBelowMin) || errors.Is(err, ErrFundingInsufficientFunds) {
+		t.Errorf("Expected a below minimum error, got %v", err)
+	}
+}
+
+func TestEarnStrategies(t *testing.T) {
+	var bodies []map[string]interface{}
+	api := newTestAPI(func(method string, req *http.Request) string {
+		var body map[string]interface{}
+		json.NewDecoder(req.Body).Decode(&body)
+		bodies = append(bodies, body)
+		if body["cursor"] == nil {
+			return `{"error":[],"result":{"next_cursor":"2","items":[{"id":"ESRFUO3-Q62XD-WIOIL7","asset":"DOT",
+				"lock_type":{"type":"instant","payout_frequency":604800},"apr_estimate":{"low":"8.0000","high":"12.0000"},
+				"user_min_allocation":"0.01","allocation_fee":"0.0000","deallocation_fee":"0.0000",
+				"auto_compound":{"type":"enabled"},"yield_source":{"type":"staking"},"can_allocate":true,"can_deallocate":true}]}}`
+		}
+		return `{"error":[],"result":{"next_cursor":null,"items":[{"id":"ES3IA5J-ZDTSV-ODL2R6","asset":"DOT",
+			"lock_type":{"type":"bonded","bonding_period":0,"unbonding_period":2419200,"exit_queue_period":0,"payout_frequency":604800},
+			"apr_estimate":{"low":"15.0000","high":"18.0000"},"user_min_allocation":"1","user_cap":"1000",
+			"allocation_fee":"0","deallocation_fee":"0","can_allocate":true,"can_deallocate":false}]}}`
+	})
+
+	args := EarnStrategiesArgs{Asset: "DOT", LockTypes: []EarnLockKind{EarnLockInstant, EarnLockBonded}, Limit: 1}
+	var strategies []EarnStrategy
+	for {
+		page, err := api.EarnStrategies(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		strategies = append(strategies, page.Items...)
+		if page.NextCursor == "" {
+			break
+		}
+		args.Cursor = page.NextCursor
+	}
+
+	if len(bodies) != 2 || bodies[0]["asset"] != "DOT" || bodies[0]["limit"] != float64(1) || bodies[1]["cursor"] != "2" {
+		t.Errorf("Unexpected requests %v", bodies)
+	}
+	if locks, _ := bodies[0]["lock_type"].([]interface{}); len(locks) != 2 || locks[0] != "instant" || bodies[0]["ascending"] != nil {
+		t.Errorf("Unexpected lock type filter %v", bodies[0])
+	}
+	if len(strategies) != 2 {
+		t.Fatalf("Expected two strategies, got %+v", strategies)
+	}
+	instant, bonded := strategies[0], strategies[1]
+	if instant.LockType.Type != EarnLockInstant || instant.APREstimate.High != 12 || instant.UserMinAllocation != 0.01 || !instant.CanDeallocate {
+		t.Errorf("Unexpected strategy %+v", instant)
+	}
+	if bonded.LockType.UnbondingPeriod != 28*24*3600 || bonded.UserCap != 1000 || bonded.CanDeallocate {
+		t.Errorf("Unexpected strategy %+v", bonded)
+	}
+}
+
+func TestEarnAllocations(t *testing.T) {
+	var body map[string]interface{}
+	api := newTestAPI(func(method string, req *http.Request) string {
+		json.NewDecoder(req.Body).Decode(&body)
+		return `{"error":[],"result":{"converted_asset":"EUR","total_allocated":"49.2398","total_rewarded":"0.0675","items":[
+			{"strategy_id":"ESDQCOL-WTZEU-NU55QF","native_asset":"ETH","amount_allocated":{
+				"bonding":{"native":"0.0210000000","converted":"39.0645","allocation_count":1,"holds":[
+					{"native":"0.0210000000","converted":"39.0645","created_at":"2023-07-06T10:52:05Z","expires":"2023-08-19T02:34:05.807Z"}]},
+				"total":{"native":"0.0210000000","converted":"39.0645"}},
+			"total_rewarded":{"native":"0","converted":"0.0000"}},
+			{"strategy_id":"ESRFUO3-Q62XD-WIOIL7","native_asset":"DOT","amount_allocated":{
+				"unbonding":{"native":"0.5","converted":"2.1753","allocation_count":1,"holds":[]},
+				"total":{"native":"2.5","converted":"10.1753"}},
+			"total_rewarded":{"native":"0.0155","converted":"0.0675"}}]}}`
+	})
+
+	resp, err := api.EarnAllocations("EUR", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body["converted_asset"] != "EUR" || body["hide_zero_allocations"] != true {
+		t.Errorf("Unexpected request %v", body)
+	}
+	if resp.ConvertedAsset != "EUR" || resp.TotalAllocated != 49.2398 || len(resp.Items) != 2 {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+	eth := resp.Items[0].AmountAllocated
+	if eth.Total.Native != 0.021 || eth.Bonding.Converted != 39.0645 || eth.Bonding.AllocationCount != 1 || eth.Unbonding.Native != 0 {
+		t.Errorf("Unexpected amounts %+v", eth)
+	}
+	if len(eth.Bonding.Holds) != 1 || !eth.Bonding.Holds[0].Expires.Equal(time.Date(2023, 8, 19, 2, 34, 5, 807e6, time.UTC)) {
+		t.Errorf("Unexpected holds %+v", eth.Bonding.Holds)
+	}
+	if dot := resp.Items[1]; dot.NativeAsset != "DOT" || dot.AmountAllocated.Unbonding.Native != 0.5 || dot.TotalRewarded.Native != 0.0155 {
+		t.Errorf("Unexpected allocation %+v", dot)
+	}
+
+	body = nil
+	api.EarnAllocations("", false)
+	if body["converted_asset"] != nil || body["hide_zero_allocations"] != nil {
+		t.Errorf("Defaults should not be sent, got %v", body)
+	}
+}
@@ -0,0 +1,149 @@
+package krakenapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultParamDecimals is the precision of amounts sent to Kraken when the
+// decimals of the asset or pair are not known
+const DefaultParamDecimals = 10
+
+// FormatDecimal renders value for a request parameter: plain decimal notation,
+// never an exponent, rounded to decimals places and without trailing zeros.
+// Negative decimals use DefaultParamDecimals.
+func FormatDecimal(value float64, decimals int) string {
+	if decimals < 0 {
+		decimals = DefaultParamDecimals
+	}
+	return trimZeros(strconv.FormatFloat(value, 'f', decimals, 64))
+}
+
+// FormatBigDecimal renders value like FormatDecimal
+func FormatBigDecimal(value *big.Float, decimals int) string {
+	if decimals < 0 {
+		decimals = DefaultParamDecimals
+	}
+	return trimZeros(value.Text('f', decimals))
+}
+
+// FormatPrice renders a price of the pair with the pair's precision
+func (info AssetPairInfo) FormatPrice(price float64) string {
+	return FormatDecimal(price, info.PairDecimals)
+}
+
+// FormatVolume renders a volume of the pair with the pair's lot precision
+func (info AssetPairInfo) FormatVolume(volume float64) string {
+	return FormatDecimal(volume, info.LotDecimals)
+}
+
+// FormatAmount renders an amount of the asset with the asset's precision
+func (info AssetInfo) FormatAmount(amount float64) string {
+	return FormatDecimal(amount, info.Decimals)
+}
+
+// truncationDigits is the number of digits past the cut of truncateDecimal
+// to which a value is first rounded
+const truncationDigits = 6
+
+// truncateDecimal renders value like FormatDecimal, but cuts the digits past
+// decimals places instead of rounding them, e.g. for volumes and amounts
+// that must not exceed what is available. The value is first rounded a few
+// digits past the cut, so that float noise such as 1 - 0.9 reading as
+// 0.09999999999999998 does not lose a unit of the last decimal.
+func truncateDecimal(value float64, decimals int) string {
+	if decimals < 0 {
+		decimals = DefaultParamDecimals
+	}
+	s := strconv.FormatFloat(value, 'f', decimals+truncationDigits, 64)
+	if point := strings.IndexByte(s, '.'); point >= 0 {
+		s = s[:point+1+decimals]
+	}
+	return trimZeros(s)
+}
+
+// trimZeros removes the trailing zeros of the fraction, and the point if nothing is left
+func trimZeros(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// EncodeParams returns the canonical form encoding of private request
+// parameters, which is the exact body the client signs and sends: the nonce
+// first, then all other keys sorted, each key's values in the given order.
+// Identical parameters always produce byte-identical bodies.
+func EncodeParams(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if key != "nonce" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := values["nonce"]; ok {
+		keys = append([]string{"nonce"}, keys...)
+	}
+
+	var buf strings.Builder
+	for _, key := range keys {
+		escapedKey := url.QueryEscape(key)
+		for _, value := range values[key] {
+			if buf.Len() > 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(escapedKey)
+			buf.WriteByte('=')
+			buf.WriteString(url.QueryEscape(value))
+		}
+	}
+	return buf.String()
+}
+
+// EncodeJSONParams returns the canonical JSON encoding of private request
+// parameters sent as JSON document: the nonce first, then all other keys
+// sorted. Nested objects are encoded with sorted keys as well.
+func EncodeJSONParams(params map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if key != "nonce" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if _, ok := params["nonce"]; ok {
+		keys = append([]string{"nonce"}, keys...)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(params[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,118 @@
+package krakenapi
+
+import (
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEncodeParams(t *testing.T) {
+	values := url.Values{
+		"volume":       {"1.25"},
+		"pair":         {"XBTUSD"},
+		"nonce":        {"1688669448000000000"},
+		"close[price]": {"26500"},
+		"oflags":       {"post", "fciq"},
+	}
+
+	want := "nonce=1688669448000000000&close%5Bprice%5D=26500&oflags=post&oflags=fciq&pair=XBTUSD&volume=1.25"
+	for i := 0; i < 20; i++ {
+		if got := EncodeParams(values); got != want {
+			t.Fatalf("EncodeParams() = %s, want %s", got, want)
+		}
+	}
+
+	delete(values, "nonce")
+	if got := EncodeParams(values); got != values.Encode() {
+		t.Errorf("Without nonce EncodeParams() should match url.Values.Encode(), got %s", got)
+	}
+}
+
+func TestEncodeJSONParams(t *testing.T) {
+	params := map[string]interface{}{
+		"orders":   []string{"O1", "O2"},
+		"nonce":    "1688669448000000000",
+		"deadline": "2023-07-06T18:50:48Z",
+	}
+
+	want := `{"nonce":"1688669448000000000","deadline":"2023-07-06T18:50:48Z","orders":["O1","O2"]}`
+	for i := 0; i < 20; i++ {
+		got, err := EncodeJSONParams(params)
+		if err != nil || string(got) != want {
+			t.Fatalf("EncodeJSONParams() = %s (%v), want %s", got, err, want)
+		}
+	}
+}
+
+func TestPrivateBodyIsCanonical(t *testing.T) {
+	var bodies []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		bodies = append(bodies, string(body))
+		return `{"error":[],"result":{}}`
+	})
+
+	args := map[string]string{"price": "27500.0", "oflags": "post", "userref": "7", "validate": "true"}
+	api.AddOrder("XBTUSD", "buy", OTLimit, "1.25", args)
+	api.AddOrder("XBTUSD", "buy", OTLimit, "1.25", args)
+
+	for i, body := range bodies {
+		if !strings.HasPrefix(body, "nonce=") {
+			t.Errorf("Body should start with the nonce, got %s", body)
+		}
+		bodies[i] = body[strings.Index(body, "&"):]
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("Identical requests should produce identical bodies apart from the nonce:\n%s\n%s", bodies[0], bodies[1])
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	for exp := -10; exp <= 10; exp++ {
+		for _, mantissa := range []float64{1, 1.23456789, 9.87654321} {
+			value := mantissa * math.Pow(10, float64(exp))
+			for _, decimals := range []int{-1, 0, 2, 8} {
+				got := FormatDecimal(value, decimals)
+				if strings.ContainsAny(got, "eE") {
+					t.Fatalf("FormatDecimal(%g, %d) = %s uses an exponent", value, decimals, got)
+				}
+				parsed, err := strconv.ParseFloat(got, 64)
+				if err != nil {
+					t.Fatalf("FormatDecimal(%g, %d) = %s is not a number", value, decimals, got)
+				}
+				precision := decimals
+				if precision < 0 {
+					precision = DefaultParamDecimals
+				}
+				if math.Abs(parsed-value) > 0.5*math.Pow(10, -float64(precision))*(1+1e-9) {
+					t.Errorf("FormatDecimal(%g, %d) = %s loses more than its declared precision", value, decimals, got)
+				}
+			}
+		}
+	}
+
+	cases := []struct{ got, want string }{
+		{FormatDecimal(1e-7, -1), "0.0000001"},
+		{FormatDecimal(1.5, 8), "1.5"},
+		{FormatDecimal(-0.0000000001, 8), "0"},
+		{FormatDecimal(12345678901, 2), "12345678901"},
+		{FormatBigDecimal(big.NewFloat(0.00000001), 8), "0.00000001"},
+		{AssetPairInfo{PairDecimals: 1}.FormatPrice(27500.04), "27500"},
+		{truncateDecimal(1.999999999, 8), "1.99999999"},
+		{truncateDecimal(0.3, 8), "0.3"},
+		{truncateDecimal(-0.00000001, 2), "0"},
+		{truncateDecimal(12.5, 0), "12"},
+		{truncateDecimal(1-0.9, 8), "0.1"},
+		{truncateDecimal(0.123456789012, -1), "0.123456789"},
+	}
+	for _, c := range cases {
+		if c.got != c.want {
+			t.Errorf("Expected %s, got %s", c.want, c.got)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package krakenapi
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+// Idempotency classifies whether repeating a request to an endpoint is safe
+type Idempotency int
+
+// Idempotency classes
+const (
+	// NotIdempotent endpoints may execute their action twice when repeated, e.g. AddOrder or Withdraw
+	NotIdempotent Idempotency = iota
+	// Idempotent endpoints only read state and are always safe to repeat
+	Idempotent
+	// IdempotentInEffect endpoints change state, but repeating them has no additional effect, e.g. CancelOrder
+	IdempotentInEffect
+)
+
+// String returns the name of the idempotency class
+func (i Idempotency) String() string {
+	switch i {
+	case Idempotent:
+		return "idempotent"
+	case IdempotentInEffect:
+		return "idempotent-in-effect"
+	}
+	return "not-idempotent"
+}
+
+// ParamType is the type of a request parameter as Kraken parses it
+type ParamType string
+
+// Parameter types
+const (
+	ParamString    ParamType = "string"
+	ParamInt       ParamType = "int"
+	ParamBool      ParamType = "bool"
+	ParamDecimal   ParamType = "decimal"   // Decimal number sent as string to keep its precision
+	ParamTimestamp ParamType = "timestamp" // Unix timestamp or, where accepted, a txid
+	ParamList      ParamType = "list"      // Comma separated list, or a JSON array for JSON bodies
+)
+
+// Param describes a request parameter of an endpoint
+type Param struct {
+	Name     string
+	Type     ParamType
+	Required bool
+}
+
+// Endpoint describes a Kraken API method
+type Endpoint struct {
+	Name        string       // API method, e.g. "Balance"
+	Private     bool         // Whether the method requires authentication
+	Idempotency Idempotency  // Whether the method is safe to retry
+	Cost        float64      // Cost against the private call counter; trading calls have their own limits and cost 0
+	Params      []Param      // Request parameters, excluding the nonce of private methods
+	Response    reflect.Type // Go type the client decodes the result into, nil when it returns the raw result
+}
+
+// Path returns the URL path of the endpoint, e.g. "/0/private/Balance"
+func (e Endpoint) Path() string {
+	visibility := "public"
+	if e.Private {
+		visibility = "private"
+	}
+	return fmt.Sprintf("/%s/%s/%s", APIVersion, visibility, e.Name)
+}
+
+// Param returns the description of the named parameter
+func (e Endpoint) Param(name string) (Param, bool) {
+	for _, p := range e.Params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Param{}, false
+}
+
+// validate checks that every required parameter is present in values
+func (e Endpoint) validate(values url.Values) error {
+	for _, p := range e.Params {
+		if p.Required && values.Get(p.Name) == "" {
+			return fmt.Errorf("Method '%s' requires parameter '%s'", e.Name, p.Name)
+		}
+	}
+	return nil
+}
+
+// Shorthands to keep the endpoint table readable
+func required(name string, typ ParamType) Param { return Param{Name: name, Type: typ, Required: true} }
+func optional(name string, typ ParamType) Param { return Param{Name: name, Type: typ} }
+func responseOf(v interface{}) reflect.Type     { return reflect.TypeOf(v) }
+
+// endpoints is the table of every Kraken API method known to the client. It
+// drives routing of Query, parameter validation, retry classification and
+// rate limiting.
+var endpoints = []Endpoint{
+	{Name: "Assets", Idempotency: Idempotent, Response: responseOf(AssetsResponse{}), Params: []Param{
+		optional("asset", ParamList), optional("aclass", ParamString),
+	}},
+	{Name: "AssetPairs", Idempotency: Idempotent, Response: responseOf(AssetPairsResponse{}), Params: []Param{
+		optional("pair", ParamList), optional("info", ParamString),
+	}},
+	{Name: "Depth", Idempotency: Idempotent, Response: responseOf(OrderBook{}), Params: []Param{
+		required("pair", ParamString), optional("count", ParamInt),
+	}},
+	{Name: "OHLC", Idempotency: Idempotent, Response: responseOf(OHLCResponse{}), Params: []Param{
+		required("pair", ParamString), optional("interval", ParamInt), optional("since", ParamTimestamp),
+	}},
+	{Name: "Spread", Idempotency: Idempotent, Response: responseOf(SpreadResponse{}), Params: []Param{
+		required("pair", ParamString), optional("since", ParamTimestamp),
+	}},
+	{Name: "SystemStatus", Idempotency: Idempotent, Response: responseOf(SystemStatusResponse{})},
+	{Name: "Ticker", Idempotency: Idempotent, Response: responseOf(TickerResponse{}), Params: []Param{
+		optional("pair", ParamList),
+	}},
+	{Name: "Time", Idempotency: Idempotent, Response: responseOf(TimeResponse{})},
+	{Name: "Trades", Idempotency: Idempotent, Response: responseOf(TradesResponse{}), Params: []Param{
+		required("pair", ParamString), optional("since", ParamTimestamp), optional("count", ParamInt),
+	}},
+
+	{Name: "AccountTransfer", Private: true, Idempotency: NotIdempotent, Cost: 1, Response: responseOf(AccountTransferResponse{}), Params: []Param{
+		required("asset", ParamString), required("amount", ParamDecimal), required("from", ParamString), required("to", ParamString),
+	}},
+	{Name: "AddExport", Private: true, Idempotency: NotIdempotent, Cost: 1, Response: responseOf(AddExportResponse{}), Params: []Param{
+		required("report", ParamString), required("description", ParamString), optional("format", ParamString),
+		optional("fields", ParamList), optional("starttm", ParamTimestamp), optional("endtm", ParamTimestamp),
+	}},
+	{Name: "AddOrder", Private: true, Idempotency: NotIdempotent, Cost: 0, Response: responseOf(AddOrderResponse{}), Params: []Param{
+		required("pair", ParamString), required("type", ParamString), required("ordertype", ParamString),
+		required("volume", ParamDecimal), optional("price", ParamDecimal), optional("price2", ParamDecimal),
+		optional("trigger", ParamString), optional("leverage", ParamString), optional("reduce_only", ParamBool),
+		optional("oflags", ParamList), optional("timeinforce", ParamString), optional("starttm", ParamTimestamp),
+		optional("expiretm", ParamTimestamp), optional("userref", ParamInt), optional("cl_ord_id", ParamString),
+		optional("close[ordertype]", ParamString), optional("close[price]", ParamDecimal),
+		optional("close[price2]", ParamDecimal), optional("deadline", ParamString), optional("validate", ParamBool),
+	}},
+	{Name: "AddOrderBatch", Private: true, Idempotency: NotIdempotent, Cost: 0, Response: responseOf(AddOrderBatchResponse{}), Params: []Param{
+		required("pair", ParamString), required("orders", ParamList), optional("deadline", ParamString), optional("validate", ParamBool),
+	}},
+	{Name: "Balance", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(BalanceResponse{})},
+	{Name: "BalanceEx", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(BalanceExResponse{})},
+	{Name: "CancelAll", Private: true, Idempotency: IdempotentInEffect, Cost: 0, Response: responseOf(CancelOrderResponse{})},
+	{Name: "CancelAllOrdersAfter", Private: true, Idempotency: IdempotentInEffect, Cost: 0, Response: responseOf(CancelAllOrdersAfterResponse{}), Params: []Param{
+		required("timeout", ParamInt),
+	}},
+	{Name: "CancelOrder", Private: true, Idempotency: IdempotentInEffect, Cost: 0, Response: responseOf(CancelOrderResponse{}), Params: []Param{
+		required("txid", ParamString),
+	}},
+	{Name: "CancelOrderBatch", Private: true, Idempotency: IdempotentInEffect, Cost: 0, Response: responseOf(CancelOrderResponse{}), Params: []Param{
+		required("orders", ParamList),
+	}},
+	{Name: "ClosedOrders", Private: true, Idempotency: Idempotent, Cost: 2, Response: responseOf(ClosedOrdersResponse{}), Params: []Param{
+		optional("trades", ParamBool), optional("userref", ParamInt), optional("start", ParamTimestamp),
+		optional("end", ParamTimestamp), optional("ofs", ParamInt), optional("closetime", ParamString),
+		optional("cl_ord_id", ParamString),
+	}},
+	{Name: "CreateSubaccount", Private: true, Idempotency: NotIdempotent, Cost: 1, Response: responseOf(false), Params: []Param{
+		required("username", ParamString), required("email", ParamString),
+	}},
+	{Name: "DepositAddresses", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(DepositAddressesResponse{}), Params: []Param{
+		required("asset", ParamString), required("method", ParamString), optional("new", ParamBool),
+	}},
+	{Name: "DepositMethods", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf([]DepositMethodInfo{}), Params: []Param{
+		required("asset", ParamString),
+	}},
+	{Name: "DepositStatus", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf([]DepositStatusInfo{}), Params: []Param{
+		optional("asset", ParamString), optional("method", ParamString),
+	}},
+	{Name: "Earn/Allocate", Private: true, Idempotency: NotIdempotent, Cost: 1, Response: responseOf(false), Params: []Param{
+		required("strategy_id", ParamString), required("amount", ParamDecimal),
+	}},
+	{Name: "Earn/AllocateStatus", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(EarnOperationStatus{}), Params: []Param{
+		required("strategy_id", ParamString),
+	}},
+	{Name: "Earn/Allocations", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(EarnAllocationsResponse{}), Params: []Param{
+		optional("converted_asset", ParamString), optional("hide_zero_allocations", ParamBool), optional("ascending", ParamBool),
+	}},
+	{Name: "Earn/Deallocate", Private: true, Idempotency: NotIdempotent, Cost: 1, Response: responseOf(false), Params: []Param{
+		required("strategy_id", ParamString), required("amount", ParamDecimal),
+	}},
+	{Name: "Earn/DeallocateStatus", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(EarnOperationStatus{}), Params: []Param{
+		required("strategy_id", ParamString),
+	}},
+	{Name: "Earn/Strategies", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(EarnStrategiesResponse{}), Params: []Param{
+		optional("asset", ParamString), optional("lock_type", ParamList), optional("cursor", ParamString),
+		optional("limit", ParamInt), optional("ascending", ParamBool),
+	}},
+	{Name: "EditOrder", Private: true, Idempotency: NotIdempotent, Cost: 0, Response: responseOf(EditOrderResponse{}), Params: []Param{
+		required("txid", ParamString), required("pair", ParamString), optional("volume", ParamDecimal),
+		optional("price", ParamDecimal), optional("price2", ParamDecimal), optional("oflags", ParamList),
+		optional("userref", ParamInt), optional("deadline", ParamString), optional("cancel_response", ParamBool),
+		optional("validate", ParamBool),
+	}},
+	{Name: "ExportStatus", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf([]ExportStatusInfo{}), Params: []Param{
+		required("report", ParamString),
+	}},
+	{Name: "GetWebSocketsToken", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(WebSocketsTokenResponse{})},
+	{Name: "Ledgers", Private: true, Idempotency: Idempotent, Cost: 2, Response: responseOf(LedgersResponse{}), Params: []Param{
+		optional("asset", ParamList), optional("aclass", ParamString), optional("type", ParamString),
+		optional("start", ParamTimestamp), optional("end", ParamTimestamp), optional("ofs", ParamInt),
+	}},
+	{Name: "OpenOrders", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(OpenOrdersResponse{}), Params: []Param{
+		optional("trades", ParamBool), optional("userref", ParamInt), optional("cl_ord_id", ParamString),
+	}},
+	{Name: "OpenPositions", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(map[string]PositionInfo{}), Params: []Param{
+		optional("txid", ParamList), optional("docalcs", ParamBool), optional("consolidation", ParamString),
+	}},
+	{Name: "QueryLedgers", Private: true, Idempotency: Idempotent, Cost: 2, Response: responseOf(map[string]LedgerInfo{}), Params: []Param{
+		required("id", ParamList), optional("trades", ParamBool),
+	}},
+	{Name: "QueryOrders", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(QueryOrdersResponse{}), Params: []Param{
+		required("txid", ParamList), optional("trades", ParamBool), optional("userref", ParamInt),
+	}},
+	{Name: "QueryTrades", Private: true, Idempotency: Idempotent, Cost: 2, Response: responseOf(map[string]TradeHistoryInfo{}), Params: []Param{
+		required("txid", ParamList), optional("trades", ParamBool), optional("ledgers", ParamBool),
+	}},
+	{Name: "RemoveExport", Private: true, Idempotency: IdempotentInEffect, Cost: 1, Response: responseOf(RemoveExportResponse{}), Params: []Param{
+		required("id", ParamString), required("type", ParamString),
+	}},
+	{Name: "RetrieveExport", Private: true, Idempotency: Idempotent, Cost: 1, Params: []Param{
+		required("id", ParamString),
+	}},
+	{Name: "Stake", Private: true, Idempotency: NotIdempotent, Cost: 1, Response: responseOf(StakeResponse{}), Params: []Param{
+		required("asset", ParamString), required("amount", ParamDecimal), required("method", ParamString),
+	}},
+	{Name: "Staking/Assets", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf([]StakingAssetInfo{})},
+	{Name: "Staking/Pending", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf([]StakingTransaction{})},
+	{Name: "Staking/Transactions", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf([]StakingTransaction{})},
+	{Name: "TradeBalance", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(TradeBalanceResponse{}), Params: []Param{
+		optional("asset", ParamString),
+	}},
+	{Name: "TradesHistory", Private: true, Idempotency: Idempotent, Cost: 2, Response: responseOf(TradesHistoryResponse{}), Params: []Param{
+		optional("type", ParamString), optional("trades", ParamBool), optional("start", ParamTimestamp),
+		optional("end", ParamTimestamp), optional("ofs", ParamInt), optional("ledgers", ParamBool),
+	}},
+	{Name: "TradeVolume", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(TradeVolumeResponse{}), Params: []Param{
+		optional("pair", ParamList), optional("fee-info", ParamBool),
+	}},
+	{Name: "Unstake", Private: true, Idempotency: NotIdempotent, Cost: 1, Response: responseOf(UnstakeResponse{}), Params: []Param{
+		required("asset", ParamString), required("amount", ParamDecimal),
+	}},
+	{Name: "WalletTransfer", Private: true, Idempotency: NotIdempotent, Cost: 1, Response: responseOf(WalletTransferResponse{}), Params: []Param{
+		required("asset", ParamString), required("from", ParamString), required("to", ParamString),
+		required("amount", ParamDecimal),
+	}},
+	{Name: "Withdraw", Private: true, Idempotency: NotIdempotent, Cost: 1, Response: responseOf(WithdrawResponse{}), Params: []Param{
+		required("asset", ParamString), required("key", ParamString), required("amount", ParamDecimal),
+		optional("address", ParamString),
+	}},
+	{Name: "WithdrawAddresses", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf([]WithdrawAddressInfo{}), Params: []Param{
+		optional("asset", ParamString), optional("aclass", ParamString), optional("method", ParamString), optional("key", ParamString),
+		optional("verified", ParamBool),
+	}},
+	{Name: "WithdrawCancel", Private: true, Idempotency: IdempotentInEffect, Cost: 1, Params: []Param{
+		required("asset", ParamString), required("refid", ParamString),
+	}},
+	{Name: "WithdrawInfo", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf(WithdrawInfoResponse{}), Params: []Param{
+		required("asset", ParamString), required("key", ParamString), required("amount", ParamDecimal),
+	}},
+	{Name: "WithdrawMethods", Private: true, Idempotency: Idempotent, Cost: 1, Response: responseOf([]WithdrawMethodInfo{}), Params: []Param{
+		optional("asset", ParamString), optional("aclass", ParamString), optional("network", ParamString),
+	}},
+	{Name: "WithdrawStatus", Private: true, Idempotency: Idempotent, Cost: 1, Params: []Param{
+		optional("asset", ParamString), optional("method", ParamString),
+	}},
+}
+
+// Endpoints returns a copy of the table of every Kraken API method known to
+// the client, e.g. to generate wrappers or documentation
+func Endpoints() []Endpoint {
+	list := make([]Endpoint, len(endpoints))
+	for i, e := range endpoints {
+		e.Params = append([]Param(nil), e.Params...)
+		list[i] = e
+	}
+	return list
+}
+
+// LookupEndpoint returns the description of the named API method
+func LookupEndpoint(name string) (Endpoint, bool) {
+	for _, e := range endpoints {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Endpoint{}, false
+}
+
+// EndpointIdempotency returns the idempotency class of the named API method.
+// Unknown methods are reported as NotIdempotent.
+func EndpointIdempotency(name string) Idempotency {
+	e, _ := LookupEndpoint(name)
+	return e.Idempotency
+}
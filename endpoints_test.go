@@ -0,0 +1,247 @@
+package krakenapi
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sergey-lipin/kraken-go-api-client/krakentest"
+)
+
+// methodEndpoints lists the API methods called by every exported method of
+// KrakenAPI. Methods that do not talk to Kraken map to nil. The *WithContext
+// variants share the entry of their plain counterpart.
+var methodEndpoints = map[string][]string{
+	"AccountTransfer":                  {"AccountTransfer"},
+	"AddExport":                        {"AddExport"},
+	"AddOrder":                         {"AddOrder", "AssetPairs", "BalanceEx"},
+	"AddOrderBatch":                    {"AddOrderBatch"},
+	"AssetPair":                        {"AssetPairs"},
+	"AssetPairs":                       {"AssetPairs"},
+	"AssetPairsStream":                 {"AssetPairs"},
+	"Assets":                           {"Assets"},
+	"AssetsStream":                     {"Assets"},
+	"BackfillAndFollow":                {"Trades"},
+	"Balance":                          {"Balance"},
+	"BalanceEx":                        {"BalanceEx"},
+	"BuildOrderAudit":                  {"QueryOrders", "QueryTrades", "QueryLedgers", "Ledgers"},
+	"CancelAll":                        {"CancelAll"},
+	"CancelAllForPair":                 {"AssetPairs", "OpenOrders", "CancelOrderBatch", "CancelOrder", "QueryOrders"},
+	"CancelAllOrdersAfter":             {"CancelAllOrdersAfter"},
+	"CancelOrder":                      {"CancelOrder"},
+	"CancelOrderBatch":                 {"CancelOrderBatch"},
+	"ClosedOrders":                     {"ClosedOrders"},
+	"CreateSubaccount":                 {"CreateSubaccount"},
+	"DepositAddresses":                 {"DepositAddresses"},
+	"DepositMethods":                   {"DepositMethods"},
+	"DepositStatus":                    {"DepositStatus"},
+	"Depth":                            {"Depth"},
+	"DepthForPair":                     {"Depth", "Ticker"},
+	"EarnAllocate":                     {"Earn/Allocate"},
+	"EarnAllocateStatus":               {"Earn/AllocateStatus"},
+	"EarnAllocations":                  {"Earn/Allocations"},
+	"EarnDeallocate":                   {"Earn/Deallocate"},
+	"EarnDeallocateStatus":             {"Earn/DeallocateStatus"},
+	"EarnStrategies":                   {"Earn/Strategies"},
+	"EditOrder":                        {"AssetPairs", "EditOrder"},
+	"Environment":                      nil,
+	"ExportOrderState":                 {"OpenOrders", "ClosedOrders", "QueryTrades"},
+	"ExportStatus":                     {"ExportStatus"},
+	"FlattenAccount":                   {"CancelAllOrdersAfter", "CancelAll", "OpenOrders", "AssetPairs", "CancelOrderBatch", "CancelOrder", "QueryOrders"},
+	"GetWebSocketsToken":               {"GetWebSocketsToken"},
+	"KeepDeadMansSwitch":               {"CancelAllOrdersAfter"},
+	"Ledgers":                          {"Ledgers"},
+	"LiquidityScore":                   {"Spread", "Depth"},
+	"NewPriceCache":                    nil,
+	"NewRolloverTracker":               nil,
+	"NewWSTokenSource":                 nil,
+	"OHLC":                             {"OHLC"},
+	"OHLCMulti":                        {"OHLC"},
+	"OHLCWithArgs":                     {"OHLC"},
+	"OHLCWithInterval":                 {"OHLC"},
+	"OpenOrders":                       {"OpenOrders"},
+	"OpenPositions":                    {"OpenPositions"},
+	"OrderByClientID":                  {"OpenOrders", "ClosedOrders"},
+	"PlaceBracket":                     {"AddOrder", "QueryOrders", "OpenOrders", "CancelOrderBatch", "CancelOrder"},
+	"PriceAt":                          {"OHLC", "Trades"},
+	"Query":                            nil,
+	"QueryOrders":                      {"QueryOrders"},
+	"QueryTrades":                      {"QueryTrades"},
+	"RecordWebSocketConnection":        nil,
+	"RecordWebSocketMessage":           nil,
+	"RemoveExport":                     {"RemoveExport"},
+	"ResolvePrice":                     {"AssetPairs", "Ticker"},
+	"RetrieveExport":                   {"RetrieveExport"},
+	"RetrieveExportTo":                 {"RetrieveExport"},
+	"RewardHistory":                    {"Ledgers", "AssetPairs", "OHLC"},
+	"Spread":                           {"Spread"},
+	"StakeAsset":                       {"Stake"},
+	"StakeAssetFloat":                  {"Assets", "Stake"},
+	"StakingAssets":                    {"Staking/Assets"},
+	"StakingPendingTransactions":       {"Staking/Pending"},
+	"StakingTransactions":              {"Staking/Transactions"},
+	"Stats":                            nil,
+	"SystemStatus":                     {"SystemStatus"},
+	"Ticker":                           {"Ticker"},
+	"TickerSummary":                    {"Ticker"},
+	"Time":                             {"Time"},
+	"TradeBalance":                     {"TradeBalance"},
+	"TradeBalanceIn":                   {"TradeBalance"},
+	"TradeVolume":                      {"TradeVolume"},
+	"Trades":                           {"Trades"},
+	"TradesHistory":                    {"TradesHistory"},
+	"TradesWithCount":                  {"Trades"},
+	"UnstakeAsset":                     {"Unstake"},
+	"UnstakeAssetFloat":                {"Assets", "Unstake"},
+	"WaitForAllocation":                {"Earn/AllocateStatus"},
+	"WaitForDeallocation":              {"Earn/DeallocateStatus"},
+	"WalletTransfer":                   {"Assets", "WalletTransfer"},
+	"WatchBestQuotes":                  {"AssetPairs", "Ticker"},
+	"WatchOpenOrders":                  {"OpenOrders"},
+	"WatchStatus":                      {"SystemStatus"},
+	"WithAccountTier":                  nil,
+	"WithBookValidation":               nil,
+	"WithClient":                       nil,
+	"WithEnumPolicy":                   nil,
+	"WithEnvironment":                  nil,
+	"WithExactOHLC":                    nil,
+	"WithHooks":                        nil,
+	"WithInsufficientFundsDiagnostics": nil,
+	"WithMaxSkew":                      nil,
+	"WithMetadataCache":                nil,
+	"WithRateLimiter":                  nil,
+	"WithRetryPolicy":                  nil,
+	"WithStatusGate":                   nil,
+	"Withdraw":                         {"Withdraw"},
+	"WithdrawAddresses":                {"WithdrawAddresses"},
+	"WithdrawChecked":                  {"Assets", "WithdrawInfo", "WithdrawMethods", "Withdraw"},
+	"WithdrawInfo":                     {"WithdrawInfo"},
+	"WithdrawMethods":                  {"WithdrawMethods"},
+}
+
+func TestEveryMethodIsClassified(t *testing.T) {
+	typ := reflect.TypeOf(&KrakenAPI{})
+	for i := 0; i < typ.NumMethod(); i++ {
+		name := strings.TrimSuffix(typ.Method(i).Name, "WithContext")
+		names, ok := methodEndpoints[name]
+		if !ok {
+			t.Errorf("Method %s is not listed in methodEndpoints", typ.Method(i).Name)
+			continue
+		}
+		for _, endpoint := range names {
+			if _, found := LookupEndpoint(endpoint); !found {
+				t.Errorf("Endpoint %s of method %s has no idempotency classification", endpoint, name)
+			}
+		}
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, e := range Endpoints() {
+		if seen[e.Name] {
+			t.Errorf("Endpoint %s is listed twice", e.Name)
+		}
+		seen[e.Name] = true
+		if e.Response != nil && e.Response.Kind() == reflect.Ptr {
+			t.Errorf("Response type of %s should not be a pointer", e.Name)
+		}
+	}
+
+	e := Endpoints()
+	e[0].Params[0].Name = "changed"
+	if Endpoints()[0].Params[0].Name == "changed" {
+		t.Errorf("Endpoints() should return a copy of the table")
+	}
+
+	balance, _ := LookupEndpoint("Balance")
+	if balance.Path() != "/0/private/Balance" {
+		t.Errorf("Unexpected path %s", balance.Path())
+	}
+}
+
+func TestQueryRoutesByEndpointTable(t *testing.T) {
+	var paths []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		paths = append(paths, req.URL.Path)
+		return `{"error":[],"result":{}}`
+	})
+
+	if _, err := api.Query("Balance", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Query("Spread", map[string]string{"pair": "XBTUSD"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(paths, []string{"/0/private/Balance", "/0/public/Spread"}) {
+		t.Errorf("Unexpected paths %v", paths)
+	}
+
+	if _, err := api.Query("Depth", nil); err == nil || !strings.Contains(err.Error(), "pair") {
+		t.Errorf("Query should reject a missing required parameter, got %v", err)
+	}
+	if _, err := api.Query("OHLCWithInterval", nil); err == nil {
+		t.Errorf("Query should reject methods Kraken does not know")
+	}
+	if len(paths) != 2 {
+		t.Errorf("Rejected queries should not reach Kraken")
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	calls := make(map[string]int)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls[method]++
+		if calls[method] == 1 {
+			return `{"error":["EService:Unavailable"]}`
+		}
+		return `{"error":[],"result":{"ZEUR":"1.0"}}`
+	}).WithRetryPolicy(RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, Retryable: IsRetryable})
+
+	if _, err := api.BalanceWithContext(context.Background()); err != nil {
+		t.Errorf("Balance should be retried, got %s", err)
+	}
+	if calls["Balance"] != 2 {
+		t.Errorf("Balance should be attempted twice, got %d", calls["Balance"])
+	}
+
+	if _, err := api.AddOrder("XBTUSD", "buy", "market", "1", nil); err == nil {
+		t.Errorf("AddOrder should not be retried after EService:Unavailable")
+	}
+	if calls["AddOrder"] != 1 {
+		t.Errorf("AddOrder should be attempted once, got %d", calls["AddOrder"])
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	addOrder, _ := LookupEndpoint("AddOrder")
+	if !IsRetryable(addOrder, &APIError{Errors: []string{"EAPI:Invalid nonce"}}) {
+		t.Errorf("Requests rejected for their nonce were never executed and can be retried")
+	}
+	if IsRetryable(addOrder, context.DeadlineExceeded) {
+		t.Errorf("AddOrder must not be retried when the outcome is unknown")
+	}
+	cancel, _ := LookupEndpoint("CancelOrder")
+	if !IsRetryable(cancel, context.DeadlineExceeded) {
+		t.Errorf("CancelOrder is idempotent in effect and can be retried")
+	}
+}
+
+func TestRetryPolicyUnderSimulatedThrottling(t *testing.T) {
+	server := krakentest.NewServer()
+	server.Throttle(krakentest.ThrottleConfig{MaxCounter: 1, Decay: 50})
+	api := NewWithClient("key", "c2VjcmV0", server.Client()).
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 5, Backoff: 10 * time.Millisecond, Retryable: IsRetryable})
+
+	for i := 0; i < 3; i++ {
+		if _, err := api.Balance(); err != nil {
+			t.Fatalf("Balance should be retried after being throttled, got %s", err)
+		}
+	}
+	if server.Requests("Balance") <= 3 {
+		t.Errorf("Some requests should have been throttled, got %d requests", server.Requests("Balance"))
+	}
+}
@@ -0,0 +1,111 @@
+package krakenapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// EnumPolicy decides how responses carrying enum values unknown to this
+// client are handled
+type EnumPolicy int
+
+const (
+	// EnumLenient keeps unknown values as the raw strings Kraken sent (default)
+	EnumLenient EnumPolicy = iota
+	// EnumStrict fails requests whose response carries an unknown value with
+	// an *UnknownEnumValueError, e.g. to learn about API changes in staging
+	EnumStrict
+)
+
+// ErrUnknownEnumValue is matched by every *UnknownEnumValueError
+var ErrUnknownEnumValue = errors.New("unknown enum value")
+
+// UnknownEnumValueError names a response field holding a value this client does not know
+type UnknownEnumValueError struct {
+	Field string // Type and JSON name of the field, e.g. "Order.status"
+	Value string
+}
+
+func (e *UnknownEnumValueError) Error() string {
+	return fmt.Sprintf("unknown value %q of %s", e.Value, e.Field)
+}
+
+// Unwrap makes errors.Is(err, ErrUnknownEnumValue) hold
+func (e *UnknownEnumValueError) Unwrap() error {
+	return ErrUnknownEnumValue
+}
+
+// enumValues lists the known values of the enums named by the enum tag of
+// response fields. Empty values are always accepted.
+var enumValues = map[string][]string{
+	"side":         {"buy", "sell"},
+	"order_type":   {OTMarket, OTLimit, OTStopLoss, OTTakeProfi, OTStopLossProfit, OTStopLossProfitLimit, OTStopLossLimit, OTTakeProfitLimit, OTTrailingStop, OTTrailingStopLimit, OTStopLossAndLimit, OTSettlePosition, "iceberg"},
+	"order_status": {"pending", "open", "closed", "canceled", "expired"},
+	"ledger_type": {LedgerTypeTrade, LedgerTypeDeposit, LedgerTypeWithdraw, LedgerTypeTransfer, LedgerTypeMargin, LedgerTypeRollover, LedgerTypeStaking, LedgerTypeEarn,
+		"adjustment", "credit", "settled", "dividend", "sale", "spend", "receive", "reward", "nfttrade", "nftcreatorfee", "nftrebate", "custodytransfer"},
+	"system_status": {SystemStatusOnline, SystemStatusMaintenance, SystemStatusCancelOnly, SystemStatusPostOnly},
+	"pair_status":   {"online", "cancel_only", "post_only", "limit_only", "reduce_only"},
+	"funding_status": {string(FundingStatusInitial), string(FundingStatusPending), string(FundingStatusSettled),
+		string(FundingStatusSuccess), string(FundingStatusFailure), string(FundingStatusPartial)},
+	"funding_status_prop": {string(FundingPropCancelPending), string(FundingPropCanceled), string(FundingPropCancelDenied),
+		string(FundingPropReturn), string(FundingPropOnHold)},
+}
+
+// WithEnumPolicy sets how responses carrying unknown enum values are handled
+func (api *KrakenAPI) WithEnumPolicy(policy EnumPolicy) *KrakenAPI {
+	api.enumPolicy = policy
+	return api
+}
+
+// checkEnums returns an *UnknownEnumValueError for the first unknown enum
+// value found in the decoded response v, if the policy of api is strict
+func (api *KrakenAPI) checkEnums(v interface{}) error {
+	if api.enumPolicy != EnumStrict || v == nil {
+		return nil
+	}
+	return walkEnums(reflect.ValueOf(v))
+}
+
+func walkEnums(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			return walkEnums(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkEnums(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := walkEnums(iter.Value()); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		typ := v.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			if enum, ok := field.Tag.Lookup("enum"); ok {
+				value := v.Field(i).String()
+				if value != "" && !isStringInSlice(value, enumValues[enum]) {
+					name := strings.Split(field.Tag.Get("json"), ",")[0]
+					return &UnknownEnumValueError{Field: typ.Name() + "." + name, Value: value}
+				}
+				continue
+			}
+			if err := walkEnums(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
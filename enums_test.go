@@ -0,0 +1,69 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/sergey-lipin/kraken-go-api-client/krakentest"
+)
+
+func TestEnumPolicy(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":[],"result":{"open":{"O1":{"status":"frozen","descr":{"pair":"XBTUSD","type":"buy"}}}}}`
+	})
+
+	resp, err := api.OpenOrders(nil)
+	if err != nil || resp.Open["O1"].Status != "frozen" {
+		t.Errorf("Unknown values should be kept by default, got %+v, %v", resp, err)
+	}
+
+	_, err = api.WithEnumPolicy(EnumStrict).OpenOrdersWithContext(context.Background(), nil)
+	var unknown *UnknownEnumValueError
+	if !errors.Is(err, ErrUnknownEnumValue) || !errors.As(err, &unknown) {
+		t.Fatalf("Strict policy should reject unknown values, got %v", err)
+	}
+	if unknown.Field != "Order.status" || unknown.Value != "frozen" {
+		t.Errorf("Unexpected error %+v", unknown)
+	}
+}
+
+func TestEnumPolicyFundingStatus(t *testing.T) {
+	for _, test := range []struct {
+		deposit string
+		field   string
+		value   string
+	}{
+		{`{"status":"Reversed"}`, "DepositStatusInfo.status", "Reversed"},
+		{`{"status":"Success","status-prop":"frozen"}`, "DepositStatusInfo.status-prop", "frozen"},
+	} {
+		api := newTestAPI(func(method string, req *http.Request) string {
+			return `{"error":[],"result":[` + test.deposit + `]}`
+		}).WithEnumPolicy(EnumStrict)
+
+		_, err := api.DepositStatus("XBT", "")
+		var unknown *UnknownEnumValueError
+		if !errors.As(err, &unknown) || unknown.Field != test.field || unknown.Value != test.value {
+			t.Errorf("%s: expected an unknown %s, got %v", test.deposit, test.field, err)
+		}
+	}
+
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":[],"result":[{"status":"Success","status-prop":"return"}]}`
+	}).WithEnumPolicy(EnumStrict)
+	if _, err := api.DepositStatus("XBT", ""); err != nil {
+		t.Errorf("Known funding statuses should be accepted, got %v", err)
+	}
+}
+
+func TestFixturesHaveKnownEnumValues(t *testing.T) {
+	for _, name := range krakentest.Fixtures() {
+		api := newTestAPI(func(method string, req *http.Request) string {
+			return string(krakentest.MustFixture(name))
+		}).WithEnumPolicy(EnumStrict)
+		if _, err := conformanceCalls[name](api); errors.Is(err, ErrUnknownEnumValue) {
+			t.Errorf("Fixture %s: %s", name, err)
+		}
+	}
+}
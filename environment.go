@@ -0,0 +1,72 @@
+package krakenapi
+
+import (
+	"fmt"
+)
+
+// Environment holds the hosts of a Kraken deployment
+type Environment struct {
+	Name             string
+	RESTURL          string   // Base URL of the REST API, without the version
+	WebSocketURL     string   // Public WebSocket API
+	AuthWebSocketURL string   // Private WebSocket API
+	Endpoints        []string // REST endpoints served by the environment, nil for all
+}
+
+var (
+	// Production is the live Kraken spot exchange, used by default
+	Production = Environment{
+		Name:             "production",
+		RESTURL:          APIURL,
+		WebSocketURL:     "wss://ws.kraken.com",
+		AuthWebSocketURL: "wss://ws-auth.kraken.com",
+	}
+	// Beta is Kraken's spot beta environment, which only serves the
+	// WebSocket API. Real keys and funds are not available there.
+	Beta = Environment{
+		Name:             "beta",
+		RESTURL:          APIURL,
+		WebSocketURL:     "wss://beta-ws.kraken.com",
+		AuthWebSocketURL: "wss://beta-ws-auth.kraken.com",
+		Endpoints:        []string{},
+	}
+)
+
+// Serves reports whether the REST endpoint is available in the environment
+func (e Environment) Serves(endpoint string) bool {
+	return e.Endpoints == nil || isStringInSlice(endpoint, e.Endpoints)
+}
+
+// EndpointUnavailableError is returned without contacting Kraken for
+// endpoints the environment of the client does not serve
+type EndpointUnavailableError struct {
+	Endpoint    string
+	Environment string
+}
+
+func (e *EndpointUnavailableError) Error() string {
+	return fmt.Sprintf("%s is not available in the %s environment", e.Endpoint, e.Environment)
+}
+
+// WithEnvironment makes the KrakenAPI talk to the hosts of env
+func (api *KrakenAPI) WithEnvironment(env Environment) *KrakenAPI {
+	api.env = env
+	return api
+}
+
+// Environment returns the environment the KrakenAPI talks to
+func (api *KrakenAPI) Environment() Environment {
+	if api.env.RESTURL == "" {
+		return Production
+	}
+	return api.env
+}
+
+// endpointURL returns the URL of the endpoint in the environment of the client
+func (api *KrakenAPI) endpointURL(e Endpoint) (string, error) {
+	env := api.Environment()
+	if !env.Serves(e.Name) {
+		return "", &EndpointUnavailableError{Endpoint: e.Name, Environment: env.Name}
+	}
+	return env.RESTURL + e.Path(), nil
+}
@@ -0,0 +1,36 @@
+package krakenapi
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestWithEnvironment(t *testing.T) {
+	var hosts []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		hosts = append(hosts, req.URL.Host)
+		return `{"error":[],"result":{"unixtime":1}}`
+	})
+	if api.Environment().Name != Production.Name {
+		t.Errorf("Clients should talk to production by default, got %s", api.Environment().Name)
+	}
+
+	demo := Environment{Name: "demo", RESTURL: "https://demo.example.com", Endpoints: []string{"Time"}}
+	api.WithEnvironment(demo)
+	if _, err := api.Time(); err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 || hosts[0] != "demo.example.com" {
+		t.Errorf("Requests should go to the environment's host, got %v", hosts)
+	}
+
+	_, err := api.Balance()
+	var unavailable *EndpointUnavailableError
+	if !errors.As(err, &unavailable) || unavailable.Endpoint != "Balance" {
+		t.Errorf("Endpoints outside the environment should be refused, got %v", err)
+	}
+	if len(hosts) != 1 {
+		t.Errorf("Refused endpoints should not be requested")
+	}
+}
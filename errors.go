@@ -0,0 +1,122 @@
+package krakenapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kraken error codes the client reacts to
+const (
+	ErrCodeUnknownOrder      = "EOrder:Unknown order"
+	ErrCodeInsufficientFunds = "EOrder:Insufficient funds"
+	ErrCodeInvalidArguments  = "EGeneral:Invalid arguments"
+	ErrCodePermissionDenied  = "EGeneral:Permission denied"
+	ErrCodeUnknownAsset      = "EQuery:Unknown asset"
+
+	ErrCodeFundingInsufficientFunds = "EFunding:Insufficient funds"
+	ErrCodeFundingInvalidAmount     = "EFunding:Invalid amount"
+	ErrCodeEarnBelowMin             = "EEarn:Below min"
+)
+
+// Errors matching an *APIError carrying their code with errors.Is
+var (
+	ErrFundingInsufficientFunds = &APIError{Errors: []string{ErrCodeFundingInsufficientFunds}}
+	ErrFundingInvalidAmount     = &APIError{Errors: []string{ErrCodeFundingInvalidAmount}}
+	ErrEarnBelowMin             = &APIError{Errors: []string{ErrCodeEarnBelowMin}}
+	ErrPermissionDenied         = &APIError{Errors: []string{ErrCodePermissionDenied}} // The API key lacks the permission
+)
+
+// APIError is returned when Kraken answers a request with a non empty error list
+type APIError struct {
+	Errors []string
+}
+
+// Error keeps the historical message format of the client
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Could not execute request! #7 (%s)", e.Errors)
+}
+
+// HasCode reports whether Kraken returned the given error code, e.g. "EOrder:Unknown order"
+func (e *APIError) HasCode(code string) bool {
+	if e == nil {
+		return false
+	}
+	for _, msg := range e.Errors {
+		if msg == code || strings.HasPrefix(msg, code+":") {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether target is an *APIError of a single code that e
+// carries, so that errors.Is(err, ErrFundingInsufficientFunds) holds
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	if !ok || t == nil || len(t.Errors) != 1 {
+		return false
+	}
+	return e.HasCode(t.Errors[0])
+}
+
+// UnknownAssetError is returned by Assets when Kraken rejects a requested
+// asset. It wraps the *APIError Kraken answered with.
+type UnknownAssetError struct {
+	Asset string // Rejected asset, empty if Kraken did not name it and several were requested
+	Err   *APIError
+}
+
+func (e *UnknownAssetError) Error() string {
+	if e.Asset == "" {
+		return "unknown asset among the requested ones"
+	}
+	return fmt.Sprintf("unknown asset %s", e.Asset)
+}
+
+// Unwrap returns the *APIError Kraken answered with
+func (e *UnknownAssetError) Unwrap() error {
+	if e.Err == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// unknownAssetError turns an "EQuery:Unknown asset" answer to a query of
+// requested into an *UnknownAssetError naming the rejected asset. Other
+// errors are returned unchanged.
+func unknownAssetError(err error, requested []string) error {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	for _, msg := range apiErr.Errors {
+		switch {
+		case msg == ErrCodeUnknownAsset:
+			unknown := &UnknownAssetError{Err: apiErr}
+			if len(requested) == 1 {
+				unknown.Asset = requested[0]
+			}
+			return unknown
+		case strings.HasPrefix(msg, ErrCodeUnknownAsset+":"):
+			return &UnknownAssetError{Asset: strings.TrimSpace(msg[len(ErrCodeUnknownAsset)+1:]), Err: apiErr}
+		}
+	}
+	return err
+}
+
+// isAPIError reports whether err was answered by Kraken, as opposed to a
+// transport failure
+func isAPIError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr)
+}
+
+// hasErrorCode reports whether err is an APIError carrying the given code
+func hasErrorCode(err error, code string) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.HasCode(code)
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package krakenapi_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+	"github.com/sergey-lipin/kraken-go-api-client/krakentest"
+)
+
+// The examples run against the fixtures of krakentest instead of the live API
+
+func Example_placeLimitOrder() {
+	server := krakentest.NewServer()
+	api := krakenapi.NewWithClient("key", "c2VjcmV0", server.Client())
+
+	resp, err := api.AddOrderWithContext(context.Background(), "XBTUSD", "buy", krakenapi.OTLimit, "1.25",
+		map[string]string{"price": "27500.0", "close_order_type": krakenapi.OTStopLoss, "close_price": "26000.0"})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(resp.TxId[0])
+	fmt.Println(resp.Description.Order)
+	// Output:
+	// OU22CG-KLAF2-FWUDD7
+	// buy 1.25000000 XBTUSD @ limit 27500.0
+}
+
+func Example_downloadLedger() {
+	server := krakentest.NewServer()
+	api := krakenapi.NewWithClient("key", "c2VjcmV0", server.Client())
+
+	// Page through the ledger until every entry Kraken counted was received
+	entries := make(map[string]krakenapi.LedgerInfo)
+	for {
+		resp, err := api.LedgersWithContext(context.Background(), map[string]string{"ofs": strconv.Itoa(len(entries))})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for id, entry := range resp.Ledger {
+			entries[id] = entry
+		}
+		if len(resp.Ledger) == 0 || len(entries) >= resp.Count {
+			break
+		}
+	}
+
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return entries[ids[i]].Time < entries[ids[j]].Time })
+	for _, id := range ids {
+		entry := entries[id]
+		fmt.Println(id, entry.Type, entry.Asset, entry.Amount.Text('f', 4))
+	}
+	// Output:
+	// LFV4SK-MKNLC-5QF3ZC transfer DOT.S 12.0000
+	// L4UESK-KG3EQ-UFO4T5 staking DOT.S 0.0129
+}
+
+func Example_maintainOrderBook() {
+	server := krakentest.NewServer()
+	api := krakenapi.NewWithClient("key", "c2VjcmV0", server.Client()).WithBookValidation(0)
+
+	book, err := api.DepthForPair(context.Background(), "XXBTZEUR", 10)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("bid %.1f ask %.1f\n", book.Bids[0].Price, book.Asks[0].Price)
+
+	// A crossed snapshot is refused instead of being traded on
+	server.Respond("Depth", `{"error":[],"result":{"XXBTZEUR":{
+		"asks":[["27970.00000","1.0",1688671282]],"bids":[["27979.90000","2.0",1688671283]]}}}`)
+	_, err = api.DepthForPair(context.Background(), "XXBTZEUR", 10)
+	fmt.Println(err)
+	// Output:
+	// bid 27979.9 ask 27980.0
+	// suspect order book of XXBTZEUR: crossed
+}
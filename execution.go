@@ -0,0 +1,221 @@
+package krakenapi
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// PricePoint is a public market price at a point in time
+type PricePoint struct {
+	Time  time.Time
+	Price float64
+}
+
+// PricePointsFromCandles returns the opening price of every candle at the
+// candle's start time
+func PricePointsFromCandles(candles Candles) []PricePoint {
+	points := make([]PricePoint, 0, len(candles))
+	for _, candle := range candles {
+		if candle != nil {
+			points = append(points, PricePoint{Time: candle.Time, Price: candle.Open})
+		}
+	}
+	return points
+}
+
+// PricePointsFromTrades returns the price of every public trade
+func PricePointsFromTrades(trades []TradeInfo) []PricePoint {
+	points := make([]PricePoint, 0, len(trades))
+	for _, trade := range trades {
+		points = append(points, PricePoint{Time: trade.Timestamp, Price: trade.PriceFloat})
+	}
+	return points
+}
+
+// OrderExecution is the execution quality of an order, aggregated over its fills
+type OrderExecution struct {
+	OrderID      string
+	Pair         string
+	UserRef      int
+	Side         string    // "buy" or "sell"
+	Placed       time.Time // Open time of the order, or its first fill if the order is unknown
+	ArrivalPrice float64   // Market price at placement
+	ArrivalTime  time.Time // Time of the price point used as arrival price
+	Fills        int
+	FirstFill    time.Time
+	LastFill     time.Time
+	Volume       float64 // Filled volume (base currency)
+	AvgPrice     float64 // Volume weighted fill price
+	Cost         float64 // Filled cost (quote currency)
+	Fee          float64 // Fees (quote currency)
+	SlippageBps  float64 // AvgPrice against ArrivalPrice, positive when worse
+	FeeBps       float64 // Fee relative to Cost
+	Shortfall    float64 // Slippage plus fees (quote currency), positive when worse
+}
+
+// ExecutionSummary aggregates the executions of a pair or a userref
+type ExecutionSummary struct {
+	Orders       int
+	Volume       float64
+	Cost         float64
+	Fee          float64
+	Shortfall    float64
+	ShortfallBps float64 // Shortfall relative to the cost at arrival prices
+	arrivalCost  float64
+}
+
+func (s *ExecutionSummary) add(e OrderExecution) {
+	s.Orders++
+	s.Volume += e.Volume
+	s.Cost += e.Cost
+	s.Fee += e.Fee
+	s.Shortfall += e.Shortfall
+	s.arrivalCost += e.ArrivalPrice * e.Volume
+	if s.arrivalCost > 0 {
+		s.ShortfallBps = s.Shortfall / s.arrivalCost * 10000
+	}
+}
+
+// ExecutionReport is the outcome of AnalyzeExecution
+type ExecutionReport struct {
+	Orders    []OrderExecution // Executions sorted by placement
+	ByPair    map[string]ExecutionSummary
+	ByUserRef map[int]ExecutionSummary
+	NoPrices  []string // Orders without a price point of their pair
+}
+
+// AnalyzeExecution joins fills from TradesHistory to their orders and to the
+// public prices of their pair, indexed by the pair names of the fills. The
+// arrival price of an order is the last price point at or before its
+// placement, or the first one after it if none precedes it. Orders missing
+// from orders are assumed to be placed at their first fill.
+func AnalyzeExecution(fills map[string]TradeHistoryInfo, orders map[string]Order, prices map[string][]PricePoint) *ExecutionReport {
+	report := &ExecutionReport{
+		ByPair:    make(map[string]ExecutionSummary),
+		ByUserRef: make(map[int]ExecutionSummary),
+	}
+
+	executions := make(map[string]*OrderExecution)
+	for _, fill := range fills {
+		e, ok := executions[fill.TransactionID]
+		if !ok {
+			e = &OrderExecution{OrderID: fill.TransactionID, Pair: fill.AssetPair, Side: fill.Type}
+			executions[fill.TransactionID] = e
+		}
+		at := fill.Timestamp()
+		if e.Fills == 0 || at.Before(e.FirstFill) {
+			e.FirstFill = at
+		}
+		if at.After(e.LastFill) {
+			e.LastFill = at
+		}
+		e.Fills++
+		e.Volume += fill.Volume
+		e.Cost += fill.Cost
+		e.Fee += fill.Fee
+	}
+
+	for id, e := range executions {
+		e.Placed = e.FirstFill
+		if order, ok := orders[id]; ok {
+			e.UserRef = order.UserRef
+			if order.OpenTime > 0 {
+				e.Placed = order.OpenTimestamp()
+			}
+		}
+		if e.Volume > 0 {
+			e.AvgPrice = e.Cost / e.Volume
+		}
+		if e.Cost > 0 {
+			e.FeeBps = e.Fee / e.Cost * 10000
+		}
+
+		point, ok := arrivalPoint(prices[e.Pair], e.Placed)
+		if !ok {
+			report.NoPrices = append(report.NoPrices, id)
+		} else {
+			e.ArrivalPrice, e.ArrivalTime = point.Price, point.Time
+			sign := 1.0
+			if e.Side == "sell" {
+				sign = -1
+			}
+			e.SlippageBps = sign * (e.AvgPrice - e.ArrivalPrice) / e.ArrivalPrice * 10000
+			e.Shortfall = sign*(e.AvgPrice-e.ArrivalPrice)*e.Volume + e.Fee
+		}
+		report.Orders = append(report.Orders, *e)
+	}
+
+	sort.Slice(report.Orders, func(i, j int) bool {
+		if report.Orders[i].Placed.Equal(report.Orders[j].Placed) {
+			return report.Orders[i].OrderID < report.Orders[j].OrderID
+		}
+		return report.Orders[i].Placed.Before(report.Orders[j].Placed)
+	})
+	sort.Strings(report.NoPrices)
+
+	for _, e := range report.Orders {
+		if e.ArrivalPrice == 0 {
+			continue
+		}
+		pair := report.ByPair[e.Pair]
+		pair.add(e)
+		report.ByPair[e.Pair] = pair
+		ref := report.ByUserRef[e.UserRef]
+		ref.add(e)
+		report.ByUserRef[e.UserRef] = ref
+	}
+	return report
+}
+
+// arrivalPoint returns the last point at or before t, or the first point
+// after it. points must be sorted by time.
+func arrivalPoint(points []PricePoint, t time.Time) (PricePoint, bool) {
+	if len(points) == 0 {
+		return PricePoint{}, false
+	}
+	i := sort.Search(len(points), func(i int) bool { return points[i].Time.After(t) })
+	if i == 0 {
+		return points[0], true
+	}
+	return points[i-1], true
+}
+
+// executionCSVHeader are the columns written by WriteExecutionCSV
+var executionCSVHeader = []string{
+	"order_id", "pair", "userref", "side", "placed", "arrival_price", "arrival_time", "fills",
+	"first_fill", "last_fill", "volume", "avg_price", "cost", "fee", "slippage_bps", "fee_bps", "shortfall",
+}
+
+// WriteExecutionCSV writes the executions of report to w, one order per row
+func WriteExecutionCSV(w io.Writer, report *ExecutionReport) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(executionCSVHeader); err != nil {
+		return err
+	}
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	formatFloat := func(f float64) string {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	for _, e := range report.Orders {
+		row := []string{
+			e.OrderID, e.Pair, strconv.Itoa(e.UserRef), e.Side, formatTime(e.Placed),
+			formatFloat(e.ArrivalPrice), formatTime(e.ArrivalTime), strconv.Itoa(e.Fills),
+			formatTime(e.FirstFill), formatTime(e.LastFill), formatFloat(e.Volume), formatFloat(e.AvgPrice),
+			formatFloat(e.Cost), formatFloat(e.Fee), formatFloat(e.SlippageBps), formatFloat(e.FeeBps),
+			formatFloat(e.Shortfall),
+		}
+		if err := out.Write(row); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,66 @@
+package krakenapi
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeExecution(t *testing.T) {
+	fills := map[string]TradeHistoryInfo{
+		// O1 buys 2 at 101 on average in two fills, placed when the price was 100
+		"T1": {TransactionID: "O1", AssetPair: "XXBTZUSD", Time: 1030, Type: "buy", Price: 100.5, Volume: 1, Cost: 100.5, Fee: 0.2},
+		"T2": {TransactionID: "O1", AssetPair: "XXBTZUSD", Time: 1200, Type: "buy", Price: 101.5, Volume: 1, Cost: 101.5, Fee: 0.2},
+		// O2 sells 1 at 99 placed when the price was 100
+		"T3": {TransactionID: "O2", AssetPair: "XXBTZUSD", Time: 1300, Type: "sell", Price: 99, Volume: 1, Cost: 99, Fee: 0.1},
+		// O3 has no prices
+		"T4": {TransactionID: "O3", AssetPair: "XETHZUSD", Time: 1300, Type: "sell", Price: 10, Volume: 1, Cost: 10},
+	}
+	orders := map[string]Order{
+		"O1": {UserRef: 7, OpenTime: 1010},
+		"O2": {UserRef: 8, OpenTime: 1250},
+	}
+	prices := map[string][]PricePoint{
+		"XXBTZUSD": {{time.Unix(1000, 0), 100}, {time.Unix(1060, 0), 105}},
+	}
+
+	report := AnalyzeExecution(fills, orders, prices)
+	if len(report.Orders) != 3 {
+		t.Fatalf("Expected 3 orders, got %+v", report.Orders)
+	}
+
+	o1 := report.Orders[0]
+	if o1.OrderID != "O1" || o1.Fills != 2 || o1.AvgPrice != 101 || o1.ArrivalPrice != 100 || o1.UserRef != 7 {
+		t.Errorf("Unexpected execution of O1 %+v", o1)
+	}
+	if math.Abs(o1.SlippageBps-100) > 1e-9 || math.Abs(o1.Shortfall-2.4) > 1e-9 {
+		t.Errorf("O1 should slip 100 bps with 2.4 shortfall, got %v and %v", o1.SlippageBps, o1.Shortfall)
+	}
+
+	o2 := report.Orders[1]
+	if o2.OrderID != "O2" || o2.ArrivalPrice != 105 || math.Abs(o2.SlippageBps-600/1.05) > 1e-9 {
+		t.Errorf("Unexpected execution of O2 %+v", o2)
+	}
+
+	if len(report.NoPrices) != 1 || report.NoPrices[0] != "O3" {
+		t.Errorf("O3 should have no prices, got %v", report.NoPrices)
+	}
+	pair := report.ByPair["XXBTZUSD"]
+	if pair.Orders != 2 || math.Abs(pair.Shortfall-(2.4+6.1)) > 1e-9 {
+		t.Errorf("Unexpected pair summary %+v", pair)
+	}
+	if report.ByUserRef[7].Orders != 1 || report.ByUserRef[8].Orders != 1 {
+		t.Errorf("Unexpected userref summaries %+v", report.ByUserRef)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteExecutionCSV(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 4 || rows[1][0] != "O1" || rows[1][4] != "1970-01-01T00:16:50Z" {
+		t.Errorf("Unexpected CSV %v, %v", rows, err)
+	}
+}
@@ -0,0 +1,56 @@
+package krakenapi
+
+import "fmt"
+
+// Reports to pass to AddExport and ExportStatus
+const (
+	ExportTrades  = "trades"
+	ExportLedgers = "ledgers"
+)
+
+// Formats of the files written by AddExport
+const (
+	ExportCSV = "CSV"
+	ExportTSV = "TSV"
+)
+
+// Removal types to pass to RemoveExport
+const (
+	ExportCancel = "cancel" // Cancel a queued or processing export
+	ExportDelete = "delete" // Delete a processed export
+)
+
+// ExportState is the processing state of an export as returned by
+// ExportStatus. Values not listed below are kept as is.
+type ExportState string
+
+// Export states
+const (
+	ExportQueued     ExportState = "Queued"
+	ExportProcessing ExportState = "Processing"
+	ExportProcessed  ExportState = "Processed" // The export can be retrieved
+)
+
+// exportFields lists the fields each report can be restricted to
+var exportFields = map[string][]string{
+	ExportTrades:  {"ordertxid", "time", "ordertype", "price", "cost", "fee", "vol", "margin", "misc", "ledgers"},
+	ExportLedgers: {"refid", "time", "type", "aclass", "asset", "amount", "fee", "balance"},
+}
+
+// checkExportFields checks that report is known and lists every field
+func checkExportFields(report string, fields []string) error {
+	allowed, ok := exportFields[report]
+	if !ok {
+		return fmt.Errorf("unknown export report %q", report)
+	}
+next:
+	for _, field := range fields {
+		for _, name := range allowed {
+			if field == name {
+				continue next
+			}
+		}
+		return fmt.Errorf("field %q is not part of the %s report", field, report)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package krakenapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddExport(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		return `{"error":[],"result":{"id":"TCJA"}}`
+	})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp, err := api.AddExport(ExportLedgers, ExportCSV, "January", []string{"refid", "time", "amount"}, start, start.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ID != "TCJA" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if params.Get("report") != "ledgers" || params.Get("format") != "CSV" || params.Get("description") != "January" ||
+		params.Get("fields") != "refid,time,amount" || params.Get("starttm") != "1704067200" || params.Get("endtm") != "1706745600" {
+		t.Errorf("Unexpected request %v", params)
+	}
+
+	params = nil
+	if _, err := api.AddExport(ExportTrades, "", "All trades", nil, time.Time{}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if params.Has("format") || params.Has("fields") || params.Has("starttm") || params.Has("endtm") {
+		t.Errorf("Defaults should not be sent, got %v", params)
+	}
+
+	params = nil
+	if _, err := api.AddExport(ExportTrades, ExportCSV, "Bad", []string{"ordertxid", "balance"}, start, time.Time{}); err == nil || params != nil {
+		t.Errorf("A ledgers field should be refused for trades before sending, got %v", err)
+	}
+	if _, err := api.AddExport("orders", ExportCSV, "Bad", nil, start, time.Time{}); err == nil || params != nil {
+		t.Errorf("Unknown reports should be refused, got %v", err)
+	}
+}
+
+func TestExportStatus(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		return `{"error":[],"result":[
+			{"id":"VSKC","descr":"my_trades_1","format":"CSV","report":"trades","subtype":"all","status":"Processed",
+			"flags":"0","fields":"all","createdtm":"1688669085","expiretm":"1688878685","starttm":"1688669093",
+			"completedtm":"1688669093","datastarttm":"1683556800","dataendtm":"1688669085","aclass":"forex","asset":"all"},
+			{"id":"TCJA","descr":"my_trades_2","format":"TSV","report":"trades","status":"Queued","flags":"0",
+			"fields":"ordertxid,time","createdtm":"1688669100","starttm":"0","completedtm":"0"},
+			{"id":"QWER","descr":"my_trades_3","format":"CSV","report":"trades","status":"Failed"}]}`
+	})
+
+	exports, err := api.ExportStatus(ExportTrades)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("report") != "trades" {
+		t.Errorf("Unexpected request %v", params)
+	}
+	if len(exports) != 3 {
+		t.Fatalf("Expected three exports, got %+v", exports)
+	}
+	processed, queued := exports[0], exports[1]
+	if processed.ID != "VSKC" || processed.Description != "my_trades_1" || processed.Status != ExportProcessed ||
+		!processed.CompletedTime.Equal(time.Unix(1688669093, 0)) || !processed.DataStartTime.Equal(time.Unix(1683556800, 0)) {
+		t.Errorf("Unexpected export %+v", processed)
+	}
+	if queued.Status != ExportQueued || queued.Format != ExportTSV || !queued.StartTime.IsZero() || !queued.CompletedTime.IsZero() {
+		t.Errorf("Unexpected export %+v", queued)
+	}
+	if exports[2].Status != "Failed" {
+		t.Errorf("Unknown statuses should be kept, got %q", exports[2].Status)
+	}
+}
+
+func TestRetrieveExport(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/export.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var params url.Values
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		rec := httptest.NewRecorder()
+		if params.Get("id") == "PROXY" {
+			rec.Header().Set("Content-Type", "text/html")
+			rec.WriteHeader(http.StatusBadGateway)
+			rec.WriteString("<html><body>502 Bad Gateway</body></html>")
+			return rec.Result(), nil
+		}
+		if params.Get("id") != "VSKC" {
+			rec.Header().Set("Content-Type", "application/json")
+			rec.WriteString(`{"error":["EGeneral:Invalid arguments"]}`)
+			return rec.Result(), nil
+		}
+		rec.Header().Set("Content-Type", "application/zip")
+		rec.Write(fixture)
+		return rec.Result(), nil
+	})
+	api := NewWithClient("key", "c2VjcmV0", &http.Client{Transport: transport})
+
+	archive, err := api.RetrieveExport("VSKC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(archive, fixture) || params.Get("nonce") == "" {
+		t.Fatalf("Expected the archive as sent, got %d bytes", len(archive))
+	}
+	files, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil || len(files.File) != 1 || files.File[0].Name != "trades.csv" {
+		t.Fatalf("Unexpected archive %v", err)
+	}
+	csv, _ := files.File[0].Open()
+	content, _ := io.ReadAll(csv)
+	if !strings.Contains(string(content), "TZX2WP-XSEOP-FP7WYR") {
+		t.Errorf("Unexpected content %s", content)
+	}
+
+	var w bytes.Buffer
+	err = api.RetrieveExportTo("UNKNOWN", &w)
+	if !errors.Is(err, &APIError{Errors: []string{ErrCodeInvalidArguments}}) || w.Len() != 0 {
+		t.Errorf("Expected the Kraken error with nothing written, got %v and %d bytes", err, w.Len())
+	}
+
+	err = api.RetrieveExportToWithContext(context.Background(), "PROXY", &w)
+	if err == nil || !strings.Contains(err.Error(), "502") || !strings.Contains(err.Error(), "Bad Gateway") || w.Len() != 0 {
+		t.Errorf("Expected an error page to fail with nothing written, got %v and %d bytes", err, w.Len())
+	}
+}
+
+func TestRemoveExport(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		return `{"error":[],"result":{"delete":true}}`
+	})
+
+	resp, err := api.RemoveExport("VSKC", ExportDelete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Delete || resp.Cancel {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if params.Get("id") != "VSKC" || params.Get("type") != "delete" {
+		t.Errorf("Unexpected request %v", params)
+	}
+
+	params = nil
+	if _, err := api.RemoveExport("VSKC", "purge"); err == nil || params != nil {
+		t.Errorf("Unknown removal types should be refused before sending, got %v", err)
+	}
+}
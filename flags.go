@@ -0,0 +1,79 @@
+package krakenapi
+
+import "strings"
+
+// MiscFlag is one entry of the comma delimited misc field of trades and orders.
+// Values not listed below are kept as is.
+type MiscFlag string
+
+// Misc flags of trades
+const (
+	MiscClosing MiscFlag = "closing" // Trade closes all or part of a position
+)
+
+// Misc flags of orders
+const (
+	MiscStopped    MiscFlag = "stopped"    // Triggered by stop price
+	MiscTouched    MiscFlag = "touched"    // Triggered by touch price
+	MiscLiquidated MiscFlag = "liquidated" // Liquidation
+	MiscPartial    MiscFlag = "partial"    // Partial fill
+	MiscAmended    MiscFlag = "amended"    // Order parameters modified
+)
+
+// MiscFlags is a parsed misc field
+type MiscFlags []MiscFlag
+
+// ParseMiscFlags splits a comma delimited misc field, ignoring blanks
+func ParseMiscFlags(misc string) MiscFlags {
+	var flags MiscFlags
+	for _, flag := range strings.Split(misc, ",") {
+		if flag = strings.TrimSpace(flag); flag != "" {
+			flags = append(flags, MiscFlag(flag))
+		}
+	}
+	return flags
+}
+
+// Has reports whether flag is set
+func (f MiscFlags) Has(flag MiscFlag) bool {
+	for _, set := range f {
+		if set == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// String joins the flags back into Kraken's representation
+func (f MiscFlags) String() string {
+	flags := make([]string, len(f))
+	for i, flag := range f {
+		flags[i] = string(flag)
+	}
+	return strings.Join(flags, ",")
+}
+
+// MiscFlags returns the parsed misc field of the trade
+func (t TradeHistoryInfo) MiscFlags() MiscFlags {
+	return ParseMiscFlags(t.Misc)
+}
+
+// IsClosingTrade reports whether the trade closed all or part of a position
+func (t TradeHistoryInfo) IsClosingTrade() bool {
+	return t.MiscFlags().Has(MiscClosing)
+}
+
+// MiscFlags returns the parsed misc field of the order
+func (o Order) MiscFlags() MiscFlags {
+	return ParseMiscFlags(o.Misc)
+}
+
+// IsPartiallyFilled reports whether Kraken flagged the order as partially filled
+func (o Order) IsPartiallyFilled() bool {
+	return o.MiscFlags().Has(MiscPartial)
+}
+
+// IsLiquidation reports whether the order was a liquidation
+func (o Order) IsLiquidation() bool {
+	return o.MiscFlags().Has(MiscLiquidated)
+}
@@ -0,0 +1,41 @@
+package krakenapi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMiscFlags(t *testing.T) {
+	tests := []struct {
+		misc string
+		want MiscFlags
+	}{
+		{"", nil},
+		{"closing", MiscFlags{MiscClosing}},
+		{"stopped, partial,", MiscFlags{MiscStopped, MiscPartial}},
+		{"partial,newflag", MiscFlags{MiscPartial, "newflag"}},
+	}
+
+	for _, test := range tests {
+		got := ParseMiscFlags(test.misc)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseMiscFlags(%q) = %v, want %v", test.misc, got, test.want)
+		}
+	}
+}
+
+func TestMiscPredicates(t *testing.T) {
+	if !(TradeHistoryInfo{Misc: "closing"}).IsClosingTrade() {
+		t.Errorf("Trade with closing flag should be a closing trade")
+	}
+	if (TradeHistoryInfo{Misc: "closingish"}).IsClosingTrade() {
+		t.Errorf("Flags should be matched exactly")
+	}
+	order := Order{Misc: "stopped,partial"}
+	if !order.IsPartiallyFilled() || order.IsLiquidation() {
+		t.Errorf("Unexpected predicates for %q", order.Misc)
+	}
+	if order.MiscFlags().String() != "stopped,partial" {
+		t.Errorf("String() should round trip, got %q", order.MiscFlags().String())
+	}
+}
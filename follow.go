@@ -0,0 +1,317 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// followPollInterval is the delay between Trades polls while the backfill
+// waits for the live feed to overlap it
+const followPollInterval = time.Second
+
+// followClockSkew bounds how much earlier than the subscription of the live
+// feed a live trade can be stamped; older backfilled trades cannot be
+// duplicated by the feed and are not remembered
+const followClockSkew = time.Minute
+
+// LiveTradeSource delivers the trades of a pair as they happen, e.g. from
+// the WebSocket trade feed. The channel is closed when ctx is done or the
+// feed fails.
+type LiveTradeSource interface {
+	SubscribeTrades(ctx context.Context, pair string) (<-chan TradeInfo, error)
+}
+
+// LiveTradeFeed is a subscription of a LiveTradeFeedSource
+type LiveTradeFeed interface {
+	// Trades delivers the trades. It is closed when the context is done or
+	// the feed fails.
+	Trades() <-chan TradeInfo
+	// Err returns why the feed failed, nil while it runs or if its context was done
+	Err() error
+}
+
+// LiveTradeFeedSource is a LiveTradeSource that also reports why its feeds
+// fail. BackfillAndFollow subscribes through SubscribeTradeFeed when the
+// source implements it, so the follower's error wraps the feed's.
+type LiveTradeFeedSource interface {
+	LiveTradeSource
+	SubscribeTradeFeed(ctx context.Context, pair string) (LiveTradeFeed, error)
+}
+
+// plainTradeFeed is the feed of a LiveTradeSource that does not report errors
+type plainTradeFeed <-chan TradeInfo
+
+func (f plainTradeFeed) Trades() <-chan TradeInfo { return f }
+
+func (f plainTradeFeed) Err() error { return nil }
+
+// subscribeLiveTrades subscribes to the trades of pair on live
+func subscribeLiveTrades(ctx context.Context, live LiveTradeSource, pair string) (LiveTradeFeed, error) {
+	if source, ok := live.(LiveTradeFeedSource); ok {
+		return source.SubscribeTradeFeed(ctx, pair)
+	}
+	trades, err := live.SubscribeTrades(ctx, pair)
+	if err != nil {
+		return nil, err
+	}
+	return plainTradeFeed(trades), nil
+}
+
+// feedClosed returns the error of a follower whose live feed closed
+func feedClosed(feed LiveTradeFeed, msg string) error {
+	if err := feed.Err(); err != nil {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+	return errors.New(msg)
+}
+
+// TradeEventKind tells where a TradeEvent comes from
+type TradeEventKind string
+
+// Kinds of TradeEvent
+const (
+	TradeEventHistorical TradeEventKind = "historical" // Trade read from the Trades endpoint
+	TradeEventRealTime   TradeEventKind = "realtime"   // Marker without trade: every later trade is live
+	TradeEventLive       TradeEventKind = "live"       // Trade delivered by the live feed
+)
+
+// TradeEvent is a trade of a TradeFollower, or the marker of its handover
+// from the backfill to the live feed
+type TradeEvent struct {
+	Kind  TradeEventKind
+	Trade TradeInfo
+}
+
+// TradeFollower streams the trades of a pair from a point in the past on.
+// Its methods are safe for concurrent use.
+type TradeFollower struct {
+	// C receives every trade once, in the order of the exchange. It is
+	// closed when the context is done or the follower fails, see Err.
+	C <-chan TradeEvent
+
+	mu  sync.Mutex
+	err error
+}
+
+// Err returns why the follower stopped, nil while it runs or if its context was done
+func (f *TradeFollower) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+func (f *TradeFollower) fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// BackfillAndFollow streams the trades of pair since from, first from the
+// Trades endpoint, then from live. The live feed is subscribed before the
+// backfill starts and buffered meanwhile. The backfill goes on until it has
+// read past the first live trade, polling Trades if the live feed is slow to
+// start, so the handover never leaves a gap. Live trades the backfill already
+// delivered are dropped, matched by trade id when the live trade has one, or
+// else by exact timestamp, price and volume. A TradeEventRealTime marker is
+// sent at the handover.
+func (api *KrakenAPI) BackfillAndFollow(ctx context.Context, pair string, from time.Time, live LiveTradeSource) (*TradeFollower, error) {
+	if live == nil {
+		return nil, errors.New("no live trade source")
+	}
+	subscribed := time.Now()
+	feed, err := subscribeLiveTrades(ctx, live, pair)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan TradeEvent)
+	f := &TradeFollower{C: ch}
+	buf := newTradeBuffer()
+	go buf.fill(feed.Trades())
+	go func() {
+		defer close(ch)
+		if err := api.follow(ctx, pair, from, subscribed, feed, buf, ch); err != nil && ctx.Err() == nil {
+			f.fail(err)
+		}
+	}()
+	return f, nil
+}
+
+func (api *KrakenAPI) follow(ctx context.Context, pair string, from, subscribed time.Time, feed LiveTradeFeed, buf *tradeBuffer, ch chan<- TradeEvent) error {
+	send := func(kind TradeEventKind, trade TradeInfo) error {
+		select {
+		case ch <- TradeEvent{Kind: kind, Trade: trade}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	seen := newTradeSet()
+	since := from.UnixNano()
+	var last time.Time
+	for {
+		resp, err := api.trades(ctx, pair, since, 0)
+		if err != nil {
+			return err
+		}
+		for _, trade := range resp.Trades {
+			if trade.Timestamp.Before(from) {
+				continue
+			}
+			if !trade.Timestamp.Before(subscribed.Add(-followClockSkew)) {
+				seen.add(trade)
+			}
+			last = trade.Timestamp
+			if err := send(TradeEventHistorical, trade); err != nil {
+				return err
+			}
+		}
+		caughtUp := len(resp.Trades) == 0 || resp.Last <= since
+		if resp.Last > since {
+			since = resp.Last
+		}
+
+		first, ok, closed := buf.first()
+		if ok && !last.Before(first.Timestamp) {
+			break
+		}
+		if closed && !ok {
+			return feedClosed(feed, "live trade feed closed before the handover")
+		}
+		if !caughtUp {
+			continue
+		}
+		// Wait for the live feed to start or for new trades to read
+		timer := time.NewTimer(followPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-buf.notify:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	if err := send(TradeEventRealTime, TradeInfo{}); err != nil {
+		return err
+	}
+	for {
+		trades, closed := buf.take()
+		for _, trade := range trades {
+			if seen.remove(trade) {
+				continue
+			}
+			if err := send(TradeEventLive, trade); err != nil {
+				return err
+			}
+		}
+		if closed {
+			return feedClosed(feed, "live trade feed closed")
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-buf.notify:
+		}
+	}
+}
+
+// tradeBuffer holds the live trades not streamed yet
+type tradeBuffer struct {
+	mu     sync.Mutex
+	trades []TradeInfo
+	closed bool
+	notify chan struct{}
+}
+
+func newTradeBuffer() *tradeBuffer {
+	return &tradeBuffer{notify: make(chan struct{}, 1)}
+}
+
+func (b *tradeBuffer) fill(feed <-chan TradeInfo) {
+	for trade := range feed {
+		b.mu.Lock()
+		b.trades = append(b.trades, trade)
+		b.mu.Unlock()
+		b.wake()
+	}
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	b.wake()
+}
+
+func (b *tradeBuffer) wake() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// first returns the earliest buffered trade, and whether the feed is closed
+func (b *tradeBuffer) first() (trade TradeInfo, ok bool, closed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.trades) == 0 {
+		return TradeInfo{}, false, b.closed
+	}
+	return b.trades[0], true, b.closed
+}
+
+// take empties the buffer
+func (b *tradeBuffer) take() ([]TradeInfo, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	trades := b.trades
+	b.trades = nil
+	return trades, b.closed
+}
+
+// tradeSet counts the backfilled trades a live trade may duplicate
+type tradeSet struct {
+	ids    map[int64]bool
+	prints map[string]int
+}
+
+func newTradeSet() *tradeSet {
+	return &tradeSet{ids: make(map[int64]bool), prints: make(map[string]int)}
+}
+
+// tradePrint identifies a trade by its timestamp, to the microsecond Kraken
+// publishes, its price and its volume
+func tradePrint(trade TradeInfo) string {
+	return strconv.FormatInt(trade.Timestamp.Round(time.Microsecond).UnixNano(), 10) +
+		" " + trimZeros(trade.Price) + " " + trimZeros(trade.Volume)
+}
+
+func (s *tradeSet) add(trade TradeInfo) {
+	if trade.TradeID != 0 {
+		s.ids[trade.TradeID] = true
+	}
+	s.prints[tradePrint(trade)]++
+}
+
+// remove reports whether trade was backfilled, and forgets it
+func (s *tradeSet) remove(trade TradeInfo) bool {
+	if trade.TradeID != 0 {
+		if !s.ids[trade.TradeID] {
+			return false
+		}
+		delete(s.ids, trade.TradeID)
+	}
+	key := tradePrint(trade)
+	if s.prints[key] == 0 {
+		return trade.TradeID != 0
+	}
+	s.prints[key]--
+	if s.prints[key] == 0 {
+		delete(s.prints, key)
+	}
+	return true
+}
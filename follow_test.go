@@ -0,0 +1,198 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeTradeHistory serves the first visible trades of a pair two per page
+type fakeTradeHistory struct {
+	mu      sync.Mutex
+	trades  []TradeInfo
+	visible int
+	empty   chan struct{} // Receives when a caught up page is served
+}
+
+func newFakeTradeHistory(start time.Time, n, visible int) *fakeTradeHistory {
+	h := &fakeTradeHistory{visible: visible, empty: make(chan struct{}, 10)}
+	for i := 1; i <= n; i++ {
+		h.trades = append(h.trades, TradeInfo{
+			Price: fmt.Sprintf("%d.00000", 30000+i), Volume: "0.10000000",
+			Timestamp: start.Add(time.Duration(i) * time.Second), TradeID: int64(i),
+		})
+	}
+	return h
+}
+
+func (h *fakeTradeHistory) show(n int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.visible = n
+}
+
+func (h *fakeTradeHistory) serve(method string, req *http.Request) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	since, _ := strconv.ParseInt(req.URL.Query().Get("since"), 10, 64)
+	var rows []string
+	last := since
+	for _, trade := range h.trades[:h.visible] {
+		if trade.Timestamp.UnixNano() <= since || len(rows) == 2 {
+			continue
+		}
+		rows = append(rows, fmt.Sprintf(`["%s","%s",%f,"b","l","",%d]`,
+			trade.Price, trade.Volume, float64(trade.Timestamp.UnixNano())/1e9, trade.TradeID))
+		last = trade.Timestamp.UnixNano()
+	}
+	if len(rows) == 0 {
+		h.empty <- struct{}{}
+	}
+	return fmt.Sprintf(`{"error":[],"result":{"XXBTZUSD":[%s],"last":"%d"}}`, strings.Join(rows, ","), last)
+}
+
+// live returns trade i as the WebSocket feed publishes it, without trade id
+// and with its own number formatting
+func (h *fakeTradeHistory) live(i int) TradeInfo {
+	trade := h.trades[i-1]
+	return TradeInfo{Price: strings.TrimRight(trade.Price, "0"), Volume: trade.Volume, Timestamp: trade.Timestamp}
+}
+
+type fakeLiveTrades chan TradeInfo
+
+func (f fakeLiveTrades) SubscribeTrades(ctx context.Context, pair string) (<-chan TradeInfo, error) {
+	return f, nil
+}
+
+// failingLiveTrades is a feed that fails with err once its trades are consumed
+type failingLiveTrades struct {
+	trades chan TradeInfo
+	err    error
+}
+
+func (f *failingLiveTrades) SubscribeTrades(ctx context.Context, pair string) (<-chan TradeInfo, error) {
+	return f.trades, nil
+}
+
+func (f *failingLiveTrades) SubscribeTradeFeed(ctx context.Context, pair string) (LiveTradeFeed, error) {
+	return f, nil
+}
+
+func (f *failingLiveTrades) Trades() <-chan TradeInfo { return f.trades }
+
+func (f *failingLiveTrades) Err() error { return f.err }
+
+// collectTrades reads n trades of f and checks the marker sits between the
+// historical and the live ones
+func collectTrades(t *testing.T, f *TradeFollower, n int) []time.Time {
+	var times []time.Time
+	markers := 0
+	for len(times) < n || markers == 0 {
+		select {
+		case event, ok := <-f.C:
+			if !ok {
+				t.Fatalf("Follower stopped after %d trades: %v", len(times), f.Err())
+			}
+			switch {
+			case event.Kind == TradeEventRealTime:
+				markers++
+			case (event.Kind == TradeEventLive) != (markers == 1):
+				t.Errorf("%s trade %v on the wrong side of the marker", event.Kind, event.Trade.Timestamp)
+			default:
+				times = append(times, event.Trade.Timestamp)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Timed out after %d trades", len(times))
+		}
+	}
+	if markers != 1 {
+		t.Errorf("Expected one marker, got %d", markers)
+	}
+	return times
+}
+
+func checkTradeTimes(t *testing.T, h *fakeTradeHistory, times []time.Time) {
+	for i, ts := range times {
+		if !ts.Round(time.Microsecond).Equal(h.trades[i].Timestamp) {
+			t.Errorf("Trade %d should be at %v, got %v", i+1, h.trades[i].Timestamp, ts)
+		}
+	}
+}
+
+func TestBackfillAndFollowOverlap(t *testing.T) {
+	start := time.Now().Truncate(time.Second).Add(-30 * time.Second)
+	history := newFakeTradeHistory(start, 6, 6)
+	api := newTestAPI(history.serve)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	live := make(fakeLiveTrades, 10)
+	for i := 4; i <= 6; i++ {
+		live <- history.live(i)
+	}
+	f, err := api.BackfillAndFollow(ctx, "XBTUSD", start, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	times := collectTrades(t, f, 6)
+	checkTradeTimes(t, history, times)
+
+	select {
+	case event := <-f.C:
+		t.Errorf("Overlapping trades should be delivered once, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestBackfillAndFollowLateFeed(t *testing.T) {
+	start := time.Now().Truncate(time.Second).Add(-30 * time.Second)
+	history := newFakeTradeHistory(start, 6, 3)
+	api := newTestAPI(history.serve)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	live := make(fakeLiveTrades, 10)
+	f, err := api.BackfillAndFollow(ctx, "XBTUSD", start, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		// The feed starts after the backfill caught up, missing trade 4
+		<-history.empty
+		history.show(5)
+		live <- history.live(5)
+		live <- history.live(6)
+	}()
+	times := collectTrades(t, f, 6)
+	checkTradeTimes(t, history, times)
+}
+
+func TestBackfillAndFollowFeedError(t *testing.T) {
+	start := time.Now().Truncate(time.Second).Add(-30 * time.Second)
+	history := newFakeTradeHistory(start, 3, 3)
+	api := newTestAPI(history.serve)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	feedErr := errors.New("heartbeat timeout")
+	live := &failingLiveTrades{trades: make(chan TradeInfo, 10), err: feedErr}
+	live.trades <- history.live(3)
+	f, err := api.BackfillAndFollow(ctx, "XBTUSD", start, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collectTrades(t, f, 3)
+	close(live.trades)
+	for range f.C {
+	}
+	if !errors.Is(f.Err(), feedErr) {
+		t.Errorf("The error of the live feed should be wrapped, got %v", f.Err())
+	}
+}
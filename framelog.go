@@ -0,0 +1,130 @@
+package krakenapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// maxFrameLine bounds the size of a line of a frame log, book snapshots of
+// the deepest subscriptions included
+const maxFrameLine = 16 << 20
+
+// Frame is a raw WebSocket message with the time it was received
+type Frame struct {
+	Time time.Time
+	Data []byte
+}
+
+// frameLine is a Frame as written to a frame log. Compact JSON frames, as
+// Kraken sends them, are kept readable in Data; any other frame, malformed
+// ones included, is kept base64 encoded in Raw so that it replays byte for
+// byte.
+type frameLine struct {
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data,omitempty"`
+	Raw  []byte          `json:"raw,omitempty"`
+}
+
+// FrameRecorder writes the frames of a WebSocket connection to a frame log,
+// one JSON object per line, e.g. when attached to a ws.Client with
+// WithRecorder. Its methods are safe for concurrent use.
+type FrameRecorder struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewFrameRecorder returns a recorder writing to w
+func NewFrameRecorder(w io.Writer) *FrameRecorder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &FrameRecorder{enc: enc}
+}
+
+// Record appends a frame received at t to the log
+func (r *FrameRecorder) Record(t time.Time, data []byte) error {
+	line := frameLine{Time: t, Raw: data}
+	if isCompactJSON(data) {
+		line = frameLine{Time: t, Data: data}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(line)
+}
+
+// isCompactJSON reports whether data is valid UTF-8 JSON which encoding it
+// as a json.RawMessage leaves unchanged
+func isCompactJSON(data []byte) bool {
+	if !utf8.Valid(data) || !json.Valid(data) {
+		return false
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return false
+	}
+	return bytes.Equal(compact.Bytes(), data)
+}
+
+// ReplayOptions tunes ReplayFrames
+type ReplayOptions struct {
+	// RealTime waits between frames as long as they were apart when
+	// recorded, instead of replaying as fast as possible
+	RealTime bool
+	// Speed divides the waits of RealTime, e.g. 10 replays ten times faster.
+	// Defaults to 1.
+	Speed float64
+}
+
+// ReplayFrames reads the frame log written by a FrameRecorder from r and
+// calls fn with every frame in order, until the log ends, fn fails or ctx is
+// done. Frames keep their recorded time, so consumers deriving state from
+// timestamps behave as they did live. ws.NewReplayClient replays a log
+// through the subscriptions of the ws package.
+func ReplayFrames(ctx context.Context, r io.Reader, opts ReplayOptions, fn func(Frame) error) error {
+	speed := opts.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxFrameLine)
+	var previous time.Time
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var line frameLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("frame log line %d: %w", n, err)
+		}
+
+		if opts.RealTime && !previous.IsZero() && line.Time.After(previous) {
+			timer := time.NewTimer(time.Duration(float64(line.Time.Sub(previous)) / speed))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+		previous = line.Time
+
+		data := line.Raw
+		if line.Data != nil {
+			data = line.Data
+		}
+		if err := fn(Frame{Time: line.Time, Data: data}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,64 @@
+package krakenapi
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrameLogRoundTrip(t *testing.T) {
+	var log bytes.Buffer
+	recorder := NewFrameRecorder(&log)
+	start := time.Date(2023, 7, 6, 12, 0, 0, 0, time.UTC)
+	frames := []string{
+		`{"event":"heartbeat"}`,
+		`[336,{"a":[["30000.10000","0.50000000","1688644800.123456"]],"c":"974942666"},"book-10","XBT/USD"]`,
+		`[336,{"b":[["29999.9`,   // Truncated frame of an incident
+		"[\xff\xfe]",             // Invalid UTF-8
+		`{"event": "heartbeat"}`, // Not compact
+		`{"a":"<&>"}`,
+	}
+	for i, frame := range frames {
+		if err := recorder.Record(start.Add(time.Duration(i)*100*time.Millisecond), []byte(frame)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !strings.Contains(log.String(), `"data":{"event":"heartbeat"}`) {
+		t.Errorf("JSON frames should be logged as is, got %s", log.String())
+	}
+
+	var replayed []Frame
+	began := time.Now()
+	err := ReplayFrames(context.Background(), bytes.NewReader(log.Bytes()), ReplayOptions{RealTime: true, Speed: 2}, func(f Frame) error {
+		replayed = append(replayed, f)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(began); elapsed < 250*time.Millisecond {
+		t.Errorf("A real time replay at double speed should take 250ms, took %v", elapsed)
+	}
+	if len(replayed) != len(frames) {
+		t.Fatalf("Expected %d frames, got %d", len(frames), len(replayed))
+	}
+	for i, f := range replayed {
+		if string(f.Data) != frames[i] || !f.Time.Equal(start.Add(time.Duration(i)*100*time.Millisecond)) {
+			t.Errorf("Frame %d should replay unchanged, got %s at %v", i, f.Data, f.Time)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ReplayFrames(ctx, bytes.NewReader(log.Bytes()), ReplayOptions{}, func(Frame) error { return nil }); err != context.Canceled {
+		t.Errorf("Replay should stop when the context is done, got %v", err)
+	}
+
+	err = ReplayFrames(context.Background(), strings.NewReader("{\"time\":\"2023-07-06T12:00:00Z\",\"data\":\"x\"}\nnot json\n"), ReplayOptions{}, func(Frame) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Malformed logs should be reported with their line, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package krakenapi
+
+// Wallets to pass as from and to of WalletTransfer
+const (
+	WalletSpot    = "Spot Wallet"
+	WalletFutures = "Futures Wallet"
+)
+
+// FundingStatus is the IFEX status of a deposit or withdrawal as returned by
+// DepositStatus and WithdrawStatus. Values not listed below are kept as is.
+type FundingStatus string
+
+// Funding statuses
+const (
+	FundingStatusInitial FundingStatus = "Initial" // The transfer has been created
+	FundingStatusPending FundingStatus = "Pending" // The transfer is being processed
+	FundingStatusSettled FundingStatus = "Settled" // The transfer settled on the network, not yet credited
+	FundingStatusSuccess FundingStatus = "Success" // The transfer completed
+	FundingStatusFailure FundingStatus = "Failure" // The transfer failed
+	FundingStatusPartial FundingStatus = "Partial" // The transfer partially completed
+)
+
+// FundingStatusProp is the status-prop modifier of a deposit or withdrawal
+type FundingStatusProp string
+
+// Funding status modifiers
+const (
+	FundingPropCancelPending FundingStatusProp = "cancel-pending" // Cancelation requested
+	FundingPropCanceled      FundingStatusProp = "canceled"       // Canceled
+	FundingPropCancelDenied  FundingStatusProp = "cancel-denied"  // Cancelation requested but was denied
+	FundingPropReturn        FundingStatusProp = "return"         // A return transaction initiated by Kraken
+	FundingPropOnHold        FundingStatusProp = "onhold"         // Withdrawal is on hold pending review
+)
+
+// IsTerminal reports whether the status will not change anymore. Unknown
+// statuses are never terminal, so polling loops keep watching them.
+func (s FundingStatus) IsTerminal() bool {
+	return s == FundingStatusSuccess || s == FundingStatusFailure
+}
+
+// IsSuccessful reports whether the transfer completed
+func (s FundingStatus) IsSuccessful() bool {
+	return s == FundingStatusSuccess
+}
+
+// FundingState combines a funding status with its status-prop modifier
+type FundingState struct {
+	Status FundingStatus
+	Prop   FundingStatusProp
+}
+
+// IsTerminal reports whether a transfer in this state can stop being tracked.
+// Transfers that are on hold or waiting for a cancelation are still moving,
+// canceled and returned ones are done regardless of the status.
+func (s FundingState) IsTerminal() bool {
+	switch s.Prop {
+	case FundingPropOnHold, FundingPropCancelPending:
+		return false
+	case FundingPropCanceled, FundingPropReturn:
+		return true
+	}
+	return s.Status.IsTerminal()
+}
+
+// IsSuccessful reports whether the funds arrived and were not returned or canceled
+func (s FundingState) IsSuccessful() bool {
+	switch s.Prop {
+	case FundingPropCanceled, FundingPropReturn, FundingPropOnHold, FundingPropCancelPending:
+		return false
+	}
+	return s.Status.IsSuccessful()
+}
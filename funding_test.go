@@ -0,0 +1,271 @@
+package krakenapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestFundingState(t *testing.T) {
+	tests := []struct {
+		state      FundingState
+		terminal   bool
+		successful bool
+	}{
+		{FundingState{Status: FundingStatusInitial}, false, false},
+		{FundingState{Status: FundingStatusPending}, false, false},
+		{FundingState{Status: FundingStatusSettled}, false, false},
+		{FundingState{Status: FundingStatusSuccess}, true, true},
+		{FundingState{Status: FundingStatusFailure}, true, false},
+		{FundingState{Status: FundingStatusSuccess, Prop: FundingPropReturn}, true, false},
+		{FundingState{Status: FundingStatusPending, Prop: FundingPropOnHold}, false, false},
+		{FundingState{Status: FundingStatusSuccess, Prop: FundingPropCancelPending}, false, false},
+		{FundingState{Status: FundingStatusPending, Prop: FundingPropCanceled}, true, false},
+		{FundingState{Status: FundingStatusSuccess, Prop: FundingPropCancelDenied}, true, true},
+		{FundingState{Status: "Reviewing"}, false, false},
+	}
+
+	for _, test := range tests {
+		if got := test.state.IsTerminal(); got != test.terminal {
+			t.Errorf("%+v IsTerminal() = %v, want %v", test.state, got, test.terminal)
+		}
+		if got := test.state.IsSuccessful(); got != test.successful {
+			t.Errorf("%+v IsSuccessful() = %v, want %v", test.state, got, test.successful)
+		}
+	}
+}
+
+func TestFundingStatusDecodeUnknown(t *testing.T) {
+	var status FundingStatus
+	if err := json.Unmarshal([]byte(`"Reviewing"`), &status); err != nil {
+		t.Fatalf("Unknown status should decode, got %s", err)
+	}
+	if status.IsTerminal() {
+		t.Errorf("Unknown status should not be terminal")
+	}
+}
+
+func TestDepositMethods(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":[],"result":[
+			{"method":"Bitcoin","limit":false,"fee":"0.0000000000","gen-address":true,"minimum":"0.0001"},
+			{"method":"Bitcoin Lightning","limit":"5.00000","fee":"0.0001000000","address-setup-cost":"0.00","gen-address":false}]}`
+	})
+
+	methods, err := api.DepositMethods("XBT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(methods) != 2 {
+		t.Fatalf("Expected two methods, got %+v", methods)
+	}
+	if m := methods[0]; m.Method != "Bitcoin" || m.Limit.Limited || !m.GenAddress || m.Minimum != 0.0001 {
+		t.Errorf("Unexpected unlimited method %+v", m)
+	}
+	if m := methods[1]; !m.Limit.Limited || m.Limit.Amount != 5 || m.Fee != 0.0001 || m.GenAddress {
+		t.Errorf("Unexpected limited method %+v", m)
+	}
+
+	var limit DepositLimit
+	if err := json.Unmarshal([]byte(`12.5`), &limit); err != nil || limit.Amount != 12.5 || !limit.Limited {
+		t.Errorf("Numeric limits should decode, got %+v, %v", limit, err)
+	}
+	if err := json.Unmarshal([]byte(`true`), &limit); err == nil {
+		t.Errorf("A limit of true should be rejected")
+	}
+}
+
+func TestDepositStatus(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		return `{"error":[],"result":[
+			{"method":"Bitcoin","aclass":"currency","asset":"XXBT","refid":"FTQcuak-V6Za8qrWnhzTx67yYHz8Tg",
+				"txid":"6544b41b607d8b2512baf801755a3a87b6890eacdb451be8a94059fb11f0a8d9","info":"2Myd4eaAW96ojk38A2uDK4FbioCayvkEgVq",
+				"amount":"0.78125000","fee":"0.0000000000","time":1688992722,"status":"Success","status-prop":"return"},
+			{"method":"Bitcoin","aclass":"currency","asset":"XXBT","refid":"FTQcuak-V6Za8qrPnhsTx47yYLz8Tg","txid":"",
+				"info":"","amount":"0.1","fee":"0","time":1688993000,"status":"Settled"}]}`
+	})
+
+	deposits, err := api.DepositStatus("XBT", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := params["method"]; ok || params.Get("asset") != "XBT" {
+		t.Errorf("An empty method should not be sent, got %v", params)
+	}
+	if len(deposits) != 2 {
+		t.Fatalf("Expected two deposits, got %+v", deposits)
+	}
+	returned := deposits[0]
+	if !returned.Time.Equal(time.Unix(1688992722, 0)) || returned.Amount.Text('f', -1) != "0.78125" {
+		t.Errorf("Unexpected deposit %+v", returned)
+	}
+	if returned.StatusProp != FundingPropReturn || returned.State().IsSuccessful() || !returned.State().IsTerminal() {
+		t.Errorf("A returned deposit should be terminal without success, got %+v", returned.State())
+	}
+	if deposits[1].State().IsTerminal() {
+		t.Errorf("A settled deposit is not credited yet")
+	}
+}
+
+func TestWalletTransfer(t *testing.T) {
+	var params url.Values
+	assetCalls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method == "Assets" {
+			assetCalls++
+			return `{"error":[],"result":{"XXBT":{"aclass":"currency","altname":"XBT","decimals":10,"display_decimals":5},
+				"USDT":{"aclass":"currency","altname":"USDT","decimals":8,"display_decimals":4}}}`
+		}
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		return `{"error":[],"result":{"refid":"BOG5AE5-KSCNR4-VPNPEV"}}`
+	}).WithMetadataCache(NewMetadataCache(time.Hour))
+
+	resp, err := api.WalletTransfer("USDT", WalletSpot, WalletFutures, 1234.567890129)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RefID != "BOG5AE5-KSCNR4-VPNPEV" {
+		t.Errorf("Unexpected refid %s", resp.RefID)
+	}
+	if params.Get("amount") != "1234.56789012" || params.Get("from") != "Spot Wallet" || params.Get("to") != "Futures Wallet" {
+		t.Errorf("The amount should be truncated to the decimals of the asset, got %v", params)
+	}
+	if _, err := api.WalletTransfer("USDT", WalletSpot, WalletFutures, 0.3); err != nil || params.Get("amount") != "0.3" {
+		t.Errorf("Expected an amount of 0.3, got %v, %v", params, err)
+	}
+	if assetCalls != 1 {
+		t.Errorf("The assets should be read from the metadata cache, got %d calls", assetCalls)
+	}
+
+	if _, err := api.WalletTransfer("NOPE", WalletSpot, WalletFutures, 1); err == nil {
+		t.Errorf("An unknown asset should be rejected")
+	}
+}
+
+func TestWithdrawMethods(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		return `{"error":[],"result":[
+			{"asset":"USDT","method_id":"QSWL7TW-CYTSW-MKXRMB","method":"Tether USD (TRC20)","network_id":"TRON","network":"Tron","minimum":"5.00000000"},
+			{"asset":"USDT","method_id":"N7OOPXN-VETZK-VLPRFW","method":"Tether USD (ERC20)","network_id":"ETH","network":"Ethereum","minimum":"10.00000000"}]}`
+	})
+
+	methods, err := api.WithdrawMethods("USDT", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Has("aclass") || params.Has("network") || params.Get("asset") != "USDT" {
+		t.Errorf("Empty filters should not be sent, got %v", params)
+	}
+	if len(methods) != 2 || methods[0].Network != "Tron" || methods[0].MethodID != "QSWL7TW-CYTSW-MKXRMB" || methods[1].Minimum != 10 {
+		t.Errorf("Unexpected methods %+v", methods)
+	}
+
+	if _, err := api.WithdrawMethods("", "currency", "Tron"); err != nil {
+		t.Fatal(err)
+	}
+	if params.Has("asset") || params.Get("aclass") != "currency" || params.Get("network") != "Tron" {
+		t.Errorf("Only the given filters should be sent, got %v", params)
+	}
+}
+
+func TestWithdrawAddresses(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		return `{"error":[],"result":[{"address":"bc1qxdsh4sdd29h6ldehz0se5c61asq8cgwyjf2y3z","asset":"XBT","method":"Bitcoin",
+			"key":"btc-wallet-1","verified":true}]}`
+	})
+
+	addresses, err := api.WithdrawAddresses(WithdrawAddressesArgs{Asset: "XBT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("asset") != "XBT" || params.Has("verified") || params.Has("method") {
+		t.Errorf("Only the given filters should be sent, got %v", params)
+	}
+	if len(addresses) != 1 || addresses[0].Key != "btc-wallet-1" || !addresses[0].Verified {
+		t.Errorf("Unexpected addresses %+v", addresses)
+	}
+
+	unverified := false
+	if _, err := api.WithdrawAddresses(WithdrawAddressesArgs{Verified: &unverified}); err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("verified") != "false" || params.Has("asset") {
+		t.Errorf("An explicit false should be sent, got %v", params)
+	}
+
+	args := WithdrawAddressesArgs{Asset: "XBT", AClass: "currency", Method: "Bitcoin", Key: "btc-wallet-1", Verified: &unverified}
+	if _, err := api.WithdrawAddresses(args); err != nil {
+		t.Fatal(err)
+	}
+	endpoint, _ := LookupEndpoint("WithdrawAddresses")
+	for name := range params {
+		if _, ok := endpoint.Param(name); !ok && name != "nonce" {
+			t.Errorf("Parameter %q is missing from the endpoint table", name)
+		}
+	}
+}
+
+func TestAccountTransfer(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		if params.Get("from") == "AA00 0000 0000 0000" {
+			return `{"error":["EGeneral:Permission denied"]}`
+		}
+		return `{"error":[],"result":{"transfer_id":"TOH3AS2-LPCWR8-JDQGEU","status":"complete"}}`
+	})
+
+	resp, err := api.AccountTransfer("XBT", "1.5", "AA37 N84G 5MA6 HZ2M", "AA68 M36I VZ2H 5I7W")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TransferID != "TOH3AS2-LPCWR8-JDQGEU" || resp.Status != "complete" {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+	if params.Get("asset") != "XBT" || params.Get("amount") != "1.5" || params.Get("from") != "AA37 N84G 5MA6 HZ2M" || params.Get("to") != "AA68 M36I VZ2H 5I7W" {
+		t.Errorf("Unexpected request %v", params)
+	}
+
+	if _, err := api.AccountTransfer("XBT", "1.5", "AA00 0000 0000 0000", "AA68 M36I VZ2H 5I7W"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected an error matching ErrPermissionDenied, got %v", err)
+	}
+}
+
+func TestCreateSubaccount(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		if params.Get("username") == "restricted" {
+			return `{"error":["EGeneral:Permission denied"]}`
+		}
+		return `{"error":[],"result":true}`
+	})
+
+	created, err := api.CreateSubaccount("strategy-07", "strategy-07@example.com")
+	if err != nil || !created {
+		t.Fatalf("Expected the subaccount to be created, got %v and %v", created, err)
+	}
+	if params.Get("username") != "strategy-07" || params.Get("email") != "strategy-07@example.com" {
+		t.Errorf("Unexpected request %v", params)
+	}
+
+	if _, err := api.CreateSubaccount("restricted", "restricted@example.com"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected an error matching ErrPermissionDenied, got %v", err)
+	}
+}
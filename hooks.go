@@ -0,0 +1,64 @@
+package krakenapi
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestEvent describes a finished request to the Kraken API
+type RequestEvent struct {
+	Method   string        // API method, e.g. "Balance"
+	Private  bool          // Whether the method is private
+	Duration time.Duration // Time spent waiting for the response
+	Err      error         // Error returned to the caller, if any
+}
+
+// RateLimitEvent describes a wait for rate limit budget
+type RateLimitEvent struct {
+	Method     string        // API method waiting for budget
+	QueueDepth int           // Callers already waiting when the wait started
+	Waited     time.Duration // Time spent waiting
+	Err        error         // Context error if the wait was abandoned
+}
+
+// CacheEvent describes a lookup in a client side cache
+type CacheEvent struct {
+	Cache string        // Name of the cache, e.g. "price"
+	Key   string        // Looked up key, e.g. the pair
+	Hit   bool          // Whether the cached value was fresh enough to be used
+	Age   time.Duration // Age of the value used on a hit
+}
+
+// WarningEvent describes a likely misconfiguration noticed by the client
+type WarningEvent struct {
+	Method  string // API method that triggered the warning
+	Message string
+}
+
+// Hooks are optional logging and metrics callbacks. The same Hooks may be
+// installed into several clients; callbacks must be safe for concurrent use.
+type Hooks struct {
+	// OnRequest is called after every request to the Kraken API
+	OnRequest func(RequestEvent)
+	// OnRateLimitWait is called after every wait for rate limit budget
+	OnRateLimitWait func(RateLimitEvent)
+	// OnCacheLookup is called for every lookup in a PriceCache
+	OnCacheLookup func(CacheEvent)
+	// OnWarning is called when the client notices a likely misconfiguration,
+	// e.g. call rates the configured account tier cannot sustain
+	OnWarning func(WarningEvent)
+}
+
+// requestEvent builds the RequestEvent for req from its URL path
+func requestEvent(req *http.Request, duration time.Duration, err error) RequestEvent {
+	path := strings.TrimPrefix(req.URL.Path, "/"+APIVersion+"/")
+	event := RequestEvent{Duration: duration, Err: err}
+	if method := strings.TrimPrefix(path, "private/"); method != path {
+		event.Method = method
+		event.Private = true
+	} else {
+		event.Method = strings.TrimPrefix(path, "public/")
+	}
+	return event
+}
@@ -0,0 +1,24 @@
+// Package chanutil holds the channel helpers shared by the packages of the
+// module.
+package chanutil
+
+// SendDropOldest delivers v to the buffered out without blocking, dropping
+// the oldest values while it is full, so that a slow reader only misses
+// stale values. With a buffer of one, the reader always gets the latest
+// value. out must have no other sender. It returns the number of values
+// dropped.
+func SendDropOldest[T any](out chan T, v T) int {
+	dropped := 0
+	for {
+		select {
+		case out <- v:
+			return dropped
+		default:
+		}
+		select {
+		case <-out:
+			dropped++
+		default:
+		}
+	}
+}
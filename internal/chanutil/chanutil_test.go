@@ -0,0 +1,17 @@
+package chanutil
+
+import "testing"
+
+func TestSendDropOldest(t *testing.T) {
+	out := make(chan int, 2)
+	dropped := 0
+	for i := 1; i <= 5; i++ {
+		dropped += SendDropOldest(out, i)
+	}
+	if dropped != 3 {
+		t.Errorf("Expected the 3 oldest values dropped, got %d", dropped)
+	}
+	if first, second := <-out, <-out; first != 4 || second != 5 {
+		t.Errorf("Expected the newest values kept, got %d and %d", first, second)
+	}
+}
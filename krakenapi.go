@@ -1,6 +1,8 @@
 package krakenapi
 
 import (
+	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"crypto/sha512"
@@ -15,6 +17,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,51 +30,6 @@ const (
 	APIUserAgent = "Kraken GO API Agent (https://github.com/sergey-lipin/kraken-go-api-client)"
 )
 
-// List of valid public methods
-var publicMethods = []string{
-	"Assets",
-	"AssetPairs",
-	"Depth",
-	"OHLC",
-	"OHLCWithInterval",
-	"Spread",
-	"Ticker",
-	"Time",
-	"Trades",
-}
-
-// List of valid private methods
-var privateMethods = []string{
-	"AddExport",
-	"AddOrder",
-	"Balance",
-	"CancelOrder",
-	"ClosedOrders",
-	"DepositAddresses",
-	"DepositMethods",
-	"DepositStatus",
-	"ExportStatus",
-	"GetWebSocketsToken",
-	"Ledgers",
-	"OpenOrders",
-	"OpenPositions",
-	"QueryLedgers",
-	"QueryOrders",
-	"QueryTrades",
-	"RemoveExport",
-	"RetrieveExport",
-	"TradeBalance",
-	"TradesHistory",
-	"TradeVolume",
-	"WalletTransfer",
-	"Withdraw",
-	"WithdrawAddresses",
-	"WithdrawCancel",
-	"WithdrawInfo",
-	"WithdrawMethods",
-	"WithdrawStatus",
-}
-
 // These represent the minimum order sizes for the respective coins
 // Should be monitored through here: https://support.kraken.com/hc/en-us/articles/205893708-What-is-the-minimum-order-size-
 const (
@@ -97,19 +55,45 @@ const (
 // KrakenApi represents a Kraken API Client connection
 type KrakenApi = KrakenAPI
 
-// KrakenAPI represents a Kraken API Client connection
+// KrakenAPI represents a Kraken API Client connection. It is safe for
+// concurrent use once configured; the With options must be applied before the
+// client is shared between goroutines.
 type KrakenAPI struct {
-	key    string
-	secret string
-	client *http.Client
+	key      string
+	secret   string
+	client   *http.Client
+	nonce    int64
+	metadata *MetadataCache
+	hooks    Hooks
+	retry    RetryPolicy
+	limiter  *RateLimiter
+
+	statusGate *StatusGate
+	env        Environment
+	enumPolicy EnumPolicy
+	maxSkew    time.Duration
+	tier       Tier
+	tierUsage  *tierUsage
+	// tierLimiter tells that limiter was installed by WithAccountTier
+	// rather than set with WithRateLimiter
+	tierLimiter bool
+	history     *priceHistory
+	stats       *clientStats
+
+	diagnoseFunds bool
+	exactOHLC     bool
+	validateBooks bool
+	bookDeviation float64
 }
 
 // New creates a new Kraken API client
 func New(key, secret string) *KrakenAPI {
 	krakenAPI := KrakenAPI{
-		key:    key,
-		secret: secret,
-		client: http.DefaultClient,
+		key:     key,
+		secret:  secret,
+		client:  http.DefaultClient,
+		history: &priceHistory{},
+		stats:   newClientStats(),
 	}
 	return &krakenAPI
 }
@@ -126,9 +110,27 @@ func (api *KrakenAPI) WithClient(httpClient *http.Client) *KrakenAPI {
 	return api
 }
 
+// WithMetadataCache makes the KrakenAPI read account independent metadata
+// such as AssetPairs through cache, which may be shared between clients
+func (api *KrakenAPI) WithMetadataCache(cache *MetadataCache) *KrakenAPI {
+	api.metadata = cache
+	return api
+}
+
+// WithHooks installs logging and metrics callbacks into the KrakenAPI
+func (api *KrakenAPI) WithHooks(hooks Hooks) *KrakenAPI {
+	api.hooks = hooks
+	return api
+}
+
 // Time returns the server's time
 func (api *KrakenAPI) Time() (*TimeResponse, error) {
-	resp, err := api.queryPublic("Time", nil, &TimeResponse{})
+	return api.TimeWithContext(context.Background())
+}
+
+// TimeWithContext returns the server's time
+func (api *KrakenAPI) TimeWithContext(ctx context.Context) (*TimeResponse, error) {
+	resp, err := api.queryPublicContext(ctx, "Time", nil, &TimeResponse{})
 	if err != nil {
 		return nil, err
 	}
@@ -136,19 +138,78 @@ func (api *KrakenAPI) Time() (*TimeResponse, error) {
 	return resp.(*TimeResponse), nil
 }
 
-// Assets returns the servers available assets
-func (api *KrakenAPI) Assets() (*AssetsResponse, error) {
-	resp, err := api.queryPublic("Assets", nil, &AssetsResponse{})
+// SystemStatus returns the current trading mode of the exchange
+func (api *KrakenAPI) SystemStatus() (*SystemStatusResponse, error) {
+	return api.SystemStatusWithContext(context.Background())
+}
+
+// SystemStatusWithContext returns the current trading mode of the exchange
+func (api *KrakenAPI) SystemStatusWithContext(ctx context.Context) (*SystemStatusResponse, error) {
+	resp, err := api.queryPublicContext(ctx, "SystemStatus", nil, &SystemStatusResponse{})
 	if err != nil {
 		return nil, err
 	}
 
+	status := resp.(*SystemStatusResponse)
+	if api.statusGate != nil {
+		api.statusGate.Observe(*status)
+	}
+	return status, nil
+}
+
+// Assets returns the servers available assets. See AssetsWithContext.
+func (api *KrakenAPI) Assets(opts ...AssetsOpts) (*AssetsResponse, error) {
+	return api.AssetsWithContext(context.Background(), opts...)
+}
+
+// AssetsWithContext returns the servers available assets, restricted to the
+// assets selected by opts if given. An asset Kraken does not know fails the
+// call with an *UnknownAssetError.
+func (api *KrakenAPI) AssetsWithContext(ctx context.Context, opts ...AssetsOpts) (*AssetsResponse, error) {
+	if len(opts) > 1 {
+		return nil, fmt.Errorf("Assets takes at most one AssetsOpts, got %d", len(opts))
+	}
+	var values url.Values
+	var requested []string
+	if len(opts) == 1 {
+		values = url.Values{}
+		requested = opts[0].Assets
+		if len(requested) > 0 {
+			values.Set("asset", strings.Join(requested, ","))
+		}
+		if opts[0].AClass != "" {
+			values.Set("aclass", opts[0].AClass)
+		}
+	}
+	resp, err := api.queryPublicContext(ctx, "Assets", values, &AssetsResponse{})
+	if err != nil {
+		return nil, unknownAssetError(err, requested)
+	}
+
 	return resp.(*AssetsResponse), nil
 }
 
-// AssetPairs returns the servers available asset pairs
-func (api *KrakenAPI) AssetPairs() (*AssetPairsResponse, error) {
-	resp, err := api.queryPublic("AssetPairs", nil, &AssetPairsResponse{})
+// AssetPairs returns the servers available asset pairs. See AssetPairsWithContext.
+func (api *KrakenAPI) AssetPairs(opts ...AssetPairsOpts) (*AssetPairsResponse, error) {
+	return api.AssetPairsWithContext(context.Background(), opts...)
+}
+
+// AssetPairsWithContext returns the servers available asset pairs, restricted
+// to the pairs and fields selected by opts if given. Fields left out by the
+// info level are zero, e.g. everything but the fee schedules with
+// AssetPairsFees.
+func (api *KrakenAPI) AssetPairsWithContext(ctx context.Context, opts ...AssetPairsOpts) (*AssetPairsResponse, error) {
+	if len(opts) > 1 {
+		return nil, fmt.Errorf("AssetPairs takes at most one AssetPairsOpts, got %d", len(opts))
+	}
+	var values url.Values
+	if len(opts) == 1 {
+		var err error
+		if values, err = opts[0].values(); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := api.queryPublicContext(ctx, "AssetPairs", values, &AssetPairsResponse{})
 	if err != nil {
 		return nil, err
 	}
@@ -156,19 +217,40 @@ func (api *KrakenAPI) AssetPairs() (*AssetPairsResponse, error) {
 	return resp.(*AssetPairsResponse), nil
 }
 
+// AssetPair returns the information of a single asset pair
 func (api *KrakenAPI) AssetPair(pair string) (*AssetPairsResponse, error) {
-	result, err := api.queryPublic("AssetPairs", url.Values{"pair": {pair}}, &AssetPairsResponse{})
+	return api.AssetPairWithContext(context.Background(), pair)
+}
+
+// AssetPairWithContext returns the information of a single asset pair
+func (api *KrakenAPI) AssetPairWithContext(ctx context.Context, pair string) (*AssetPairsResponse, error) {
+	result, err := api.queryPublicContext(ctx, "AssetPairs", url.Values{"pair": {pair}}, &AssetPairsResponse{})
 	if err != nil {
 		return nil, err
 	}
 	return result.(*AssetPairsResponse), nil
 }
 
-// Ticker returns the ticker for given comma separated pairs
+// Ticker returns the ticker for given pairs. See TickerWithContext.
 func (api *KrakenAPI) Ticker(pairs ...string) (*TickerResponse, error) {
-	resp, err := api.queryPublic("Ticker", url.Values{
-		"pair": {strings.Join(pairs, ",")},
-	}, &TickerResponse{})
+	return api.TickerWithContext(context.Background(), pairs...)
+}
+
+// TickerWithContext returns the tickers of pairs in a single request,
+// indexed by the names Kraken answers with, e.g. "XXBTZUSD" for "XBTUSD".
+// Without pairs it returns the tickers of every tradable pair. It fails
+// without sending anything if pairs holds an empty name.
+func (api *KrakenAPI) TickerWithContext(ctx context.Context, pairs ...string) (*TickerResponse, error) {
+	values := url.Values{}
+	for _, pair := range pairs {
+		if pair == "" {
+			return nil, fmt.Errorf("empty pair name in %q", pairs)
+		}
+	}
+	if len(pairs) > 0 {
+		values.Set("pair", strings.Join(pairs, ","))
+	}
+	resp, err := api.queryPublicContext(ctx, "Ticker", values, &TickerResponse{})
 	if err != nil {
 		return nil, err
 	}
@@ -178,44 +260,103 @@ func (api *KrakenAPI) Ticker(pairs ...string) (*TickerResponse, error) {
 
 // OHLCWithInterval returns a OHLCResponse struct based on the given pair
 func (api *KrakenAPI) OHLCWithInterval(pair string, interval string) (*OHLCResponse, error) {
+	return api.ohlc(context.Background(), pair, interval, "")
+}
+
+// OHLCWithIntervalWithContext returns a OHLCResponse struct based on the given pair
+func (api *KrakenAPI) OHLCWithIntervalWithContext(ctx context.Context, pair string, interval string) (*OHLCResponse, error) {
+	return api.ohlc(ctx, pair, interval, "")
+}
+
+// OHLCWithArgs returns the candles of pair selected by args. See OHLCWithArgsWithContext.
+func (api *KrakenAPI) OHLCWithArgs(pair string, args OHLCArgs) (*OHLCResponse, error) {
+	return api.OHLCWithArgsWithContext(context.Background(), pair, args)
+}
+
+// OHLCWithArgsWithContext returns the candles of pair at args.Interval,
+// one minute if it is zero, starting after args.Since. Intervals Kraken does
+// not support fail before anything is sent. Pass the LastCursor of the
+// response as the next Since to poll for new candles.
+func (api *KrakenAPI) OHLCWithArgsWithContext(ctx context.Context, pair string, args OHLCArgs) (*OHLCResponse, error) {
+	interval := ""
+	if args.Interval != 0 {
+		if err := args.Interval.Validate(); err != nil {
+			return nil, err
+		}
+		interval = strconv.Itoa(int(args.Interval))
+	}
+	return api.ohlc(ctx, pair, interval, args.Since)
+}
+
+// ohlc queries the OHLC data of pair, starting after the since cursor if it is set
+func (api *KrakenAPI) ohlc(ctx context.Context, pair string, interval string, since string) (*OHLCResponse, error) {
 	urlValue := url.Values{}
 	urlValue.Add("pair", pair)
 
 	if interval == "" {
 		urlValue.Add("interval", "1")
 	} else {
-		switch interval {
-		// supported values https://www.kraken.com/features/api#get-ohlc-data
-		case "1", "5", "15", "30", "60", "240", "1440", "10080", "21600":
-			urlValue.Add("interval", interval)
-		default:
-			return nil, fmt.Errorf("Unsupported value for Interval: " + interval)
+		minutes, err := strconv.Atoi(interval)
+		if err != nil || OHLCInterval(minutes).Validate() != nil {
+			return nil, fmt.Errorf("Unsupported value for Interval: %s, Kraken accepts %v minutes", interval, ohlcIntervals)
 		}
+		urlValue.Add("interval", interval)
+	}
+	if since != "" {
+		urlValue.Add("since", since)
 	}
 
-	// Returns a map[string]interface{} as an interface{}
-	interfaceResponse, err := api.queryPublic("OHLC", urlValue, nil)
+	resp, err := api.queryPublicContext(ctx, "OHLC", urlValue, &map[string]json.RawMessage{})
 	if err != nil {
 		return nil, err
 	}
+	mapResponse := *resp.(*map[string]json.RawMessage)
 
-	// Converts the interface into map[string]interface{}
-	mapResponse := interfaceResponse.(map[string]interface{})
-	// Extracts the list of OHLC from the map to build a slice of interfaces
-	OHLCsUnstructured := mapResponse[pair].([]interface{})
+	// Extracts the list of OHLC from the map to build a slice of interfaces.
+	// Kraken answers with the canonical pair name, which may differ from the requested one.
+	raw, ok := mapResponse[pair]
+	if !ok {
+		for key, value := range mapResponse {
+			if key != "last" {
+				raw = value
+			}
+		}
+	}
+	var OHLCsUnstructured []interface{}
+	if raw != nil {
+		if err := json.Unmarshal(raw, &OHLCsUnstructured); err != nil {
+			return nil, err
+		}
+	}
 
 	ret := new(OHLCResponse)
 	for _, OHLCInterfaceSlice := range OHLCsUnstructured {
-		OHLCObj, OHLCErr := NewOHLC(OHLCInterfaceSlice.([]interface{}))
+		candle, _ := OHLCInterfaceSlice.([]interface{})
+		OHLCObj, OHLCErr := NewOHLC(candle)
 		if OHLCErr != nil {
 			return nil, OHLCErr
 		}
 
 		ret.OHLC = append(ret.OHLC, OHLCObj)
+
+		if api.exactOHLC {
+			exact, err := NewOHLCExact(candle)
+			if err != nil {
+				return nil, err
+			}
+			ret.Exact = append(ret.Exact, exact)
+		}
 	}
 
 	ret.Pair = pair
-	ret.Last = mapResponse["last"].(float64)
+	if last, ok := mapResponse["last"]; ok {
+		var cursor json.Number
+		if err := json.Unmarshal(last, &cursor); err != nil {
+			return nil, err
+		}
+		ret.LastCursor = cursor.String()
+		ret.Last, _ = cursor.Float64()
+	}
 
 	return ret, nil
 }
@@ -227,8 +368,18 @@ func (api *KrakenAPI) OHLC(pair string) (*OHLCResponse, error) {
 	return ret, err
 }
 
+// OHLCWithContext returns a OHLCResponse struct based on the given pair
+func (api *KrakenAPI) OHLCWithContext(ctx context.Context, pair string) (*OHLCResponse, error) {
+	return api.ohlc(ctx, pair, "1", "")
+}
+
 // TradesHistory returns the Trades History within a specified time frame (start to end).
 func (api *KrakenAPI) TradesHistory(start int64, end int64, args map[string]string) (*TradesHistoryResponse, error) {
+	return api.TradesHistoryWithContext(context.Background(), start, end, args)
+}
+
+// TradesHistoryWithContext returns the Trades History within a specified time frame (start to end).
+func (api *KrakenAPI) TradesHistoryWithContext(ctx context.Context, start int64, end int64, args map[string]string) (*TradesHistoryResponse, error) {
 	params := url.Values{}
 	if start > 0 {
 		params.Add("start", strconv.FormatInt(start, 10))
@@ -246,7 +397,7 @@ func (api *KrakenAPI) TradesHistory(start int64, end int64, args map[string]stri
 		params.Add("ofs", value)
 	}
 
-	resp, err := api.queryPrivate("TradesHistory", params, &TradesHistoryResponse{})
+	resp, err := api.queryPrivateContext(ctx, "TradesHistory", params, &TradesHistoryResponse{})
 
 	if err != nil {
 		return nil, err
@@ -255,50 +406,176 @@ func (api *KrakenAPI) TradesHistory(start int64, end int64, args map[string]stri
 	return resp.(*TradesHistoryResponse), nil
 }
 
+// Spread returns the recent spreads of pair. See SpreadWithContext.
+func (api *KrakenAPI) Spread(pair string, since int64) (*SpreadResponse, error) {
+	return api.SpreadWithContext(context.Background(), pair, since)
+}
+
+// SpreadWithContext returns the best bid and ask of pair recorded after
+// since, or the recent ones if since is 0. Pass the Last of the response as
+// since to get the next spreads.
+func (api *KrakenAPI) SpreadWithContext(ctx context.Context, pair string, since int64) (*SpreadResponse, error) {
+	values := url.Values{"pair": {pair}}
+	if since > 0 {
+		values.Set("since", strconv.FormatInt(since, 10))
+	}
+	resp, err := api.queryPublicContext(ctx, "Spread", values, &SpreadResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*SpreadResponse), nil
+}
+
+// QueryTrades returns the trades of the account with the given txids. See QueryTradesWithContext.
+func (api *KrakenAPI) QueryTrades(trades bool, txids ...string) (map[string]TradeHistoryInfo, error) {
+	return api.QueryTradesWithContext(context.Background(), trades, txids...)
+}
+
+// QueryTradesWithContext returns the trades of the account with the given
+// txids, indexed by txid, including the trades of their positions if trades
+// is set. More txids than Kraken accepts in a call are queried in several
+// calls; a txid listed twice is queried once.
+func (api *KrakenAPI) QueryTradesWithContext(ctx context.Context, trades bool, txids ...string) (map[string]TradeHistoryInfo, error) {
+	params := url.Values{}
+	if trades {
+		params.Set("trades", "true")
+	}
+	return api.queryTrades(ctx, params, txids, nil)
+}
+
+// queryTrades queries the trades with the given txids in calls of at most
+// maxIDsPerQuery ids, sending params along. A call failing with an error
+// skip accepts leaves its trades out instead of failing the query.
+func (api *KrakenAPI) queryTrades(ctx context.Context, params url.Values, txids []string, skip func(error) bool) (map[string]TradeHistoryInfo, error) {
+	seen := make(map[string]bool, len(txids))
+	unique := make([]string, 0, len(txids))
+	for _, txid := range txids {
+		if !seen[txid] {
+			seen[txid] = true
+			unique = append(unique, txid)
+		}
+	}
+
+	result := make(map[string]TradeHistoryInfo, len(unique))
+	for _, chunk := range chunkStrings(unique, maxIDsPerQuery) {
+		params.Set("txid", strings.Join(chunk, ","))
+		var found map[string]TradeHistoryInfo
+		if _, err := api.queryPrivateContext(ctx, "QueryTrades", params, &found); err != nil {
+			if skip != nil && skip(err) {
+				continue
+			}
+			return nil, err
+		}
+		for txid, trade := range found {
+			if _, ok := result[txid]; !ok {
+				result[txid] = trade
+			}
+		}
+	}
+	return result, nil
+}
+
+// maxTradesCount is the largest page Kraken returns from Trades
+const maxTradesCount = 1000
+
 // Trades returns the recent trades for given pair
 func (api *KrakenAPI) Trades(pair string, since int64) (*TradesResponse, error) {
+	return api.trades(context.Background(), pair, since, 0)
+}
+
+// TradesWithContext returns the recent trades for given pair
+func (api *KrakenAPI) TradesWithContext(ctx context.Context, pair string, since int64) (*TradesResponse, error) {
+	return api.trades(ctx, pair, since, 0)
+}
+
+// TradesWithCount returns at most count trades of pair made after since.
+// See TradesWithCountWithContext.
+func (api *KrakenAPI) TradesWithCount(pair string, since int64, count int) (*TradesResponse, error) {
+	return api.TradesWithCountWithContext(context.Background(), pair, since, count)
+}
+
+// TradesWithCountWithContext returns at most count trades of pair made after
+// since. Kraken accepts counts between 1 and 1000; 0 leaves the page size to
+// Kraken. Pass the Last of the response as the next since to page through
+// the trades without gaps or duplicates.
+func (api *KrakenAPI) TradesWithCountWithContext(ctx context.Context, pair string, since int64, count int) (*TradesResponse, error) {
+	if count < 0 || count > maxTradesCount {
+		return nil, fmt.Errorf("count must be between 1 and %d, or 0 for Kraken's default, got %d", maxTradesCount, count)
+	}
+	return api.trades(ctx, pair, since, count)
+}
+
+// trades queries the public trades of pair
+func (api *KrakenAPI) trades(ctx context.Context, pair string, since int64, count int) (*TradesResponse, error) {
 	values := url.Values{"pair": {pair}}
 	if since > 0 {
 		values.Set("since", strconv.FormatInt(since, 10))
 	}
-	resp, err := api.queryPublic("Trades", values, nil)
+	if count > 0 {
+		values.Set("count", strconv.Itoa(count))
+	}
+	resp, err := api.queryPublicContext(ctx, "Trades", values, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	v := resp.(map[string]interface{})
 
-	last, err := strconv.ParseInt(v["last"].(string), 10, 64)
+	lastCursor, _ := v["last"].(string)
+	last, err := strconv.ParseInt(lastCursor, 10, 64)
 	if err != nil {
 		return nil, err
 	}
 
 	result := &TradesResponse{
-		Last:   last,
-		Trades: make([]TradeInfo, 0),
+		Pair:       pair,
+		Last:       last,
+		LastCursor: lastCursor,
+		Trades:     make([]TradeInfo, 0),
+	}
+
+	// Kraken answers with the canonical pair name, which may differ from the requested one
+	trades, ok := v[pair].([]interface{})
+	if !ok {
+		for key, value := range v {
+			if key != "last" {
+				result.Pair = key
+				trades, _ = value.([]interface{})
+			}
+		}
 	}
 
-	trades := v[pair].([]interface{})
 	for _, v := range trades {
-		trade := v.([]interface{})
+		trade, ok := v.([]interface{})
+		if !ok || len(trade) < 6 {
+			return nil, fmt.Errorf("unexpected trade %v", v)
+		}
+		priceString, _ := trade[0].(string)
+		volumeString, _ := trade[1].(string)
+		timestamp, _ := trade[2].(float64)
+		side, _ := trade[3].(string)
+		orderType, _ := trade[4].(string)
+		misc, _ := trade[5].(string)
 
-		priceString := trade[0].(string)
 		price, _ := strconv.ParseFloat(priceString, 64)
-
-		volumeString := trade[1].(string)
-		volume, _ := strconv.ParseFloat(trade[1].(string), 64)
+		volume, _ := strconv.ParseFloat(volumeString, 64)
 
 		tradeInfo := TradeInfo{
 			Price:         priceString,
 			PriceFloat:    price,
 			Volume:        volumeString,
 			VolumeFloat:   volume,
-			Time:          int64(trade[2].(float64)),
-			Buy:           trade[3].(string) == BUY,
-			Sell:          trade[3].(string) == SELL,
-			Market:        trade[4].(string) == MARKET,
-			Limit:         trade[4].(string) == LIMIT,
-			Miscellaneous: trade[5].(string),
+			Time:          int64(timestamp),
+			Timestamp:     unixFloat(timestamp),
+			Buy:           side == BUY,
+			Sell:          side == SELL,
+			Market:        orderType == MARKET,
+			Limit:         orderType == LIMIT,
+			Miscellaneous: misc,
+		}
+		if len(trade) > 6 {
+			id, _ := trade[6].(float64)
+			tradeInfo.TradeID = int64(id)
 		}
 
 		result.Trades = append(result.Trades, tradeInfo)
@@ -309,7 +586,27 @@ func (api *KrakenAPI) Trades(pair string, since int64) (*TradesResponse, error)
 
 // Balance returns all account asset balances
 func (api *KrakenAPI) Balance() (*BalanceResponse, error) {
-	resp, err := api.queryPrivate("Balance", url.Values{}, &BalanceResponse{})
+	return api.BalanceWithContext(context.Background())
+}
+
+// BalanceEx returns all account asset balances including credit and the amounts held by open orders
+func (api *KrakenAPI) BalanceEx() (*BalanceExResponse, error) {
+	return api.BalanceExWithContext(context.Background())
+}
+
+// BalanceExWithContext returns all account asset balances including credit and the amounts held by open orders
+func (api *KrakenAPI) BalanceExWithContext(ctx context.Context) (*BalanceExResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "BalanceEx", url.Values{}, &BalanceExResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*BalanceExResponse), nil
+}
+
+// BalanceWithContext returns all account asset balances
+func (api *KrakenAPI) BalanceWithContext(ctx context.Context) (*BalanceResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "Balance", url.Values{}, &BalanceResponse{})
 	if err != nil {
 		return nil, err
 	}
@@ -319,6 +616,11 @@ func (api *KrakenAPI) Balance() (*BalanceResponse, error) {
 
 // TradeBalance returns trade balance info
 func (api *KrakenAPI) TradeBalance(args map[string]string) (*TradeBalanceResponse, error) {
+	return api.TradeBalanceWithContext(context.Background(), args)
+}
+
+// TradeBalanceWithContext returns trade balance info
+func (api *KrakenAPI) TradeBalanceWithContext(ctx context.Context, args map[string]string) (*TradeBalanceResponse, error) {
 	params := url.Values{}
 	if value, ok := args["aclass"]; ok {
 		params.Add("aclass", value)
@@ -326,7 +628,7 @@ func (api *KrakenAPI) TradeBalance(args map[string]string) (*TradeBalanceRespons
 	if value, ok := args["asset"]; ok {
 		params.Add("asset", value)
 	}
-	resp, err := api.queryPrivate("TradeBalance", params, &TradeBalanceResponse{})
+	resp, err := api.queryPrivateContext(ctx, "TradeBalance", params, &TradeBalanceResponse{})
 	if err != nil {
 		return nil, err
 	}
@@ -334,8 +636,28 @@ func (api *KrakenAPI) TradeBalance(args map[string]string) (*TradeBalanceRespons
 	return resp.(*TradeBalanceResponse), nil
 }
 
+// TradeBalanceIn returns trade balance info denominated in asset. See TradeBalanceInWithContext.
+func (api *KrakenAPI) TradeBalanceIn(asset string) (*TradeBalanceResponse, error) {
+	return api.TradeBalanceInWithContext(context.Background(), asset)
+}
+
+// TradeBalanceInWithContext returns trade balance info denominated in asset,
+// e.g. "ZEUR", or in Kraken's default ZUSD if asset is empty
+func (api *KrakenAPI) TradeBalanceInWithContext(ctx context.Context, asset string) (*TradeBalanceResponse, error) {
+	args := map[string]string{}
+	if asset != "" {
+		args["asset"] = asset
+	}
+	return api.TradeBalanceWithContext(ctx, args)
+}
+
 // TradeVolume returns trade volume info
 func (api *KrakenAPI) TradeVolume(args map[string]string) (*TradeVolumeResponse, error) {
+	return api.TradeVolumeWithContext(context.Background(), args)
+}
+
+// TradeVolumeWithContext returns trade volume info
+func (api *KrakenAPI) TradeVolumeWithContext(ctx context.Context, args map[string]string) (*TradeVolumeResponse, error) {
 	params := url.Values{}
 	if value, ok := args["pair"]; ok {
 		params.Add("pair", value)
@@ -343,7 +665,7 @@ func (api *KrakenAPI) TradeVolume(args map[string]string) (*TradeVolumeResponse,
 	if value, ok := args["fee-info"]; ok {
 		params.Add("fee-info", value)
 	}
-	resp, err := api.queryPrivate("TradeVolume", params, &TradeVolumeResponse{})
+	resp, err := api.queryPrivateContext(ctx, "TradeVolume", params, &TradeVolumeResponse{})
 	if err != nil {
 		return nil, err
 	}
@@ -353,6 +675,11 @@ func (api *KrakenAPI) TradeVolume(args map[string]string) (*TradeVolumeResponse,
 
 // OpenOrders returns all open orders
 func (api *KrakenAPI) OpenOrders(args map[string]string) (*OpenOrdersResponse, error) {
+	return api.OpenOrdersWithContext(context.Background(), args)
+}
+
+// OpenOrdersWithContext returns all open orders
+func (api *KrakenAPI) OpenOrdersWithContext(ctx context.Context, args map[string]string) (*OpenOrdersResponse, error) {
 	params := url.Values{}
 	if value, ok := args["trades"]; ok {
 		params.Add("trades", value)
@@ -360,8 +687,11 @@ func (api *KrakenAPI) OpenOrders(args map[string]string) (*OpenOrdersResponse, e
 	if value, ok := args["userref"]; ok {
 		params.Add("userref", value)
 	}
+	if value, ok := args["cl_ord_id"]; ok {
+		params.Add("cl_ord_id", value)
+	}
 
-	resp, err := api.queryPrivate("OpenOrders", params, &OpenOrdersResponse{})
+	resp, err := api.queryPrivateContext(ctx, "OpenOrders", params, &OpenOrdersResponse{})
 
 	if err != nil {
 		return nil, err
@@ -372,6 +702,11 @@ func (api *KrakenAPI) OpenOrders(args map[string]string) (*OpenOrdersResponse, e
 
 // ClosedOrders returns all closed orders
 func (api *KrakenAPI) ClosedOrders(args map[string]string) (*ClosedOrdersResponse, error) {
+	return api.ClosedOrdersWithContext(context.Background(), args)
+}
+
+// ClosedOrdersWithContext returns all closed orders
+func (api *KrakenAPI) ClosedOrdersWithContext(ctx context.Context, args map[string]string) (*ClosedOrdersResponse, error) {
 	params := url.Values{}
 	if value, ok := args["trades"]; ok {
 		params.Add("trades", value)
@@ -391,7 +726,10 @@ func (api *KrakenAPI) ClosedOrders(args map[string]string) (*ClosedOrdersRespons
 	if value, ok := args["closetime"]; ok {
 		params.Add("closetime", value)
 	}
-	resp, err := api.queryPrivate("ClosedOrders", params, &ClosedOrdersResponse{})
+	if value, ok := args["cl_ord_id"]; ok {
+		params.Add("cl_ord_id", value)
+	}
+	resp, err := api.queryPrivateContext(ctx, "ClosedOrders", params, &ClosedOrdersResponse{})
 
 	if err != nil {
 		return nil, err
@@ -400,12 +738,32 @@ func (api *KrakenAPI) ClosedOrders(args map[string]string) (*ClosedOrdersRespons
 	return resp.(*ClosedOrdersResponse), nil
 }
 
-// Depth returns the order book for given pair and orders count.
+// maxDepthCount is the largest number of levels per side Kraken returns from Depth
+const maxDepthCount = 500
+
+// Depth returns the order book for given pair and orders count. Kraken
+// accepts counts between 1 and 500; 0 leaves the depth to Kraken.
 func (api *KrakenAPI) Depth(pair string, count int) (*OrderBook, error) {
+	return api.depth(context.Background(), pair, count)
+}
+
+// DepthWithContext returns the order book for given pair and orders count.
+// Kraken accepts counts between 1 and 500; 0 leaves the depth to Kraken.
+func (api *KrakenAPI) DepthWithContext(ctx context.Context, pair string, count int) (*OrderBook, error) {
+	return api.depth(ctx, pair, count)
+}
+
+// depth queries the order book of pair
+func (api *KrakenAPI) depth(ctx context.Context, pair string, count int) (*OrderBook, error) {
+	if count < 0 || count > maxDepthCount {
+		return nil, fmt.Errorf("count must be between 1 and %d, got %d", maxDepthCount, count)
+	}
+	values := url.Values{"pair": {pair}}
+	if count > 0 {
+		values.Set("count", strconv.Itoa(count))
+	}
 	dr := DepthResponse{}
-	_, err := api.queryPublic("Depth", url.Values{
-		"pair": {pair}, "count": {strconv.Itoa(count)},
-	}, &dr)
+	_, err := api.queryPublicContext(ctx, "Depth", values, &dr)
 
 	if err != nil {
 		return nil, err
@@ -414,17 +772,105 @@ func (api *KrakenAPI) Depth(pair string, count int) (*OrderBook, error) {
 	if book, found := dr[pair]; found {
 		return &book, nil
 	}
+	// Kraken answers with the canonical pair name, which may differ from the requested one
+	if len(dr) == 1 {
+		for _, book := range dr {
+			return &book, nil
+		}
+	}
 
 	return nil, errors.New("invalid response")
 }
 
 // CancelOrder cancels order
 func (api *KrakenAPI) CancelOrder(txid string) (*CancelOrderResponse, error) {
+	return api.CancelOrderWithContext(context.Background(), txid)
+}
+
+// CancelOrderWithContext cancels order
+func (api *KrakenAPI) CancelOrderWithContext(ctx context.Context, txid string) (*CancelOrderResponse, error) {
 	params := url.Values{}
 	params.Add("txid", txid)
-	resp, err := api.queryPrivate("CancelOrder", params, &CancelOrderResponse{})
+	resp, err := api.queryPrivateContext(ctx, "CancelOrder", params, &CancelOrderResponse{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*CancelOrderResponse), nil
+}
+
+// CancelAll cancels all open orders
+func (api *KrakenAPI) CancelAll() (*CancelOrderResponse, error) {
+	return api.CancelAllWithContext(context.Background())
+}
+
+// CancelAllWithContext cancels all open orders
+func (api *KrakenAPI) CancelAllWithContext(ctx context.Context) (*CancelOrderResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "CancelAll", url.Values{}, &CancelOrderResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*CancelOrderResponse), nil
+}
+
+// CancelAllOrdersAfter arms a timer cancelling all open orders once timeout
+// expires without the timer being refreshed. A zero timeout disarms it.
+func (api *KrakenAPI) CancelAllOrdersAfter(timeout time.Duration) (*CancelAllOrdersAfterResponse, error) {
+	return api.CancelAllOrdersAfterWithContext(context.Background(), timeout)
+}
+
+// CancelAllOrdersAfterWithContext arms a timer cancelling all open orders once
+// timeout expires without the timer being refreshed. A zero timeout disarms
+// it; other timeouts are rounded up to whole seconds so they never disarm.
+// Negative timeouts are rejected.
+func (api *KrakenAPI) CancelAllOrdersAfterWithContext(ctx context.Context, timeout time.Duration) (*CancelAllOrdersAfterResponse, error) {
+	if timeout < 0 {
+		return nil, fmt.Errorf("CancelAllOrdersAfter timeout must not be negative, got %v", timeout)
+	}
+	seconds := int(timeout / time.Second)
+	if timeout%time.Second > 0 {
+		seconds++
+	}
+	resp, err := api.queryPrivateContext(ctx, "CancelAllOrdersAfter", url.Values{
+		"timeout": {strconv.Itoa(seconds)},
+	}, &CancelAllOrdersAfterResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*CancelAllOrdersAfterResponse), nil
+}
+
+// CancelOrderBatch cancels up to 50 orders by txid or userref in a single
+// call. See CancelOrderBatchWithContext.
+func (api *KrakenAPI) CancelOrderBatch(txids []string) (*CancelOrderResponse, error) {
+	return api.CancelOrderBatchWithContext(context.Background(), txids)
+}
+
+// CancelOrderBatchWithContext cancels up to 50 orders by txid or userref in a
+// single call; numeric entries are sent as userrefs. When Kraken rejects the
+// batch because of some of the orders, they are cancelled one by one and the
+// count of the cancelled ones is returned along with a *BatchCancelError
+// listing the error of every order that could not be cancelled.
+func (api *KrakenAPI) CancelOrderBatchWithContext(ctx context.Context, txids []string) (*CancelOrderResponse, error) {
+	orders := make([]interface{}, len(txids))
+	for i, txid := range txids {
+		if userref, err := strconv.ParseInt(txid, 10, 32); err == nil {
+			orders[i] = userref
+		} else {
+			orders[i] = txid
+		}
+	}
 
+	resp, err := api.queryPrivateJSON(ctx, "CancelOrderBatch", map[string]interface{}{
+		"orders": orders,
+	}, &CancelOrderResponse{})
 	if err != nil {
+		if hasErrorCode(err, ErrCodeUnknownOrder) || hasErrorCode(err, ErrCodeInvalidArguments) {
+			return api.cancelOneByOne(ctx, txids)
+		}
 		return nil, err
 	}
 
@@ -433,6 +879,11 @@ func (api *KrakenAPI) CancelOrder(txid string) (*CancelOrderResponse, error) {
 
 // QueryOrders shows order
 func (api *KrakenAPI) QueryOrders(txids string, args map[string]string) (*QueryOrdersResponse, error) {
+	return api.QueryOrdersWithContext(context.Background(), txids, args)
+}
+
+// QueryOrdersWithContext shows order
+func (api *KrakenAPI) QueryOrdersWithContext(ctx context.Context, txids string, args map[string]string) (*QueryOrdersResponse, error) {
 	params := url.Values{"txid": {txids}}
 	if value, ok := args["trades"]; ok {
 		params.Add("trades", value)
@@ -440,7 +891,7 @@ func (api *KrakenAPI) QueryOrders(txids string, args map[string]string) (*QueryO
 	if value, ok := args["userref"]; ok {
 		params.Add("userref", value)
 	}
-	resp, err := api.queryPrivate("QueryOrders", params, &QueryOrdersResponse{})
+	resp, err := api.queryPrivateContext(ctx, "QueryOrders", params, &QueryOrdersResponse{})
 
 	if err != nil {
 		return nil, err
@@ -451,6 +902,11 @@ func (api *KrakenAPI) QueryOrders(txids string, args map[string]string) (*QueryO
 
 // AddOrder adds new order
 func (api *KrakenAPI) AddOrder(pair string, direction string, orderType string, volume string, args map[string]string) (*AddOrderResponse, error) {
+	return api.AddOrderWithContext(context.Background(), pair, direction, orderType, volume, args)
+}
+
+// AddOrderWithContext adds new order
+func (api *KrakenAPI) AddOrderWithContext(ctx context.Context, pair string, direction string, orderType string, volume string, args map[string]string) (*AddOrderResponse, error) {
 	params := url.Values{
 		"pair":      {pair},
 		"type":      {direction},
@@ -458,6 +914,9 @@ func (api *KrakenAPI) AddOrder(pair string, direction string, orderType string,
 		"volume":    {volume},
 	}
 
+	if err := checkCloseArgs(args); err != nil {
+		return nil, err
+	}
 	if value, ok := args["price"]; ok {
 		params.Add("price", value)
 	}
@@ -465,7 +924,11 @@ func (api *KrakenAPI) AddOrder(pair string, direction string, orderType string,
 		params.Add("price2", value)
 	}
 	if value, ok := args["leverage"]; ok {
-		params.Add("leverage", value)
+		leverage, err := parseLeverageArg(value)
+		if err != nil {
+			return nil, err
+		}
+		params.Add("leverage", leverage.String())
 	}
 	if value, ok := args["oflags"]; ok {
 		params.Add("oflags", value)
@@ -494,17 +957,179 @@ func (api *KrakenAPI) AddOrder(pair string, direction string, orderType string,
 	if value, ok := args["userref"]; ok {
 		params.Add("userref", value)
 	}
-	resp, err := api.queryPrivate("AddOrder", params, &AddOrderResponse{})
+	if value, ok := args["cl_ord_id"]; ok {
+		params.Add("cl_ord_id", value)
+	}
+	resp, err := api.queryPrivateContext(ctx, "AddOrder", params, &AddOrderResponse{})
 
 	if err != nil {
+		if api.diagnoseFunds && hasErrorCode(err, ErrCodeInsufficientFunds) {
+			return nil, api.diagnoseInsufficientFunds(ctx, err, pair, direction, volume, args)
+		}
 		return nil, err
 	}
 
 	return resp.(*AddOrderResponse), nil
 }
 
+// AddOrderBatch places up to 15 orders on pair in a single call. See
+// AddOrderBatchWithContext.
+func (api *KrakenAPI) AddOrderBatch(pair string, orders []AddOrderRequest) ([]AddOrderBatchResult, error) {
+	return api.AddOrderBatchWithContext(context.Background(), pair, orders)
+}
+
+// AddOrderBatchWithContext places 2 to 15 orders on pair in a single call,
+// which costs one nonce and one rate limit slot. The result of every order
+// is returned in the order of the request; orders Kraken rejected carry
+// their error instead of a txid.
+func (api *KrakenAPI) AddOrderBatchWithContext(ctx context.Context, pair string, orders []AddOrderRequest) ([]AddOrderBatchResult, error) {
+	if len(orders) < minAddOrderBatch || len(orders) > maxAddOrderBatch {
+		return nil, fmt.Errorf("AddOrderBatch takes %d to %d orders, got %d", minAddOrderBatch, maxAddOrderBatch, len(orders))
+	}
+
+	batch := make([]map[string]interface{}, len(orders))
+	for i, order := range orders {
+		entry, err := batchOrder(order)
+		if err != nil {
+			return nil, fmt.Errorf("order %d of the batch: %w", i, err)
+		}
+		batch[i] = entry
+	}
+
+	resp, err := api.queryPrivateJSON(ctx, "AddOrderBatch", map[string]interface{}{
+		"pair":   pair,
+		"orders": batch,
+	}, &AddOrderBatchResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*AddOrderBatchResponse).Orders, nil
+}
+
+// batchOrder returns the JSON form of an order of an AddOrderBatch call
+func batchOrder(order AddOrderRequest) (map[string]interface{}, error) {
+	entry := map[string]interface{}{
+		"type":      order.Direction,
+		"ordertype": order.OrderType,
+		"volume":    order.Volume,
+	}
+	if err := checkCloseArgs(order.Args); err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"price", "price2", "oflags", "starttm", "expiretm", "timeinforce", "cl_ord_id"} {
+		if value, ok := order.Args[key]; ok {
+			entry[key] = value
+		}
+	}
+	leverage := order.Leverage
+	if value, ok := order.Args["leverage"]; ok && leverage.IsNone() {
+		var err error
+		if leverage, err = parseLeverageArg(value); err != nil {
+			return nil, err
+		}
+	}
+	if !leverage.IsNone() {
+		entry["leverage"] = leverage.String()
+	}
+	if value, ok := order.Args["userref"]; ok {
+		userref, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid userref %q", value)
+		}
+		entry["userref"] = userref
+	}
+	if value, ok := order.Args["close_order_type"]; ok {
+		closing := map[string]interface{}{"ordertype": value}
+		if price, ok := order.Args["close_price"]; ok {
+			closing["price"] = price
+		}
+		if price2, ok := order.Args["close_price2"]; ok {
+			closing["price2"] = price2
+		}
+		entry["close"] = closing
+	}
+	return entry, nil
+}
+
+// closeArgs are the arguments of AddOrder describing the conditional close
+var closeArgs = []string{"close_order_type", "close_price", "close_price2"}
+
+// checkCloseArgs rejects close arguments AddOrder does not read, e.g. the raw
+// "close[ordertype]", which would otherwise be dropped silently and place the
+// order without its conditional close
+func checkCloseArgs(args map[string]string) error {
+	for key := range args {
+		if strings.HasPrefix(key, "close") && !isStringInSlice(key, closeArgs) {
+			return fmt.Errorf("unknown order argument %q, expected one of %s", key, strings.Join(closeArgs, ", "))
+		}
+	}
+	return nil
+}
+
+// EditOrder amends an open order in place. See EditOrderWithContext.
+func (api *KrakenAPI) EditOrder(txid string, pair string, args EditOrderArgs) (*EditOrderResponse, error) {
+	return api.EditOrderWithContext(context.Background(), txid, pair, args)
+}
+
+// EditOrderWithContext amends an open order in place, which unlike a cancel
+// and replace never leaves the book without the order. Prices and volume are
+// formatted with the decimals of the pair. Kraken may answer with Status
+// "err" and an ErrorMessage instead of failing the request.
+func (api *KrakenAPI) EditOrderWithContext(ctx context.Context, txid string, pair string, args EditOrderArgs) (*EditOrderResponse, error) {
+	pairs, err := api.assetPairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names, ok := pairs.FindPair(pair)
+	if !ok {
+		return nil, fmt.Errorf("unknown asset pair %q", pair)
+	}
+	info := (*pairs)[names.Name]
+
+	params := url.Values{
+		"txid": {txid},
+		"pair": {pair},
+	}
+	if args.Volume != 0 {
+		params.Set("volume", info.FormatVolume(args.Volume))
+	}
+	if args.Price != 0 {
+		params.Set("price", info.FormatPrice(args.Price))
+	}
+	if args.Price2 != 0 {
+		params.Set("price2", info.FormatPrice(args.Price2))
+	}
+	if args.OFlags != "" {
+		params.Set("oflags", args.OFlags)
+	}
+	if args.UserRef != 0 {
+		params.Set("userref", strconv.Itoa(args.UserRef))
+	}
+	if !args.Deadline.IsZero() {
+		params.Set("deadline", args.Deadline.UTC().Format(time.RFC3339Nano))
+	}
+	if args.CancelResponse {
+		params.Set("cancel_response", "true")
+	}
+	if args.Validate {
+		params.Set("validate", "true")
+	}
+
+	resp, err := api.queryPrivateContext(ctx, "EditOrder", params, &EditOrderResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*EditOrderResponse), nil
+}
+
 // Ledgers returns ledgers informations
 func (api *KrakenAPI) Ledgers(args map[string]string) (*LedgersResponse, error) {
+	return api.LedgersWithContext(context.Background(), args)
+}
+
+// LedgersWithContext returns ledgers informations
+func (api *KrakenAPI) LedgersWithContext(ctx context.Context, args map[string]string) (*LedgersResponse, error) {
 	params := url.Values{}
 	if value, ok := args["aclass"]; ok {
 		params.Add("aclass", value)
@@ -524,7 +1149,7 @@ func (api *KrakenAPI) Ledgers(args map[string]string) (*LedgersResponse, error)
 	if value, ok := args["ofs"]; ok {
 		params.Add("ofs", value)
 	}
-	resp, err := api.queryPrivate("Ledgers", params, &LedgersResponse{})
+	resp, err := api.queryPrivateContext(ctx, "Ledgers", params, &LedgersResponse{})
 	if err != nil {
 		return nil, err
 	}
@@ -532,9 +1157,95 @@ func (api *KrakenAPI) Ledgers(args map[string]string) (*LedgersResponse, error)
 	return resp.(*LedgersResponse), nil
 }
 
+// DepositMethods returns the methods available for depositing asset
+func (api *KrakenAPI) DepositMethods(asset string) ([]DepositMethodInfo, error) {
+	return api.DepositMethodsWithContext(context.Background(), asset)
+}
+
+// DepositMethodsWithContext returns the methods available for depositing
+// asset. Their names are what DepositAddresses and DepositStatus take.
+func (api *KrakenAPI) DepositMethodsWithContext(ctx context.Context, asset string) ([]DepositMethodInfo, error) {
+	var methods []DepositMethodInfo
+	if _, err := api.queryPrivateContext(ctx, "DepositMethods", url.Values{"asset": {asset}}, &methods); err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// WithdrawMethods returns the methods available for withdrawing. See WithdrawMethodsWithContext.
+func (api *KrakenAPI) WithdrawMethods(asset string, aclass string, network string) ([]WithdrawMethodInfo, error) {
+	return api.WithdrawMethodsWithContext(context.Background(), asset, aclass, network)
+}
+
+// WithdrawMethodsWithContext returns the methods available for withdrawing
+// asset of asset class aclass on network, e.g. to compare the networks of
+// an asset. Empty arguments are not filtered on.
+func (api *KrakenAPI) WithdrawMethodsWithContext(ctx context.Context, asset string, aclass string, network string) ([]WithdrawMethodInfo, error) {
+	params := url.Values{}
+	for key, value := range map[string]string{"asset": asset, "aclass": aclass, "network": network} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	var methods []WithdrawMethodInfo
+	if _, err := api.queryPrivateContext(ctx, "WithdrawMethods", params, &methods); err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// WithdrawAddresses returns the withdrawal addresses of the account. See WithdrawAddressesWithContext.
+func (api *KrakenAPI) WithdrawAddresses(args WithdrawAddressesArgs) ([]WithdrawAddressInfo, error) {
+	return api.WithdrawAddressesWithContext(context.Background(), args)
+}
+
+// WithdrawAddressesWithContext returns the withdrawal addresses of the
+// account matching args, with the keys to withdraw to them
+func (api *KrakenAPI) WithdrawAddressesWithContext(ctx context.Context, args WithdrawAddressesArgs) ([]WithdrawAddressInfo, error) {
+	params := url.Values{}
+	for key, value := range map[string]string{"asset": args.Asset, "aclass": args.AClass, "method": args.Method, "key": args.Key} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	if args.Verified != nil {
+		params.Set("verified", strconv.FormatBool(*args.Verified))
+	}
+	var addresses []WithdrawAddressInfo
+	if _, err := api.queryPrivateContext(ctx, "WithdrawAddresses", params, &addresses); err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// DepositStatus returns the recent deposits of asset. See DepositStatusWithContext.
+func (api *KrakenAPI) DepositStatus(asset string, method string) ([]DepositStatusInfo, error) {
+	return api.DepositStatusWithContext(context.Background(), asset, method)
+}
+
+// DepositStatusWithContext returns the recent deposits of asset made with
+// method, or with any method if method is empty. A deposit is credited once
+// its State is successful.
+func (api *KrakenAPI) DepositStatusWithContext(ctx context.Context, asset string, method string) ([]DepositStatusInfo, error) {
+	params := url.Values{"asset": {asset}}
+	if method != "" {
+		params.Set("method", method)
+	}
+	var deposits []DepositStatusInfo
+	if _, err := api.queryPrivateContext(ctx, "DepositStatus", params, &deposits); err != nil {
+		return nil, err
+	}
+	return deposits, nil
+}
+
 // DepositAddresses returns deposit addresses
 func (api *KrakenAPI) DepositAddresses(asset string, method string) (*DepositAddressesResponse, error) {
-	resp, err := api.queryPrivate("DepositAddresses", url.Values{
+	return api.DepositAddressesWithContext(context.Background(), asset, method)
+}
+
+// DepositAddressesWithContext returns deposit addresses
+func (api *KrakenAPI) DepositAddressesWithContext(ctx context.Context, asset string, method string) (*DepositAddressesResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "DepositAddresses", url.Values{
 		"asset":  {asset},
 		"method": {method},
 	}, &DepositAddressesResponse{})
@@ -546,10 +1257,15 @@ func (api *KrakenAPI) DepositAddresses(asset string, method string) (*DepositAdd
 
 // Withdraw executes a withdrawal, returning a reference ID
 func (api *KrakenAPI) Withdraw(asset string, key string, amount *big.Float) (*WithdrawResponse, error) {
-	resp, err := api.queryPrivate("Withdraw", url.Values{
+	return api.WithdrawWithContext(context.Background(), asset, key, amount)
+}
+
+// WithdrawWithContext executes a withdrawal, returning a reference ID
+func (api *KrakenAPI) WithdrawWithContext(ctx context.Context, asset string, key string, amount *big.Float) (*WithdrawResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "Withdraw", url.Values{
 		"asset":  {asset},
 		"key":    {key},
-		"amount": {amount.String()},
+		"amount": {FormatBigDecimal(amount, -1)},
 	}, &WithdrawResponse{})
 	if err != nil {
 		return nil, err
@@ -559,66 +1275,621 @@ func (api *KrakenAPI) Withdraw(asset string, key string, amount *big.Float) (*Wi
 
 // WithdrawInfo returns withdrawal information
 func (api *KrakenAPI) WithdrawInfo(asset string, key string, amount *big.Float) (*WithdrawInfoResponse, error) {
-	resp, err := api.queryPrivate("WithdrawInfo", url.Values{
+	return api.WithdrawInfoWithContext(context.Background(), asset, key, amount)
+}
+
+// WithdrawInfoWithContext returns withdrawal information
+func (api *KrakenAPI) WithdrawInfoWithContext(ctx context.Context, asset string, key string, amount *big.Float) (*WithdrawInfoResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "WithdrawInfo", url.Values{
 		"asset":  {asset},
 		"key":    {key},
-		"amount": {amount.String()},
+		"amount": {FormatBigDecimal(amount, -1)},
 	}, &WithdrawInfoResponse{})
 	if err != nil {
 		return nil, err
 	}
-	return resp.(*WithdrawInfoResponse), nil
+	info := resp.(*WithdrawInfoResponse)
+	info.DailyLimitUSD = api.tier.Limits().DailyWithdrawalUSD
+	return info, nil
+}
+
+// WalletTransfer moves amount of asset between wallets, e.g. from
+// WalletSpot to WalletFutures, returning a reference ID
+func (api *KrakenAPI) WalletTransfer(asset string, from string, to string, amount float64) (*WalletTransferResponse, error) {
+	return api.WalletTransferWithContext(context.Background(), asset, from, to, amount)
+}
+
+// WalletTransferWithContext moves amount of asset between wallets, e.g. from
+// WalletSpot to WalletFutures, returning a reference ID. The amount is
+// truncated to the decimals of the asset, which are looked up first.
+func (api *KrakenAPI) WalletTransferWithContext(ctx context.Context, asset string, from string, to string, amount float64) (*WalletTransferResponse, error) {
+	formatted, err := api.formatAssetAmount(ctx, asset, amount)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := api.queryPrivateContext(ctx, "WalletTransfer", url.Values{
+		"asset":  {asset},
+		"from":   {from},
+		"to":     {to},
+		"amount": {formatted},
+	}, &WalletTransferResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*WalletTransferResponse), nil
+}
+
+// AccountTransfer moves funds between a master account and its subaccounts. See AccountTransferWithContext.
+func (api *KrakenAPI) AccountTransfer(asset string, amount string, fromAccount, toAccount string) (*AccountTransferResponse, error) {
+	return api.AccountTransferWithContext(context.Background(), asset, amount, fromAccount, toAccount)
+}
+
+// AccountTransferWithContext moves amount of asset from the account with IIBAN
+// fromAccount to toAccount, between a master account and its subaccounts.
+// Only institutional accounts can transfer; keys without the permission fail
+// with an error matching ErrPermissionDenied.
+func (api *KrakenAPI) AccountTransferWithContext(ctx context.Context, asset string, amount string, fromAccount, toAccount string) (*AccountTransferResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "AccountTransfer", url.Values{
+		"asset":  {asset},
+		"amount": {amount},
+		"from":   {fromAccount},
+		"to":     {toAccount},
+	}, &AccountTransferResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*AccountTransferResponse), nil
+}
+
+// CreateSubaccount creates a trading subaccount. See CreateSubaccountWithContext.
+func (api *KrakenAPI) CreateSubaccount(username, email string) (bool, error) {
+	return api.CreateSubaccountWithContext(context.Background(), username, email)
+}
+
+// CreateSubaccountWithContext creates a trading subaccount of the account
+// with username and email. Only institutional accounts can create
+// subaccounts; keys without the permission fail with an error matching
+// ErrPermissionDenied.
+func (api *KrakenAPI) CreateSubaccountWithContext(ctx context.Context, username, email string) (bool, error) {
+	var created bool
+	if _, err := api.queryPrivateContext(ctx, "CreateSubaccount", url.Values{
+		"username": {username},
+		"email":    {email},
+	}, &created); err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// formatAssetAmount renders amount truncated to the decimals of asset, which
+// are looked up first, so that moving a whole balance never asks for more
+func (api *KrakenAPI) formatAssetAmount(ctx context.Context, asset string, amount float64) (string, error) {
+	assets, err := api.assets(ctx)
+	if err != nil {
+		return "", err
+	}
+	info, ok := findAsset(*assets, asset)
+	if !ok {
+		return "", fmt.Errorf("unknown asset %s", asset)
+	}
+	return truncateDecimal(amount, info.Decimals), nil
+}
+
+// EarnAllocate allocates funds to an Earn strategy. See EarnAllocateWithContext.
+func (api *KrakenAPI) EarnAllocate(strategyID string, amount string) (bool, error) {
+	return api.EarnAllocateWithContext(context.Background(), strategyID, amount)
+}
+
+// EarnAllocateWithContext allocates amount of the strategy's asset to the
+// Earn strategy, returning whether Kraken accepted the request. The
+// allocation completes asynchronously, see WaitForAllocation.
+func (api *KrakenAPI) EarnAllocateWithContext(ctx context.Context, strategyID string, amount string) (bool, error) {
+	var accepted bool
+	if _, err := api.queryPrivateJSON(ctx, "Earn/Allocate", map[string]interface{}{
+		"strategy_id": strategyID,
+		"amount":      amount,
+	}, &accepted); err != nil {
+		return false, err
+	}
+	return accepted, nil
+}
+
+// EarnAllocateStatus returns the status of the last allocation to a strategy. See EarnAllocateStatusWithContext.
+func (api *KrakenAPI) EarnAllocateStatus(strategyID string) (*EarnOperationStatus, error) {
+	return api.EarnAllocateStatusWithContext(context.Background(), strategyID)
+}
+
+// EarnAllocateStatusWithContext returns the status of the last allocation to
+// the Earn strategy
+func (api *KrakenAPI) EarnAllocateStatusWithContext(ctx context.Context, strategyID string) (*EarnOperationStatus, error) {
+	resp, err := api.queryPrivateJSON(ctx, "Earn/AllocateStatus", map[string]interface{}{
+		"strategy_id": strategyID,
+	}, &EarnOperationStatus{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*EarnOperationStatus), nil
+}
+
+// EarnDeallocate deallocates funds from an Earn strategy. See EarnDeallocateWithContext.
+func (api *KrakenAPI) EarnDeallocate(strategyID string, amount string) (bool, error) {
+	return api.EarnDeallocateWithContext(context.Background(), strategyID, amount)
+}
+
+// EarnDeallocateWithContext deallocates amount of the strategy's asset from
+// the Earn strategy, returning whether Kraken accepted the request. The
+// amount is sent as given, so it should carry the precision of the asset,
+// e.g. from AssetInfo.FormatAmount. Amounts below the strategy's minimum
+// fail with an error matching ErrEarnBelowMin. The deallocation may only
+// complete at the end of the unbonding period, see WaitForDeallocation.
+func (api *KrakenAPI) EarnDeallocateWithContext(ctx context.Context, strategyID string, amount string) (bool, error) {
+	var accepted bool
+	if _, err := api.queryPrivateJSON(ctx, "Earn/Deallocate", map[string]interface{}{
+		"strategy_id": strategyID,
+		"amount":      amount,
+	}, &accepted); err != nil {
+		return false, err
+	}
+	return accepted, nil
+}
+
+// EarnDeallocateStatus returns the status of the last deallocation from a strategy. See EarnDeallocateStatusWithContext.
+func (api *KrakenAPI) EarnDeallocateStatus(strategyID string) (*EarnOperationStatus, error) {
+	return api.EarnDeallocateStatusWithContext(context.Background(), strategyID)
+}
+
+// EarnDeallocateStatusWithContext returns the status of the last
+// deallocation from the Earn strategy
+func (api *KrakenAPI) EarnDeallocateStatusWithContext(ctx context.Context, strategyID string) (*EarnOperationStatus, error) {
+	resp, err := api.queryPrivateJSON(ctx, "Earn/DeallocateStatus", map[string]interface{}{
+		"strategy_id": strategyID,
+	}, &EarnOperationStatus{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*EarnOperationStatus), nil
+}
+
+// OpenPositions returns the open margin positions. See OpenPositionsWithContext.
+func (api *KrakenAPI) OpenPositions(txids []string, docalcs bool) (map[string]PositionInfo, error) {
+	return api.OpenPositionsWithContext(context.Background(), txids, docalcs)
+}
+
+// OpenPositionsWithContext returns the open margin positions indexed by
+// position id, restricted to txids unless empty. docalcs has Kraken compute
+// the Value and Net of each position, which are zero otherwise.
+func (api *KrakenAPI) OpenPositionsWithContext(ctx context.Context, txids []string, docalcs bool) (map[string]PositionInfo, error) {
+	params := url.Values{}
+	if len(txids) > 0 {
+		params.Set("txid", strings.Join(txids, ","))
+	}
+	if docalcs {
+		params.Set("docalcs", "true")
+	}
+	var positions map[string]PositionInfo
+	if _, err := api.queryPrivateContext(ctx, "OpenPositions", params, &positions); err != nil {
+		return nil, err
+	}
+	return positions, nil
+}
+
+// AddExport requests an export of the trades or ledgers of the account. See AddExportWithContext.
+func (api *KrakenAPI) AddExport(report, format, description string, fields []string, starttm, endtm time.Time) (*AddExportResponse, error) {
+	return api.AddExportWithContext(context.Background(), report, format, description, fields, starttm, endtm)
+}
+
+// AddExportWithContext requests an export of report, ExportTrades or
+// ExportLedgers, between starttm and endtm in format, ExportCSV or ExportTSV.
+// An empty format, empty fields or zero times leave Kraken's defaults: CSV,
+// every field, and the whole account history. Fields are checked against the
+// fields of the report before anything is sent. The export is ready to
+// retrieve once ExportStatus reports it processed.
+func (api *KrakenAPI) AddExportWithContext(ctx context.Context, report, format, description string, fields []string, starttm, endtm time.Time) (*AddExportResponse, error) {
+	if err := checkExportFields(report, fields); err != nil {
+		return nil, err
+	}
+	params := url.Values{"report": {report}, "description": {description}}
+	if format != "" {
+		params.Set("format", format)
+	}
+	if len(fields) > 0 {
+		params.Set("fields", strings.Join(fields, ","))
+	}
+	if !starttm.IsZero() {
+		params.Set("starttm", strconv.FormatInt(starttm.Unix(), 10))
+	}
+	if !endtm.IsZero() {
+		params.Set("endtm", strconv.FormatInt(endtm.Unix(), 10))
+	}
+	resp, err := api.queryPrivateContext(ctx, "AddExport", params, &AddExportResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*AddExportResponse), nil
+}
+
+// ExportStatus returns the exports of report. See ExportStatusWithContext.
+func (api *KrakenAPI) ExportStatus(report string) ([]ExportStatusInfo, error) {
+	return api.ExportStatusWithContext(context.Background(), report)
+}
+
+// ExportStatusWithContext returns the queued, processing and processed
+// exports of report, ExportTrades or ExportLedgers
+func (api *KrakenAPI) ExportStatusWithContext(ctx context.Context, report string) ([]ExportStatusInfo, error) {
+	var exports []ExportStatusInfo
+	if _, err := api.queryPrivateContext(ctx, "ExportStatus", url.Values{"report": {report}}, &exports); err != nil {
+		return nil, err
+	}
+	return exports, nil
+}
+
+// RetrieveExport returns the ZIP archive of a processed export. See RetrieveExportWithContext.
+func (api *KrakenAPI) RetrieveExport(id string) ([]byte, error) {
+	return api.RetrieveExportWithContext(context.Background(), id)
+}
+
+// RetrieveExportWithContext returns the ZIP archive of the export id, which
+// ExportStatus has to report processed. Use RetrieveExportTo to avoid
+// holding large archives in memory.
+func (api *KrakenAPI) RetrieveExportWithContext(ctx context.Context, id string) ([]byte, error) {
+	var archive bytes.Buffer
+	if err := api.RetrieveExportToWithContext(ctx, id, &archive); err != nil {
+		return nil, err
+	}
+	return archive.Bytes(), nil
+}
+
+// RetrieveExportTo writes the ZIP archive of the export id to w. See
+// RetrieveExportToWithContext.
+func (api *KrakenAPI) RetrieveExportTo(id string, w io.Writer) error {
+	return api.RetrieveExportToWithContext(context.Background(), id, w)
+}
+
+// RetrieveExportToWithContext writes the ZIP archive of the export id to w
+// as it is received. Kraken errors, e.g. for an unknown id, are returned as
+// an *APIError with nothing written. It is not retried.
+func (api *KrakenAPI) RetrieveExportToWithContext(ctx context.Context, id string, w io.Writer) error {
+	return api.downloadPrivate(ctx, "RetrieveExport", url.Values{"id": {id}}, w)
+}
+
+// RemoveExport cancels or deletes an export. See RemoveExportWithContext.
+func (api *KrakenAPI) RemoveExport(id string, removalType string) (*RemoveExportResponse, error) {
+	return api.RemoveExportWithContext(context.Background(), id, removalType)
+}
+
+// RemoveExportWithContext cancels the export id if removalType is
+// ExportCancel, or deletes it if it is ExportDelete. Kraken only cancels
+// exports that are not processed yet and only deletes processed ones.
+func (api *KrakenAPI) RemoveExportWithContext(ctx context.Context, id string, removalType string) (*RemoveExportResponse, error) {
+	if removalType != ExportCancel && removalType != ExportDelete {
+		return nil, fmt.Errorf("unknown export removal type %q", removalType)
+	}
+	resp, err := api.queryPrivateContext(ctx, "RemoveExport", url.Values{
+		"id":   {id},
+		"type": {removalType},
+	}, &RemoveExportResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*RemoveExportResponse), nil
+}
+
+// EarnAllocations returns the funds allocated to Earn strategies. See EarnAllocationsWithContext.
+func (api *KrakenAPI) EarnAllocations(convertedAsset string, hideZero bool) (*EarnAllocationsResponse, error) {
+	return api.EarnAllocationsWithContext(context.Background(), convertedAsset, hideZero)
+}
+
+// EarnAllocationsWithContext returns the funds allocated to Earn strategies,
+// which Balance does not include, converted to convertedAsset, or to USD if
+// it is empty. hideZero leaves out the strategies without funds.
+func (api *KrakenAPI) EarnAllocationsWithContext(ctx context.Context, convertedAsset string, hideZero bool) (*EarnAllocationsResponse, error) {
+	params := map[string]interface{}{}
+	if convertedAsset != "" {
+		params["converted_asset"] = convertedAsset
+	}
+	if hideZero {
+		params["hide_zero_allocations"] = true
+	}
+	resp, err := api.queryPrivateJSON(ctx, "Earn/Allocations", params, &EarnAllocationsResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*EarnAllocationsResponse), nil
+}
+
+// EarnStrategies returns a page of the Earn strategies. See EarnStrategiesWithContext.
+func (api *KrakenAPI) EarnStrategies(args EarnStrategiesArgs) (*EarnStrategiesResponse, error) {
+	return api.EarnStrategiesWithContext(context.Background(), args)
+}
+
+// EarnStrategiesWithContext returns a page of the Earn strategies matching
+// args. Further pages are fetched by passing NextCursor as Cursor until it
+// comes back empty.
+func (api *KrakenAPI) EarnStrategiesWithContext(ctx context.Context, args EarnStrategiesArgs) (*EarnStrategiesResponse, error) {
+	params := map[string]interface{}{}
+	if args.Asset != "" {
+		params["asset"] = args.Asset
+	}
+	if len(args.LockTypes) > 0 {
+		params["lock_type"] = args.LockTypes
+	}
+	if args.Cursor != "" {
+		params["cursor"] = args.Cursor
+	}
+	if args.Limit > 0 {
+		params["limit"] = args.Limit
+	}
+	if args.Ascending {
+		params["ascending"] = true
+	}
+	resp, err := api.queryPrivateJSON(ctx, "Earn/Strategies", params, &EarnStrategiesResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*EarnStrategiesResponse), nil
+}
+
+// StakeAsset stakes amount of asset with the staking method. See StakeAssetWithContext.
+func (api *KrakenAPI) StakeAsset(asset string, amount string, method string) (*StakeResponse, error) {
+	return api.StakeAssetWithContext(context.Background(), asset, amount, method)
+}
+
+// StakeAssetWithContext stakes amount of asset with the staking method,
+// returning a reference ID. Kraken's refusals, e.g. for lack of funds, match
+// their sentinel such as ErrFundingInsufficientFunds with errors.Is.
+func (api *KrakenAPI) StakeAssetWithContext(ctx context.Context, asset string, amount string, method string) (*StakeResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "Stake", url.Values{
+		"asset":  {asset},
+		"amount": {amount},
+		"method": {method},
+	}, &StakeResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*StakeResponse), nil
+}
+
+// StakeAssetFloat stakes amount of asset like StakeAsset. See StakeAssetFloatWithContext.
+func (api *KrakenAPI) StakeAssetFloat(asset string, amount float64, method string) (*StakeResponse, error) {
+	return api.StakeAssetFloatWithContext(context.Background(), asset, amount, method)
+}
+
+// StakeAssetFloatWithContext stakes amount of asset like StakeAssetWithContext,
+// truncating the amount to the decimals of the asset, which are looked up first
+func (api *KrakenAPI) StakeAssetFloatWithContext(ctx context.Context, asset string, amount float64, method string) (*StakeResponse, error) {
+	formatted, err := api.formatAssetAmount(ctx, asset, amount)
+	if err != nil {
+		return nil, err
+	}
+	return api.StakeAssetWithContext(ctx, asset, formatted, method)
+}
+
+// StakingAssets returns the assets that can be staked. See StakingAssetsWithContext.
+func (api *KrakenAPI) StakingAssets() ([]StakingAssetInfo, error) {
+	return api.StakingAssetsWithContext(context.Background())
+}
+
+// StakingAssetsWithContext returns the assets that can be staked with their
+// staking method, minimums, lock periods and rewards
+func (api *KrakenAPI) StakingAssetsWithContext(ctx context.Context) ([]StakingAssetInfo, error) {
+	var assets []StakingAssetInfo
+	if _, err := api.queryPrivateContext(ctx, "Staking/Assets", url.Values{}, &assets); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// StakingPendingTransactions returns the staking transactions in progress. See StakingPendingTransactionsWithContext.
+func (api *KrakenAPI) StakingPendingTransactions() ([]StakingTransaction, error) {
+	return api.StakingPendingTransactionsWithContext(context.Background())
+}
+
+// StakingPendingTransactionsWithContext returns the staking transactions in
+// progress. Funds of a pending StakingUnbonding transaction are still in
+// their unbonding period and not available yet.
+func (api *KrakenAPI) StakingPendingTransactionsWithContext(ctx context.Context) ([]StakingTransaction, error) {
+	var transactions []StakingTransaction
+	if _, err := api.queryPrivateContext(ctx, "Staking/Pending", url.Values{}, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// StakingTransactions returns the recent staking transactions. See StakingTransactionsWithContext.
+func (api *KrakenAPI) StakingTransactions() ([]StakingTransaction, error) {
+	return api.StakingTransactionsWithContext(context.Background())
+}
+
+// StakingTransactionsWithContext returns the recent staking transactions:
+// stakings, unstakings and rewards, completed or not
+func (api *KrakenAPI) StakingTransactionsWithContext(ctx context.Context) ([]StakingTransaction, error) {
+	var transactions []StakingTransaction
+	if _, err := api.queryPrivateContext(ctx, "Staking/Transactions", url.Values{}, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// UnstakeAsset unstakes amount of the staked asset. See UnstakeAssetWithContext.
+func (api *KrakenAPI) UnstakeAsset(asset string, amount string) (*UnstakeResponse, error) {
+	return api.UnstakeAssetWithContext(context.Background(), asset, amount)
+}
+
+// UnstakeAssetWithContext unstakes amount of asset, which is the staked
+// variant (e.g. "DOT.S") or the asset itself, returning a reference ID. The
+// funds may stay bonded for a while afterwards.
+func (api *KrakenAPI) UnstakeAssetWithContext(ctx context.Context, asset string, amount string) (*UnstakeResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "Unstake", url.Values{
+		"asset":  {asset},
+		"amount": {amount},
+	}, &UnstakeResponse{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*UnstakeResponse), nil
+}
+
+// UnstakeAssetFloat unstakes amount of asset like UnstakeAsset. See UnstakeAssetFloatWithContext.
+func (api *KrakenAPI) UnstakeAssetFloat(asset string, amount float64) (*UnstakeResponse, error) {
+	return api.UnstakeAssetFloatWithContext(context.Background(), asset, amount)
+}
+
+// UnstakeAssetFloatWithContext unstakes amount of asset like
+// UnstakeAssetWithContext, truncating the amount to the decimals of the
+// asset, which are looked up first
+func (api *KrakenAPI) UnstakeAssetFloatWithContext(ctx context.Context, asset string, amount float64) (*UnstakeResponse, error) {
+	formatted, err := api.formatAssetAmount(ctx, asset, amount)
+	if err != nil {
+		return nil, err
+	}
+	return api.UnstakeAssetWithContext(ctx, asset, formatted)
 }
 
 // Query sends a query to Kraken api for given method and parameters
 func (api *KrakenAPI) Query(method string, data map[string]string) (interface{}, error) {
+	return api.QueryWithContext(context.Background(), method, data)
+}
+
+// QueryWithContext sends a query to Kraken api for given method and parameters
+func (api *KrakenAPI) QueryWithContext(ctx context.Context, method string, data map[string]string) (interface{}, error) {
 	values := url.Values{}
 	for key, value := range data {
 		values.Set(key, value)
 	}
 
+	endpoint, ok := LookupEndpoint(method)
+	if !ok {
+		return nil, fmt.Errorf("Method '%s' is not valid", method)
+	}
+	if err := endpoint.validate(values); err != nil {
+		return nil, err
+	}
+
 	// Check if method is public or private
-	if isStringInSlice(method, publicMethods) {
-		return api.queryPublic(method, values, nil)
-	} else if isStringInSlice(method, privateMethods) {
-		return api.queryPrivate(method, values, nil)
+	if endpoint.Private {
+		return api.queryPrivateContext(ctx, method, values, nil)
 	}
+	return api.queryPublicContext(ctx, method, values, nil)
+}
 
-	return nil, fmt.Errorf("Method '%s' is not valid", method)
+// queryPublicContext executes a public method query bound to ctx
+func (api *KrakenAPI) queryPublicContext(ctx context.Context, reqURL string, values url.Values, typ interface{}) (interface{}, error) {
+	url, err := api.endpointURL(Endpoint{Name: reqURL})
+	if err != nil {
+		return nil, err
+	}
+	return api.doubleRead(ctx, reqURL, typ, func(typ interface{}) (interface{}, error) {
+		return api.withRetry(ctx, reqURL, func() (interface{}, error) {
+			return api.doGet(ctx, url, values, nil, typ)
+		})
+	})
 }
 
-// Execute a public method query
-func (api *KrakenAPI) queryPublic(reqURL string, values url.Values, typ interface{}) (interface{}, error) {
-	url := fmt.Sprintf("%s/%s/public/%s", APIURL, APIVersion, reqURL)
-	return api.doGet(url, values, nil, typ)
+// queryPrivateContext executes a private method query bound to ctx
+func (api *KrakenAPI) queryPrivateContext(ctx context.Context, method string, values url.Values, typ interface{}) (interface{}, error) {
+	urlPath := Endpoint{Name: method, Private: true}.Path()
+	reqURL, err := api.endpointURL(Endpoint{Name: method, Private: true})
+	if err != nil {
+		return nil, err
+	}
+	secret, _ := base64.StdEncoding.DecodeString(api.secret)
+
+	if err := api.statusGate.allow(method); err != nil {
+		return nil, err
+	}
+
+	return api.doubleRead(ctx, method, typ, func(typ interface{}) (interface{}, error) {
+		return api.withRetry(ctx, method, func() (interface{}, error) {
+			if err := api.waitForBudget(ctx, method); err != nil {
+				return nil, err
+			}
+			values.Set("nonce", api.nextNonce())
+
+			// Create signature
+			signature := createSignature(urlPath, values, secret)
+
+			// Add Key and signature to request headers
+			headers := map[string]string{
+				"API-Key":  api.key,
+				"API-Sign": signature,
+			}
+
+			return api.doPost(ctx, reqURL, strings.NewReader(EncodeParams(values)), headers, typ)
+		})
+	})
 }
 
-// queryPrivate executes a private method query
-func (api *KrakenAPI) queryPrivate(method string, values url.Values, typ interface{}) (interface{}, error) {
-	urlPath := fmt.Sprintf("/%s/private/%s", APIVersion, method)
-	reqURL := fmt.Sprintf("%s%s", APIURL, urlPath)
+// downloadPrivate executes a private method query whose result is a file,
+// copying it to w. It is not retried, since part of the file may already have
+// been written when a request fails.
+func (api *KrakenAPI) downloadPrivate(ctx context.Context, method string, values url.Values, w io.Writer) error {
+	urlPath := Endpoint{Name: method, Private: true}.Path()
+	reqURL, err := api.endpointURL(Endpoint{Name: method, Private: true})
+	if err != nil {
+		return err
+	}
 	secret, _ := base64.StdEncoding.DecodeString(api.secret)
-	values.Set("nonce", fmt.Sprintf("%d", time.Now().UnixNano()))
 
-	// Create signature
-	signature := createSignature(urlPath, values, secret)
+	if err := api.statusGate.allow(method); err != nil {
+		return err
+	}
+	if err := api.waitForBudget(ctx, method); err != nil {
+		return err
+	}
+	values.Set("nonce", api.nextNonce())
 
-	// Add Key and signature to request headers
 	headers := map[string]string{
 		"API-Key":  api.key,
-		"API-Sign": signature,
+		"API-Sign": createSignature(urlPath, values, secret),
 	}
 
-	resp, err := api.doPost(reqURL, values, headers, typ)
+	_, err = api.doPost(ctx, reqURL, strings.NewReader(EncodeParams(values)), headers, &fileResult{w: w})
+	return err
+}
 
-	return resp, err
+// queryPrivateJSON executes a private method query whose parameters have to
+// be sent as a JSON document, e.g. the batch endpoints taking arrays.
+func (api *KrakenAPI) queryPrivateJSON(ctx context.Context, method string, params map[string]interface{}, typ interface{}) (interface{}, error) {
+	urlPath := Endpoint{Name: method, Private: true}.Path()
+	reqURL, err := api.endpointURL(Endpoint{Name: method, Private: true})
+	if err != nil {
+		return nil, err
+	}
+	secret, _ := base64.StdEncoding.DecodeString(api.secret)
+
+	if err := api.statusGate.allow(method); err != nil {
+		return nil, err
+	}
+
+	return api.doubleRead(ctx, method, typ, func(typ interface{}) (interface{}, error) {
+		return api.withRetry(ctx, method, func() (interface{}, error) {
+			if err := api.waitForBudget(ctx, method); err != nil {
+				return nil, err
+			}
+			nonce := api.nextNonce()
+			params["nonce"] = nonce
+
+			body, err := EncodeJSONParams(params)
+			if err != nil {
+				return nil, fmt.Errorf("Could not execute request! #1 (%s)", err.Error())
+			}
+
+			headers := map[string]string{
+				"API-Key":      api.key,
+				"API-Sign":     signPayload(urlPath, nonce, body, secret),
+				"Content-Type": "application/json",
+			}
+
+			return api.doPost(ctx, reqURL, bytes.NewReader(body), headers, typ)
+		})
+	})
 }
 
-func (api *KrakenAPI) doGet(reqURL string, values url.Values, headers map[string]string, typ interface{}) (interface{}, error) {
+func (api *KrakenAPI) doGet(ctx context.Context, reqURL string, values url.Values, headers map[string]string, typ interface{}) (interface{}, error) {
 	encodedValues := values.Encode()
 	fullURL := reqURL + "?" + encodedValues
 
-	req, err := http.NewRequest("GET", fullURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fullURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Could not execute request! #1 (%s)", err.Error())
 	}
@@ -627,10 +1898,10 @@ func (api *KrakenAPI) doGet(reqURL string, values url.Values, headers map[string
 }
 
 // doPost executes a HTTP Request to the Kraken API and returns the result
-func (api *KrakenAPI) doPost(reqURL string, values url.Values, headers map[string]string, typ interface{}) (interface{}, error) {
+func (api *KrakenAPI) doPost(ctx context.Context, reqURL string, body io.Reader, headers map[string]string, typ interface{}) (interface{}, error) {
 
 	// Create request
-	req, err := http.NewRequest("POST", reqURL, strings.NewReader(values.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("Could not execute request! #1 (%s)", err.Error())
 	}
@@ -638,7 +1909,34 @@ func (api *KrakenAPI) doPost(reqURL string, values url.Values, headers map[strin
 	return api.doAPIRequest(req, headers, typ)
 }
 
+// nextNonce returns a nonce strictly greater than every nonce this client
+// issued before, even when called concurrently within the same nanosecond
+func (api *KrakenAPI) nextNonce() string {
+	for {
+		last := atomic.LoadInt64(&api.nonce)
+		next := time.Now().UnixNano()
+		if next <= last {
+			next = last + 1
+		}
+		if atomic.CompareAndSwapInt64(&api.nonce, last, next) {
+			return strconv.FormatInt(next, 10)
+		}
+	}
+}
+
+// doAPIRequest executes req and reports it to the installed hooks
 func (api *KrakenAPI) doAPIRequest(req *http.Request, headers map[string]string, typ interface{}) (interface{}, error) {
+	start := time.Now()
+	result, err := api.executeRequest(req, headers, typ)
+	event := requestEvent(req, time.Since(start), err)
+	api.stats.request(event.Method, err)
+	if api.hooks.OnRequest != nil {
+		api.hooks.OnRequest(event)
+	}
+	return result, err
+}
+
+func (api *KrakenAPI) executeRequest(req *http.Request, headers map[string]string, typ interface{}) (interface{}, error) {
 	req.Header.Add("User-Agent", APIUserAgent)
 	for key, value := range headers {
 		req.Header.Add(key, value)
@@ -647,14 +1945,18 @@ func (api *KrakenAPI) doAPIRequest(req *http.Request, headers map[string]string,
 	// Execute request
 	resp, err := api.client.Do(req)
 	if err != nil {
+		// Wrap cancellations so that errors.Is(err, context.Canceled) holds
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, fmt.Errorf("Could not execute request! #2 (%w)", ctxErr)
+		}
 		return nil, fmt.Errorf("Could not execute request! #2 (%s)", err.Error())
 	}
 	defer resp.Body.Close()
+	api.stats.observeClock(resp.Header, time.Now())
 
-	// Read request
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("Could not execute request! #3 (%s)", err.Error())
+	// Copy files as they come, their only JSON bodies are errors
+	if file, ok := typ.(*fileResult); ok {
+		return nil, file.decode(resp)
 	}
 
 	// Check mime type of response
@@ -666,6 +1968,17 @@ func (api *KrakenAPI) doAPIRequest(req *http.Request, headers map[string]string,
 		return nil, fmt.Errorf("Could not execute request #5! (%s)", fmt.Sprintf("Response Content-Type is '%s', but should be 'application/json'.", mimeType))
 	}
 
+	// Decode large responses entry by entry instead of reading them whole
+	if stream, ok := typ.(*resultStream); ok {
+		return nil, stream.decode(resp.Body)
+	}
+
+	// Read request
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Could not execute request! #3 (%s)", err.Error())
+	}
+
 	// Parse request
 	var jsonData KrakenResponse
 
@@ -682,7 +1995,11 @@ func (api *KrakenAPI) doAPIRequest(req *http.Request, headers map[string]string,
 
 	// Check for Kraken API error
 	if len(jsonData.Error) > 0 {
-		return nil, fmt.Errorf("Could not execute request! #7 (%s)", jsonData.Error)
+		return nil, &APIError{Errors: jsonData.Error}
+	}
+
+	if err := api.checkEnums(jsonData.Result); err != nil {
+		return nil, err
 	}
 
 	return jsonData.Result, nil
@@ -713,8 +2030,13 @@ func getHMacSha512(message, secret []byte) []byte {
 }
 
 func createSignature(urlPath string, values url.Values, secret []byte) string {
+	return signPayload(urlPath, values.Get("nonce"), []byte(EncodeParams(values)), secret)
+}
+
+// signPayload signs an already encoded request body
+func signPayload(urlPath string, nonce string, body []byte, secret []byte) string {
 	// See https://www.kraken.com/help/api#general-usage for more information
-	shaSum := getSha256([]byte(values.Get("nonce") + values.Encode()))
+	shaSum := getSha256(append([]byte(nonce), body...))
 	macSum := getHMacSha512(append([]byte(urlPath), shaSum...), secret)
 	return base64.StdEncoding.EncodeToString(macSum)
 }
@@ -1,14 +1,41 @@
 package krakenapi
 
 import (
+	"context"
 	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 )
 
 var publicAPI = New("", "")
 
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestAPI returns a client whose requests are answered by handler instead
+// of Kraken. The handler receives the API method name, e.g. "OpenOrders".
+func newTestAPI(handler func(method string, req *http.Request) string) *KrakenAPI {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		method := strings.TrimPrefix(req.URL.Path, "/"+APIVersion+"/public/")
+		method = strings.TrimPrefix(method, "/"+APIVersion+"/private/")
+
+		rec := httptest.NewRecorder()
+		rec.Header().Set("Content-Type", "application/json")
+		rec.WriteString(handler(method, req))
+		return rec.Result(), nil
+	})
+	return NewWithClient("key", "c2VjcmV0", &http.Client{Transport: transport})
+}
+
 func TestKrakenApi(t *testing.T) {
 	var kk interface{} = KrakenApi{
 		key:    "key",
@@ -170,3 +197,135 @@ func TestQueryDepth(t *testing.T) {
 		t.Errorf("Bids length must be less than count , got %d > %d", len(result.Bids), count)
 	}
 }
+
+func TestCancelledRequest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// Kraken hangs until the caller gives up
+		cancel()
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	api := NewWithClient("key", "c2VjcmV0", &http.Client{Transport: transport})
+
+	_, err := api.TimeWithContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Cancelled requests should return an error matching context.Canceled, got %v", err)
+	}
+	if isAPIError(err) {
+		t.Errorf("Cancellation should not look like a Kraken error")
+	}
+}
+
+func TestBalanceEx(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":[],"result":{
+			"ZUSD":{"balance":"1000.0000","credit":"50.0000","credit_used":"20.0000","hold_trade":"300.0000"},
+			"XXBT":{"balance":"0.5000000000","hold_trade":"0.0000000000"}}}`
+	})
+
+	balances, err := api.BalanceEx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	usd := (*balances)["ZUSD"]
+	if usd.Balance != 1000 || usd.Credit != 50 || usd.CreditUsed != 20 || usd.HoldTrade != 300 || usd.Available() != 730 {
+		t.Errorf("Unexpected balance %+v, available %v", usd, usd.Available())
+	}
+	if xbt := balances.Get("XBT"); xbt.Available() != 0.5 {
+		t.Errorf("Missing credit fields should decode as zero, got %+v", xbt)
+	}
+}
+
+func TestTradeBalanceIn(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		return `{"error":[],"result":{"eb":"1101.3425","tb":"392.2264","m":"0.0000","n":"0.0000",
+			"c":"0.0000","v":"0.0000","e":"392.2264","mf":"392.2264","uv":"25.5000"}}`
+	})
+
+	balance, err := api.TradeBalanceIn("ZEUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("asset") != "ZEUR" {
+		t.Errorf("Expected the asset to be sent, got %v", params)
+	}
+	if balance.EquivalentBalance != 1101.3425 || balance.UnexecutedValue != 25.5 || balance.MarginLevel != 0 {
+		t.Errorf("Unexpected balance %+v", balance)
+	}
+
+	if _, err := api.TradeBalanceIn(""); err != nil || params.Has("asset") {
+		t.Errorf("An empty asset should leave Kraken's default, got %v and %v", params, err)
+	}
+}
+
+func TestAssetPairsOpts(t *testing.T) {
+	var query url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		query = req.URL.Query()
+		if query.Get("info") == AssetPairsFees {
+			return `{"error":[],"result":{
+				"XXBTZUSD":{"fees":[[0,0.26],[50000,0.24]],"fees_maker":[[0,0.16],[50000,0.14]],"fee_volume_currency":"ZUSD"},
+				"XETHZUSD":{"fees":[[0,0.26]],"fees_maker":[[0,0.16]],"fee_volume_currency":"ZUSD"}}}`
+		}
+		return testAssetPairs
+	})
+
+	pairs, err := api.AssetPairs(AssetPairsOpts{Pairs: []string{"XBTUSD", "ETHUSD"}, Info: AssetPairsFees})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("pair") != "XBTUSD,ETHUSD" || query.Get("info") != "fees" {
+		t.Errorf("Unexpected request %v", query)
+	}
+	if xbt := (*pairs)["XXBTZUSD"]; len(*pairs) != 2 || len(xbt.FeesMaker) != 2 || xbt.FeesMaker[1][1] != 0.14 || xbt.Altname != "" {
+		t.Errorf("Fee schedules should decode without the other fields, got %+v", *pairs)
+	}
+
+	if _, err := api.AssetPairs(); err != nil || len(query) != 0 {
+		t.Errorf("The zero call should send no parameter, got %v and %v", query, err)
+	}
+	query = nil
+	if _, err := api.AssetPairs(AssetPairsOpts{Info: "volume"}); err == nil || query != nil {
+		t.Errorf("Unknown info levels should fail before sending, got %v", err)
+	}
+}
+
+func TestAssetsOpts(t *testing.T) {
+	var query url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		query = req.URL.Query()
+		switch query.Get("asset") {
+		case "XBT,NOPE":
+			return `{"error":["EQuery:Unknown asset"]}`
+		case "NOPE":
+			return `{"error":["EQuery:Unknown asset: NOPE"]}`
+		}
+		return `{"error":[],"result":{"XXBT":{"aclass":"currency","altname":"XBT","decimals":10,"display_decimals":5},
+			"ZEUR":{"aclass":"currency","altname":"EUR","decimals":4,"display_decimals":2}}}`
+	})
+
+	assets, err := api.Assets(AssetsOpts{Assets: []string{"XBT", "EUR"}, AClass: "currency"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("asset") != "XBT,EUR" || query.Get("aclass") != "currency" || len(*assets) != 2 {
+		t.Errorf("Unexpected request %v or response %v", query, assets)
+	}
+	if _, err := api.Assets(); err != nil || len(query) != 0 {
+		t.Errorf("The zero call should send no parameter, got %v and %v", query, err)
+	}
+
+	var unknown *UnknownAssetError
+	_, err = api.Assets(AssetsOpts{Assets: []string{"NOPE"}})
+	if !errors.As(err, &unknown) || unknown.Asset != "NOPE" || !errors.Is(err, &APIError{Errors: []string{ErrCodeUnknownAsset}}) {
+		t.Errorf("Expected an UnknownAssetError for NOPE, got %v", err)
+	}
+	_, err = api.Assets(AssetsOpts{Assets: []string{"XBT", "NOPE"}})
+	if !errors.As(err, &unknown) || unknown.Asset != "" {
+		t.Errorf("An unnamed asset among several should stay unnamed, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+// Package krakentest provides realistic Kraken API responses for testing code
+// built on top of the krakenapi package.
+package krakentest
+
+import (
+	"embed"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+//go:embed corpus/*.json
+var corpus embed.FS
+
+// Fixture returns the raw response body of the named fixture. Names are the
+// Kraken API method, optionally followed by a variant, e.g. "OpenOrders" or
+// "OpenOrders.empty".
+func Fixture(name string) ([]byte, error) {
+	return corpus.ReadFile("corpus/" + name + ".json")
+}
+
+// MustFixture is like Fixture but panics if the fixture does not exist
+func MustFixture(name string) []byte {
+	data, err := Fixture(name)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// Fixtures returns the sorted names of all fixtures in the corpus
+func Fixtures() []string {
+	entries, err := fs.ReadDir(corpus, "corpus")
+	if err != nil {
+		panic(err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Method returns the Kraken API method a fixture answers, e.g. "OpenOrders"
+// for "OpenOrders.empty"
+func Method(name string) string {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i]
+	}
+	return name
+}
@@ -0,0 +1,33 @@
+package krakentest
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFixturesAreKrakenResponses(t *testing.T) {
+	names := Fixtures()
+	if len(names) == 0 {
+		t.Fatal("Corpus should not be empty")
+	}
+
+	for _, name := range names {
+		var envelope struct {
+			Error  []string        `json:"error"`
+			Result json.RawMessage `json:"result"`
+		}
+		if err := json.Unmarshal(MustFixture(name), &envelope); err != nil {
+			t.Errorf("Fixture %s is not valid JSON: %s", name, err)
+			continue
+		}
+		if envelope.Error == nil {
+			t.Errorf("Fixture %s should carry an error list", name)
+		}
+	}
+}
+
+func TestMethod(t *testing.T) {
+	if Method("OpenOrders.empty") != "OpenOrders" || Method("Balance") != "Balance" {
+		t.Errorf("Method() should strip the variant")
+	}
+}
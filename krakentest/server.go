@@ -0,0 +1,185 @@
+package krakentest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors returned by a throttling Server, as Kraken sends them
+const (
+	ErrRateLimitExceeded = "EAPI:Rate limit exceeded"
+	ErrTemporaryLockout  = "EGeneral:Temporary lockout"
+)
+
+// ThrottleConfig describes how a Server simulates Kraken's rate limiting.
+// Every counted request adds its cost to a counter that decays over time.
+// A request that would push the counter above MaxCounter is rejected with
+// ErrRateLimitExceeded. LockoutAfter rejections within Window lock the
+// account out for Lockout, during which every counted request fails with
+// ErrTemporaryLockout.
+type ThrottleConfig struct {
+	MaxCounter   float64            // Counter limit, e.g. 15 for a starter account
+	Decay        float64            // Counter decrease per second, e.g. 0.33
+	Costs        map[string]float64 // Cost per API method; methods not listed cost 1
+	Endpoints    []string           // Methods subject to throttling; empty means every private method
+	LockoutAfter int                // Rejections within Window that trigger a lockout; 0 disables lockouts
+	Window       time.Duration      // Period over which rejections are counted
+	Lockout      time.Duration      // Duration of a lockout
+	Now          func() time.Time   // Clock, time.Now if nil
+}
+
+// Server answers Kraken API requests with the fixtures of the corpus. It is
+// an http.Handler and can also be used in-process through Client.
+type Server struct {
+	mu        sync.Mutex
+	responses map[string]string
+	throttle  *ThrottleConfig
+
+	counter     float64
+	updated     time.Time
+	rejections  []time.Time
+	lockedUntil time.Time
+	requests    map[string]int
+}
+
+// NewServer returns a server answering every method with the fixture of the
+// same name, e.g. "Balance" for /0/private/Balance, or else with the first
+// variant of the method, e.g. "Ledgers.staking"
+func NewServer() *Server {
+	return &Server{responses: make(map[string]string), requests: make(map[string]int)}
+}
+
+// Respond makes the server answer method with body instead of its fixture
+func (s *Server) Respond(method, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[method] = body
+}
+
+// Throttle enables the simulation of rate limiting
+func (s *Server) Throttle(config ThrottleConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	s.throttle = &config
+	s.counter = 0
+	s.updated = config.Now()
+	s.rejections = nil
+	s.lockedUntil = time.Time{}
+}
+
+// Requests returns how many requests the server received for method
+func (s *Server) Requests(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[method]
+}
+
+// Client returns an HTTP client whose requests are served by s in-process,
+// whatever host they are sent to
+func (s *Server) Client() *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Result(), nil
+	})}
+}
+
+// ServeHTTP answers a Kraken API request
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, "/0/")
+	method := strings.TrimPrefix(strings.TrimPrefix(path, "public/"), "private/")
+	private := strings.HasPrefix(path, "private/")
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, s.respond(method, private))
+}
+
+// respond returns the body answering method
+func (s *Server) respond(method string, private bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[method]++
+
+	if s.throttle != nil && s.throttled(method, private) {
+		if errCode := s.admit(method); errCode != "" {
+			return fmt.Sprintf(`{"error":["%s"]}`, errCode)
+		}
+	}
+
+	if body, ok := s.responses[method]; ok {
+		return body
+	}
+	if data, err := Fixture(method); err == nil {
+		return string(data)
+	}
+	for _, name := range Fixtures() {
+		if Method(name) == method {
+			return string(MustFixture(name))
+		}
+	}
+	return `{"error":["EGeneral:Unknown method"]}`
+}
+
+// throttled reports whether method is subject to the simulated rate limit
+func (s *Server) throttled(method string, private bool) bool {
+	if len(s.throttle.Endpoints) == 0 {
+		return private
+	}
+	for _, name := range s.throttle.Endpoints {
+		if name == method {
+			return true
+		}
+	}
+	return false
+}
+
+// admit charges method to the counter and returns the error rejecting it, if any
+func (s *Server) admit(method string) string {
+	config := s.throttle
+	now := config.Now()
+	if now.Before(s.lockedUntil) {
+		return ErrTemporaryLockout
+	}
+
+	s.counter -= now.Sub(s.updated).Seconds() * config.Decay
+	if s.counter < 0 {
+		s.counter = 0
+	}
+	s.updated = now
+
+	cost, ok := config.Costs[method]
+	if !ok {
+		cost = 1
+	}
+	if s.counter+cost <= config.MaxCounter {
+		s.counter += cost
+		return ""
+	}
+
+	recent := s.rejections[:0]
+	for _, t := range s.rejections {
+		if now.Sub(t) < config.Window {
+			recent = append(recent, t)
+		}
+	}
+	s.rejections = append(recent, now)
+	if config.LockoutAfter > 0 && len(s.rejections) >= config.LockoutAfter {
+		s.lockedUntil = now.Add(config.Lockout)
+		s.rejections = nil
+		return ErrTemporaryLockout
+	}
+	return ErrRateLimitExceeded
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
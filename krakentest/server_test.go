@@ -0,0 +1,80 @@
+package krakentest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, client *http.Client, path string) []string {
+	t.Helper()
+	resp, err := client.Post("https://api.kraken.com"+path, "application/x-www-form-urlencoded", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	var envelope struct {
+		Error []string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("Invalid response %s", body)
+	}
+	return envelope.Error
+}
+
+func TestServerThrottle(t *testing.T) {
+	now := time.Unix(0, 0)
+	server := NewServer()
+	server.Throttle(ThrottleConfig{
+		MaxCounter:   2,
+		Decay:        1,
+		Costs:        map[string]float64{"Ledgers": 2},
+		LockoutAfter: 2,
+		Window:       time.Minute,
+		Lockout:      10 * time.Second,
+		Now:          func() time.Time { return now },
+	})
+	client := server.Client()
+
+	if errs := get(t, client, "/0/private/Balance"); len(errs) != 0 {
+		t.Fatalf("First request should pass, got %v", errs)
+	}
+	if errs := get(t, client, "/0/private/Ledgers"); len(errs) != 1 || errs[0] != ErrRateLimitExceeded {
+		t.Fatalf("Request above the counter should be rejected, got %v", errs)
+	}
+	if errs := get(t, client, "/0/public/Time"); len(errs) != 0 {
+		t.Fatalf("Public requests should not be throttled, got %v", errs)
+	}
+
+	now = now.Add(time.Second)
+	if errs := get(t, client, "/0/private/Ledgers"); len(errs) != 0 {
+		t.Fatalf("Counter should decay, got %v", errs)
+	}
+	if errs := get(t, client, "/0/private/Balance"); len(errs) != 1 || errs[0] != ErrTemporaryLockout {
+		t.Fatalf("Repeated rejections should lock out, got %v", errs)
+	}
+
+	now = now.Add(5 * time.Second)
+	if errs := get(t, client, "/0/private/Balance"); len(errs) != 1 || errs[0] != ErrTemporaryLockout {
+		t.Fatalf("Lockout should last, got %v", errs)
+	}
+	now = now.Add(5 * time.Second)
+	if errs := get(t, client, "/0/private/Balance"); len(errs) != 0 {
+		t.Fatalf("Lockout should end, got %v", errs)
+	}
+	if server.Requests("Balance") != 4 {
+		t.Errorf("Expected 4 Balance requests, got %d", server.Requests("Balance"))
+	}
+}
+
+func TestServerRespond(t *testing.T) {
+	server := NewServer()
+	server.Respond("Balance", `{"error":["EAPI:Invalid key"]}`)
+	if errs := get(t, server.Client(), "/0/private/Balance"); len(errs) != 1 || errs[0] != "EAPI:Invalid key" {
+		t.Errorf("Expected the custom response, got %v", errs)
+	}
+}
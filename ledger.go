@@ -0,0 +1,44 @@
+package krakenapi
+
+import (
+	"context"
+	"strconv"
+)
+
+// Ledger entry types
+const (
+	LedgerTypeTrade    = "trade"
+	LedgerTypeDeposit  = "deposit"
+	LedgerTypeWithdraw = "withdrawal"
+	LedgerTypeTransfer = "transfer"
+	LedgerTypeMargin   = "margin"
+	LedgerTypeRollover = "rollover"
+	LedgerTypeStaking  = "staking"
+	LedgerTypeEarn     = "earn"
+)
+
+// allLedgers fetches every ledger entry matching args, following Kraken's
+// offset based pagination
+func (api *KrakenAPI) allLedgers(ctx context.Context, args map[string]string) (map[string]LedgerInfo, error) {
+	params := make(map[string]string, len(args)+1)
+	for key, value := range args {
+		params[key] = value
+	}
+
+	entries := make(map[string]LedgerInfo)
+	for {
+		params["ofs"] = strconv.Itoa(len(entries))
+		page, err := api.LedgersWithContext(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+
+		before := len(entries)
+		for id, entry := range page.Ledger {
+			entries[id] = entry
+		}
+		if len(entries) == before || len(entries) >= page.Count {
+			return entries, nil
+		}
+	}
+}
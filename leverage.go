@@ -0,0 +1,129 @@
+package krakenapi
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrLeverageNotAllowed is wrapped by the errors of Leverage.ValidFor
+var ErrLeverageNotAllowed = errors.New("leverage not allowed")
+
+// Leverage is a leverage ratio such as "2:1". The zero value means no leverage.
+type Leverage struct {
+	Numerator   int
+	Denominator int
+}
+
+// ParseLeverage parses Kraken's leverage representation: "none" or an empty
+// string for no leverage, otherwise "<numerator>:<denominator>"
+func ParseLeverage(s string) (Leverage, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "none" {
+		return Leverage{}, nil
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return Leverage{}, fmt.Errorf("invalid leverage %q", s)
+	}
+	numerator, err := strconv.Atoi(parts[0])
+	if err != nil || numerator <= 0 {
+		return Leverage{}, fmt.Errorf("invalid leverage %q", s)
+	}
+	denominator, err := strconv.Atoi(parts[1])
+	if err != nil || denominator <= 0 {
+		return Leverage{}, fmt.Errorf("invalid leverage %q", s)
+	}
+
+	return Leverage{Numerator: numerator, Denominator: denominator}, nil
+}
+
+// LeverageFromFloat returns the leverage of ratio to one, as listed in the
+// LeverageBuy and LeverageSell of an asset pair. A ratio of 1 means no leverage.
+func LeverageFromFloat(ratio float64) (Leverage, error) {
+	if math.IsNaN(ratio) || ratio < 1 || ratio > math.MaxInt32 || ratio != math.Trunc(ratio) {
+		return Leverage{}, fmt.Errorf("invalid leverage %v", ratio)
+	}
+	if ratio == 1 {
+		return Leverage{}, nil
+	}
+	return Leverage{Numerator: int(ratio), Denominator: 1}, nil
+}
+
+// parseLeverageArg parses the leverage argument of an order, which may be
+// given like Kraken's descriptions ("2:1", "none") or as plain number ("2")
+func parseLeverageArg(s string) (Leverage, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "none" || strings.Contains(s, ":") {
+		return ParseLeverage(s)
+	}
+	ratio, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Leverage{}, fmt.Errorf("invalid leverage %q", s)
+	}
+	return LeverageFromFloat(ratio)
+}
+
+// IsNone reports whether the order is not leveraged
+func (l Leverage) IsNone() bool {
+	return l.Numerator == 0 || l.Denominator == 0 || l.Numerator == l.Denominator
+}
+
+// Ratio returns the leverage as a number, 1 when there is no leverage
+func (l Leverage) Ratio() float64 {
+	if l.IsNone() {
+		return 1
+	}
+	return float64(l.Numerator) / float64(l.Denominator)
+}
+
+// ParsedLeverage parses the leverage of the order description
+func (d OrderDescription) ParsedLeverage() (Leverage, error) {
+	return ParseLeverage(d.Leverage)
+}
+
+// String returns the leverage as AddOrder expects it: "none" or the ratio
+// to one, e.g. "2"
+func (l Leverage) String() string {
+	if l.IsNone() {
+		return "none"
+	}
+	return FormatDecimal(l.Ratio(), -1)
+}
+
+// ValidFor checks that the pair allows the leverage for orders in direction
+// ("buy" or "sell"). No leverage is always allowed.
+func (l Leverage) ValidFor(pair AssetPairInfo, direction string) error {
+	if l.IsNone() {
+		return nil
+	}
+	allowed := pair.Leverages(direction)
+	for _, a := range allowed {
+		if a.Ratio() == l.Ratio() {
+			return nil
+		}
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("%w: %s cannot be traded on margin when %sing", ErrLeverageNotAllowed, pair.Altname, direction)
+	}
+	return fmt.Errorf("%w: %s on %s when %sing, allowed are %v", ErrLeverageNotAllowed, l, pair.Altname, direction, allowed)
+}
+
+// Leverages returns the leverages the pair allows for orders in direction
+// ("buy" or "sell"), without the implicit "none"
+func (info AssetPairInfo) Leverages(direction string) []Leverage {
+	ratios := info.LeverageBuy
+	if direction == "sell" {
+		ratios = info.LeverageSell
+	}
+	var leverages []Leverage
+	for _, ratio := range ratios {
+		if l, err := LeverageFromFloat(ratio); err == nil && !l.IsNone() {
+			leverages = append(leverages, l)
+		}
+	}
+	return leverages
+}
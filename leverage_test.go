@@ -0,0 +1,124 @@
+package krakenapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestParseLeverage(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Leverage
+		ratio float64
+		none  bool
+	}{
+		{"none", Leverage{}, 1, true},
+		{"", Leverage{}, 1, true},
+		{"2:1", Leverage{2, 1}, 2, false},
+		{"5:1", Leverage{5, 1}, 5, false},
+		{"1:1", Leverage{1, 1}, 1, true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseLeverage(test.input)
+		if err != nil {
+			t.Errorf("ParseLeverage(%q) should not return an error, got %s", test.input, err)
+			continue
+		}
+		if got != test.want || got.Ratio() != test.ratio || got.IsNone() != test.none {
+			t.Errorf("ParseLeverage(%q) = %+v (ratio %f, none %v)", test.input, got, got.Ratio(), got.IsNone())
+		}
+	}
+
+	for _, invalid := range []string{"2", "2:0", "a:1", "2:1:1", "-2:1"} {
+		if _, err := ParseLeverage(invalid); err == nil {
+			t.Errorf("ParseLeverage(%q) should return an error", invalid)
+		}
+	}
+}
+
+func TestOrderTriggerDecode(t *testing.T) {
+	var resp QueryOrdersResponse
+	decodeStrict(t, "QueryOrders.trigger", &resp)
+
+	order := resp["OQCLML-BW3P3-BUCMWZ"]
+	if order.Trigger != "index" {
+		t.Errorf("Expected trigger index, got %q", order.Trigger)
+	}
+	leverage, err := order.Description.ParsedLeverage()
+	if err != nil || leverage.Ratio() != 3 {
+		t.Errorf("Expected 3:1 leverage, got %+v (%v)", leverage, err)
+	}
+}
+
+func TestLeverageRequestFormat(t *testing.T) {
+	for input, want := range map[string]string{"none": "none", "": "none", "1": "none", "1:1": "none", "2": "2", "2:1": "2", "5:1": "5"} {
+		leverage, err := parseLeverageArg(input)
+		if err != nil || leverage.String() != want {
+			t.Errorf("parseLeverageArg(%q) = %s, %v, want %s", input, leverage, err, want)
+		}
+	}
+	for _, invalid := range []string{"0", "0.5", "2.5", "x", "2:0"} {
+		if _, err := parseLeverageArg(invalid); err == nil {
+			t.Errorf("parseLeverageArg(%q) should return an error", invalid)
+		}
+	}
+
+	if leverage, err := LeverageFromFloat(3); err != nil || leverage != (Leverage{3, 1}) {
+		t.Errorf("LeverageFromFloat(3) = %+v, %v", leverage, err)
+	}
+	if leverage, err := LeverageFromFloat(1); err != nil || !leverage.IsNone() {
+		t.Errorf("LeverageFromFloat(1) should be none, got %+v, %v", leverage, err)
+	}
+}
+
+func TestLeverageValidFor(t *testing.T) {
+	pair := AssetPairInfo{Altname: "XBTUSD", LeverageBuy: []float64{2, 3, 4, 5}, LeverageSell: []float64{2, 3}}
+	if err := (Leverage{5, 1}).ValidFor(pair, "buy"); err != nil {
+		t.Errorf("5:1 should be allowed when buying, got %s", err)
+	}
+	if err := (Leverage{5, 1}).ValidFor(pair, "sell"); !errors.Is(err, ErrLeverageNotAllowed) {
+		t.Errorf("5:1 should not be allowed when selling, got %v", err)
+	}
+	if err := (Leverage{}).ValidFor(AssetPairInfo{}, "sell"); err != nil {
+		t.Errorf("No leverage should always be allowed, got %s", err)
+	}
+	if err := (Leverage{2, 1}).ValidFor(AssetPairInfo{Altname: "DOTUSD"}, "buy"); !errors.Is(err, ErrLeverageNotAllowed) {
+		t.Errorf("Leverage should not be allowed on spot only pairs, got %v", err)
+	}
+	if got := pair.Leverages("sell"); len(got) != 2 || got[1] != (Leverage{3, 1}) {
+		t.Errorf("Unexpected sell leverages %v", got)
+	}
+}
+
+func TestAddOrderLeverage(t *testing.T) {
+	var sent []url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(body))
+		sent = append(sent, params)
+		return `{"error":[],"result":{"descr":{"order":"buy 1 XBTUSD @ limit 30000 with 2:1 leverage"},"txid":["OUF4EM-FRGI2-MQMWZD"]}}`
+	})
+
+	if _, err := api.AddOrder("XBTUSD", "buy", OTLimit, "1", map[string]string{"price": "30000", "leverage": "2:1"}); err != nil {
+		t.Fatal(err)
+	}
+	if sent[0].Get("leverage") != "2" {
+		t.Errorf("Leverage should be sent as a plain number, got %v", sent[0])
+	}
+	if _, err := api.AddOrder("XBTUSD", "buy", OTLimit, "1", map[string]string{"leverage": "2x"}); err == nil || len(sent) != 1 {
+		t.Errorf("Invalid leverage should be rejected before sending, got %v", err)
+	}
+
+	entry, err := batchOrder(AddOrderRequest{Direction: "buy", OrderType: OTMarket, Volume: "1", Leverage: Leverage{3, 1}})
+	if err != nil || entry["leverage"] != "3" {
+		t.Errorf("Batch orders should send the leverage of the request, got %v, %v", entry, err)
+	}
+	entry, err = batchOrder(AddOrderRequest{Direction: "buy", OrderType: OTMarket, Volume: "1", Args: map[string]string{"leverage": "none"}})
+	if _, ok := entry["leverage"]; err != nil || ok {
+		t.Errorf("Batch orders without leverage should not send it, got %v, %v", entry, err)
+	}
+}
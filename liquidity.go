@@ -0,0 +1,150 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// LiquidityOptions configures LiquidityScore
+type LiquidityOptions struct {
+	Notional       float64       // Order size in quote currency used for the cost estimate
+	DepthSamples   int           // Number of Depth samples; defaults to 1
+	SampleInterval time.Duration // Pause between Depth samples; defaults to one second
+	DepthCount     int           // Levels per side requested from Depth; defaults to 100
+}
+
+// LiquidityReport summarizes how cheaply a pair can be traded
+type LiquidityReport struct {
+	Pair           string
+	SpreadSamples  int     // Number of spread entries within the window
+	AvgSpreadBps   float64 // Average spread relative to the mid price, in basis points
+	P95SpreadBps   float64 // 95th percentile of the spread, in basis points
+	AvgBidDepth    float64 // Average volume at the best bid (base currency)
+	AvgAskDepth    float64 // Average volume at the best ask (base currency)
+	BuyCostBps     float64 // Average price of buying Notional against the mid price, in basis points
+	SellCostBps    float64 // Average price of selling Notional against the mid price, in basis points
+	InsufficientAt int     // Number of Depth samples too shallow to fill Notional; they are excluded from the costs
+}
+
+// LiquidityScore measures the liquidity of pair from the spreads Kraken
+// recorded during the last window and from periodic Depth samples. Sampling
+// stops early with ctx.Err() when ctx is done.
+func (api *KrakenAPI) LiquidityScore(ctx context.Context, pair string, window time.Duration, opts LiquidityOptions) (*LiquidityReport, error) {
+	if opts.DepthSamples <= 0 {
+		opts.DepthSamples = 1
+	}
+	if opts.SampleInterval <= 0 {
+		opts.SampleInterval = time.Second
+	}
+	if opts.DepthCount <= 0 {
+		opts.DepthCount = 100
+	}
+
+	spreads, err := api.spread(ctx, pair, time.Now().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+	report := &LiquidityReport{Pair: pair}
+	report.SpreadSamples, report.AvgSpreadBps, report.P95SpreadBps = spreadStats(spreads, time.Now().Add(-window))
+
+	var buyCosts, sellCosts []float64
+	for i := 0; i < opts.DepthSamples; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(opts.SampleInterval):
+			}
+		}
+
+		book, err := api.depth(ctx, pair, opts.DepthCount)
+		if err != nil {
+			return nil, err
+		}
+		if len(book.Bids) == 0 || len(book.Asks) == 0 {
+			return nil, fmt.Errorf("order book of %s is empty", pair)
+		}
+		report.AvgBidDepth += book.Bids[0].Amount / float64(opts.DepthSamples)
+		report.AvgAskDepth += book.Asks[0].Amount / float64(opts.DepthSamples)
+
+		mid := (book.Bids[0].Price + book.Asks[0].Price) / 2
+		buy, okBuy := executionPrice(book.Asks, opts.Notional)
+		sell, okSell := executionPrice(book.Bids, opts.Notional)
+		if !okBuy || !okSell {
+			report.InsufficientAt++
+			continue
+		}
+		buyCosts = append(buyCosts, (buy-mid)/mid*10000)
+		sellCosts = append(sellCosts, (mid-sell)/mid*10000)
+	}
+	report.BuyCostBps = mean(buyCosts)
+	report.SellCostBps = mean(sellCosts)
+
+	return report, nil
+}
+
+// spread queries the recent spreads of pair recorded after since
+func (api *KrakenAPI) spread(ctx context.Context, pair string, since time.Time) ([]SpreadItem, error) {
+	var sinceID int64
+	if !since.IsZero() {
+		sinceID = since.Unix()
+	}
+	resp, err := api.SpreadWithContext(ctx, pair, sinceID)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Spreads, nil
+}
+
+// spreadStats returns the count, average and 95th percentile of the spreads
+// in basis points, ignoring entries before since and crossed quotes
+func spreadStats(spreads []SpreadItem, since time.Time) (int, float64, float64) {
+	var bps []float64
+	for _, s := range spreads {
+		mid := (s.Bid + s.Ask) / 2
+		if s.Time.Before(since) || mid <= 0 || s.Ask < s.Bid {
+			continue
+		}
+		bps = append(bps, (s.Ask-s.Bid)/mid*10000)
+	}
+	if len(bps) == 0 {
+		return 0, 0, 0
+	}
+	sort.Float64s(bps)
+	p95 := bps[int(math.Ceil(0.95*float64(len(bps))))-1]
+	return len(bps), mean(bps), p95
+}
+
+// executionPrice returns the average price of filling notional in quote
+// currency against levels, and false if the levels do not hold enough volume
+func executionPrice(levels []OrderBookItem, notional float64) (float64, bool) {
+	if notional <= 0 {
+		return levels[0].Price, true
+	}
+	remaining, volume := notional, 0.0
+	for _, level := range levels {
+		value := level.Price * level.Amount
+		if value >= remaining {
+			volume += remaining / level.Price
+			return notional / volume, true
+		}
+		remaining -= value
+		volume += level.Amount
+	}
+	return 0, false
+}
+
+// mean returns the average of values, 0 for none
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
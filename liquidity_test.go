@@ -0,0 +1,81 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLiquidityScore(t *testing.T) {
+	now := time.Now().Unix()
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "Spread":
+			return fmt.Sprintf(`{"error":[],"result":{"XXBTZUSD":[
+				[%d,"99.00","101.00"],
+				[%d,"99.90","100.10"],
+				[%d,"99.95","100.05"]],"last":%d}}`, now-7200, now-60, now-30, now)
+		case "Depth":
+			return `{"error":[],"result":{"XXBTZUSD":{
+				"asks":[["100.5","1.0",1],["101.5","10.0",1]],
+				"bids":[["99.5","2.0",1],["98.5","10.0",1]]}}}`
+		}
+		t.Fatalf("Unexpected call to %s", method)
+		return ""
+	})
+
+	report, err := api.LiquidityScore(context.Background(), "XBTUSD", time.Hour, LiquidityOptions{
+		Notional: 199, DepthSamples: 2, SampleInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.SpreadSamples != 2 || math.Abs(report.AvgSpreadBps-15) > 1e-6 || math.Abs(report.P95SpreadBps-20) > 1e-6 {
+		t.Errorf("Spreads outside the window should be ignored, got %+v", report)
+	}
+	if report.AvgBidDepth != 2 || report.AvgAskDepth != 1 {
+		t.Errorf("Unexpected top of book depth %+v", report)
+	}
+	// Buying 199 USD takes 100.5 USD at 100.5 and 98.5 USD at 101.5; selling fills at 99.5
+	wantBuy := (199/(1+98.5/101.5) - 100) / 100 * 10000
+	if math.Abs(report.BuyCostBps-wantBuy) > 1e-6 || math.Abs(report.SellCostBps-50) > 1e-6 {
+		t.Errorf("Unexpected costs %+v", report)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := api.LiquidityScore(ctx, "XBTUSD", time.Hour, LiquidityOptions{DepthSamples: 2}); err == nil {
+		t.Errorf("LiquidityScore should stop when the context is done")
+	}
+}
+
+func TestSpread(t *testing.T) {
+	var since string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		since = req.URL.Query().Get("since")
+		return `{"error":[],"result":{"XXBTZUSD":[
+			[1688671834,"30292.10000","30297.50000"],
+			[1688671834,"30292.10000","30296.70000"]],"last":1688672106}}`
+	})
+
+	resp, err := api.Spread("XBTUSD", 1688671800)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if since != "1688671800" {
+		t.Errorf("Expected since to be sent, got %q", since)
+	}
+	if resp.Pair != "XXBTZUSD" || resp.Last != 1688672106 || len(resp.Spreads) != 2 {
+		t.Fatalf("Unexpected response %+v", resp)
+	}
+	if s := resp.Spreads[1]; !s.Time.Equal(time.Unix(1688671834, 0)) || s.Bid != 30292.1 || s.Ask != 30296.7 {
+		t.Errorf("Unexpected spread %+v", s)
+	}
+
+	if _, err := api.Spread("XBTUSD", 0); err != nil || since != "" {
+		t.Errorf("since 0 should not be sent, got %q and %v", since, err)
+	}
+}
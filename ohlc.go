@@ -0,0 +1,239 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// OHLCInterval is the duration of a candle in minutes
+type OHLCInterval int
+
+// Candle intervals Kraken supports
+const (
+	OHLCInterval1m  OHLCInterval = 1
+	OHLCInterval5m  OHLCInterval = 5
+	OHLCInterval15m OHLCInterval = 15
+	OHLCInterval30m OHLCInterval = 30
+	OHLCInterval1h  OHLCInterval = 60
+	OHLCInterval4h  OHLCInterval = 240
+	OHLCInterval1d  OHLCInterval = 1440
+	OHLCInterval1w  OHLCInterval = 10080
+	OHLCInterval15d OHLCInterval = 21600
+)
+
+// Validate returns an error listing the supported intervals unless Kraken supports i
+func (i OHLCInterval) Validate() error {
+	for _, interval := range ohlcIntervals {
+		if int(i) == interval {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported OHLC interval %d, Kraken accepts %v minutes", i, ohlcIntervals)
+}
+
+// OHLCArgs selects the candles returned by OHLCWithArgs
+type OHLCArgs struct {
+	Interval OHLCInterval // Zero for one minute candles
+	Since    string       // LastCursor of a previous response, empty for the most recent candles
+}
+
+// ohlcMaxCandles is the number of candles Kraken returns at most per OHLC call
+const ohlcMaxCandles = 720
+
+// Candles is a list of OHLC entries in chronological order, as returned in OHLCResponse.OHLC
+type Candles []*OHLC
+
+// OHLCSeries holds candles as parallel slices, the layout most TA libraries expect
+type OHLCSeries struct {
+	Time   []time.Time
+	Open   []float64
+	High   []float64
+	Low    []float64
+	Close  []float64
+	Vwap   []float64
+	Volume []float64
+	Count  []int
+}
+
+// Len returns the number of candles in the series
+func (s OHLCSeries) Len() int {
+	return len(s.Time)
+}
+
+// ToSeries converts the candles into parallel slices, preserving order. Nil
+// candles are skipped.
+func (c Candles) ToSeries() OHLCSeries {
+	s := OHLCSeries{
+		Time:   make([]time.Time, 0, len(c)),
+		Open:   make([]float64, 0, len(c)),
+		High:   make([]float64, 0, len(c)),
+		Low:    make([]float64, 0, len(c)),
+		Close:  make([]float64, 0, len(c)),
+		Vwap:   make([]float64, 0, len(c)),
+		Volume: make([]float64, 0, len(c)),
+		Count:  make([]int, 0, len(c)),
+	}
+	for _, candle := range c {
+		if candle == nil {
+			continue
+		}
+		s.Time = append(s.Time, candle.Time)
+		s.Open = append(s.Open, candle.Open)
+		s.High = append(s.High, candle.High)
+		s.Low = append(s.Low, candle.Low)
+		s.Close = append(s.Close, candle.Close)
+		s.Vwap = append(s.Vwap, candle.Vwap)
+		s.Volume = append(s.Volume, candle.Volume)
+		s.Count = append(s.Count, candle.Count)
+	}
+	return s
+}
+
+// ToMatrix converts the candles into rows of [unix time, open, high, low, close, volume].
+// Nil candles are skipped.
+func (c Candles) ToMatrix() [][]float64 {
+	rows := make([][]float64, 0, len(c))
+	for _, candle := range c {
+		if candle == nil {
+			continue
+		}
+		rows = append(rows, []float64{
+			float64(candle.Time.Unix()),
+			candle.Open,
+			candle.High,
+			candle.Low,
+			candle.Close,
+			candle.Volume,
+		})
+	}
+	return rows
+}
+
+// FromSeries converts parallel slices back into candles. Time, Open, High, Low
+// and Close are required and must have equal lengths; Vwap, Volume and Count
+// may be nil, otherwise they must have the same length too.
+func FromSeries(s OHLCSeries) (Candles, error) {
+	n := len(s.Time)
+	required := map[string]int{"Open": len(s.Open), "High": len(s.High), "Low": len(s.Low), "Close": len(s.Close)}
+	for name, l := range required {
+		if l != n {
+			return nil, fmt.Errorf("series %s has %d entries, expected %d", name, l, n)
+		}
+	}
+	optional := map[string]int{"Vwap": len(s.Vwap), "Volume": len(s.Volume), "Count": len(s.Count)}
+	for name, l := range optional {
+		if l != 0 && l != n {
+			return nil, fmt.Errorf("series %s has %d entries, expected %d", name, l, n)
+		}
+	}
+
+	candles := make(Candles, n)
+	for i := 0; i < n; i++ {
+		candle := &OHLC{
+			Time:  s.Time[i],
+			Open:  s.Open[i],
+			High:  s.High[i],
+			Low:   s.Low[i],
+			Close: s.Close[i],
+		}
+		if len(s.Vwap) > 0 {
+			candle.Vwap = s.Vwap[i]
+		}
+		if len(s.Volume) > 0 {
+			candle.Volume = s.Volume[i]
+		}
+		if len(s.Count) > 0 {
+			candle.Count = s.Count[i]
+		}
+		candles[i] = candle
+	}
+	return candles, nil
+}
+
+// ToHeikinAshi returns the Heikin-Ashi transform of the candles. Time, Vwap,
+// Volume and Count are copied unchanged; nil candles are skipped.
+func (c Candles) ToHeikinAshi() Candles {
+	ha := make(Candles, 0, len(c))
+	var prev *OHLC
+	for _, candle := range c {
+		if candle == nil {
+			continue
+		}
+		next := *candle
+		next.Close = (candle.Open + candle.High + candle.Low + candle.Close) / 4
+		if prev == nil {
+			next.Open = (candle.Open + candle.Close) / 2
+		} else {
+			next.Open = (prev.Open + prev.Close) / 2
+		}
+		next.High = math.Max(candle.High, math.Max(next.Open, next.Close))
+		next.Low = math.Min(candle.Low, math.Min(next.Open, next.Close))
+
+		ha = append(ha, &next)
+		prev = &next
+	}
+	return ha
+}
+
+// WithExactOHLC makes the OHLC methods fill OHLCResponse.Exact with the
+// candles as exact decimal strings, in addition to the float64 candles
+func (api *KrakenAPI) WithExactOHLC(exact bool) *KrakenAPI {
+	api.exactOHLC = exact
+	return api
+}
+
+// OHLCMultiResult holds the outcome of OHLCMulti, indexed by requested pair
+type OHLCMultiResult struct {
+	Responses map[string]*OHLCResponse // Candles of the pairs that succeeded
+	Errors    map[string]error         // Error of the pairs that failed
+	Truncated map[string]bool          // Pairs cut at the candle cap; query again from their Last cursor
+}
+
+// OHLCMulti queries the OHLC data of several pairs concurrently. A failing
+// pair is reported in Errors without aborting the others. A zero since
+// requests the most recent candles.
+func (api *KrakenAPI) OHLCMulti(ctx context.Context, pairs []string, interval int, since time.Time) *OHLCMultiResult {
+	result := &OHLCMultiResult{
+		Responses: make(map[string]*OHLCResponse, len(pairs)),
+		Errors:    make(map[string]error),
+		Truncated: make(map[string]bool),
+	}
+
+	sinceUnix := ""
+	if !since.IsZero() {
+		sinceUnix = strconv.FormatInt(since.Unix(), 10)
+	}
+	intervalParam := ""
+	if interval > 0 {
+		intervalParam = strconv.Itoa(interval)
+	}
+
+	var mu sync.Mutex
+	fanOut(ctx, len(pairs), maxPublicWorkers, func(i int) {
+		pair := pairs[i]
+		resp, err := api.ohlc(ctx, pair, intervalParam, sinceUnix)
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			result.Errors[pair] = err
+			return
+		}
+		result.Responses[pair] = resp
+		if len(resp.OHLC) >= ohlcMaxCandles {
+			result.Truncated[pair] = true
+		}
+	})
+
+	// Pairs skipped because ctx was done
+	for _, pair := range pairs {
+		if _, ok := result.Responses[pair]; !ok && result.Errors[pair] == nil {
+			result.Errors[pair] = ctx.Err()
+		}
+	}
+	return result
+}
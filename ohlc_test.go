@@ -0,0 +1,212 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testCandles() Candles {
+	return Candles{
+		{Time: time.Unix(60, 0), Open: 10, High: 14, Low: 9, Close: 12, Vwap: 11, Volume: 2, Count: 3},
+		nil,
+		{Time: time.Unix(120, 0), Open: 12, High: 13, Low: 8, Close: 9, Vwap: 10, Volume: 4, Count: 5},
+	}
+}
+
+func TestCandlesSeriesRoundTrip(t *testing.T) {
+	series := testCandles().ToSeries()
+	if series.Len() != 2 {
+		t.Fatalf("ToSeries() should skip nil candles, got %d entries", series.Len())
+	}
+	if !reflect.DeepEqual(series.Close, []float64{12, 9}) {
+		t.Errorf("ToSeries() should preserve order, got %v", series.Close)
+	}
+
+	candles, err := FromSeries(series)
+	if err != nil {
+		t.Fatalf("FromSeries() should not return an error, got %s", err)
+	}
+	want := testCandles()
+	if !reflect.DeepEqual(candles, Candles{want[0], want[2]}) {
+		t.Errorf("FromSeries() should reverse ToSeries(), got %+v", candles)
+	}
+}
+
+func TestFromSeriesLengthMismatch(t *testing.T) {
+	series := testCandles().ToSeries()
+	series.High = series.High[:1]
+	if _, err := FromSeries(series); err == nil {
+		t.Errorf("FromSeries() should reject series of unequal length")
+	}
+}
+
+func TestCandlesToMatrix(t *testing.T) {
+	rows := testCandles().ToMatrix()
+	if len(rows) != 2 || !reflect.DeepEqual(rows[1], []float64{120, 12, 13, 8, 9, 4}) {
+		t.Errorf("ToMatrix() returned unexpected rows %v", rows)
+	}
+}
+
+func TestCandlesToHeikinAshi(t *testing.T) {
+	ha := testCandles().ToHeikinAshi()
+	if len(ha) != 2 {
+		t.Fatalf("ToHeikinAshi() should skip nil candles, got %d", len(ha))
+	}
+	if ha[0].Open != 11 || ha[0].Close != 11.25 || ha[0].High != 14 || ha[0].Low != 9 {
+		t.Errorf("Unexpected first Heikin-Ashi candle %+v", ha[0])
+	}
+	if ha[1].Open != 11.125 || ha[1].Close != 10.5 || ha[1].High != 13 || ha[1].Low != 8 {
+		t.Errorf("Unexpected second Heikin-Ashi candle %+v", ha[1])
+	}
+}
+
+func TestOHLCMulti(t *testing.T) {
+	var mu sync.Mutex
+	var since []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		query := req.URL.Query()
+		mu.Lock()
+		since = append(since, query.Get("since"))
+		mu.Unlock()
+		switch query.Get("pair") {
+		case "XBTUSD":
+			candles := make([]string, ohlcMaxCandles)
+			for i := range candles {
+				candles[i] = fmt.Sprintf(`[%d,"1","1","1","1","1","1",1]`, 60*(i+1))
+			}
+			return `{"error":[],"result":{"XXBTZUSD":[` + strings.Join(candles, ",") + `],"last":43200}}`
+		case "ETHUSD":
+			return `{"error":[],"result":{"XETHZUSD":[[60,"1","1","1","1","1","1",1]],"last":60}}`
+		}
+		return `{"error":["EQuery:Unknown asset pair"]}`
+	})
+
+	result := api.OHLCMulti(context.Background(), []string{"XBTUSD", "ETHUSD", "NOPE"}, 1, time.Unix(30, 0))
+	if len(result.Responses) != 2 || result.Errors["NOPE"] == nil {
+		t.Fatalf("Unknown pair should fail alone, got %+v", result)
+	}
+	if result.Responses["XBTUSD"].Last != 43200 || result.Responses["ETHUSD"].Last != 60 {
+		t.Errorf("Each pair should keep its own cursor")
+	}
+	if !result.Truncated["XBTUSD"] || result.Truncated["ETHUSD"] {
+		t.Errorf("Only pairs at the candle cap should be truncated, got %v", result.Truncated)
+	}
+	for _, s := range since {
+		if s != "30" {
+			t.Errorf("since should be passed to every request, got %s", s)
+		}
+	}
+}
+
+func TestExactOHLC(t *testing.T) {
+	body := `{"error":[],"result":{"SHIBUSD":[[1688671200,"0.000007123456789","0.00000713","0.0000071","0.00000712","0.00000711","1234567890.12345678",12]],"last":1688671200}}`
+	api := newTestAPI(func(method string, req *http.Request) string { return body })
+
+	resp, err := api.OHLC("SHIBUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Exact != nil {
+		t.Errorf("Exact candles should only be decoded on request")
+	}
+
+	resp, err = api.WithExactOHLC(true).OHLC("SHIBUSD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Exact) != 1 || resp.Exact[0].Open != "0.000007123456789" || resp.Exact[0].Volume != "1234567890.12345678" {
+		t.Errorf("Exact candles should keep the strings sent by Kraken, got %+v", resp.Exact)
+	}
+	if resp.OHLC[0].Open != 0.000007123456789 || !resp.Exact[0].Time.Equal(resp.OHLC[0].Time) {
+		t.Errorf("Float candles should still be decoded, got %+v", resp.OHLC[0])
+	}
+}
+
+func benchmarkOHLCDecode(b *testing.B, exact bool) {
+	candles := make([]string, ohlcMaxCandles)
+	for i := range candles {
+		candles[i] = fmt.Sprintf(`[%d,"0.000007123456789","0.00000713","0.0000071","0.00000712","0.00000711","1234567890.12345678",12]`, 60*(i+1))
+	}
+	body := `{"error":[],"result":{"SHIBUSD":[` + strings.Join(candles, ",") + `],"last":43200}}`
+	api := newTestAPI(func(method string, req *http.Request) string { return body }).WithExactOHLC(exact)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := api.OHLC("SHIBUSD"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOHLCDecodeFloat(b *testing.B) { benchmarkOHLCDecode(b, false) }
+func BenchmarkOHLCDecodeExact(b *testing.B) { benchmarkOHLCDecode(b, true) }
+
+func TestAnalyzeOHLC(t *testing.T) {
+	at := func(minute int64) time.Time { return time.Unix(60*minute, 0) }
+	candles := Candles{
+		{Time: at(0), Open: 10, High: 11, Low: 9, Close: 10, Volume: 1},
+		{Time: at(1), Open: 10, High: 11, Low: 9, Close: 12, Volume: 1},
+		{Time: at(3), Open: 10, High: 10, Low: 10, Close: 10},
+		{Time: at(4), Open: 10, High: 10, Low: 10, Close: 10},
+		{Time: at(10), Open: 10, High: 9, Low: 11, Close: 10, Volume: 1},
+	}
+
+	report := AnalyzeOHLC(candles, time.Minute)
+	if !reflect.DeepEqual(report.Gaps, []OHLCGap{{After: at(1), Before: at(3), Missing: 1}, {After: at(4), Before: at(10), Missing: 5}}) {
+		t.Errorf("Unexpected gaps %+v", report.Gaps)
+	}
+	if !reflect.DeepEqual(report.ZeroVolume, []OHLCStretch{{From: at(3), To: at(4), Candles: 2}}) {
+		t.Errorf("Unexpected zero volume stretches %+v", report.ZeroVolume)
+	}
+	if len(report.Anomalies) != 2 || report.Anomalies[0].Index != 1 || report.Anomalies[1].Index != 4 {
+		t.Errorf("Unexpected anomalies %+v", report.Anomalies)
+	}
+
+	filled, untradeable := FillGaps(candles, report, 2)
+	if len(filled) != 6 || !filled[2].Time.Equal(at(2)) || filled[2].Close != 12 || filled[2].Volume != 0 {
+		t.Errorf("Short gap should be filled at the previous close, got %+v", filled[2])
+	}
+	if len(untradeable) != 1 || untradeable[0].Missing != 5 {
+		t.Errorf("Long gap should be untradeable, got %+v", untradeable)
+	}
+	if AnalyzeOHLC(filled, time.Minute).Gaps[0].Missing != 5 {
+		t.Errorf("Only the long gap should remain")
+	}
+}
+
+func TestOHLCWithArgs(t *testing.T) {
+	var query url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		query = req.URL.Query()
+		return `{"error":[],"result":{"XXBTZUSD":[
+			[1688671200,"30306.1","30306.2","30305.7","30305.7","30306.1","3.39243896",23]],"last":1688671200123456789}}`
+	})
+
+	resp, err := api.OHLCWithArgs("XBTUSD", OHLCArgs{Interval: OHLCInterval4h, Since: "1688670000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("interval") != "240" || query.Get("since") != "1688670000" {
+		t.Errorf("Unexpected request %v", query)
+	}
+	if resp.LastCursor != "1688671200123456789" || len(resp.OHLC) != 1 || resp.OHLC[0].Count != 23 {
+		t.Errorf("Expected last to be kept as sent, got %+v", resp)
+	}
+
+	if _, err := api.OHLCWithArgs("XBTUSD", OHLCArgs{Since: resp.LastCursor}); err != nil || query.Get("interval") != "1" || query.Get("since") != resp.LastCursor {
+		t.Errorf("Expected the cursor to be passed on, got %v and %v", query, err)
+	}
+
+	query = nil
+	_, err = api.OHLCWithArgs("XBTUSD", OHLCArgs{Interval: 120})
+	if err == nil || query != nil || !strings.Contains(err.Error(), "21600") {
+		t.Errorf("Unsupported intervals should fail listing the supported ones before sending, got %v", err)
+	}
+}
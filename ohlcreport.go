@@ -0,0 +1,142 @@
+package krakenapi
+
+import (
+	"fmt"
+	"time"
+)
+
+// OHLCGap is a stretch of missing candles
+type OHLCGap struct {
+	After   time.Time // Time of the last candle before the gap
+	Before  time.Time // Time of the first candle after the gap
+	Missing int       // Number of missing candles
+}
+
+// OHLCStretch is a run of consecutive candles
+type OHLCStretch struct {
+	From    time.Time // Time of the first candle
+	To      time.Time // Time of the last candle
+	Candles int
+}
+
+// OHLCAnomaly is a candle whose prices contradict each other
+type OHLCAnomaly struct {
+	Index  int // Position in the analyzed candles
+	Time   time.Time
+	Reason string
+}
+
+// OHLCReport lists the irregularities AnalyzeOHLC found
+type OHLCReport struct {
+	Interval   time.Duration
+	Gaps       []OHLCGap     // Missing intervals between candles
+	ZeroVolume []OHLCStretch // Runs of candles without volume
+	Anomalies  []OHLCAnomaly // Candles with impossible prices
+	Unordered  int           // Number of candles not after their predecessor; they are skipped for gap detection
+}
+
+// Clean reports whether no irregularity was found
+func (r OHLCReport) Clean() bool {
+	return len(r.Gaps) == 0 && len(r.ZeroVolume) == 0 && len(r.Anomalies) == 0 && r.Unordered == 0
+}
+
+// AnalyzeOHLC checks chronologically ordered candles of the given interval
+// for gaps, runs of zero volume and candles whose close or low lie outside
+// their range. Nil candles are ignored.
+func AnalyzeOHLC(candles []*OHLC, interval time.Duration) OHLCReport {
+	report := OHLCReport{Interval: interval}
+	var prev *OHLC
+	var zero *OHLCStretch
+
+	for i, candle := range candles {
+		if candle == nil {
+			continue
+		}
+
+		if reason := candleAnomaly(candle); reason != "" {
+			report.Anomalies = append(report.Anomalies, OHLCAnomaly{Index: i, Time: candle.Time, Reason: reason})
+		}
+
+		if candle.Volume == 0 {
+			if zero == nil {
+				zero = &OHLCStretch{From: candle.Time}
+			}
+			zero.To = candle.Time
+			zero.Candles++
+		} else if zero != nil {
+			report.ZeroVolume = append(report.ZeroVolume, *zero)
+			zero = nil
+		}
+
+		if prev != nil {
+			if !candle.Time.After(prev.Time) {
+				report.Unordered++
+				continue
+			}
+			if interval > 0 {
+				if missing := int(candle.Time.Sub(prev.Time)/interval) - 1; missing > 0 {
+					report.Gaps = append(report.Gaps, OHLCGap{After: prev.Time, Before: candle.Time, Missing: missing})
+				}
+			}
+		}
+		prev = candle
+	}
+	if zero != nil {
+		report.ZeroVolume = append(report.ZeroVolume, *zero)
+	}
+
+	return report
+}
+
+// candleAnomaly returns why the prices of candle are impossible, if they are
+func candleAnomaly(c *OHLC) string {
+	switch {
+	case c.Low > c.High:
+		return fmt.Sprintf("low %v above high %v", c.Low, c.High)
+	case c.Close < c.Low || c.Close > c.High:
+		return fmt.Sprintf("close %v outside [%v, %v]", c.Close, c.Low, c.High)
+	case c.Open < c.Low || c.Open > c.High:
+		return fmt.Sprintf("open %v outside [%v, %v]", c.Open, c.Low, c.High)
+	}
+	return ""
+}
+
+// FillGaps returns the candles with the gaps of report of at most maxMissing
+// candles filled by flat candles at the previous close and without volume.
+// Longer gaps are left open and returned as untradeable.
+func FillGaps(candles Candles, report OHLCReport, maxMissing int) (Candles, []OHLCGap) {
+	fillable := make(map[time.Time]OHLCGap)
+	var untradeable []OHLCGap
+	for _, gap := range report.Gaps {
+		if gap.Missing <= maxMissing {
+			fillable[gap.After] = gap
+		} else {
+			untradeable = append(untradeable, gap)
+		}
+	}
+
+	filled := make(Candles, 0, len(candles))
+	for _, candle := range candles {
+		if candle == nil {
+			continue
+		}
+		filled = append(filled, candle)
+
+		gap, ok := fillable[candle.Time]
+		if !ok {
+			continue
+		}
+		delete(fillable, candle.Time)
+		for i := 1; i <= gap.Missing; i++ {
+			filled = append(filled, &OHLC{
+				Time:  candle.Time.Add(time.Duration(i) * report.Interval),
+				Open:  candle.Close,
+				High:  candle.Close,
+				Low:   candle.Close,
+				Close: candle.Close,
+				Vwap:  candle.Close,
+			})
+		}
+	}
+	return filled, untradeable
+}
@@ -0,0 +1,232 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrStale is matched by every *StaleError
+var ErrStale = errors.New("stale data")
+
+// StaleError is returned by cached queries whose data is older than allowed
+type StaleError struct {
+	SyncedAt time.Time     // Last successful synchronization, zero if none
+	MaxAge   time.Duration // Allowed age of the data
+}
+
+func (e *StaleError) Error() string {
+	if e.SyncedAt.IsZero() {
+		return "data was never synchronized"
+	}
+	return fmt.Sprintf("data is %s old, more than %s", time.Since(e.SyncedAt).Round(time.Millisecond), e.MaxAge)
+}
+
+// Unwrap makes errors.Is(err, ErrStale) hold
+func (e *StaleError) Unwrap() error {
+	return ErrStale
+}
+
+// OpenOrderCache keeps an in-memory copy of the open orders of an account
+// for fast synchronous queries. Queries fail with a *StaleError once the
+// last synchronization is older than the maximum age of the cache. It is
+// safe for concurrent use.
+type OpenOrderCache struct {
+	maxAge time.Duration
+
+	mu       sync.RWMutex
+	orders   map[string]Order
+	pairs    AssetPairsResponse // Resolves the pairs of ForPair, nil to match them as given
+	syncedAt time.Time
+	err      error
+}
+
+// NewOpenOrderCache returns an empty cache whose data may be at most maxAge
+// old. Feed it with Replace and Update, or let WatchOpenOrders poll it.
+func NewOpenOrderCache(maxAge time.Duration) *OpenOrderCache {
+	return &OpenOrderCache{maxAge: maxAge, orders: make(map[string]Order)}
+}
+
+// WatchOpenOrders returns a cache synchronized with OpenOrders every
+// interval, DefaultWatchInterval if not positive, until ctx is done. Data
+// older than maxAge is reported stale.
+func (api *KrakenAPI) WatchOpenOrders(ctx context.Context, interval, maxAge time.Duration) *OpenOrderCache {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	cache := NewOpenOrderCache(maxAge)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			// The pairs are refreshed here, with the cached metadata of
+			// api, so that queries never wait on the network
+			start := time.Now()
+			pairs, err := api.assetPairs(ctx)
+			var resp *OpenOrdersResponse
+			if err == nil {
+				resp, err = api.OpenOrdersWithContext(ctx, nil)
+			}
+			if err == nil {
+				cache.SetAssetPairs(*pairs)
+				cache.Replace(resp.Open, start)
+			} else {
+				cache.setErr(err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return cache
+}
+
+// SetAssetPairs makes ForPair resolve pairs through pairs, so that any name
+// of a pair finds its orders. Caches of WatchOpenOrders keep them up to date
+// with their client.
+func (c *OpenOrderCache) SetAssetPairs(pairs AssetPairsResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pairs = pairs
+}
+
+// Replace sets the whole content of the cache from a snapshot taken at
+// syncedAt, e.g. an OpenOrders response or a WebSocket snapshot
+func (c *OpenOrderCache) Replace(orders map[string]Order, syncedAt time.Time) {
+	copied := make(map[string]Order, len(orders))
+	for txid, order := range orders {
+		copied[txid] = order
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.orders = copied
+	c.syncedAt = syncedAt
+	c.err = nil
+}
+
+// Update applies a change of a single order observed at syncedAt, e.g. from
+// a WebSocket update. Orders that are no longer open or pending are removed.
+func (c *OpenOrderCache) Update(txid string, order Order, syncedAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if order.Status == "open" || order.Status == "pending" {
+		c.orders[txid] = order
+	} else {
+		delete(c.orders, txid)
+	}
+	if syncedAt.After(c.syncedAt) {
+		c.syncedAt = syncedAt
+	}
+}
+
+// setErr records a failed synchronization
+func (c *OpenOrderCache) setErr(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+}
+
+// SyncedAt returns when the cache was last synchronized, zero if never
+func (c *OpenOrderCache) SyncedAt() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.syncedAt
+}
+
+// Err returns the error of the last synchronization attempt, nil if it succeeded
+func (c *OpenOrderCache) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.err
+}
+
+// fresh returns a *StaleError when the data is too old; c.mu must be held
+func (c *OpenOrderCache) fresh() error {
+	if c.syncedAt.IsZero() || time.Since(c.syncedAt) > c.maxAge {
+		return &StaleError{SyncedAt: c.syncedAt, MaxAge: c.maxAge}
+	}
+	return nil
+}
+
+// filter returns the orders for which keep returns true
+func (c *OpenOrderCache) filter(keep func(Order) bool) (map[string]Order, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if err := c.fresh(); err != nil {
+		return nil, err
+	}
+
+	orders := make(map[string]Order)
+	for txid, order := range c.orders {
+		if keep(order) {
+			orders[txid] = order
+		}
+	}
+	return orders, nil
+}
+
+// All returns every open order indexed by txid
+func (c *OpenOrderCache) All() (map[string]Order, error) {
+	return c.filter(func(Order) bool { return true })
+}
+
+// ForPair returns the open orders of pair, given as canonical name
+// ("XXBTZUSD"), altname ("XBTUSD") or wsname ("XBT/USD"), which is resolved
+// through the asset pairs of the cache, see SetAssetPairs. Without them,
+// pair must be given as it appears in the order description, the altname.
+func (c *OpenOrderCache) ForPair(pair string) (map[string]Order, error) {
+	c.mu.RLock()
+	pairs := c.pairs
+	c.mu.RUnlock()
+	if pairs == nil {
+		return c.filter(func(order Order) bool { return order.Description.Pair == pair })
+	}
+	names, ok := pairs.FindPair(pair)
+	if !ok {
+		return nil, fmt.Errorf("unknown asset pair %q", pair)
+	}
+	return c.filter(func(order Order) bool { return names.Matches(order.Description.Pair) })
+}
+
+// ByUserRef returns the open orders placed with the given userref
+func (c *OpenOrderCache) ByUserRef(userref int) (map[string]Order, error) {
+	return c.filter(func(order Order) bool { return order.UserRef == userref })
+}
+
+// ByClientID returns the txid and open order placed with the given cl_ord_id,
+// or an *OrderNotFoundError
+func (c *OpenOrderCache) ByClientID(clOrdID string) (string, *Order, error) {
+	orders, err := c.filter(func(order Order) bool { return order.ClientOrderID == clOrdID })
+	if err != nil {
+		return "", nil, err
+	}
+	if txid, order, ok := findClientOrder(orders, clOrdID); ok {
+		return txid, order, nil
+	}
+	return "", nil, &OrderNotFoundError{ClientOrderID: clOrdID}
+}
+
+// RestingVolume returns the unfilled volume of the open orders of pair per side
+func (c *OpenOrderCache) RestingVolume(pair string) (buy, sell float64, err error) {
+	orders, err := c.ForPair(pair)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, order := range orders {
+		switch order.Description.Type {
+		case "buy":
+			buy += order.Volume - order.VolumeExecuted
+		case "sell":
+			sell += order.Volume - order.VolumeExecuted
+		}
+	}
+	return buy, sell, nil
+}
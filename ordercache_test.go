@@ -0,0 +1,84 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestOpenOrderCache(t *testing.T) {
+	cache := NewOpenOrderCache(time.Minute)
+	if _, err := cache.All(); !errors.Is(err, ErrStale) {
+		t.Errorf("Unsynchronized cache should be stale, got %v", err)
+	}
+
+	cache.Replace(map[string]Order{
+		"O1": {Status: "open", UserRef: 7, Volume: 1, VolumeExecuted: 0.25, Description: OrderDescription{Pair: "XBTUSD", Type: "buy"}},
+		"O2": {Status: "open", ClientOrderID: "mine", Volume: 2, Description: OrderDescription{Pair: "XBTUSD", Type: "sell"}},
+		"O3": {Status: "open", UserRef: 7, Volume: 5, Description: OrderDescription{Pair: "ETHUSD", Type: "buy"}},
+	}, time.Now())
+
+	if orders, _ := cache.ForPair("XBTUSD"); len(orders) != 2 {
+		t.Errorf("Expected 2 orders on XBTUSD, got %v", orders)
+	}
+	if orders, _ := cache.ByUserRef(7); len(orders) != 2 {
+		t.Errorf("Expected 2 orders with userref 7, got %v", orders)
+	}
+	if txid, _, err := cache.ByClientID("mine"); txid != "O2" || err != nil {
+		t.Errorf("Expected O2, got %s %v", txid, err)
+	}
+	if buy, sell, _ := cache.RestingVolume("XBTUSD"); buy != 0.75 || sell != 2 {
+		t.Errorf("Expected resting volume 0.75/2, got %v/%v", buy, sell)
+	}
+
+	cache.SetAssetPairs(AssetPairsResponse{"XXBTZUSD": {Altname: "XBTUSD", WSName: "XBT/USD"}})
+	if buy, sell, err := cache.RestingVolume("XBT/USD"); buy != 0.75 || sell != 2 || err != nil {
+		t.Errorf("Expected resting volume 0.75/2 by wsname, got %v/%v %v", buy, sell, err)
+	}
+
+	cache.Update("O2", Order{Status: "closed"}, time.Now())
+	if _, _, err := cache.ByClientID("mine"); err == nil {
+		t.Errorf("Closed orders should leave the cache")
+	}
+
+	cache.Replace(nil, time.Now().Add(-2*time.Minute))
+	var stale *StaleError
+	if _, err := cache.ForPair("XBTUSD"); !errors.As(err, &stale) {
+		t.Errorf("Old data should be reported stale, got %v", err)
+	}
+}
+
+func TestWatchOpenOrders(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method == "AssetPairs" {
+			return testAssetPairs
+		}
+		return `{"error":[],"result":{"open":{"O1":{"status":"open","descr":{"pair":"XBTUSD"}}}}}`
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache := api.WatchOpenOrders(ctx, time.Millisecond, time.Minute)
+	for i := 0; cache.SyncedAt().IsZero(); i++ {
+		if i > 1000 {
+			t.Fatal("Cache was never synchronized")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if orders, err := cache.All(); err != nil || len(orders) != 1 {
+		t.Errorf("Expected the polled order, got %v %v", orders, err)
+	}
+	for _, pair := range []string{"XBTUSD", "XXBTZUSD", "XBT/USD"} {
+		if orders, err := cache.ForPair(pair); err != nil || len(orders) != 1 {
+			t.Errorf("Expected the order of %s, got %v %v", pair, orders, err)
+		}
+	}
+	if orders, err := cache.ForPair("XETHZUSD"); err != nil || len(orders) != 0 {
+		t.Errorf("Expected no order on XETHZUSD, got %v %v", orders, err)
+	}
+	if _, err := cache.ForPair("NOPE"); err == nil {
+		t.Errorf("Unknown pairs should be reported")
+	}
+}
@@ -0,0 +1,73 @@
+package krakenapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportOrderState(t *testing.T) {
+	var offsets []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(body))
+		switch method {
+		case "OpenOrders":
+			return `{"error":[],"result":{"open":{
+				"OB5VMB-B4U2U-DK2WRW":{"userref":7,"status":"open","opentm":1688666600.5,"vol":"1.123456789012345678","vol_exec":"0",
+					"descr":{"pair":"XBTUSD","type":"sell","ordertype":"limit","price":"31000.1","price2":"0"},"cost":"0","fee":"0","price":"0"},
+				"OQCLML-BW3P3-BUCMWZ":{"status":"open","opentm":1688666000,"vol":"1","vol_exec":"0",
+					"descr":{"pair":"XBTUSD","type":"buy","ordertype":"limit","price":"29000","price2":"0"},"cost":"0","fee":"0","price":"0"}}}}`
+		case "ClosedOrders":
+			offsets = append(offsets, params.Get("start")+"/"+params.Get("ofs"))
+			if params.Get("ofs") == "0" {
+				return `{"error":[],"result":{"count":2,"closed":{
+					"OQCLML-BW3P3-BUCMWZ":{"status":"closed","opentm":1688666000,"closetm":1688666900.25,"vol":"1.00000000","vol_exec":"1.00000000",
+						"descr":{"pair":"XBTUSD","type":"buy","ordertype":"limit","price":"29000.0","price2":"0"},
+						"cost":"29000.00000","fee":"46.40000","price":"29000.0","trades":["TCCCTY-WE2O6-P3NB37","TZX2WP-XSEOP-FP7WYR"]}}}}`
+			}
+			return `{"error":[],"result":{"count":2,"closed":{
+				"OGTT3Y-C6I3P-XRI6HX":{"cl_ord_id":"ladder-1","status":"canceled","reason":"User requested","opentm":1688665000,
+					"closetm":1688665100,"vol":"0.1","vol_exec":"0","descr":{"pair":"ETHUSD","type":"buy","ordertype":"limit","price":"1800","price2":"0"},
+					"cost":"0","fee":"0","price":"0"}}}}`
+		case "QueryTrades":
+			return `{"error":[],"result":{"TCCCTY-WE2O6-P3NB37":{"time":1688666800.123456,"price":"29000","vol":"0.4","cost":"11600","fee":"18.56"},
+				"TZX2WP-XSEOP-FP7WYR":{"time":1688666900.25,"price":"29000","vol":"0.6","cost":"17400","fee":"27.84"}}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	var out bytes.Buffer
+	if err := api.ExportOrderState(context.Background(), time.Unix(1688600000, 0), &out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(offsets, ",") != "1688600000/0,1688600000/1" {
+		t.Errorf("Closed orders should be paged from the start time, got %v", offsets)
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 || strings.Join(records[0], ",") != strings.Join(OrderStateColumns, ",") {
+		t.Fatalf("Expected a header and three orders, got %v", records)
+	}
+	want := []string{
+		"OGTT3Y-C6I3P-XRI6HX,ladder-1,0,ETHUSD,buy,limit,1800,0,0,0.1,0,0,0,canceled,User requested,2023-07-06T17:36:40Z,2023-07-06T17:38:20Z,,,",
+		"OQCLML-BW3P3-BUCMWZ,,0,XBTUSD,buy,limit,29000.0,0,29000.0,1.00000000,1.00000000,29000.00000,46.40000,closed,,2023-07-06T17:53:20Z,2023-07-06T18:08:20.25Z," +
+			"TCCCTY-WE2O6-P3NB37 TZX2WP-XSEOP-FP7WYR,2023-07-06T18:06:40.123456Z,2023-07-06T18:08:20.25Z",
+		"OB5VMB-B4U2U-DK2WRW,,7,XBTUSD,sell,limit,31000.1,0,0,1.123456789012345678,0,0,0,open,,2023-07-06T18:03:20.5Z,,,,",
+	}
+	for i, line := range want {
+		if got := strings.Join(records[i+1], ","); got != line {
+			t.Errorf("Record %d:\n got %s\nwant %s", i+1, got, line)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxOrdersPerCall is the number of txids Kraken accepts in CancelOrderBatch and QueryOrders
+const maxOrdersPerCall = 50
+
+// Bounds of the number of orders Kraken accepts in AddOrderBatch
+const (
+	minAddOrderBatch = 2
+	maxAddOrderBatch = 15
+)
+
+// CancelOutcome describes what happened to a single order of a bulk cancel
+type CancelOutcome string
+
+// Outcomes of a bulk cancel
+const (
+	CancelOutcomeCancelled       CancelOutcome = "cancelled"        // The order was cancelled by this call
+	CancelOutcomeAlreadyTerminal CancelOutcome = "already_terminal" // The order filled, expired or vanished before it could be cancelled
+	CancelOutcomeStillOpen       CancelOutcome = "still_open"       // Kraken still reports the order as open or pending
+)
+
+// CancelPairResult reports the per-order outcome of CancelAllForPair
+type CancelPairResult struct {
+	Pair            PairNames
+	Orders          map[string]CancelOutcome // Outcome indexed by txid
+	Cancelled       int                      // Number of orders cancelled by this call
+	AlreadyTerminal int                      // Number of orders that were already gone
+	StillOpen       int                      // Number of orders Kraken still reports as open
+	// Err is why FlattenAccount failed to cancel the pair, in which case
+	// the other fields are zero. CancelAllForPair returns its error instead.
+	Err error
+}
+
+// CancelAllForPair cancels every open order on the given pair. The pair may
+// be given as canonical name ("XXBTZUSD"), altname ("XBTUSD") or wsname ("XBT/USD").
+// Orders that fill between listing and cancelling are reported as already terminal.
+func (api *KrakenAPI) CancelAllForPair(ctx context.Context, pair string) (*CancelPairResult, error) {
+	names, err := api.resolvePair(ctx, pair)
+	if err != nil {
+		return nil, err
+	}
+
+	open, err := api.OpenOrdersWithContext(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	txids := make([]string, 0)
+	for txid, order := range open.Open {
+		if names.Matches(order.Description.Pair) {
+			txids = append(txids, txid)
+		}
+	}
+	sort.Strings(txids)
+
+	result := &CancelPairResult{
+		Pair:   names,
+		Orders: make(map[string]CancelOutcome, len(txids)),
+	}
+
+	for _, chunk := range chunkStrings(txids, maxOrdersPerCall) {
+		if err := api.cancelChunk(ctx, chunk); err != nil {
+			return nil, err
+		}
+
+		orders, err := api.QueryOrdersWithContext(ctx, strings.Join(chunk, ","), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, txid := range chunk {
+			order, found := (*orders)[txid]
+			switch {
+			case !found, order.Status == "closed", order.Status == "expired":
+				result.Orders[txid] = CancelOutcomeAlreadyTerminal
+				result.AlreadyTerminal++
+			case order.Status == "canceled":
+				result.Orders[txid] = CancelOutcomeCancelled
+				result.Cancelled++
+			default:
+				result.Orders[txid] = CancelOutcomeStillOpen
+				result.StillOpen++
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// OrderNotFoundError is returned when no order carries the requested client order id
+type OrderNotFoundError struct {
+	ClientOrderID string
+}
+
+func (e *OrderNotFoundError) Error() string {
+	return fmt.Sprintf("no order with cl_ord_id %q", e.ClientOrderID)
+}
+
+// OrderByClientID looks up the order placed with the given cl_ord_id, first
+// among the open orders and then among the closed ones. It returns the txid
+// of the order along with the order, or an *OrderNotFoundError.
+func (api *KrakenAPI) OrderByClientID(ctx context.Context, clOrdID string) (string, *Order, error) {
+	args := map[string]string{"cl_ord_id": clOrdID}
+
+	open, err := api.OpenOrdersWithContext(ctx, args)
+	if err != nil {
+		return "", nil, err
+	}
+	if txid, order, ok := findClientOrder(open.Open, clOrdID); ok {
+		return txid, order, nil
+	}
+
+	closed, err := api.ClosedOrdersWithContext(ctx, args)
+	if err != nil {
+		return "", nil, err
+	}
+	if txid, order, ok := findClientOrder(closed.Closed, clOrdID); ok {
+		return txid, order, nil
+	}
+
+	return "", nil, &OrderNotFoundError{ClientOrderID: clOrdID}
+}
+
+// findClientOrder returns the order of orders carrying clOrdID. Kraken already
+// filters by cl_ord_id; the check protects against a filter being ignored.
+func findClientOrder(orders map[string]Order, clOrdID string) (string, *Order, bool) {
+	for txid, order := range orders {
+		if order.ClientOrderID == clOrdID {
+			return txid, &order, true
+		}
+	}
+	return "", nil, false
+}
+
+// ErrBatchCancel is matched by every *BatchCancelError
+var ErrBatchCancel = errors.New("orders of the batch could not be cancelled")
+
+// BatchCancelError lists the orders of a CancelOrderBatch call that could not be cancelled
+type BatchCancelError struct {
+	Cancelled int              // Number of orders cancelled
+	Orders    map[string]error // Error of every order not cancelled, indexed by txid or userref
+}
+
+func (e *BatchCancelError) Error() string {
+	ids := make([]string, 0, len(e.Orders))
+	for id := range e.Orders {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	details := make([]string, len(ids))
+	for i, id := range ids {
+		details[i] = fmt.Sprintf("%s: %v", id, e.Orders[id])
+	}
+	return fmt.Sprintf("%d orders could not be cancelled (%s)", len(ids), strings.Join(details, ", "))
+}
+
+// Unwrap makes errors.Is(err, ErrBatchCancel) hold
+func (e *BatchCancelError) Unwrap() error {
+	return ErrBatchCancel
+}
+
+// cancelOneByOne cancels txids with one CancelOrder call each after Kraken
+// rejected them as a batch. Transport failures abort, since the outcome of
+// the remaining orders is unknown.
+func (api *KrakenAPI) cancelOneByOne(ctx context.Context, txids []string) (*CancelOrderResponse, error) {
+	batchErr := &BatchCancelError{Orders: make(map[string]error)}
+	for _, txid := range txids {
+		resp, err := api.CancelOrderWithContext(ctx, txid)
+		if err != nil {
+			if !isAPIError(err) {
+				return nil, err
+			}
+			batchErr.Orders[txid] = err
+			continue
+		}
+		batchErr.Cancelled += resp.Count
+	}
+
+	resp := &CancelOrderResponse{Count: batchErr.Cancelled}
+	if len(batchErr.Orders) > 0 {
+		return resp, batchErr
+	}
+	return resp, nil
+}
+
+// cancelChunk cancels txids with a single batch call. Orders that are
+// already gone are not an error.
+func (api *KrakenAPI) cancelChunk(ctx context.Context, txids []string) error {
+	_, err := api.CancelOrderBatchWithContext(ctx, txids)
+	var batchErr *BatchCancelError
+	if !errors.As(err, &batchErr) {
+		return err
+	}
+	for _, orderErr := range batchErr.Orders {
+		if !hasErrorCode(orderErr, ErrCodeUnknownOrder) {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkStrings splits list into slices of at most size elements
+func chunkStrings(list []string, size int) [][]string {
+	var chunks [][]string
+	for len(list) > size {
+		chunks = append(chunks, list[:size])
+		list = list[size:]
+	}
+	if len(list) > 0 {
+		chunks = append(chunks, list)
+	}
+	return chunks
+}
@@ -0,0 +1,344 @@
+package krakenapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const testAssetPairs = `{"error":[],"result":{
+	"XXBTZUSD":{"altname":"XBTUSD","wsname":"XBT/USD","base":"XXBT","quote":"ZUSD"},
+	"XETHZUSD":{"altname":"ETHUSD","wsname":"ETH/USD","base":"XETH","quote":"ZUSD"}}}`
+
+func TestCancelAllForPair(t *testing.T) {
+	var batches [][]string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "AssetPairs":
+			return testAssetPairs
+		case "OpenOrders":
+			return `{"error":[],"result":{"open":{
+				"O1":{"status":"open","descr":{"pair":"XBTUSD"}},
+				"O2":{"status":"open","descr":{"pair":"XBTUSD"}},
+				"O3":{"status":"open","descr":{"pair":"ETHUSD"}}}}}`
+		case "CancelOrderBatch":
+			body, _ := io.ReadAll(req.Body)
+			var params struct {
+				Orders []string `json:"orders"`
+			}
+			if err := json.Unmarshal(body, &params); err != nil {
+				t.Fatalf("CancelOrderBatch body should be JSON, got %s", body)
+			}
+			batches = append(batches, params.Orders)
+			return `{"error":[],"result":{"count":1}}`
+		case "QueryOrders":
+			return `{"error":[],"result":{
+				"O1":{"status":"canceled","descr":{"pair":"XBTUSD"}},
+				"O2":{"status":"closed","descr":{"pair":"XBTUSD"}}}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	result, err := api.CancelAllForPair(context.Background(), "XBT/USD")
+	if err != nil {
+		t.Fatalf("CancelAllForPair() should not return an error, got %s", err)
+	}
+
+	if len(batches) != 1 || strings.Join(batches[0], ",") != "O1,O2" {
+		t.Errorf("CancelAllForPair() should cancel only the pair's orders, got %v", batches)
+	}
+	if result.Cancelled != 1 || result.AlreadyTerminal != 1 {
+		t.Errorf("CancelAllForPair() should report 1 cancelled and 1 terminal, got %+v", result)
+	}
+	if result.Orders["O2"] != CancelOutcomeAlreadyTerminal {
+		t.Errorf("Filled order should be already terminal, got %s", result.Orders["O2"])
+	}
+}
+
+func TestCancelAllForPairUnknownOrder(t *testing.T) {
+	var single []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "AssetPairs":
+			return testAssetPairs
+		case "OpenOrders":
+			return `{"error":[],"result":{"open":{
+				"O1":{"status":"open","descr":{"pair":"XBTUSD"}},
+				"O2":{"status":"open","descr":{"pair":"XBTUSD"}}}}}`
+		case "CancelOrderBatch":
+			return `{"error":["EOrder:Unknown order"]}`
+		case "CancelOrder":
+			req.ParseForm()
+			txid := req.PostForm.Get("txid")
+			single = append(single, txid)
+			if txid == "O1" {
+				return `{"error":["EOrder:Unknown order"]}`
+			}
+			return `{"error":[],"result":{"count":1}}`
+		case "QueryOrders":
+			return `{"error":[],"result":{"O2":{"status":"canceled"}}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	result, err := api.CancelAllForPair(context.Background(), "XXBTZUSD")
+	if err != nil {
+		t.Fatalf("CancelAllForPair() should tolerate unknown orders, got %s", err)
+	}
+	if len(single) != 2 {
+		t.Errorf("CancelAllForPair() should fall back to single cancels, got %v", single)
+	}
+	if result.Cancelled != 1 || result.AlreadyTerminal != 1 {
+		t.Errorf("CancelAllForPair() should report 1 cancelled and 1 terminal, got %+v", result)
+	}
+}
+
+func TestCancelOrderBatchMixed(t *testing.T) {
+	var batch string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "CancelOrderBatch":
+			body, _ := io.ReadAll(req.Body)
+			batch = string(body)
+			return `{"error":["EOrder:Unknown order"]}`
+		case "CancelOrder":
+			body, _ := io.ReadAll(req.Body)
+			params, _ := url.ParseQuery(string(body))
+			if params.Get("txid") == "BAD" {
+				return `{"error":["EOrder:Unknown order"]}`
+			}
+			return `{"error":[],"result":{"count":1}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	resp, err := api.CancelOrderBatch([]string{"O1", "BAD", "42"})
+	if !strings.Contains(batch, `"orders":["O1","BAD",42]`) {
+		t.Errorf("Userrefs should be sent as numbers, got %s", batch)
+	}
+	var batchErr *BatchCancelError
+	if !errors.As(err, &batchErr) || !errors.Is(err, ErrBatchCancel) {
+		t.Fatalf("Expected a *BatchCancelError, got %v", err)
+	}
+	if len(batchErr.Orders) != 1 || !hasErrorCode(batchErr.Orders["BAD"], ErrCodeUnknownOrder) {
+		t.Errorf("Only BAD should have failed, got %v", batchErr.Orders)
+	}
+	if resp == nil || resp.Count != 2 || batchErr.Cancelled != 2 {
+		t.Errorf("The other orders should be cancelled, got %+v", resp)
+	}
+}
+
+func TestCancelAllForPairUnknownPair(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return testAssetPairs
+	})
+
+	if _, err := api.CancelAllForPair(context.Background(), "DOGEUSD"); err == nil {
+		t.Errorf("CancelAllForPair() should fail for an unknown pair")
+	}
+}
+
+func TestOrderByClientID(t *testing.T) {
+	var filters []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		form, _ := url.ParseQuery(string(body))
+		filters = append(filters, method+"="+form.Get("cl_ord_id"))
+		switch method {
+		case "OpenOrders":
+			return `{"error":[],"result":{"open":{}}}`
+		case "ClosedOrders":
+			if form.Get("cl_ord_id") == "missing" {
+				return `{"error":[],"result":{"closed":{},"count":0}}`
+			}
+			return `{"error":[],"result":{"closed":{
+				"O1":{"status":"closed","cl_ord_id":"my-1","descr":{"pair":"XBTUSD"}}},"count":1}}`
+		}
+		t.Fatalf("Unexpected call to %s", method)
+		return ""
+	})
+
+	txid, order, err := api.OrderByClientID(context.Background(), "my-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if txid != "O1" || order.Status != "closed" {
+		t.Errorf("Closed order should be found, got %s %+v", txid, order)
+	}
+	if strings.Join(filters, ",") != "OpenOrders=my-1,ClosedOrders=my-1" {
+		t.Errorf("Open orders should be searched before closed ones, got %v", filters)
+	}
+
+	_, _, err = api.OrderByClientID(context.Background(), "missing")
+	var notFound *OrderNotFoundError
+	if !errors.As(err, &notFound) || notFound.ClientOrderID != "missing" {
+		t.Errorf("Expected an OrderNotFoundError, got %v", err)
+	}
+}
+
+func TestEditOrder(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "AssetPairs":
+			return `{"error":[],"result":{"XXBTZUSD":{"altname":"XBTUSD","wsname":"XBT/USD","pair_decimals":1,"lot_decimals":8}}}`
+		case "EditOrder":
+			body, _ := io.ReadAll(req.Body)
+			params, _ = url.ParseQuery(string(body))
+			return `{"error":[],"result":{"status":"ok","txid":"OFVXHJ-KPQ3B-VS7ELA","originaltxid":"OHYO67-6LP66-HMQ437",
+				"volume":"0.00030000","price":"19500.0","orders_cancelled":1,"descr":{"order":"buy 0.00030000 XXBTZUSD @ limit 19500.0"}}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	resp, err := api.EditOrder("OHYO67-6LP66-HMQ437", "XBTUSD", EditOrderArgs{
+		Volume: 0.0003, Price: 19500.04, OFlags: "post", CancelResponse: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("price") != "19500" || params.Get("volume") != "0.0003" || params.Get("price2") != "" {
+		t.Errorf("Numbers should be formatted with the pair decimals, got %v", params)
+	}
+	if params.Get("oflags") != "post" || params.Get("cancel_response") != "true" || params.Get("txid") != "OHYO67-6LP66-HMQ437" {
+		t.Errorf("Unexpected parameters %v", params)
+	}
+	if resp.TxID != "OFVXHJ-KPQ3B-VS7ELA" || resp.OriginalTxID != "OHYO67-6LP66-HMQ437" || resp.Price != 19500 || resp.OrdersCancelled != 1 {
+		t.Errorf("Unexpected response %+v", resp)
+	}
+
+	if _, err := api.EditOrder("O1", "DOGEUSD", EditOrderArgs{}); err == nil {
+		t.Errorf("EditOrder should reject unknown pairs")
+	}
+}
+
+func TestAddOrderCloseArgs(t *testing.T) {
+	var sent []url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(body))
+		sent = append(sent, params)
+		return `{"error":[],"result":{"descr":{"order":"buy 1 XBTUSD @ limit 30000"},"txid":["OUF4EM-FRGI2-MQMWZD"]}}`
+	})
+
+	args := map[string]string{"price": "30000", "close_order_type": OTStopLoss, "close_price": "29000"}
+	if _, err := api.AddOrder("XBTUSD", "buy", OTLimit, "1", args); err != nil {
+		t.Fatal(err)
+	}
+	if sent[0].Get("close[ordertype]") != OTStopLoss || sent[0].Get("close[price]") != "29000" {
+		t.Errorf("The conditional close should be sent, got %v", sent[0])
+	}
+
+	for _, key := range []string{"close[ordertype]", "close_type"} {
+		if _, err := api.AddOrder("XBTUSD", "buy", OTLimit, "1", map[string]string{key: OTStopLoss}); err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("Unknown close argument %s should be rejected, got %v", key, err)
+		}
+		if _, err := batchOrder(AddOrderRequest{Direction: "buy", OrderType: OTLimit, Volume: "1", Args: map[string]string{key: OTStopLoss}}); err == nil {
+			t.Errorf("Unknown close argument %s should be rejected in batches", key)
+		}
+	}
+	if len(sent) != 1 {
+		t.Errorf("Rejected orders should not reach Kraken, got %d calls", len(sent))
+	}
+}
+
+func TestAddOrderBatch(t *testing.T) {
+	var body struct {
+		Pair   string                   `json:"pair"`
+		Orders []map[string]interface{} `json:"orders"`
+	}
+	calls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls++
+		if req.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("AddOrderBatch should send JSON, got %q", req.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return `{"error":[],"result":{"orders":[{"txid":"OUF4EM-FRGI2-MQMWZD","descr":{"order":"buy 1.00000000 XBTUSD @ limit 19000.0"}},
+			{"error":"EOrder:Insufficient funds"}]}}`
+	})
+
+	results, err := api.AddOrderBatch("XBTUSD", []AddOrderRequest{
+		{Direction: "buy", OrderType: OTLimit, Volume: "1", Args: map[string]string{"price": "19000", "userref": "42"}},
+		{Direction: "buy", OrderType: OTLimit, Volume: "1", Args: map[string]string{
+			"price": "18900", "close_order_type": OTStopLoss, "close_price": "18000",
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body.Pair != "XBTUSD" || len(body.Orders) != 2 || body.Orders[0]["userref"] != float64(42) || body.Orders[0]["price"] != "19000" {
+		t.Errorf("Unexpected batch %+v", body)
+	}
+	if closing, _ := body.Orders[1]["close"].(map[string]interface{}); closing["ordertype"] != OTStopLoss || closing["price"] != "18000" {
+		t.Errorf("Conditional close should be nested, got %+v", body.Orders[1])
+	}
+	if len(results) != 2 || results[0].TxID != "OUF4EM-FRGI2-MQMWZD" || results[1].Error != ErrCodeInsufficientFunds {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	orders := make([]AddOrderRequest, 16)
+	if _, err := api.AddOrderBatch("XBTUSD", orders); err == nil || !strings.Contains(err.Error(), "16") {
+		t.Errorf("Batches above the limit should be rejected, got %v", err)
+	}
+	if _, err := api.AddOrderBatch("XBTUSD", orders[:2]); err != nil {
+		t.Fatal(err)
+	}
+	orders[0].Args = map[string]string{"userref": "x"}
+	if _, err := api.AddOrderBatch("XBTUSD", orders[:2]); err == nil {
+		t.Errorf("Invalid userrefs should be rejected")
+	}
+	if calls != 2 {
+		t.Errorf("Rejected batches should not reach Kraken, got %d calls", calls)
+	}
+}
+
+func TestQueryTradesByTxID(t *testing.T) {
+	var requested []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ := url.ParseQuery(string(body))
+		requested = append(requested, params.Get("txid"))
+		if params.Get("trades") != "true" {
+			t.Errorf("Expected trades to be requested, got %v", params)
+		}
+		trades := map[string]TradeHistoryInfo{}
+		for _, txid := range strings.Split(params.Get("txid"), ",") {
+			trades[txid] = TradeHistoryInfo{TransactionID: "O-" + txid, Volume: float64(len(requested))}
+		}
+		result, _ := json.Marshal(map[string]interface{}{"error": []string{}, "result": trades})
+		return string(result)
+	})
+
+	txids := make([]string, 45)
+	for i := range txids {
+		txids[i] = fmt.Sprintf("T%02d", i)
+	}
+	trades, err := api.QueryTrades(true, append(txids, "T00", "T44")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != 3 || strings.Count(requested[0], ",") != 19 || requested[2] != "T40,T41,T42,T43,T44" {
+		t.Errorf("Expected chunks of 20 distinct txids, got %v", requested)
+	}
+	if len(trades) != 45 || trades["T07"].TransactionID != "O-T07" || trades["T44"].Volume != 3 {
+		t.Errorf("Unexpected trades %+v", trades)
+	}
+
+	requested = nil
+	if trades, err := api.QueryTrades(true); err != nil || len(trades) != 0 || requested != nil {
+		t.Errorf("No txids should send nothing, got %v and %v", trades, err)
+	}
+}
@@ -0,0 +1,81 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Info levels of AssetPairsOpts
+const (
+	AssetPairsInfo     = "info"     // Every field, the default
+	AssetPairsLeverage = "leverage" // Only the leverage fields
+	AssetPairsFees     = "fees"     // Only the fee schedules
+	AssetPairsMargin   = "margin"   // Only the margin fields
+)
+
+// AssetPairsOpts selects the asset pairs and fields returned by AssetPairs.
+// The zero AssetPairsOpts returns every field of every pair.
+type AssetPairsOpts struct {
+	Pairs []string // Pairs to return, all of them if empty
+	Info  string   // One of the AssetPairs info levels, AssetPairsInfo if empty
+}
+
+// values returns the query parameters of the options
+func (o AssetPairsOpts) values() (url.Values, error) {
+	values := url.Values{}
+	switch o.Info {
+	case "":
+	case AssetPairsInfo, AssetPairsLeverage, AssetPairsFees, AssetPairsMargin:
+		values.Set("info", o.Info)
+	default:
+		return nil, fmt.Errorf("unknown asset pairs info %q", o.Info)
+	}
+	if len(o.Pairs) > 0 {
+		values.Set("pair", strings.Join(o.Pairs, ","))
+	}
+	return values, nil
+}
+
+// PairNames holds the three names Kraken uses for the same asset pair
+type PairNames struct {
+	Name    string // Canonical key of the AssetPairs response, e.g. "XXBTZUSD"
+	Altname string // Alternate pair name, e.g. "XBTUSD"
+	WSName  string // WebSocket pair name, e.g. "XBT/USD"
+}
+
+// Matches reports whether name is any of the known forms of the pair
+func (p PairNames) Matches(name string) bool {
+	if name == "" {
+		return false
+	}
+	return name == p.Name || name == p.Altname || name == p.WSName
+}
+
+// FindPair looks up pair in the response by its canonical name, altname or wsname
+func (r AssetPairsResponse) FindPair(pair string) (PairNames, bool) {
+	if info, ok := r[pair]; ok {
+		return PairNames{Name: pair, Altname: info.Altname, WSName: info.WSName}, true
+	}
+	for name, info := range r {
+		names := PairNames{Name: name, Altname: info.Altname, WSName: info.WSName}
+		if names.Matches(pair) {
+			return names, true
+		}
+	}
+	return PairNames{}, false
+}
+
+// resolvePair fetches the asset pairs and resolves pair into all of its names
+func (api *KrakenAPI) resolvePair(ctx context.Context, pair string) (PairNames, error) {
+	pairs, err := api.assetPairs(ctx)
+	if err != nil {
+		return PairNames{}, err
+	}
+	names, ok := pairs.FindPair(pair)
+	if !ok {
+		return PairNames{}, fmt.Errorf("unknown asset pair %q", pair)
+	}
+	return names, nil
+}
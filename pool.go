@@ -0,0 +1,42 @@
+package krakenapi
+
+import (
+	"context"
+	"sync"
+)
+
+// maxPublicWorkers bounds the concurrent public requests of the batch
+// helpers; Kraken limits public calls per IP address
+const maxPublicWorkers = 3
+
+// fanOut calls fn for every index below n with at most workers calls running
+// at once. Indexes not started before ctx is done are skipped. Private calls
+// made by fn still queue in the rate limiter of the client.
+func fanOut(ctx context.Context, n, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+}
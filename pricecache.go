@@ -0,0 +1,209 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CachedPrice is a last trade price held by a PriceCache
+type CachedPrice struct {
+	Price     float64
+	FetchedAt time.Time
+}
+
+// Age returns how long ago the price was fetched
+func (p CachedPrice) Age() time.Duration {
+	if p.FetchedAt.IsZero() {
+		return 0
+	}
+	return time.Since(p.FetchedAt)
+}
+
+// PriceCache holds the last trade price of pairs for callers which only need
+// a recent price. Expired prices are refreshed with a single Ticker call
+// shared by every concurrent caller, batching all the pairs asked for.
+type PriceCache struct {
+	api         *KrakenAPI
+	ttl         time.Duration
+	callTimeout time.Duration // Of the shared calls, sharedCallTimeout if zero
+
+	mu       sync.Mutex
+	prices   map[string]CachedPrice
+	inflight *priceCall
+	next     *priceCall // Pairs asked for while inflight was running
+}
+
+// priceCall is a Ticker call shared by every waiting caller
+type priceCall struct {
+	pairs map[string]bool
+	done  chan struct{}
+	errs  map[string]error // By pair, so one bad pair fails only its callers
+}
+
+// NewPriceCache returns a price cache refreshing prices older than ttl
+func (api *KrakenAPI) NewPriceCache(ttl time.Duration) *PriceCache {
+	return &PriceCache{api: api, ttl: ttl, prices: make(map[string]CachedPrice)}
+}
+
+// Get returns the last trade price of pair, fetching it when it is missing
+// or older than the TTL of the cache
+func (c *PriceCache) Get(ctx context.Context, pair string) (CachedPrice, error) {
+	c.mu.Lock()
+	if price, ok := c.prices[pair]; ok && time.Since(price.FetchedAt) < c.ttl {
+		c.mu.Unlock()
+		c.report(pair, true, price.Age())
+		return price, nil
+	}
+
+	call := c.join(pair)
+	c.mu.Unlock()
+	c.report(pair, false, 0)
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return CachedPrice{}, ctx.Err()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := call.errs[pair]; err != nil {
+		return CachedPrice{}, err
+	}
+	if price, ok := c.prices[pair]; ok {
+		return price, nil
+	}
+	return CachedPrice{}, fmt.Errorf("no price for %s", pair)
+}
+
+// join returns the call which will fetch pair, starting one if none is
+// running. Must hold c.mu.
+func (c *PriceCache) join(pair string) *priceCall {
+	if c.inflight != nil && c.inflight.pairs[pair] {
+		return c.inflight
+	}
+	if c.inflight != nil {
+		if c.next == nil {
+			c.next = &priceCall{pairs: make(map[string]bool), done: make(chan struct{})}
+		}
+		c.next.pairs[pair] = true
+		return c.next
+	}
+
+	call := &priceCall{pairs: map[string]bool{pair: true}, done: make(chan struct{})}
+	// Refresh the other expired prices along with it
+	for known, price := range c.prices {
+		if time.Since(price.FetchedAt) >= c.ttl {
+			call.pairs[known] = true
+		}
+	}
+	c.inflight = call
+	go c.run(call)
+	return call
+}
+
+// run performs the shared call and starts the next batch. It is not bound to
+// the context of the caller that started it, so that one cancelled caller
+// does not fail the others, but times out so that a hung request does not
+// block the cache.
+func (c *PriceCache) run(call *priceCall) {
+	ctx, cancel := sharedCallContext(c.callTimeout)
+	defer cancel()
+	pairs := make([]string, 0, len(call.pairs))
+	for pair := range call.pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+
+	start := time.Now()
+	prices, errs := c.fetchEach(ctx, pairs)
+
+	c.mu.Lock()
+	call.errs = errs
+	for pair, price := range prices {
+		c.prices[pair] = CachedPrice{Price: price, FetchedAt: start}
+	}
+	c.inflight = nil
+	if next := c.next; next != nil {
+		c.next = nil
+		c.inflight = next
+		go c.run(next)
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// fetchEach returns the last trade price of pairs and the errors of the
+// pairs it failed to fetch. Kraken fails the whole Ticker call for a single
+// unknown pair, so a batch rejected by Kraken is retried pair by pair.
+func (c *PriceCache) fetchEach(ctx context.Context, pairs []string) (map[string]float64, map[string]error) {
+	errs := make(map[string]error)
+	prices, err := c.fetch(ctx, pairs)
+	if err == nil {
+		return prices, errs
+	}
+	if len(pairs) == 1 || !isAPIError(err) {
+		for _, pair := range pairs {
+			errs[pair] = err
+		}
+		return prices, errs
+	}
+
+	prices = make(map[string]float64, len(pairs))
+	for _, pair := range pairs {
+		price, err := c.fetch(ctx, []string{pair})
+		if err != nil {
+			errs[pair] = err
+			continue
+		}
+		for name, value := range price {
+			prices[name] = value
+		}
+	}
+	return prices, errs
+}
+
+// fetch returns the last trade price of pairs, indexed by the requested names
+func (c *PriceCache) fetch(ctx context.Context, pairs []string) (map[string]float64, error) {
+	tickers, err := c.api.TickerWithContext(ctx, pairs...)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]float64, len(pairs))
+	var assetPairs *AssetPairsResponse
+	for _, pair := range pairs {
+		info, ok := (*tickers)[pair]
+		if !ok {
+			// Kraken answers with the canonical pair names, which may differ from the requested ones
+			if assetPairs == nil {
+				if assetPairs, err = c.api.assetPairs(ctx); err != nil {
+					return prices, err
+				}
+			}
+			names, found := assetPairs.FindPair(pair)
+			if info, ok = (*tickers)[names.Name]; !found || !ok {
+				continue
+			}
+		}
+		if len(info.Close) == 0 {
+			continue
+		}
+		if price, err := strconv.ParseFloat(info.Close[0], 64); err == nil {
+			prices[pair] = price
+		}
+	}
+	return prices, nil
+}
+
+// report counts the lookup in the stats of the client and calls its OnCacheLookup hook
+func (c *PriceCache) report(pair string, hit bool, age time.Duration) {
+	c.api.stats.cacheLookup("price", hit)
+	if c.api.hooks.OnCacheLookup != nil {
+		c.api.hooks.OnCacheLookup(CacheEvent{Cache: "price", Key: pair, Hit: hit, Age: age})
+	}
+}
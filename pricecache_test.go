@@ -0,0 +1,126 @@
+package krakenapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriceCache(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	release := make(chan struct{})
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method == "AssetPairs" {
+			return testAssetPairs
+		}
+		mu.Lock()
+		requested = append(requested, req.URL.Query().Get("pair"))
+		mu.Unlock()
+		<-release
+		return `{"error":[],"result":{
+			"XXBTZUSD":{"c":["30000.1","1"]},
+			"XETHZUSD":{"c":["2000.5","1"]}}}`
+	})
+	var hits, misses int
+	api.WithHooks(Hooks{OnCacheLookup: func(e CacheEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if e.Hit {
+			hits++
+		} else {
+			misses++
+		}
+	}})
+
+	cache := api.NewPriceCache(time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			price, err := cache.Get(context.Background(), "XBTUSD")
+			if err != nil || price.Price != 30000.1 {
+				t.Errorf("Unexpected price %+v, %v", price, err)
+			}
+		}()
+	}
+	// Let the callers join the call before answering it
+	for {
+		mu.Lock()
+		n := misses
+		mu.Unlock()
+		if n == 5 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	price, err := cache.Get(context.Background(), "XBTUSD")
+	if err != nil || price.Price != 30000.1 || price.Age() > time.Minute {
+		t.Errorf("Cached price should be served, got %+v, %v", price, err)
+	}
+	if price, err := cache.Get(context.Background(), "ETHUSD"); err != nil || price.Price != 2000.5 {
+		t.Errorf("Unexpected price %+v, %v", price, err)
+	}
+
+	if len(requested) != 2 || requested[0] != "XBTUSD" || requested[1] != "ETHUSD" {
+		t.Errorf("Concurrent callers should share one Ticker call, got %v", requested)
+	}
+	if hits != 1 || misses != 6 {
+		t.Errorf("Expected 1 hit and 6 misses, got %d and %d", hits, misses)
+	}
+}
+
+func TestPriceCacheInvalidPair(t *testing.T) {
+	var requested []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method == "AssetPairs" {
+			return testAssetPairs
+		}
+		pairs := req.URL.Query().Get("pair")
+		requested = append(requested, pairs)
+		switch pairs {
+		case "XBTUSD":
+			return `{"error":[],"result":{"XXBTZUSD":{"c":["30000.1","1"]}}}`
+		case "XBTUSDX", "XBTUSD,XBTUSDX":
+			return `{"error":["EQuery:Unknown asset pair"]}`
+		}
+		t.Errorf("Unexpected pairs %s", pairs)
+		return `{"error":["EGeneral:Internal error"]}`
+	})
+
+	cache := api.NewPriceCache(time.Hour)
+	prices, errs := cache.fetchEach(context.Background(), []string{"XBTUSD", "XBTUSDX"})
+	if prices["XBTUSD"] != 30000.1 || errs["XBTUSD"] != nil {
+		t.Errorf("A valid pair should not fail along with an invalid one, got %v, %v", prices, errs)
+	}
+	if !hasErrorCode(errs["XBTUSDX"], "EQuery:Unknown asset pair") {
+		t.Errorf("The invalid pair should get the error, got %v", errs["XBTUSDX"])
+	}
+	if len(requested) != 3 {
+		t.Errorf("Expected the batch then each pair alone, got %v", requested)
+	}
+}
+
+func TestPriceCacheHungCall(t *testing.T) {
+	api := newTestAPI(hangFirst("Ticker", func(method string, req *http.Request) string {
+		if method == "AssetPairs" {
+			return testAssetPairs
+		}
+		return `{"error":[],"result":{"XXBTZUSD":{"c":["30000.1","1"]}}}`
+	}))
+	cache := api.NewPriceCache(time.Hour)
+	cache.callTimeout = 20 * time.Millisecond
+
+	if _, err := cache.Get(context.Background(), "XXBTZUSD"); err == nil {
+		t.Fatal("The hung call should time out")
+	}
+	if price, err := cache.Get(context.Background(), "XXBTZUSD"); err != nil || price.Price != 30000.1 {
+		t.Errorf("A new call should follow the hung one, got %+v %v", price, err)
+	}
+}
@@ -0,0 +1,247 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ohlcWindow is the number of candles Kraken returns per interval, which
+// bounds how far back candles of that interval reach
+const ohlcWindow = 720
+
+// ohlcIntervals are the candle intervals Kraken supports, in minutes
+var ohlcIntervals = []int{1, 5, 15, 30, 60, 240, 1440, 10080, 21600}
+
+// maxPriceTradePages bounds the Trades pages read to find the trades of a minute
+const maxPriceTradePages = 5
+
+// PriceMethod tells how a HistoricalPrice was obtained
+type PriceMethod string
+
+// Methods of HistoricalPrice
+const (
+	PriceCandleClose  PriceMethod = "candle_close"  // Close of the one minute candle of the minute
+	PriceNearestTrade PriceMethod = "nearest_trade" // Last trade of the minute, or the first trade after it
+	PriceInterpolated PriceMethod = "interpolated"  // Interpolated between open and close of a coarser candle
+)
+
+// HistoricalPrice is the price of a pair in a given minute
+type HistoricalPrice struct {
+	Pair   string
+	Minute time.Time // Start of the minute the price is for
+	Price  float64
+	Method PriceMethod
+	// Uncertainty is the span of the candle the price comes from, or the
+	// distance between the end of the minute and the trade it comes from
+	Uncertainty time.Duration
+}
+
+// priceKey identifies a cached historical price
+type priceKey struct {
+	pair   string
+	minute int64
+}
+
+// maxPriceHistory bounds the number of prices a priceHistory keeps; the
+// oldest cached are evicted first
+const maxPriceHistory = 100000
+
+// priceHistory caches historical prices. It is safe for concurrent use; a
+// nil priceHistory caches nothing.
+type priceHistory struct {
+	mu     sync.Mutex
+	prices map[priceKey]HistoricalPrice
+	order  []priceKey // Keys of prices in the order they were cached
+}
+
+func (h *priceHistory) get(pair string, minute time.Time) (HistoricalPrice, bool) {
+	if h == nil {
+		return HistoricalPrice{}, false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	price, ok := h.prices[priceKey{pair, minute.Unix()}]
+	return price, ok
+}
+
+func (h *priceHistory) put(prices ...HistoricalPrice) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.prices == nil {
+		h.prices = make(map[priceKey]HistoricalPrice)
+	}
+	for _, price := range prices {
+		key := priceKey{price.Pair, price.Minute.Unix()}
+		if _, ok := h.prices[key]; !ok {
+			h.order = append(h.order, key)
+		}
+		h.prices[key] = price
+	}
+	for len(h.order) > maxPriceHistory {
+		delete(h.prices, h.order[0])
+		h.order = h.order[1:]
+	}
+}
+
+// PriceAt returns the price of pair in the minute of t. Within the reach of
+// one minute candles the close of the minute's candle is used. Further back
+// the last trade of the minute is looked up; if the minute saw no trade the
+// nearer of the next trade and an interpolation within the finest candle
+// still covering t is used. Prices are cached per pair and minute, and every
+// minute candle fetched is cached along the way, so bursts of lookups cost
+// few requests.
+func (api *KrakenAPI) PriceAt(ctx context.Context, pair string, t time.Time) (*HistoricalPrice, error) {
+	minute := t.UTC().Truncate(time.Minute)
+	price, ok := api.history.get(pair, minute)
+	api.stats.cacheLookup("price_history", ok)
+	if ok {
+		return &price, nil
+	}
+	age := time.Since(minute)
+	if age < 0 {
+		return nil, fmt.Errorf("no price for %s in the future", minute.Format(time.RFC3339))
+	}
+
+	if age < (ohlcWindow-1)*time.Minute {
+		price, err := api.minuteClose(ctx, pair, minute)
+		if err != nil || price != nil {
+			return price, err
+		}
+	}
+
+	best, err := api.tradePrice(ctx, pair, minute)
+	if err != nil {
+		return nil, err
+	}
+	if best == nil || best.Uncertainty > 0 {
+		interpolated, err := api.interpolatedPrice(ctx, pair, minute, age)
+		if err != nil {
+			return nil, err
+		}
+		if best == nil || interpolated != nil && interpolated.Uncertainty < best.Uncertainty {
+			best = interpolated
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no price of %s found for %s", pair, minute.Format(time.RFC3339))
+	}
+
+	// A price is final once the minute and the data it comes from are past
+	if !minute.Add(time.Minute + best.Uncertainty).After(time.Now()) {
+		api.history.put(*best)
+	}
+	return best, nil
+}
+
+// minuteClose fetches the recent minute candles of pair, caches the closed
+// ones and returns the one of minute, or nil if Kraken has none for it. The
+// candle still in progress, from Last on, is returned but not cached, since
+// its close may still change.
+func (api *KrakenAPI) minuteClose(ctx context.Context, pair string, minute time.Time) (*HistoricalPrice, error) {
+	resp, err := api.ohlc(ctx, pair, "1", "")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var found *HistoricalPrice
+	prices := make([]HistoricalPrice, 0, len(resp.OHLC))
+	for _, candle := range resp.OHLC {
+		price := HistoricalPrice{
+			Pair: pair, Minute: candle.Time.UTC(), Price: candle.Close, Method: PriceCandleClose, Uncertainty: time.Minute,
+		}
+		if price.Minute.Equal(minute) {
+			found = &price
+		}
+		closed := !candle.Time.Add(time.Minute).After(now)
+		if resp.Last > 0 {
+			closed = float64(candle.Time.Unix()) < resp.Last
+		}
+		if closed {
+			prices = append(prices, price)
+		}
+	}
+	api.history.put(prices...)
+	return found, nil
+}
+
+// tradePrice returns the last trade of minute, or else the first trade after
+// it, or nil if there was none
+func (api *KrakenAPI) tradePrice(ctx context.Context, pair string, minute time.Time) (*HistoricalPrice, error) {
+	end := minute.Add(time.Minute)
+	since := minute.UnixNano()
+
+	var last *TradeInfo
+	for page := 0; page < maxPriceTradePages; page++ {
+		resp, err := api.trades(ctx, pair, since, 0)
+		if err != nil {
+			return nil, err
+		}
+		for i := range resp.Trades {
+			trade := &resp.Trades[i]
+			if trade.Timestamp.Before(end) {
+				last = trade
+				continue
+			}
+			if last == nil {
+				last = trade
+			}
+			return tradeHistoricalPrice(pair, minute, last), nil
+		}
+		if len(resp.Trades) == 0 || resp.Last <= since {
+			break
+		}
+		since = resp.Last
+	}
+
+	if last == nil {
+		return nil, nil
+	}
+	return tradeHistoricalPrice(pair, minute, last), nil
+}
+
+func tradeHistoricalPrice(pair string, minute time.Time, trade *TradeInfo) *HistoricalPrice {
+	uncertainty := trade.Timestamp.Sub(minute.Add(time.Minute))
+	if uncertainty < 0 {
+		uncertainty = 0
+	}
+	return &HistoricalPrice{Pair: pair, Minute: minute, Price: trade.PriceFloat, Method: PriceNearestTrade, Uncertainty: uncertainty}
+}
+
+// interpolatedPrice interpolates the price at the end of minute within the
+// finest candle still covering it, or returns nil if no candle covers it
+func (api *KrakenAPI) interpolatedPrice(ctx context.Context, pair string, minute time.Time, age time.Duration) (*HistoricalPrice, error) {
+	for _, interval := range ohlcIntervals[1:] {
+		span := time.Duration(interval) * time.Minute
+		if age >= (ohlcWindow-1)*span {
+			continue
+		}
+
+		resp, err := api.ohlc(ctx, pair, strconv.Itoa(interval), "")
+		if err != nil {
+			return nil, err
+		}
+		at := minute.Add(time.Minute)
+		for _, candle := range resp.OHLC {
+			if minute.Before(candle.Time) || !minute.Before(candle.Time.Add(span)) {
+				continue
+			}
+			fraction := float64(at.Sub(candle.Time)) / float64(span)
+			return &HistoricalPrice{
+				Pair:        pair,
+				Minute:      minute,
+				Price:       candle.Open + (candle.Close-candle.Open)*fraction,
+				Method:      PriceInterpolated,
+				Uncertainty: span,
+			}, nil
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
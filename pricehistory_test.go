@@ -0,0 +1,145 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// candlesJSON returns an OHLC response with candles of the given interval
+// starting at from, each opening at open+i and closing at open+i+1
+func candlesJSON(from time.Time, interval time.Duration, count int, open float64) string {
+	var candles []string
+	for i := 0; i < count; i++ {
+		start := from.Add(time.Duration(i) * interval).Unix()
+		o, c := open+float64(i), open+float64(i)+1
+		candles = append(candles, fmt.Sprintf(`[%d,"%g","%g","%g","%g","%g","1",1]`, start, o, c, o, c, o))
+	}
+	return `{"error":[],"result":{"XXBTZEUR":[` + strings.Join(candles, ",") + `],"last":0}}`
+}
+
+func TestPriceAtRecent(t *testing.T) {
+	calls := make(map[string]int)
+	now := time.Now().UTC().Truncate(time.Minute)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls[method]++
+		return candlesJSON(now.Add(-10*time.Minute), time.Minute, 10, 100)
+	})
+
+	price, err := api.PriceAt(context.Background(), "XBTEUR", now.Add(-7*time.Minute+30*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Method != PriceCandleClose || price.Price != 104 || price.Uncertainty != time.Minute {
+		t.Errorf("Expected the close of the minute candle, got %+v", price)
+	}
+
+	if _, err := api.PriceAt(context.Background(), "XBTEUR", now.Add(-2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if calls["OHLC"] != 1 {
+		t.Errorf("Every fetched minute candle should be cached, got %d OHLC calls", calls["OHLC"])
+	}
+}
+
+func TestPriceAtTrade(t *testing.T) {
+	minute := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Minute)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method != "Trades" {
+			t.Fatalf("Unexpected call to %s", method)
+		}
+		if since := req.URL.Query().Get("since"); since != fmt.Sprint(minute.UnixNano()) {
+			t.Errorf("Trades should be read from the start of the minute, got %s", since)
+		}
+		at := func(d time.Duration) float64 { return float64(minute.Add(d).UnixNano()) / 1e9 }
+		return fmt.Sprintf(`{"error":[],"result":{"XXBTZEUR":[
+			["100","1",%f,"b","l",""],["101","1",%f,"s","m",""],["105","1",%f,"b","l",""]],"last":"1"}}`,
+			at(10*time.Second), at(50*time.Second), at(2*time.Minute))
+	})
+
+	price, err := api.PriceAt(context.Background(), "XBTEUR", minute.Add(20*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.Method != PriceNearestTrade || price.Price != 101 || price.Uncertainty != 0 {
+		t.Errorf("Expected the last trade of the minute, got %+v", price)
+	}
+}
+
+func TestPriceAtInterpolated(t *testing.T) {
+	// Beyond the reach of minute candles, in a minute without trades
+	start := time.Now().UTC().Add(-48 * time.Hour).Truncate(5 * time.Minute)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "Trades":
+			later := float64(start.Add(3 * time.Hour).Unix())
+			return fmt.Sprintf(`{"error":[],"result":{"XXBTZEUR":[["150","1",%f,"b","l",""]],"last":"1"}}`, later)
+		case "OHLC":
+			if interval := req.URL.Query().Get("interval"); interval != "5" {
+				t.Errorf("Expected the finest interval covering the time, got %s", interval)
+			}
+			return candlesJSON(start, 5*time.Minute, 3, 100)
+		}
+		t.Fatalf("Unexpected call to %s", method)
+		return ""
+	})
+
+	price, err := api.PriceAt(context.Background(), "XBTEUR", start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The end of the second minute lies 2/5 into a candle from 100 to 101
+	if price.Method != PriceInterpolated || price.Uncertainty != 5*time.Minute || price.Price < 100.39 || price.Price > 100.41 {
+		t.Errorf("Expected a price interpolated within the 5 minute candle, got %+v", price)
+	}
+
+	if _, err := api.PriceAt(context.Background(), "XBTEUR", time.Now().Add(time.Hour)); err == nil {
+		t.Errorf("Prices in the future should be rejected")
+	}
+}
+
+func TestPriceAtOpenCandle(t *testing.T) {
+	calls := make(map[string]int)
+	now := time.Now().UTC().Truncate(time.Minute)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls[method]++
+		// The candle of the current minute is in progress
+		return strings.Replace(candlesJSON(now.Add(-2*time.Minute), time.Minute, 3, 100), `"last":0`, fmt.Sprintf(`"last":%d`, now.Unix()), 1)
+	})
+
+	for i := 0; i < 2; i++ {
+		price, err := api.PriceAt(context.Background(), "XBTEUR", now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if price.Price != 103 {
+			t.Errorf("Expected the close of the candle so far, got %+v", price)
+		}
+	}
+	if calls["OHLC"] != 2 {
+		t.Errorf("The candle in progress should not be cached, got %d OHLC calls", calls["OHLC"])
+	}
+	if _, err := api.PriceAt(context.Background(), "XBTEUR", now.Add(-time.Minute)); err != nil || calls["OHLC"] != 2 {
+		t.Errorf("Closed candles should be cached, got %d OHLC calls and %v", calls["OHLC"], err)
+	}
+}
+
+func TestPriceHistoryBounded(t *testing.T) {
+	var h priceHistory
+	start := time.Unix(0, 0)
+	for i := 0; i < maxPriceHistory+10; i++ {
+		h.put(HistoricalPrice{Pair: "XBTEUR", Minute: start.Add(time.Duration(i) * time.Minute)})
+	}
+	if len(h.prices) != maxPriceHistory || len(h.order) != maxPriceHistory {
+		t.Errorf("Expected %d cached prices, got %d", maxPriceHistory, len(h.prices))
+	}
+	if _, ok := h.get("XBTEUR", start); ok {
+		t.Errorf("The oldest price should be evicted")
+	}
+	if _, ok := h.get("XBTEUR", start.Add((maxPriceHistory+9)*time.Minute)); !ok {
+		t.Errorf("The newest price should be kept")
+	}
+}
@@ -0,0 +1,165 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxConversionHops bounds the number of pairs a price conversion may chain
+const maxConversionHops = 3
+
+// ConversionStep is one pair of a conversion path
+type ConversionStep struct {
+	Pair     string // Canonical pair name, e.g. "XLTCXXBT"
+	From, To string // Assets converted from and to, as named in the pair
+	Inverted bool   // Whether From is the quote of the pair, so its price is divided
+}
+
+// ResolvedPrice is the price of one asset in another, possibly across several pairs
+type ResolvedPrice struct {
+	From, To  string
+	Price     float64          // Units of To per unit of From, from the mid prices along Path
+	Path      []ConversionStep // Pairs the price was derived from
+	SpreadBps float64          // Sum of the spreads along Path, in basis points
+	AsOf      time.Time        // When the tickers were fetched
+}
+
+// Confidence returns a score in (0, 1] that falls as the spreads along the
+// path widen: 1 for no spread, 0.5 at a combined spread of 100 bps
+func (p ResolvedPrice) Confidence() float64 {
+	return 1 / (1 + p.SpreadBps/100)
+}
+
+// PriceResolver finds prices between assets that may not share a pair,
+// e.g. LTC in EUR through LTC/XBT and XBT/EUR
+type PriceResolver struct {
+	pairs   AssetPairsResponse
+	tickers TickerResponse
+	asOf    time.Time
+}
+
+// NewPriceResolver returns a resolver over the given pairs and tickers
+// fetched at asOf. Tickers may be nil when only Path is needed.
+func NewPriceResolver(pairs AssetPairsResponse, tickers TickerResponse, asOf time.Time) *PriceResolver {
+	return &PriceResolver{pairs: pairs, tickers: tickers, asOf: asOf}
+}
+
+// sameAsset reports whether Kraken's asset code, e.g. "XXBT" or "ZEUR",
+// names the given asset, e.g. "XBT" or "EUR"
+func sameAsset(code, asset string) bool {
+	return AssetCodes{}.Same(code, asset)
+}
+
+// Path returns the shortest chain of pairs converting from into to, using at
+// most three pairs. Among paths of equal length the pair names decide, so the
+// result is deterministic.
+func (r *PriceResolver) Path(from, to string) ([]ConversionStep, error) {
+	names := make([]string, 0, len(r.pairs))
+	for name, info := range r.pairs {
+		// Dark pools share their assets with the regular pair
+		if !strings.HasSuffix(name, ".d") && info.Base != "" && info.Quote != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	type node struct {
+		asset string
+		path  []ConversionStep
+	}
+	queue := []node{{asset: from}}
+	visited := map[string]bool{from: true}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if len(current.path) >= maxConversionHops {
+			continue
+		}
+
+		for _, name := range names {
+			info := r.pairs[name]
+			var step ConversionStep
+			switch {
+			case sameAsset(info.Base, current.asset):
+				step = ConversionStep{Pair: name, From: info.Base, To: info.Quote}
+			case sameAsset(info.Quote, current.asset):
+				step = ConversionStep{Pair: name, From: info.Quote, To: info.Base, Inverted: true}
+			default:
+				continue
+			}
+			if visited[step.To] {
+				continue
+			}
+			path := append(append([]ConversionStep(nil), current.path...), step)
+			if sameAsset(step.To, to) {
+				return path, nil
+			}
+			visited[step.To] = true
+			queue = append(queue, node{asset: step.To, path: path})
+		}
+	}
+	return nil, fmt.Errorf("no conversion from %s to %s within %d pairs", from, to, maxConversionHops)
+}
+
+// Resolve returns the price of from in to from the mid prices of the tickers
+func (r *PriceResolver) Resolve(from, to string) (*ResolvedPrice, error) {
+	result := &ResolvedPrice{From: from, To: to, Price: 1, AsOf: r.asOf}
+	if sameAsset(from, to) || sameAsset(to, from) {
+		return result, nil
+	}
+
+	path, err := r.Path(from, to)
+	if err != nil {
+		return nil, err
+	}
+	result.Path = path
+
+	for _, step := range path {
+		ticker, ok := r.tickers[step.Pair]
+		if !ok || len(ticker.Bid) == 0 || len(ticker.Ask) == 0 {
+			return nil, fmt.Errorf("no ticker for %s", step.Pair)
+		}
+		bid, errBid := strconv.ParseFloat(ticker.Bid[0], 64)
+		ask, errAsk := strconv.ParseFloat(ticker.Ask[0], 64)
+		if errBid != nil || errAsk != nil || bid <= 0 || ask <= 0 {
+			return nil, fmt.Errorf("invalid ticker for %s", step.Pair)
+		}
+
+		mid := (bid + ask) / 2
+		result.SpreadBps += (ask - bid) / mid * 10000
+		if step.Inverted {
+			result.Price /= mid
+		} else {
+			result.Price *= mid
+		}
+	}
+	return result, nil
+}
+
+// ResolvePrice returns the current price of from in to, converting across
+// up to three pairs when the assets do not share one
+func (api *KrakenAPI) ResolvePrice(ctx context.Context, from, to string) (*ResolvedPrice, error) {
+	pairs, err := api.assetPairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path, err := NewPriceResolver(*pairs, nil, time.Time{}).Path(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(path))
+	for i, step := range path {
+		names[i] = step.Pair
+	}
+	asOf := time.Now()
+	tickers, err := api.TickerWithContext(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	return NewPriceResolver(*pairs, *tickers, asOf).Resolve(from, to)
+}
@@ -0,0 +1,55 @@
+package krakenapi
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testPricingPairs = `{"error":[],"result":{
+	"XLTCXXBT":{"altname":"LTCXBT","base":"XLTC","quote":"XXBT"},
+	"XXBTZEUR":{"altname":"XBTEUR","base":"XXBT","quote":"ZEUR"},
+	"XXBTZEUR.d":{"altname":"XBTEUR.d","base":"XXBT","quote":"ZEUR"},
+	"ZEURZUSD":{"altname":"EURUSD","base":"ZEUR","quote":"ZUSD"}}}`
+
+func TestResolvePrice(t *testing.T) {
+	var tickerPairs string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method == "AssetPairs" {
+			return testPricingPairs
+		}
+		tickerPairs = req.URL.Query().Get("pair")
+		return `{"error":[],"result":{
+			"XLTCXXBT":{"a":["0.0021","1","1"],"b":["0.0019","1","1"]},
+			"XXBTZEUR":{"a":["30010","1","1"],"b":["29990","1","1"]},
+			"ZEURZUSD":{"a":["1.11","1","1"],"b":["1.09","1","1"]}}}`
+	})
+
+	price, err := api.ResolvePrice(context.Background(), "LTC", "EUR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tickerPairs != "XLTCXXBT,XXBTZEUR" {
+		t.Errorf("Only the pairs of the path should be fetched, got %s", tickerPairs)
+	}
+	if math.Abs(price.Price-60) > 1e-9 || len(price.Path) != 2 {
+		t.Errorf("Expected 0.002 * 30000 over two pairs, got %+v", price)
+	}
+
+	price, err = api.ResolvePrice(context.Background(), "USD", "LTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(price.Price-1/(1.1*30000*0.002)) > 1e-12 || !price.Path[0].Inverted {
+		t.Errorf("Expected the inverse of LTC in USD, got %+v", price)
+	}
+	if price.Confidence() >= 1 || price.Confidence() <= 0 {
+		t.Errorf("Spreads along the path should lower the confidence, got %v", price.Confidence())
+	}
+
+	if _, err := api.ResolvePrice(context.Background(), "LTC", "JPY"); err == nil || !strings.Contains(err.Error(), "no conversion") {
+		t.Errorf("Unknown assets should not resolve, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package krakenapi
+
+import (
+	"context"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sergey-lipin/kraken-go-api-client/krakentest"
+)
+
+// raceCallers is the number of goroutines calling every method at once
+const raceCallers = 4
+
+// raceArgs returns arguments for the parameters of method from skip on,
+// chosen to get past argument validation where possible
+func raceArgs(ctx context.Context, api *KrakenAPI, method reflect.Type, skip int) []reflect.Value {
+	args := make([]reflect.Value, 0, method.NumIn())
+	for i := skip; i < method.NumIn(); i++ {
+		in := method.In(i)
+		var arg interface{}
+		switch in {
+		case reflect.TypeOf((*context.Context)(nil)).Elem():
+			arg = ctx
+		case reflect.TypeOf(api):
+			arg = api
+		case reflect.TypeOf(time.Duration(0)):
+			arg = 10 * time.Millisecond
+		case reflect.TypeOf((*big.Float)(nil)):
+			arg = big.NewFloat(1)
+		case reflect.TypeOf((*io.Reader)(nil)).Elem():
+			arg = strings.NewReader("{}")
+		case reflect.TypeOf((*io.Writer)(nil)).Elem():
+			arg = io.Discard
+		}
+		if arg != nil {
+			args = append(args, reflect.ValueOf(arg))
+			continue
+		}
+
+		switch in.Kind() {
+		case reflect.String:
+			args = append(args, reflect.ValueOf("XXBTZUSD").Convert(in))
+		case reflect.Int, reflect.Int64:
+			args = append(args, reflect.ValueOf(10).Convert(in))
+		case reflect.Map:
+			args = append(args, reflect.MakeMap(in))
+		case reflect.Func:
+			args = append(args, reflect.MakeFunc(in, func([]reflect.Value) []reflect.Value {
+				results := make([]reflect.Value, in.NumOut())
+				for i := range results {
+					results[i] = reflect.Zero(in.Out(i))
+				}
+				return results
+			}))
+		default:
+			args = append(args, reflect.Zero(in))
+		}
+	}
+	return args
+}
+
+// callConcurrently calls every exported method of v, except the ones skip
+// returns true for, from raceCallers goroutines at once
+func callConcurrently(t *testing.T, ctx context.Context, api *KrakenAPI, v interface{}, skip func(name string) bool) {
+	val := reflect.ValueOf(v)
+	typ := val.Type()
+
+	var wg sync.WaitGroup
+	for i := 0; i < typ.NumMethod(); i++ {
+		method := typ.Method(i)
+		if skip(method.Name) {
+			continue
+		}
+		for c := 0; c < raceCallers; c++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				args := append([]reflect.Value{val}, raceArgs(ctx, api, method.Type, 1)...)
+				call := method.Func.Call
+				if method.Type.IsVariadic() {
+					call = method.Func.CallSlice
+				}
+				if p := callSafely(func() { call(args) }); p != nil {
+					t.Errorf("%s.%s panics: %v", typ.Elem().Name(), method.Name, p)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+// newRaceAPI returns a client answered by the fixture server with every
+// optional feature sharing state between requests enabled
+func newRaceAPI(hooks Hooks) *KrakenAPI {
+	server := krakentest.NewServer()
+	return NewWithClient("key", "c2VjcmV0", server.Client()).
+		WithHooks(hooks).
+		WithMetadataCache(NewMetadataCache(time.Minute)).
+		WithRateLimiter(NewRateLimiter(1000, 1000)).
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, Retryable: IsRetryable}).
+		WithStatusGate(NewStatusGate()).
+		WithMaxSkew(time.Minute).
+		WithBookValidation(DefaultMaxQuoteDeviation)
+}
+
+// A configured KrakenAPI is safe for concurrent use. Run with -race.
+func TestKrakenAPIConcurrentUse(t *testing.T) {
+	var requests int64
+	api := newRaceAPI(Hooks{
+		OnRequest:       func(RequestEvent) { atomic.AddInt64(&requests, 1) },
+		OnRateLimitWait: func(RateLimitEvent) {},
+		OnCacheLookup:   func(CacheEvent) {},
+		OnWarning:       func(WarningEvent) {},
+	})
+	// FlattenAccount waits for the fixture orders to close until ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The With options configure the client before it is shared
+	callConcurrently(t, ctx, api, api, func(name string) bool { return strings.HasPrefix(name, "With") })
+	if atomic.LoadInt64(&requests) == 0 {
+		t.Errorf("Expected requests to reach the fixture server")
+	}
+}
+
+// concurrentValues builds the helper types documented as safe for
+// concurrent use. Types sharing mutable state must be added here.
+var concurrentValues = map[string]func(ctx context.Context, api *KrakenAPI) interface{}{
+	"DeadMansSwitch": func(ctx context.Context, api *KrakenAPI) interface{} {
+		return api.KeepDeadMansSwitch(ctx, time.Minute, 10*time.Millisecond)
+	},
+	"MetadataCache": func(ctx context.Context, api *KrakenAPI) interface{} { return NewMetadataCache(time.Minute) },
+	"OpenOrderCache": func(ctx context.Context, api *KrakenAPI) interface{} {
+		return NewOpenOrderCache(time.Minute)
+	},
+	"PriceCache":    func(ctx context.Context, api *KrakenAPI) interface{} { return api.NewPriceCache(time.Second) },
+	"RateLimiter":   func(ctx context.Context, api *KrakenAPI) interface{} { return NewRateLimiter(1000, 1000) },
+	"StatusGate":    func(ctx context.Context, api *KrakenAPI) interface{} { return NewStatusGate() },
+	"WSTokenSource": func(ctx context.Context, api *KrakenAPI) interface{} { return api.NewWSTokenSource(time.Minute) },
+	"BestQuoteWatcher": func(ctx context.Context, api *KrakenAPI) interface{} {
+		return api.WatchBestQuotes(ctx, []string{"XBTUSD"}, 10*time.Millisecond, BestQuoteOptions{})
+	},
+	"StatusWatcher": func(ctx context.Context, api *KrakenAPI) interface{} {
+		return api.WatchStatus(ctx, 10*time.Millisecond)
+	},
+	"Bracket": func(ctx context.Context, api *KrakenAPI) interface{} {
+		b, _ := api.PlaceBracket(ctx, BracketSpec{Pair: "XBTUSD", Direction: "buy", OrderType: OTMarket, Volume: "1", StopLoss: "1", PollInterval: time.Millisecond})
+		return b
+	},
+	"RolloverTracker": func(ctx context.Context, api *KrakenAPI) interface{} { return api.NewRolloverTracker(0.01) },
+	"FrameRecorder":   func(ctx context.Context, api *KrakenAPI) interface{} { return NewFrameRecorder(io.Discard) },
+	"TradeFollower": func(ctx context.Context, api *KrakenAPI) interface{} {
+		f, _ := api.BackfillAndFollow(ctx, "XBTUSD", time.Now(), make(fakeLiveTrades))
+		return f
+	},
+	"ClientSet": func(ctx context.Context, api *KrakenAPI) interface{} {
+		set, _ := NewClientSet(krakentest.NewServer().Client(), Hooks{}, Credentials{Name: "a", Key: "key", Secret: "c2VjcmV0"})
+		return set
+	},
+}
+
+func TestHelpersConcurrentUse(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for name, build := range concurrentValues {
+		api := newRaceAPI(Hooks{})
+		v := build(ctx, api)
+		t.Run(name, func(t *testing.T) {
+			callConcurrently(t, ctx, api, v, func(string) bool { return false })
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package krakenapi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter mirrors Kraken's call counter for private endpoints: every call
+// adds its cost to a counter which may not exceed a ceiling and decays over
+// time. Callers waiting for budget are served strictly in FIFO order, with a
+// separate priority lane that is always served first. It is safe for
+// concurrent use.
+type RateLimiter struct {
+	max   float64 // Counter ceiling
+	decay float64 // Counter decrease per second
+
+	mu       sync.Mutex
+	counter  float64
+	updated  time.Time
+	priority []*rateWaiter
+	normal   []*rateWaiter
+	timer    *time.Timer
+}
+
+type rateWaiter struct {
+	cost    float64
+	granted chan struct{}
+}
+
+// NewRateLimiter creates a limiter with the given counter ceiling and decay
+// per second, e.g. NewRateLimiter(15, 0.33) for a Starter account
+func NewRateLimiter(max, decayPerSecond float64) *RateLimiter {
+	return &RateLimiter{max: max, decay: decayPerSecond, updated: time.Now()}
+}
+
+// QueueDepth returns the number of callers waiting for budget
+func (l *RateLimiter) QueueDepth() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.priority) + len(l.normal)
+}
+
+// Counter returns the current value of the call counter and its ceiling
+func (l *RateLimiter) Counter() (counter, max float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	counter = l.counter - time.Since(l.updated).Seconds()*l.decay
+	if counter < 0 {
+		counter = 0
+	}
+	return counter, l.max
+}
+
+// Wait blocks until cost fits into the budget and consumes it. Priority
+// callers jump ahead of all normal callers. If ctx is done first, Wait
+// returns ctx.Err() without consuming any budget.
+func (l *RateLimiter) Wait(ctx context.Context, cost float64, priority bool) error {
+	if cost <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	w := &rateWaiter{cost: cost, granted: make(chan struct{})}
+	if priority {
+		l.priority = append(l.priority, w)
+	} else {
+		l.normal = append(l.normal, w)
+	}
+	l.dispatch()
+	l.mu.Unlock()
+
+	select {
+	case <-w.granted:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-w.granted:
+		// Granted while giving up, hand the budget back
+		l.counter -= w.cost
+	default:
+		l.priority = removeWaiter(l.priority, w)
+		l.normal = removeWaiter(l.normal, w)
+	}
+	l.dispatch()
+	return ctx.Err()
+}
+
+// dispatch grants budget to the waiters at the head of the queues and
+// schedules itself for when the next waiter fits. Must hold l.mu.
+func (l *RateLimiter) dispatch() {
+	now := time.Now()
+	l.counter -= now.Sub(l.updated).Seconds() * l.decay
+	if l.counter < 0 {
+		l.counter = 0
+	}
+	l.updated = now
+
+	for {
+		queue := &l.normal
+		if len(l.priority) > 0 {
+			queue = &l.priority
+		}
+		if len(*queue) == 0 {
+			return
+		}
+
+		head := (*queue)[0]
+		// A cost above the ceiling can never fit, let it through on an empty counter
+		if l.counter+head.cost > l.max && l.counter > 0 {
+			if l.decay > 0 {
+				l.schedule((l.counter + head.cost - l.max) / l.decay)
+			}
+			return
+		}
+		l.counter += head.cost
+		*queue = (*queue)[1:]
+		close(head.granted)
+	}
+}
+
+// schedule runs dispatch after seconds. Must hold l.mu.
+func (l *RateLimiter) schedule(seconds float64) {
+	delay := time.Duration(seconds * float64(time.Second))
+	if l.timer != nil {
+		l.timer.Stop()
+	}
+	l.timer = time.AfterFunc(delay, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.dispatch()
+	})
+}
+
+func removeWaiter(queue []*rateWaiter, w *rateWaiter) []*rateWaiter {
+	for i, queued := range queue {
+		if queued == w {
+			return append(queue[:i], queue[i+1:]...)
+		}
+	}
+	return queue
+}
+
+type priorityKey struct{}
+
+// WithPriority marks the requests made with ctx as high priority, letting
+// them jump the rate limiter queue, e.g. for requests needed to cancel orders
+func WithPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, priorityKey{}, true)
+}
+
+// isPriority reports whether ctx was marked with WithPriority
+func isPriority(ctx context.Context) bool {
+	priority, _ := ctx.Value(priorityKey{}).(bool)
+	return priority
+}
+
+// WithRateLimiter makes the KrakenAPI wait for budget of limiter before every
+// private request. A limiter must not be shared between API keys.
+func (api *KrakenAPI) WithRateLimiter(limiter *RateLimiter) *KrakenAPI {
+	api.limiter = limiter
+	api.tierLimiter = false
+	return api
+}
+
+// waitForBudget blocks until the private method fits into the rate limit
+func (api *KrakenAPI) waitForBudget(ctx context.Context, method string) error {
+	endpoint, ok := LookupEndpoint(method)
+	if !ok {
+		endpoint.Cost = 1
+	}
+	api.observeTierUsage(method, endpoint.Cost)
+	if api.limiter == nil {
+		return nil
+	}
+
+	start := time.Now()
+	depth := api.limiter.QueueDepth()
+	err := api.limiter.Wait(ctx, endpoint.Cost, isPriority(ctx))
+	if api.hooks.OnRateLimitWait != nil && endpoint.Cost > 0 {
+		api.hooks.OnRateLimitWait(RateLimitEvent{
+			Method:     method,
+			QueueDepth: depth,
+			Waited:     time.Since(start),
+			Err:        err,
+		})
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package krakenapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForQueue blocks until the limiter has depth waiters
+func waitForQueue(t *testing.T, l *RateLimiter, depth int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for l.QueueDepth() != depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("Queue depth should reach %d, got %d", depth, l.QueueDepth())
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+}
+
+func TestRateLimiterFIFO(t *testing.T) {
+	l := NewRateLimiter(1, 2000)
+	if err := l.Wait(context.Background(), 1, false); err != nil {
+		t.Fatal(err)
+	}
+	// Freeze the counter so callers queue up deterministically
+	l.mu.Lock()
+	l.decay = 0
+	l.mu.Unlock()
+
+	const callers = 100
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := l.Wait(context.Background(), 1, false); err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		}(i)
+		waitForQueue(t, l, i+1)
+	}
+
+	l.mu.Lock()
+	l.decay = 2000
+	l.dispatch()
+	l.mu.Unlock()
+	wg.Wait()
+
+	for i, caller := range order {
+		if caller != i {
+			t.Fatalf("Callers should be served in FIFO order, got %v", order)
+		}
+	}
+}
+
+func TestRateLimiterCancelledWaiter(t *testing.T) {
+	l := NewRateLimiter(1, 0.001)
+	if err := l.Wait(context.Background(), 1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Wait(ctx, 1, false) }()
+	waitForQueue(t, l, 1)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Errorf("Wait() should return the context error, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Cancelled waiter should exit promptly")
+	}
+	if l.QueueDepth() != 0 {
+		t.Errorf("Cancelled waiter should leave the queue, depth %d", l.QueueDepth())
+	}
+	if l.counter > 1 {
+		t.Errorf("Cancelled waiter should not consume budget, counter %f", l.counter)
+	}
+}
+
+func TestRateLimiterPriority(t *testing.T) {
+	l := NewRateLimiter(1, 0)
+	if err := l.Wait(context.Background(), 1, false); err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan string, 2)
+	go func() {
+		l.Wait(context.Background(), 1, false)
+		served <- "normal"
+	}()
+	waitForQueue(t, l, 1)
+	go func() {
+		l.Wait(context.Background(), 1, true)
+		served <- "priority"
+	}()
+	waitForQueue(t, l, 2)
+
+	l.mu.Lock()
+	l.decay = 1000
+	l.dispatch()
+	l.mu.Unlock()
+
+	if first := <-served; first != "priority" {
+		t.Errorf("Priority caller should jump the queue, got %s first", first)
+	}
+	<-served
+}
+
+func TestRateLimiterStarvation(t *testing.T) {
+	l := NewRateLimiter(3, 1000)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A large request must not be starved by a stream of small ones
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Wait(ctx, 1, false); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	if err := l.Wait(ctx, 3, false); err != nil {
+		t.Errorf("Large request should eventually be served, got %s", err)
+	}
+	wg.Wait()
+}
+
+func TestClientRateLimitHook(t *testing.T) {
+	var events []RateLimitEvent
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":[],"result":{}}`
+	}).WithRateLimiter(NewRateLimiter(20, 1)).WithHooks(Hooks{
+		OnRateLimitWait: func(e RateLimitEvent) { events = append(events, e) },
+	})
+
+	api.Balance()
+	api.CancelOrder("O1")
+	if len(events) != 1 || events[0].Method != "Balance" {
+		t.Errorf("Only counted calls should wait for budget, got %+v", events)
+	}
+}
@@ -0,0 +1,88 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Kraken error codes stating that a request was rejected before being executed
+const (
+	ErrCodeInvalidNonce      = "EAPI:Invalid nonce"
+	ErrCodeRateLimitExceeded = "EAPI:Rate limit exceeded"
+	ErrCodeServiceBusy       = "EService:Busy"
+	ErrCodeServiceUnavail    = "EService:Unavailable"
+)
+
+// RetryPolicy controls how failed requests are repeated
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, values below 2 disable retries
+	MaxAttempts int
+	// Backoff is the delay before the first retry, doubled after every attempt
+	Backoff time.Duration
+	// Retryable decides whether a failed request to endpoint may be repeated
+	Retryable func(endpoint Endpoint, err error) bool
+}
+
+// DefaultRetryPolicy returns a policy retrying up to three times. It repeats
+// idempotent endpoints on any transient failure, but repeats other endpoints
+// only when Kraken explicitly rejected the request before executing it.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     500 * time.Millisecond,
+		Retryable:   IsRetryable,
+	}
+}
+
+// IsRetryable is the retry decision of DefaultRetryPolicy. It is exported so
+// middleware written around the client can share the same classification.
+func IsRetryable(endpoint Endpoint, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.HasCode(ErrCodeInvalidNonce) || apiErr.HasCode(ErrCodeRateLimitExceeded) {
+			return true
+		}
+		if apiErr.HasCode(ErrCodeServiceBusy) || apiErr.HasCode(ErrCodeServiceUnavail) {
+			return endpoint.Idempotency != NotIdempotent
+		}
+		return false
+	}
+	// Transport failures leave the outcome unknown
+	return endpoint.Idempotency != NotIdempotent
+}
+
+// WithRetryPolicy sets the policy used to repeat failed requests
+func (api *KrakenAPI) WithRetryPolicy(policy RetryPolicy) *KrakenAPI {
+	api.retry = policy
+	return api
+}
+
+// withRetry runs attempt until it succeeds or the retry policy gives up
+func (api *KrakenAPI) withRetry(ctx context.Context, method string, attempt func() (interface{}, error)) (interface{}, error) {
+	endpoint, ok := LookupEndpoint(method)
+	if !ok {
+		endpoint = Endpoint{Name: method}
+	}
+	retryable := api.retry.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	backoff := api.retry.Backoff
+	for i := 1; ; i++ {
+		result, err := attempt()
+		if err == nil || i >= api.retry.MaxAttempts || !retryable(endpoint, err) {
+			return result, err
+		}
+		api.stats.retry(method)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w (giving up retrying after: %s)", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,172 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RewardPeriod is the calendar period rewards are grouped by
+type RewardPeriod int
+
+// Reward periods
+const (
+	RewardPeriodDay RewardPeriod = iota
+	RewardPeriodMonth
+)
+
+// RewardOptions configures RewardHistory
+type RewardOptions struct {
+	Quote  string       // Currency the rewards are valued in, e.g. "EUR". Empty skips valuation
+	Period RewardPeriod // Calendar period rewards are grouped by (UTC)
+}
+
+// RewardTotal is the sum of the rewards of one asset within one period
+type RewardTotal struct {
+	Asset    string    // Ledger asset the rewards were paid in, e.g. "DOT.S"
+	Period   time.Time // Start of the period (UTC)
+	Count    int       // Number of reward entries
+	Amount   big.Float // Sum of the rewards in asset terms
+	Value    big.Float // Sum of the rewards valued at the daily close of their day, in quote terms
+	Unvalued int       // Number of rewards no daily close was available for; they are missing from Value
+}
+
+// RewardHistoryResponse holds the rewards grouped per asset and period
+type RewardHistoryResponse struct {
+	Quote  string
+	Totals []RewardTotal // Sorted by asset, then period
+}
+
+// RewardHistory sums the staking and earn rewards of asset between from and
+// to. Staked variants of the asset (e.g. "DOT.S") are included. When a quote
+// currency is given, every reward is valued at the close of the daily candle
+// covering its timestamp, using one OHLC request per asset. The asset may be
+// given as asset code or altname, e.g. "XXBT" or "XBT".
+func (api *KrakenAPI) RewardHistory(ctx context.Context, asset string, from, to time.Time, opts RewardOptions) (*RewardHistoryResponse, error) {
+	asset = NormalizeAssetCode(asset)
+	entries, err := api.allLedgers(ctx, map[string]string{
+		"asset": strings.Join([]string{asset, asset + ".S", asset + ".M", asset + ".P", asset + ".F"}, ","),
+		"start": fmt.Sprintf("%d", from.Unix()),
+		"end":   fmt.Sprintf("%d", to.Unix()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var closes map[int64]float64
+	if opts.Quote != "" {
+		closes, err = api.dailyCloses(ctx, asset, opts.Quote, from)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	type key struct {
+		asset  string
+		period int64
+	}
+	totals := make(map[key]*RewardTotal)
+	for _, entry := range entries {
+		if !isReward(entry) {
+			continue
+		}
+
+		t := entry.Timestamp().UTC()
+		period := rewardPeriodStart(t, opts.Period)
+		k := key{entry.Asset, period.Unix()}
+		total, ok := totals[k]
+		if !ok {
+			total = &RewardTotal{Asset: entry.Asset, Period: period}
+			totals[k] = total
+		}
+
+		total.Count++
+		total.Amount.Add(&total.Amount, &entry.Amount)
+		if opts.Quote == "" {
+			continue
+		}
+		day := rewardPeriodStart(t, RewardPeriodDay).Unix()
+		if price, found := closes[day]; found {
+			value := new(big.Float).Mul(&entry.Amount, big.NewFloat(price))
+			total.Value.Add(&total.Value, value)
+		} else {
+			total.Unvalued++
+		}
+	}
+
+	result := &RewardHistoryResponse{Quote: opts.Quote}
+	for _, total := range totals {
+		result.Totals = append(result.Totals, *total)
+	}
+	sort.Slice(result.Totals, func(i, j int) bool {
+		a, b := result.Totals[i], result.Totals[j]
+		if a.Asset != b.Asset {
+			return a.Asset < b.Asset
+		}
+		return a.Period.Before(b.Period)
+	})
+
+	return result, nil
+}
+
+// isReward reports whether the ledger entry is a staking or earn reward
+func isReward(entry LedgerInfo) bool {
+	switch entry.Type {
+	case LedgerTypeStaking:
+		return entry.Subtype == "" || entry.Subtype == "reward"
+	case LedgerTypeEarn:
+		return entry.Subtype == "reward"
+	}
+	return false
+}
+
+// rewardPeriodStart truncates t to the start of its period in UTC
+func rewardPeriodStart(t time.Time, period RewardPeriod) time.Time {
+	t = t.UTC()
+	if period == RewardPeriodMonth {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dailyCloses returns the daily closes of base in quote since from, indexed
+// by the unix time of the start of the day. Assets without a common pair are
+// converted through the path of a PriceResolver.
+func (api *KrakenAPI) dailyCloses(ctx context.Context, base, quote string, from time.Time) (map[int64]float64, error) {
+	pairs, err := api.assetPairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path, err := NewPriceResolver(*pairs, nil, time.Time{}).Path(base, quote)
+	if err != nil {
+		return nil, fmt.Errorf("no asset pair to value %s in %s: %s", base, quote, err)
+	}
+
+	since := rewardPeriodStart(from, RewardPeriodDay).Add(-time.Second)
+	var closes map[int64]float64
+	for _, step := range path {
+		resp, err := api.ohlc(ctx, step.Pair, "1440", strconv.FormatInt(since.Unix(), 10))
+		if err != nil {
+			return nil, err
+		}
+
+		stepCloses := make(map[int64]float64, len(resp.OHLC))
+		for _, candle := range resp.OHLC {
+			price := candle.Close
+			if step.Inverted && price != 0 {
+				price = 1 / price
+			}
+			if closes == nil {
+				stepCloses[candle.Time.Unix()] = price
+			} else if previous, ok := closes[candle.Time.Unix()]; ok {
+				stepCloses[candle.Time.Unix()] = previous * price
+			}
+		}
+		closes = stepCloses
+	}
+	return closes, nil
+}
@@ -0,0 +1,59 @@
+package krakenapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRewardHistory(t *testing.T) {
+	calls := make(map[string]int)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls[method]++
+		switch method {
+		case "Ledgers":
+			return `{"error":[],"result":{"count":4,"ledger":{
+				"L1":{"type":"staking","asset":"DOT.S","time":1688169600.5,"amount":"0.5","fee":"0","balance":"1"},
+				"L2":{"type":"staking","asset":"DOT.S","time":1688256000.1,"amount":"0.25","fee":"0","balance":"1"},
+				"L3":{"type":"earn","subtype":"reward","asset":"DOT","time":1690848000,"amount":"1","fee":"0","balance":"1"},
+				"L4":{"type":"trade","asset":"DOT","time":1688169600,"amount":"10","fee":"0","balance":"1"}}}}`
+		case "AssetPairs":
+			return `{"error":[],"result":{"DOTEUR":{"altname":"DOTEUR","wsname":"DOT/EUR","base":"DOT","quote":"ZEUR"}}}`
+		case "OHLC":
+			return `{"error":[],"result":{"DOTEUR":[
+				[1688169600,"5.0","5.0","5.0","4.0","5.0","1.0",1],
+				[1688256000,"5.0","5.0","5.0","8.0","5.0","1.0",1]],"last":1688256000}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	from := time.Date(2023, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 8, 31, 0, 0, 0, 0, time.UTC)
+	resp, err := api.RewardHistory(context.Background(), "DOT", from, to, RewardOptions{Quote: "EUR", Period: RewardPeriodMonth})
+	if err != nil {
+		t.Fatalf("RewardHistory() should not return an error, got %s", err)
+	}
+
+	if calls["OHLC"] != 1 {
+		t.Errorf("RewardHistory() should fetch OHLC once per asset, got %d", calls["OHLC"])
+	}
+	if len(resp.Totals) != 2 {
+		t.Fatalf("Expected totals for DOT (August) and DOT.S (July), got %+v", resp.Totals)
+	}
+
+	staked := resp.Totals[1]
+	if staked.Asset != "DOT.S" || staked.Count != 2 || !staked.Period.Equal(from) {
+		t.Errorf("Unexpected DOT.S total %+v", staked)
+	}
+	if amount, _ := staked.Amount.Float64(); amount != 0.75 {
+		t.Errorf("Expected 0.75 DOT.S rewards, got %f", amount)
+	}
+	if value, _ := staked.Value.Float64(); value != 0.5*4+0.25*8 {
+		t.Errorf("Rewards should be valued at their day's close, got %f", value)
+	}
+	if resp.Totals[0].Unvalued != 1 {
+		t.Errorf("Rewards without a candle should be counted as unvalued, got %+v", resp.Totals[0])
+	}
+}
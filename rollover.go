@@ -0,0 +1,257 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rolloverGrace is how far from its predicted time a rollover charge may be
+// booked and still match the prediction
+const rolloverGrace = 15 * time.Minute
+
+// rolloverTermsPattern matches the terms of a position, e.g. "0.0100% per 4 hours"
+var rolloverTermsPattern = regexp.MustCompile(`^\s*([0-9]*\.?[0-9]+)\s*%\s*per\s+(?:([0-9]+)\s+)?(hour|hours|day|days)\s*$`)
+
+// RolloverTerms is the rollover fee of a margin position
+type RolloverTerms struct {
+	Rate   float64       // Fraction of the position cost charged per period, e.g. 0.0001
+	Period time.Duration // Time between two charges
+}
+
+// ParseRolloverTerms parses the terms of an open position, e.g. "0.0100% per 4 hours"
+func ParseRolloverTerms(terms string) (RolloverTerms, error) {
+	m := rolloverTermsPattern.FindStringSubmatch(terms)
+	if m == nil {
+		return RolloverTerms{}, fmt.Errorf("unexpected rollover terms %q", terms)
+	}
+	percent, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return RolloverTerms{}, fmt.Errorf("unexpected rollover terms %q", terms)
+	}
+	count := 1
+	if m[2] != "" {
+		count, _ = strconv.Atoi(m[2])
+	}
+	unit := time.Hour
+	if m[3] == "day" || m[3] == "days" {
+		unit = 24 * time.Hour
+	}
+	if count == 0 {
+		return RolloverTerms{}, fmt.Errorf("unexpected rollover terms %q", terms)
+	}
+	return RolloverTerms{Rate: percent / 100, Period: time.Duration(count) * unit}, nil
+}
+
+// RolloverPrediction is the next expected rollover charge of a position
+type RolloverPrediction struct {
+	PositionID string
+	OrderTxID  string
+	Pair       string
+	Time       time.Time
+	Amount     float64 // In the quote currency of the pair, like the position cost
+}
+
+// Kinds of RolloverDiscrepancy
+const (
+	RolloverMissing    = "missing"    // No charge was booked around the predicted time
+	RolloverMismatch   = "mismatch"   // The charge differs from the predicted amount
+	RolloverUnexpected = "unexpected" // A charge was booked for a position without prediction
+)
+
+// RolloverDiscrepancy is a difference between the predicted and the booked rollover charges
+type RolloverDiscrepancy struct {
+	Kind       string // One of the Rollover constants
+	Prediction RolloverPrediction
+	LedgerID   string     // Ledger entry of the charge, empty for RolloverMissing
+	Charged    float64    // Fee of the ledger entry
+	Entry      LedgerInfo // The ledger entry, zero for RolloverMissing
+}
+
+// RolloverTracker predicts the rollover charges of the open margin positions
+// and reconciles them against the rollover ledger entries. Its methods are
+// safe for concurrent use.
+type RolloverTracker struct {
+	api       *KrakenAPI
+	tolerance float64
+
+	mu      sync.Mutex
+	pending map[string]RolloverPrediction // Indexed by position id and time
+	matched map[string]bool               // Ledger ids already reconciled
+}
+
+// NewRolloverTracker returns a tracker reporting charges deviating from the
+// prediction by more than tolerance, relative to the predicted amount
+func (api *KrakenAPI) NewRolloverTracker(tolerance float64) *RolloverTracker {
+	return &RolloverTracker{
+		api:       api,
+		tolerance: tolerance,
+		pending:   make(map[string]RolloverPrediction),
+		matched:   make(map[string]bool),
+	}
+}
+
+// Predict fetches the open positions and returns their next rollover
+// charge, ordered by time. The predictions are remembered for Reconcile.
+// The charge is the rate of the terms applied to the cost of the volume
+// still open, due at the rollover time Kraken reports, or else at the next
+// multiple of the period since the position was opened.
+func (t *RolloverTracker) Predict(ctx context.Context) ([]RolloverPrediction, error) {
+	positions, err := t.api.OpenPositionsWithContext(ctx, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	predictions := make([]RolloverPrediction, 0, len(positions))
+	for id, position := range positions {
+		prediction, err := predictRollover(id, position, now)
+		if err != nil {
+			return nil, fmt.Errorf("position %s: %w", id, err)
+		}
+		predictions = append(predictions, prediction)
+	}
+	sort.Slice(predictions, func(i, j int) bool {
+		if predictions[i].Time.Equal(predictions[j].Time) {
+			return predictions[i].PositionID < predictions[j].PositionID
+		}
+		return predictions[i].Time.Before(predictions[j].Time)
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, prediction := range predictions {
+		t.pending[prediction.PositionID+"@"+strconv.FormatInt(prediction.Time.Unix(), 10)] = prediction
+	}
+	return predictions, nil
+}
+
+func predictRollover(id string, position PositionInfo, now time.Time) (RolloverPrediction, error) {
+	terms, err := ParseRolloverTerms(position.Terms)
+	if err != nil {
+		return RolloverPrediction{}, err
+	}
+	open := 1.0
+	if position.Volume > 0 {
+		open = (position.Volume - position.VolumeClosed) / position.Volume
+	}
+
+	next := time.Unix(position.RolloverTime, 0)
+	if position.RolloverTime == 0 || next.Before(now) {
+		opened := unixFloat(position.Time)
+		periods := math.Floor(float64(now.Sub(opened))/float64(terms.Period)) + 1
+		next = opened.Add(time.Duration(periods) * terms.Period)
+	}
+	return RolloverPrediction{
+		PositionID: id,
+		OrderTxID:  position.OrderTxID,
+		Pair:       position.Pair,
+		Time:       next,
+		Amount:     terms.Rate * position.Cost * open,
+	}, nil
+}
+
+// Reconcile matches the remembered predictions against the rollover ledger
+// entries booked since the earliest of them. A prediction is matched by an
+// entry referring to its position or opening order and booked within 15
+// minutes of its time; predictions still unmatched 15 minutes after their
+// time are reported missing and forgotten. Entries of tracked positions
+// matching no prediction are reported as unexpected.
+func (t *RolloverTracker) Reconcile(ctx context.Context) ([]RolloverDiscrepancy, error) {
+	t.mu.Lock()
+	var earliest time.Time
+	for _, prediction := range t.pending {
+		if earliest.IsZero() || prediction.Time.Before(earliest) {
+			earliest = prediction.Time
+		}
+	}
+	t.mu.Unlock()
+	if earliest.IsZero() {
+		return nil, nil
+	}
+
+	entries, err := t.api.allLedgers(ctx, map[string]string{
+		"type":  LedgerTypeRollover,
+		"start": strconv.FormatInt(earliest.Add(-rolloverGrace).Unix(), 10),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return entries[ids[i]].Time < entries[ids[j]].Time })
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tracked := make(map[string]RolloverPrediction)
+	for _, prediction := range t.pending {
+		tracked[prediction.PositionID] = prediction
+		tracked[prediction.OrderTxID] = prediction
+	}
+
+	var discrepancies []RolloverDiscrepancy
+	for _, id := range ids {
+		entry := entries[id]
+		if t.matched[id] {
+			continue
+		}
+		key, prediction, ok := t.matchRollover(entry)
+		if !ok {
+			if p, known := tracked[entry.RefID]; known && entry.RefID != "" {
+				t.matched[id] = true
+				discrepancies = append(discrepancies, RolloverDiscrepancy{
+					Kind: RolloverUnexpected, Prediction: RolloverPrediction{PositionID: p.PositionID, OrderTxID: p.OrderTxID, Pair: p.Pair},
+					LedgerID: id, Charged: ledgerFee(entry), Entry: entry,
+				})
+			}
+			continue
+		}
+		delete(t.pending, key)
+		t.matched[id] = true
+		charged := ledgerFee(entry)
+		if math.Abs(charged-prediction.Amount) > t.tolerance*math.Abs(prediction.Amount) {
+			discrepancies = append(discrepancies, RolloverDiscrepancy{
+				Kind: RolloverMismatch, Prediction: prediction, LedgerID: id, Charged: charged, Entry: entry,
+			})
+		}
+	}
+
+	now := time.Now()
+	for key, prediction := range t.pending {
+		if now.Sub(prediction.Time) > rolloverGrace {
+			delete(t.pending, key)
+			discrepancies = append(discrepancies, RolloverDiscrepancy{Kind: RolloverMissing, Prediction: prediction})
+		}
+	}
+	sort.SliceStable(discrepancies, func(i, j int) bool {
+		return discrepancies[i].Prediction.Time.Before(discrepancies[j].Prediction.Time)
+	})
+	return discrepancies, nil
+}
+
+// matchRollover returns the pending prediction a rollover ledger entry pays
+func (t *RolloverTracker) matchRollover(entry LedgerInfo) (string, RolloverPrediction, bool) {
+	booked := entry.Timestamp()
+	for key, prediction := range t.pending {
+		if entry.RefID != prediction.PositionID && entry.RefID != prediction.OrderTxID {
+			continue
+		}
+		if d := booked.Sub(prediction.Time); d >= -rolloverGrace && d <= rolloverGrace {
+			return key, prediction, true
+		}
+	}
+	return "", RolloverPrediction{}, false
+}
+
+// ledgerFee returns the rollover charge of a ledger entry
+func ledgerFee(entry LedgerInfo) float64 {
+	fee, _ := entry.Fee.Float64()
+	return fee
+}
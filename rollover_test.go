@@ -0,0 +1,131 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseRolloverTerms(t *testing.T) {
+	for terms, want := range map[string]RolloverTerms{
+		"0.0100% per 4 hours": {Rate: 0.0001, Period: 4 * time.Hour},
+		"0.02 % per hour":     {Rate: 0.0002, Period: time.Hour},
+		"0.1% per 1 day":      {Rate: 0.001, Period: 24 * time.Hour},
+	} {
+		got, err := ParseRolloverTerms(terms)
+		if err != nil || math.Abs(got.Rate-want.Rate) > 1e-12 || got.Period != want.Period {
+			t.Errorf("ParseRolloverTerms(%q) = %+v, %v, want %+v", terms, got, err, want)
+		}
+	}
+	for _, terms := range []string{"", "0.01%", "per 4 hours", "0.01% per 0 hours", "0.01% per week"} {
+		if _, err := ParseRolloverTerms(terms); err == nil {
+			t.Errorf("ParseRolloverTerms(%q) should fail", terms)
+		}
+	}
+}
+
+func TestOpenPositions(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		calcs := ""
+		if params.Get("docalcs") == "true" {
+			calcs = `"value":"10520.5","net":"+520.5000",`
+		}
+		return `{"error":[],"result":{"TF5GVO-T7ZZ2-6NBKBI":{"ordertxid":"OLWNFG-LLH4R-D6SFFP","posstatus":"open",
+			"pair":"XXBTZUSD","time":1704375000.1234,"type":"buy","ordertype":"limit","cost":"10000.0","fee":"16.0",
+			"vol":"1.0","vol_closed":"0.25","margin":"2000.0",` + calcs + `"terms":"0.0100% per 4 hours",
+			"rollovertm":"1704389400","misc":"","oflags":"fciq"}}}`
+	})
+
+	positions, err := api.OpenPositions([]string{"TF5GVO-T7ZZ2-6NBKBI", "T6YFT4-FKYO5-HJ7NMW"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("txid") != "TF5GVO-T7ZZ2-6NBKBI,T6YFT4-FKYO5-HJ7NMW" || params.Get("docalcs") != "true" {
+		t.Errorf("Unexpected request %v", params)
+	}
+	position := positions["TF5GVO-T7ZZ2-6NBKBI"]
+	if position.OrderTxID != "OLWNFG-LLH4R-D6SFFP" || position.Margin != 2000 || position.VolumeClosed != 0.25 ||
+		position.Value != 10520.5 || position.Net != 520.5 || position.RolloverTime != 1704389400 || position.OFlags != "fciq" {
+		t.Errorf("Unexpected position %+v", position)
+	}
+
+	positions, err = api.OpenPositions(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Has("txid") || params.Has("docalcs") {
+		t.Errorf("Defaults should not be sent, got %v", params)
+	}
+	if position := positions["TF5GVO-T7ZZ2-6NBKBI"]; position.Value != 0 || position.Net != 0 || position.Cost != 10000 {
+		t.Errorf("Positions without docalcs should decode with zero value and net, got %+v", position)
+	}
+}
+
+func TestRolloverTracker(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	next := now.Add(10 * time.Minute)
+	opened := now.Add(-5 * time.Hour)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "OpenPositions":
+			return fmt.Sprintf(`{"error":[],"result":{
+				"TF5GVO-T7ZZ2-6NBKBI":{"ordertxid":"OLWNFG-LLH4R-D6SFFP","posstatus":"open","pair":"XXBTZUSD","time":%d,"type":"buy",
+					"ordertype":"limit","cost":"10000.0","fee":"16.0","vol":"1.0","vol_closed":"0.5","margin":"2000.0",
+					"terms":"0.0100%% per 4 hours","rollovertm":"%d","misc":"","oflags":""},
+				"T6YFT4-FKYO5-HJ7NMW":{"ordertxid":"OH7UQL-BPMYI-SFLJK5","posstatus":"open","pair":"XETHZUSD","time":%d,"type":"sell",
+					"ordertype":"market","cost":"2000.0","fee":"3.2","vol":"1.0","vol_closed":"0.0","margin":"400.0",
+					"terms":"0.0200%% per 4 hours","rollovertm":"0","misc":"","oflags":""}}}`, opened.Unix(), next.Unix(), opened.Unix())
+		case "Ledgers":
+			return fmt.Sprintf(`{"error":[],"result":{"count":3,"ledger":{
+				"L1":{"refid":"TF5GVO-T7ZZ2-6NBKBI","time":%d,"type":"rollover","asset":"ZUSD","amount":"0","fee":"0.5","balance":"1"},
+				"L2":{"refid":"OH7UQL-BPMYI-SFLJK5","time":%d,"type":"rollover","asset":"ZUSD","amount":"0","fee":"0.9","balance":"1"},
+				"L3":{"refid":"TF5GVO-T7ZZ2-6NBKBI","time":%d,"type":"rollover","asset":"ZUSD","amount":"0","fee":"0.5","balance":"1"}}}}`,
+				next.Unix()+60, opened.Add(8*time.Hour).Unix(), next.Add(4*time.Hour).Unix())
+		}
+		return `{"error":["EGeneral:Unknown method"]}`
+	})
+
+	tracker := api.NewRolloverTracker(0.01)
+	predictions, err := tracker.Predict(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(predictions) != 2 {
+		t.Fatalf("Expected a prediction per position, got %+v", predictions)
+	}
+	if p := predictions[0]; p.PositionID != "TF5GVO-T7ZZ2-6NBKBI" || !p.Time.Equal(next) || math.Abs(p.Amount-0.5) > 1e-9 {
+		t.Errorf("The charge should be due at the rollover time, on the open half of the cost, got %+v", p)
+	}
+	if p := predictions[1]; !p.Time.Equal(opened.Add(8*time.Hour)) || math.Abs(p.Amount-0.4) > 1e-9 {
+		t.Errorf("Without rollover time the charge should be due at the next period, got %+v", p)
+	}
+
+	tracker.mu.Lock()
+	tracker.pending["gone"] = RolloverPrediction{PositionID: "gone", Time: now.Add(-time.Hour), Amount: 1}
+	tracker.mu.Unlock()
+
+	discrepancies, err := tracker.Reconcile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]string)
+	for _, d := range discrepancies {
+		kinds[d.Prediction.PositionID] += d.Kind + d.LedgerID
+	}
+	want := map[string]string{"gone": RolloverMissing, "T6YFT4-FKYO5-HJ7NMW": RolloverMismatch + "L2", "TF5GVO-T7ZZ2-6NBKBI": RolloverUnexpected + "L3"}
+	if fmt.Sprint(kinds) != fmt.Sprint(want) {
+		t.Errorf("Expected discrepancies %v, got %v", want, kinds)
+	}
+
+	if discrepancies, _ := tracker.Reconcile(context.Background()); len(discrepancies) != 0 {
+		t.Errorf("Discrepancies should be reported once, got %+v", discrepancies)
+	}
+}
@@ -0,0 +1,128 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FlattenOptions configures FlattenAccount
+type FlattenOptions struct {
+	// DeadMansSwitch arms CancelAllOrdersAfter with this timeout before
+	// cancelling, so orders get cancelled even if the process dies midway.
+	// Zero skips arming.
+	DeadMansSwitch time.Duration
+	// PollInterval is the delay between OpenOrders polls while waiting for
+	// the cancellations to be confirmed. Defaults to one second.
+	PollInterval time.Duration
+}
+
+// FlattenReport describes the outcome of FlattenAccount
+type FlattenReport struct {
+	TriggerTime  time.Time                    // When the armed dead man's switch fires, zero if not armed
+	ArmErr       error                        // Error arming the dead man's switch, if any
+	CancelAllErr error                        // Error of the global CancelAll call, if any
+	PairResults  map[string]*CancelPairResult // Results of the per pair fallback, indexed by pair
+	PairErr      error                        // Errors of the per pair fallback, if any, see CancelPairsError
+	Remaining    map[string]Order             // Orders still open when the context expired
+}
+
+// FlattenAccount cancels every open order within the deadline of ctx, e.g.
+// from a shutdown hook. It arms the dead man's switch first (if configured),
+// then calls CancelAll, falling back to cancelling pair by pair when the
+// global call fails, and finally polls OpenOrders until no order is left.
+// Orders still open when ctx expires are reported in Remaining, together
+// with ctx.Err().
+func (api *KrakenAPI) FlattenAccount(ctx context.Context, opts FlattenOptions) (*FlattenReport, error) {
+	report := &FlattenReport{}
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+
+	if opts.DeadMansSwitch > 0 {
+		resp, err := api.CancelAllOrdersAfterWithContext(ctx, opts.DeadMansSwitch)
+		if err != nil {
+			report.ArmErr = err
+		} else {
+			report.TriggerTime = resp.TriggerTime
+		}
+	}
+
+	if _, err := api.CancelAllWithContext(ctx); err != nil {
+		report.CancelAllErr = err
+		report.PairResults, report.PairErr = api.cancelPairByPair(ctx)
+	}
+
+	for {
+		open, err := api.OpenOrdersWithContext(ctx, nil)
+		if err == nil {
+			report.Remaining = open.Open
+			if len(open.Open) == 0 {
+				return report, nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return report, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// CancelPairsError aggregates the pairs the per pair fallback of
+// FlattenAccount failed to cancel
+type CancelPairsError struct {
+	Errors map[string]error // Indexed by pair
+}
+
+func (e *CancelPairsError) Error() string {
+	pairs := make([]string, 0, len(e.Errors))
+	for pair := range e.Errors {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	messages := make([]string, len(pairs))
+	for i, pair := range pairs {
+		messages[i] = fmt.Sprintf("%s: %s", pair, e.Errors[pair])
+	}
+	return "cancelling pair by pair failed for " + strings.Join(messages, "; ")
+}
+
+// cancelPairByPair cancels the open orders with one CancelAllForPair call
+// per pair. The error of each pair is recorded in its result, and the
+// errors are returned together as a *CancelPairsError.
+func (api *KrakenAPI) cancelPairByPair(ctx context.Context) (map[string]*CancelPairResult, error) {
+	results := make(map[string]*CancelPairResult)
+	open, err := api.OpenOrdersWithContext(ctx, nil)
+	if err != nil {
+		return results, err
+	}
+
+	pairs := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, order := range open.Open {
+		if pair := order.Description.Pair; !seen[pair] {
+			seen[pair] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	sort.Strings(pairs)
+
+	failed := make(map[string]error)
+	for _, pair := range pairs {
+		result, err := api.CancelAllForPair(ctx, pair)
+		if err != nil {
+			failed[pair] = err
+			result = &CancelPairResult{Err: err}
+		}
+		results[pair] = result
+	}
+	if len(failed) > 0 {
+		return results, &CancelPairsError{Errors: failed}
+	}
+	return results, nil
+}
@@ -0,0 +1,113 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlattenAccount(t *testing.T) {
+	var calls []string
+	polls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls = append(calls, method)
+		switch method {
+		case "CancelAllOrdersAfter":
+			return `{"error":[],"result":{"currentTime":"2023-03-24T17:41:56Z","triggerTime":"2023-03-24T17:42:56Z"}}`
+		case "CancelAll":
+			return `{"error":[],"result":{"count":1}}`
+		case "OpenOrders":
+			polls++
+			if polls == 1 {
+				return `{"error":[],"result":{"open":{"O1":{"status":"open","descr":{"pair":"XBTUSD"}}}}}`
+			}
+			return `{"error":[],"result":{"open":{}}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	})
+
+	report, err := api.FlattenAccount(context.Background(), FlattenOptions{DeadMansSwitch: time.Minute, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("FlattenAccount() should not return an error, got %s", err)
+	}
+	if calls[0] != "CancelAllOrdersAfter" || calls[1] != "CancelAll" {
+		t.Errorf("FlattenAccount() should arm the switch before cancelling, got %v", calls)
+	}
+	if report.TriggerTime.IsZero() || len(report.Remaining) != 0 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestFlattenAccountDeadline(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "CancelAll":
+			return `{"error":["EService:Unavailable"]}`
+		case "AssetPairs":
+			return testAssetPairs
+		case "CancelOrderBatch":
+			return `{"error":[],"result":{"count":1}}`
+		case "QueryOrders":
+			return `{"error":[],"result":{"O1":{"status":"open"}}}`
+		}
+		return `{"error":[],"result":{"open":{"O1":{"status":"open","descr":{"pair":"XBTUSD"}}}}}`
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err := api.FlattenAccount(ctx, FlattenOptions{PollInterval: time.Millisecond})
+	if err != context.DeadlineExceeded {
+		t.Errorf("FlattenAccount() should return the context error, got %v", err)
+	}
+	if report.CancelAllErr == nil || report.PairResults["XBTUSD"] == nil {
+		t.Errorf("FlattenAccount() should fall back to cancelling per pair, got %+v", report)
+	}
+	if _, ok := report.Remaining["O1"]; !ok {
+		t.Errorf("FlattenAccount() should report the orders still open, got %+v", report.Remaining)
+	}
+}
+
+func TestFlattenAccountPairErrors(t *testing.T) {
+	polls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "CancelAll":
+			return `{"error":["EService:Unavailable"]}`
+		case "AssetPairs":
+			return testAssetPairs
+		case "CancelOrderBatch":
+			return `{"error":[],"result":{"count":1}}`
+		case "QueryOrders":
+			return `{"error":[],"result":{"O1":{"status":"canceled"}}}`
+		case "OpenOrders":
+			polls++
+			if polls > 3 {
+				return `{"error":[],"result":{"open":{}}}`
+			}
+		}
+		return `{"error":[],"result":{"open":{"O1":{"status":"open","descr":{"pair":"XBTUSD"}},"O2":{"status":"open","descr":{"pair":"NOPEUSD"}}}}}`
+	})
+
+	report, err := api.FlattenAccount(context.Background(), FlattenOptions{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result := report.PairResults["XBTUSD"]; result == nil || result.Err != nil || result.Cancelled != 1 {
+		t.Errorf("Unexpected result for XBTUSD %+v", result)
+	}
+	failed := report.PairResults["NOPEUSD"]
+	if failed == nil || failed.Err == nil {
+		t.Fatalf("The error of NOPEUSD should be recorded, got %+v", failed)
+	}
+	var pairsErr *CancelPairsError
+	if !errors.As(report.PairErr, &pairsErr) || len(pairsErr.Errors) != 1 || pairsErr.Errors["NOPEUSD"] != failed.Err {
+		t.Errorf("Expected the error of NOPEUSD aggregated, got %v", report.PairErr)
+	}
+	if !strings.Contains(report.PairErr.Error(), "NOPEUSD") {
+		t.Errorf("The aggregated error should name the pair, got %s", report.PairErr)
+	}
+}
@@ -0,0 +1,84 @@
+package krakenapi
+
+import "strconv"
+
+// RemainderPolicy decides what to do with a remainder below the pair's ordermin
+type RemainderPolicy int
+
+// Remainder policies
+const (
+	// RemainderRoundUp orders ordermin, overshooting the target
+	RemainderRoundUp RemainderPolicy = iota
+	// RemainderSkip orders nothing, leaving the position short of the target
+	RemainderSkip
+	// RemainderMerge orders nothing and carries the remainder into the next order
+	RemainderMerge
+)
+
+// SizingBranch tells which case SizeRemainder decided on
+type SizingBranch string
+
+// Sizing branches
+const (
+	SizingComplete  SizingBranch = "complete"   // The target is reached; nothing to order
+	SizingOrder     SizingBranch = "order"      // The remainder is at least ordermin and is ordered
+	SizingRoundedUp SizingBranch = "rounded_up" // The remainder was below ordermin and ordermin is ordered
+	SizingSkipped   SizingBranch = "skipped"    // The remainder was below ordermin and is dropped
+	SizingCarried   SizingBranch = "carried"    // The remainder was below ordermin and is carried over
+)
+
+// SizingDecision explains the volume SizeRemainder computed
+type SizingDecision struct {
+	Branch    SizingBranch
+	Remaining float64 // Target minus filled volume
+	OrderMin  float64 // Minimum order volume of the pair, 0 if unknown
+	Volume    float64 // Volume to order, truncated to the pair's lot decimals; 0 for none
+	Carry     float64 // Volume to add to the target of the next order (RemainderMerge only)
+}
+
+// VolumeParam returns Volume formatted for AddOrder with the pair's lot decimals
+func (d SizingDecision) VolumeParam(pair AssetPairInfo) string {
+	return pair.FormatVolume(d.Volume)
+}
+
+// SizeRemainder computes the volume still to order to bring filled up to
+// target. Filled is typically Order.VolumeExecuted or the SumTradeVolume of
+// the fills so far. Volumes are truncated, never rounded up, to the lot
+// decimals of pair; a remainder below the pair's ordermin is handled by policy.
+func SizeRemainder(target, filled float64, pair AssetPairInfo, policy RemainderPolicy) SizingDecision {
+	decision := SizingDecision{Remaining: target - filled}
+	decision.OrderMin, _ = strconv.ParseFloat(pair.OrderMin, 64)
+
+	volume, _ := strconv.ParseFloat(truncateDecimal(decision.Remaining, pair.LotDecimals), 64)
+	if volume <= 0 {
+		decision.Branch = SizingComplete
+		return decision
+	}
+	if volume >= decision.OrderMin {
+		decision.Branch = SizingOrder
+		decision.Volume = volume
+		return decision
+	}
+
+	switch policy {
+	case RemainderRoundUp:
+		decision.Branch = SizingRoundedUp
+		decision.Volume = decision.OrderMin
+	case RemainderMerge:
+		decision.Branch = SizingCarried
+		decision.Carry = decision.Remaining
+	default:
+		decision.Branch = SizingSkipped
+	}
+	return decision
+}
+
+// SumTradeVolume returns the total volume of trades, e.g. the fills of an
+// order taken from TradesHistory
+func SumTradeVolume(trades map[string]TradeHistoryInfo) float64 {
+	total := 0.0
+	for _, trade := range trades {
+		total += trade.Volume
+	}
+	return total
+}
@@ -0,0 +1,39 @@
+package krakenapi
+
+import "testing"
+
+func TestSizeRemainder(t *testing.T) {
+	pair := AssetPairInfo{LotDecimals: 4, OrderMin: "0.0001"}
+
+	cases := []struct {
+		target, filled float64
+		orderMin       string
+		policy         RemainderPolicy
+		branch         SizingBranch
+		volume, carry  float64
+	}{
+		{1, 0.3, "0.0001", RemainderSkip, SizingOrder, 0.7, 0},
+		{1, 0.123456, "0.0001", RemainderSkip, SizingOrder, 0.8765, 0},
+		{1, 1, "0.0001", RemainderSkip, SizingComplete, 0, 0},
+		{1, 1.2, "0.0001", RemainderSkip, SizingComplete, 0, 0},
+		{1, 0.99, "0.02", RemainderRoundUp, SizingRoundedUp, 0.02, 0},
+		{1, 0.99, "0.02", RemainderSkip, SizingSkipped, 0, 0},
+		{1, 0.99, "0.02", RemainderMerge, SizingCarried, 0, 0.01},
+	}
+	for _, c := range cases {
+		pair.OrderMin = c.orderMin
+		d := SizeRemainder(c.target, c.filled, pair, c.policy)
+		if d.Branch != c.branch || d.Volume != c.volume || (d.Carry-c.carry) > 1e-12 || (c.carry-d.Carry) > 1e-12 {
+			t.Errorf("SizeRemainder(%v, %v, ordermin %s, %v) = %+v", c.target, c.filled, c.orderMin, c.policy, d)
+		}
+	}
+
+	d := SizeRemainder(1, 0.123456, pair, RemainderSkip)
+	if d.VolumeParam(pair) != "0.8765" {
+		t.Errorf("Unexpected volume parameter %s", d.VolumeParam(pair))
+	}
+	fills := map[string]TradeHistoryInfo{"T1": {Volume: 0.25}, "T2": {Volume: 0.5}}
+	if SumTradeVolume(fills) != 0.75 {
+		t.Errorf("Unexpected trade volume %v", SumTradeVolume(fills))
+	}
+}
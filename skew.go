@@ -0,0 +1,90 @@
+package krakenapi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ComponentTimes records when each component of a combined result was
+// fetched, indexed by component name, e.g. "Depth" and "Ticker"
+type ComponentTimes map[string]time.Time
+
+// Skew returns the time between the oldest and the newest component
+func (c ComponentTimes) Skew() time.Duration {
+	var oldest, newest time.Time
+	for _, t := range c {
+		if oldest.IsZero() || t.Before(oldest) {
+			oldest = t
+		}
+		if t.After(newest) {
+			newest = t
+		}
+	}
+	return newest.Sub(oldest)
+}
+
+// Oldest returns the name of the component fetched first
+func (c ComponentTimes) Oldest() string {
+	var name string
+	for component, t := range c {
+		if name == "" || t.Before(c[name]) || t.Equal(c[name]) && component < name {
+			name = component
+		}
+	}
+	return name
+}
+
+// ErrSkewExceeded is matched by every *SkewError
+var ErrSkewExceeded = errors.New("skew exceeded")
+
+// SkewError is returned by helpers combining several calls when their
+// components were fetched further apart than the MaxSkew of the client
+type SkewError struct {
+	MaxSkew    time.Duration
+	Components ComponentTimes
+}
+
+func (e *SkewError) Error() string {
+	names := make([]string, 0, len(e.Components))
+	for name := range e.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	newest := time.Time{}
+	for _, t := range e.Components {
+		if t.After(newest) {
+			newest = t
+		}
+	}
+	ages := make([]string, len(names))
+	for i, name := range names {
+		ages[i] = fmt.Sprintf("%s %s", name, newest.Sub(e.Components[name]))
+	}
+	return fmt.Sprintf("components fetched %s apart, more than %s (%s)", e.Components.Skew(), e.MaxSkew, strings.Join(ages, ", "))
+}
+
+// Unwrap makes errors.Is(err, ErrSkewExceeded) hold
+func (e *SkewError) Unwrap() error {
+	return ErrSkewExceeded
+}
+
+// WithMaxSkew bounds how far apart the components of helpers combining
+// several calls may be fetched, e.g. the book and the ticker of DepthForPair.
+// Helpers refetch a stale component once where they can, and otherwise fail
+// with a *SkewError. Zero disables the check.
+func (api *KrakenAPI) WithMaxSkew(maxSkew time.Duration) *KrakenAPI {
+	api.maxSkew = maxSkew
+	return api
+}
+
+// checkSkew returns a *SkewError if components exceed the MaxSkew of api
+func (api *KrakenAPI) checkSkew(components ComponentTimes) error {
+	if api.maxSkew > 0 && components.Skew() > api.maxSkew {
+		return &SkewError{MaxSkew: api.maxSkew, Components: components}
+	}
+	return nil
+}
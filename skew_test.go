@@ -0,0 +1,52 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestComponentTimes(t *testing.T) {
+	now := time.Now()
+	c := ComponentTimes{"Ticker": now, "Depth": now.Add(-time.Second), "Balance": now.Add(-time.Minute)}
+	if c.Skew() != time.Minute || c.Oldest() != "Balance" {
+		t.Errorf("Unexpected skew %s of oldest %s", c.Skew(), c.Oldest())
+	}
+	if (ComponentTimes{}).Skew() != 0 {
+		t.Errorf("Empty components should have no skew")
+	}
+}
+
+func TestDepthForPairMaxSkew(t *testing.T) {
+	var depthDelay time.Duration
+	depths := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "Depth":
+			depths++
+			time.Sleep(depthDelay)
+			return `{"error":[],"result":{"XXBTZUSD":{"asks":[["101","1",1]],"bids":[["100","1",1]]}}}`
+		case "Ticker":
+			time.Sleep(30 * time.Millisecond)
+			return `{"error":[],"result":{"XXBTZUSD":{"a":["101","1","1"],"b":["100","1","1"]}}}`
+		}
+		t.Fatalf("unexpected call to %s", method)
+		return ""
+	}).WithBookValidation(0).WithMaxSkew(10 * time.Millisecond)
+
+	if _, err := api.DepthForPair(context.Background(), "XBTUSD", 10); err != nil {
+		t.Errorf("The stale book should be fetched again, got %s", err)
+	}
+	if depths != 2 {
+		t.Errorf("Depth should be fetched twice, got %d", depths)
+	}
+
+	depthDelay = 30 * time.Millisecond
+	_, err := api.DepthForPair(context.Background(), "XBTUSD", 10)
+	var skew *SkewError
+	if !errors.Is(err, ErrSkewExceeded) || !errors.As(err, &skew) || len(skew.Components) != 2 {
+		t.Errorf("DepthForPair() should fail when the refetched book is still skewed, got %v", err)
+	}
+}
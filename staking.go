@@ -0,0 +1,58 @@
+package krakenapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StakingTransactionType tells whether a staking transaction moves funds
+// into or out of staking
+type StakingTransactionType string
+
+// Staking transaction types
+const (
+	StakingBonding   StakingTransactionType = "bonding"   // Funds being staked
+	StakingUnbonding StakingTransactionType = "unbonding" // Funds being unstaked, not yet available
+	StakingReward    StakingTransactionType = "reward"    // Reward paid for staked funds
+)
+
+// Range returns the lowest and highest yearly reward, which are equal unless
+// Kraken reports a range such as "4.5-7"
+func (r StakingRewards) Range() (low, high float64, err error) {
+	parts := strings.SplitN(r.Reward, "-", 2)
+	low, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid staking reward %q", r.Reward)
+	}
+	high = low
+	if len(parts) == 2 {
+		high, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || high < low {
+			return 0, 0, fmt.Errorf("invalid staking reward %q", r.Reward)
+		}
+	}
+	return low, high, nil
+}
+
+// CheckStake returns an error if amount of the asset cannot be staked
+func (info StakingAssetInfo) CheckStake(amount float64) error {
+	if info.Disabled || !info.CanStake || !info.EnabledForUser {
+		return fmt.Errorf("%s cannot be staked with %s", info.Asset, info.Method)
+	}
+	if amount <= 0 || amount < info.MinimumAmount.Staking {
+		return fmt.Errorf("cannot stake %v %s, the minimum is %v", amount, info.Asset, info.MinimumAmount.Staking)
+	}
+	return nil
+}
+
+// CheckUnstake returns an error if amount of the asset cannot be unstaked
+func (info StakingAssetInfo) CheckUnstake(amount float64) error {
+	if info.Disabled || !info.CanUnstake || !info.EnabledForUser {
+		return fmt.Errorf("%s cannot be unstaked from %s", info.Asset, info.Method)
+	}
+	if amount <= 0 || amount < info.MinimumAmount.Unstaking {
+		return fmt.Errorf("cannot unstake %v %s, the minimum is %v", amount, info.Asset, info.MinimumAmount.Unstaking)
+	}
+	return nil
+}
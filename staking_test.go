@@ -0,0 +1,155 @@
+package krakenapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestStakeAsset(t *testing.T) {
+	var params url.Values
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method == "Assets" {
+			return `{"error":[],"result":{"DOT":{"aclass":"currency","altname":"DOT","decimals":10,"display_decimals":8}}}`
+		}
+		body, _ := io.ReadAll(req.Body)
+		params, _ = url.ParseQuery(string(body))
+		if params.Get("amount") == "1000" {
+			return `{"error":["EFunding:Insufficient funds"]}`
+		}
+		return `{"error":[],"result":{"refid":"BOG5AE5-KSCNR4-VPNPEV"}}`
+	})
+
+	resp, err := api.StakeAsset("DOT", "12.5", "polkadot-staked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RefID != "BOG5AE5-KSCNR4-VPNPEV" || params.Get("method") != "polkadot-staked" || params.Get("amount") != "12.5" {
+		t.Errorf("Unexpected stake %+v with %v", resp, params)
+	}
+
+	if _, err := api.StakeAssetFloat("DOT", 0.1+0.2, "polkadot-staked"); err != nil {
+		t.Fatal(err)
+	}
+	if params.Get("amount") != "0.3" {
+		t.Errorf("The amount should be formatted with the decimals of the asset, got %s", params.Get("amount"))
+	}
+
+	unstake, err := api.UnstakeAssetFloat("DOT", 2)
+	if err != nil || unstake.RefID != "BOG5AE5-KSCNR4-VPNPEV" || params.Get("amount") != "2" || params.Has("method") {
+		t.Errorf("Unexpected unstake %+v with %v, %v", unstake, params, err)
+	}
+
+	_, err = api.StakeAsset("DOT", "1000", "polkadot-staked")
+	if !errors.Is(err, ErrFundingInsufficientFunds) || errors.Is(err, ErrFundingInvalidAmount) {
+		t.Errorf("Expected insufficient funds, got %v", err)
+	}
+	if _, err := api.UnstakeAsset("DOT.S", "1000"); !errors.Is(err, ErrFundingInsufficientFunds) {
+		t.Errorf("Expected insufficient funds, got %v", err)
+	}
+}
+
+func TestStakingAssets(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method != "Staking/Assets" {
+			t.Errorf("Unexpected call to %s", method)
+		}
+		return `{"error":[],"result":[{"method":"polkadot-staked","asset":"DOT","staking_asset":"DOT.S",
+			"rewards":{"reward":"12.00","type":"percentage"},"on_chain":true,"can_stake":true,"can_unstake":true,
+			"minimum_amount":{"staking":"0.1000000000","unstaking":"0.0000000000"},
+			"lock":{"unstaking":[{"days":28,"percentage":100}],"lockup":[],"staking":[{"days":0.5,"percentage":100}]},
+			"enabled_for_user":true,"disabled":false},
+			{"method":"ethereum-staked","asset":"XETH","staking_asset":"ETH2","rewards":{"reward":"4.5-7","type":"percentage"},
+			"on_chain":true,"can_stake":true,"can_unstake":false,"minimum_amount":{"staking":"0","unstaking":"0"},
+			"lock":{},"enabled_for_user":true,"disabled":false}]}`
+	})
+
+	assets, err := api.StakingAssets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 2 {
+		t.Fatalf("Expected two assets, got %+v", assets)
+	}
+	dot := assets[0]
+	if dot.Method != "polkadot-staked" || dot.StakingAsset != "DOT.S" || !dot.OnChain || dot.MinimumAmount.Staking != 0.1 {
+		t.Errorf("Unexpected asset %+v", dot)
+	}
+	if len(dot.Lock.Unstaking) != 1 || dot.Lock.Unstaking[0].Days != 28 || dot.Lock.Staking[0].Days != 0.5 {
+		t.Errorf("Unexpected lock periods %+v", dot.Lock)
+	}
+	if low, high, err := dot.Rewards.Range(); err != nil || low != 12 || high != 12 || dot.Rewards.Type != "percentage" {
+		t.Errorf("Unexpected rewards %v-%v (%v)", low, high, err)
+	}
+	if low, high, err := assets[1].Rewards.Range(); err != nil || low != 4.5 || high != 7 {
+		t.Errorf("Unexpected reward range %v-%v (%v)", low, high, err)
+	}
+
+	if err := dot.CheckStake(0.05); err == nil {
+		t.Errorf("Amounts below the minimum should be rejected")
+	}
+	if err := dot.CheckStake(1); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+	if err := assets[1].CheckUnstake(1); err == nil {
+		t.Errorf("Assets that cannot be unstaked should be rejected")
+	}
+}
+
+func TestStakingPendingTransactions(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":[],"result":[{"method":"polkadot-staked","aclass":"currency","asset":"DOT.S","refid":"RUSB7W6-ESIXUX-K6PVTM",
+			"amount":"25.0000000000","fee":"0.0000000000","time":1688967367.25,"status":"Pending","type":"unbonding"},
+			{"method":"polkadot-staked","aclass":"currency","asset":"DOT.S","refid":"RUSB7W6-ESIXUX-K6PVTN",
+			"amount":"10","fee":"0","time":1688967400,"status":"Initial","type":"bonding"}]}`
+	})
+
+	pending, err := api.StakingPendingTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("Expected two transactions, got %+v", pending)
+	}
+	unbonding := pending[0]
+	if unbonding.Type != StakingUnbonding || unbonding.Status != FundingStatusPending || unbonding.Amount.Text('f', -1) != "25" {
+		t.Errorf("Unexpected transaction %+v", unbonding)
+	}
+	if !unbonding.Time.Equal(time.Unix(1688967367, 250000000)) || pending[1].Type != StakingBonding {
+		t.Errorf("Unexpected time %s", unbonding.Time)
+	}
+}
+
+func TestStakingTransactions(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method != "Staking/Transactions" {
+			t.Errorf("Unexpected call to %s", method)
+		}
+		return `{"error":[],"result":[{"method":"polkadot-staked","aclass":"currency","asset":"DOT.S","refid":"RUSB7W6-ESIXUX-K6PVTM",
+			"amount":"25","fee":"0","time":1688967367,"status":"Success","type":"bonding","bond_start":1688967367,"bond_end":1688967367},
+			{"method":"polkadot-staked","aclass":"currency","asset":"DOT.S","refid":"RUSB7W6-ESIXUX-K6PVTP",
+			"amount":"0.0123","fee":"0.0002","time":1689053767,"status":"Success","type":"reward"}]}`
+	})
+
+	transactions, err := api.StakingTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("Expected two transactions, got %+v", transactions)
+	}
+	bonding := transactions[0]
+	if !bonding.BondStart.Equal(time.Unix(1688967367, 0)) || !bonding.BondEnd.Equal(bonding.BondStart) || !bonding.Time.Equal(bonding.BondStart) {
+		t.Errorf("Unexpected bonding period %s - %s", bonding.BondStart, bonding.BondEnd)
+	}
+	reward := transactions[1]
+	if reward.Type != StakingReward || reward.Fee.Text('f', -1) != "0.0002" || reward.Time.Unix() != 1689053767 {
+		t.Errorf("Unexpected reward %+v", reward)
+	}
+	if !reward.BondStart.IsZero() || !reward.BondEnd.IsZero() {
+		t.Errorf("Missing bond times should be zero, got %s - %s", reward.BondStart, reward.BondEnd)
+	}
+}
@@ -0,0 +1,234 @@
+package krakenapi
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// StatsTransportError is the key of ClientStats.Errors counting requests
+// that failed without an answer from Kraken
+const StatsTransportError = "transport"
+
+// CacheStats counts the lookups in a client side cache
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+// HitRate returns the fraction of lookups served from the cache, zero if none
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// RateLimitStats is the state of the rate limiter of a client
+type RateLimitStats struct {
+	Counter    float64 // Estimated value of Kraken's call counter
+	Max        float64 // Ceiling of the counter
+	QueueDepth int     // Callers waiting for budget
+}
+
+// WebSocketStats is the state of the WebSocket connections of a channel
+// reported to a client, see RecordWebSocketConnection
+type WebSocketStats struct {
+	Connections    int           // Open connections
+	LastMessage    time.Time     // Zero if no message, heartbeats included, was received
+	LastMessageAge time.Duration // Time since LastMessage at the snapshot, zero if none
+}
+
+// ClientStats is a snapshot of the activity of a client since its creation
+type ClientStats struct {
+	Requests map[string]int64      // HTTP requests by API method, retries included
+	Errors   map[string]int64      // Failed requests by Kraken error code, or StatsTransportError
+	Retries  map[string]int64      // Retried attempts by API method
+	Caches   map[string]CacheStats // Lookups by cache: "metadata", "price" and "price_history"
+
+	RateLimit *RateLimitStats // Nil without rate limiter
+
+	// WebSockets holds the WebSocket connections reported to the client by
+	// channel name, e.g. "ticker"
+	WebSockets map[string]WebSocketStats
+
+	// ClockSkew estimates how far Kraken's clock is ahead of the local one,
+	// from the Date header of the last response. The header has a resolution
+	// of one second.
+	ClockSkew           time.Duration
+	ClockSkewObservedAt time.Time // Zero if no response carried a Date header
+}
+
+// clientStats maintains the counters of ClientStats. It is safe for
+// concurrent use; a nil clientStats counts nothing.
+type clientStats struct {
+	mu         sync.Mutex
+	requests   map[string]int64
+	errors     map[string]int64
+	retries    map[string]int64
+	caches     map[string]CacheStats
+	websockets map[string]WebSocketStats
+	skew       time.Duration
+	skewSeenAt time.Time
+}
+
+func newClientStats() *clientStats {
+	return &clientStats{
+		requests:   make(map[string]int64),
+		errors:     make(map[string]int64),
+		retries:    make(map[string]int64),
+		caches:     make(map[string]CacheStats),
+		websockets: make(map[string]WebSocketStats),
+	}
+}
+
+// request counts a finished request and the error codes it failed with
+func (s *clientStats) request(method string, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests[method]++
+	if err == nil {
+		return
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		s.errors[StatsTransportError]++
+		return
+	}
+	for _, code := range apiErr.Errors {
+		s.errors[code]++
+	}
+}
+
+func (s *clientStats) retry(method string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries[method]++
+}
+
+func (s *clientStats) cacheLookup(cache string, hit bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := s.caches[cache]
+	if hit {
+		counts.Hits++
+	} else {
+		counts.Misses++
+	}
+	s.caches[cache] = counts
+}
+
+func (s *clientStats) websocketConnection(channel string, open bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.websockets[channel]
+	if open {
+		state.Connections++
+	} else if state.Connections > 0 {
+		state.Connections--
+	}
+	s.websockets[channel] = state
+}
+
+func (s *clientStats) websocketMessage(channel string, received time.Time) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := s.websockets[channel]
+	state.LastMessage = received
+	s.websockets[channel] = state
+}
+
+// observeClock estimates the clock skew from the Date header of a response
+// received at the given time. The header is truncated to the second, so
+// its middle is used.
+func (s *clientStats) observeClock(header http.Header, received time.Time) {
+	if s == nil {
+		return
+	}
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skew = date.Add(500 * time.Millisecond).Sub(received)
+	s.skewSeenAt = received
+}
+
+func (s *clientStats) snapshot() ClientStats {
+	stats := ClientStats{
+		Requests:   make(map[string]int64),
+		Errors:     make(map[string]int64),
+		Retries:    make(map[string]int64),
+		Caches:     make(map[string]CacheStats),
+		WebSockets: make(map[string]WebSocketStats),
+	}
+	if s == nil {
+		return stats
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range s.requests {
+		stats.Requests[k] = v
+	}
+	for k, v := range s.errors {
+		stats.Errors[k] = v
+	}
+	for k, v := range s.retries {
+		stats.Retries[k] = v
+	}
+	for k, v := range s.caches {
+		stats.Caches[k] = v
+	}
+	now := time.Now()
+	for k, v := range s.websockets {
+		if !v.LastMessage.IsZero() {
+			v.LastMessageAge = now.Sub(v.LastMessage)
+		}
+		stats.WebSockets[k] = v
+	}
+	stats.ClockSkew = s.skew
+	stats.ClockSkewObservedAt = s.skewSeenAt
+	return stats
+}
+
+// Stats returns a snapshot of the requests, errors, retries and cache
+// lookups of the client, the state of its rate limiter and of the WebSocket
+// connections reported to it, and the estimated skew of the local clock. It is safe to call concurrently with requests.
+func (api *KrakenAPI) Stats() ClientStats {
+	stats := api.stats.snapshot()
+	if api.limiter != nil {
+		counter, max := api.limiter.Counter()
+		stats.RateLimit = &RateLimitStats{Counter: counter, Max: max, QueueDepth: api.limiter.QueueDepth()}
+	}
+	return stats
+}
+
+// RecordWebSocketConnection records a WebSocket connection to channel being
+// opened or closed, for the WebSockets of Stats. Clients of the ws package
+// set up with WithStats call it for every subscription.
+func (api *KrakenAPI) RecordWebSocketConnection(channel string, open bool) {
+	api.stats.websocketConnection(channel, open)
+}
+
+// RecordWebSocketMessage records a message received on a WebSocket
+// connection to channel, for the WebSockets of Stats
+func (api *KrakenAPI) RecordWebSocketMessage(channel string) {
+	api.stats.websocketMessage(channel, time.Now())
+}
@@ -0,0 +1,98 @@
+package krakenapi
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	calls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls++
+		switch {
+		case method == "Balance" && calls == 1:
+			return `{"error":["EService:Unavailable"]}`
+		case method == "AddOrder":
+			return `{"error":["EOrder:Insufficient funds","EGeneral:Invalid arguments"]}`
+		case method == "AssetPairs":
+			return testAssetPairs
+		}
+		return `{"error":[],"result":{"ZUSD":"1.0"}}`
+	}).WithRetryPolicy(RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond, Retryable: IsRetryable}).
+		WithMetadataCache(NewMetadataCache(time.Minute)).
+		WithRateLimiter(NewRateLimiter(15, 0.33))
+
+	if _, err := api.Balance(); err != nil {
+		t.Fatal(err)
+	}
+	api.AddOrder("XBTUSD", "buy", OTMarket, "1", nil)
+	for i := 0; i < 3; i++ {
+		if _, err := api.assetPairs(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := api.Stats()
+	if stats.Requests["Balance"] != 2 || stats.Retries["Balance"] != 1 || stats.Requests["AddOrder"] != 1 {
+		t.Errorf("Unexpected counts %v, retries %v", stats.Requests, stats.Retries)
+	}
+	if stats.Errors["EService:Unavailable"] != 1 || stats.Errors[ErrCodeInsufficientFunds] != 1 || stats.Errors[ErrCodeInvalidArguments] != 1 {
+		t.Errorf("Errors should be counted by code, got %v", stats.Errors)
+	}
+	if metadata := stats.Caches["metadata"]; metadata.Hits != 2 || metadata.Misses != 1 || metadata.HitRate() < 0.66 {
+		t.Errorf("Unexpected metadata cache counts %+v", metadata)
+	}
+	if stats.RateLimit == nil || stats.RateLimit.Max != 15 || stats.RateLimit.Counter < 1 {
+		t.Errorf("Unexpected rate limit %+v", stats.RateLimit)
+	}
+
+	stats.Requests["Balance"] = 100
+	if api.Stats().Requests["Balance"] != 2 {
+		t.Errorf("Stats should return a snapshot")
+	}
+}
+
+func TestStatsClockSkew(t *testing.T) {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header: http.Header{
+				"Content-Type": {"application/json"},
+				"Date":         {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)},
+			},
+			Body: http.NoBody,
+		}
+		return resp, nil
+	})
+	api := NewWithClient("key", "c2VjcmV0", &http.Client{Transport: transport})
+	api.Time()
+
+	stats := api.Stats()
+	if stats.ClockSkew < time.Hour-time.Second || stats.ClockSkew > time.Hour+time.Second || stats.ClockSkewObservedAt.IsZero() {
+		t.Errorf("Expected a skew of an hour, got %v", stats.ClockSkew)
+	}
+	if stats.Errors[StatsTransportError] != 1 {
+		t.Errorf("Undecodable responses should count as transport errors, got %v", stats.Errors)
+	}
+}
+
+func TestStatsWebSockets(t *testing.T) {
+	api := New("key", "c2VjcmV0")
+	api.RecordWebSocketConnection("ticker", true)
+	api.RecordWebSocketConnection("ticker", true)
+	api.RecordWebSocketConnection("book", true)
+	api.RecordWebSocketConnection("book", false)
+	api.RecordWebSocketMessage("ticker")
+	time.Sleep(10 * time.Millisecond)
+
+	stats := api.Stats()
+	ticker, book := stats.WebSockets["ticker"], stats.WebSockets["book"]
+	if ticker.Connections != 2 || ticker.LastMessage.IsZero() || ticker.LastMessageAge < 10*time.Millisecond {
+		t.Errorf("Unexpected ticker state %+v", ticker)
+	}
+	if book.Connections != 0 || !book.LastMessage.IsZero() || book.LastMessageAge != 0 {
+		t.Errorf("Unexpected book state %+v", book)
+	}
+}
@@ -0,0 +1,178 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergey-lipin/kraken-go-api-client/internal/chanutil"
+)
+
+// Trading modes reported by SystemStatus
+const (
+	SystemStatusOnline      = "online"      // Kraken is operating normally
+	SystemStatusMaintenance = "maintenance" // Kraken is offline for maintenance
+	SystemStatusCancelOnly  = "cancel_only" // Orders can be cancelled but not placed
+	SystemStatusPostOnly    = "post_only"   // Only post-only limit orders can be placed
+)
+
+// Known reports whether the trading mode is one of the SystemStatus
+// constants. Kraken may introduce new modes, which are decoded as they are.
+func (s SystemStatusResponse) Known() bool {
+	return isStringInSlice(s.Status, enumValues["system_status"])
+}
+
+// AcceptsOrders reports whether new orders can be placed in the trading
+// mode. Modes this client does not know are assumed not to accept orders.
+func (s SystemStatusResponse) AcceptsOrders() bool {
+	return s.Status == SystemStatusOnline || s.Status == SystemStatusPostOnly
+}
+
+// ErrExchangeDegraded is matched by every *ExchangeDegradedError
+var ErrExchangeDegraded = errors.New("exchange degraded")
+
+// ExchangeDegradedError is returned without contacting Kraken when a status
+// gate refuses a request because of the observed trading mode
+type ExchangeDegradedError struct {
+	Method     string    // Refused API method
+	Status     string    // Observed trading mode
+	ObservedAt time.Time // When the trading mode was observed
+}
+
+func (e *ExchangeDegradedError) Error() string {
+	return fmt.Sprintf("%s refused: exchange is in %s mode since %s", e.Method, e.Status, e.ObservedAt.Format(time.RFC3339))
+}
+
+// Unwrap makes errors.Is(err, ErrExchangeDegraded) hold
+func (e *ExchangeDegradedError) Unwrap() error {
+	return ErrExchangeDegraded
+}
+
+// StatusGate remembers the last observed trading mode and refuses requests
+// that cannot succeed in it. It is safe for concurrent use and may be shared
+// by several clients.
+type StatusGate struct {
+	mu         sync.Mutex
+	status     string
+	observedAt time.Time
+}
+
+// NewStatusGate returns a gate that lets every request through until a
+// degraded status is observed
+func NewStatusGate() *StatusGate {
+	return &StatusGate{status: SystemStatusOnline}
+}
+
+// Observe records the trading mode reported by Kraken
+func (g *StatusGate) Observe(status SystemStatusResponse) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status = status.Status
+	g.observedAt = time.Now()
+}
+
+// Status returns the last observed trading mode and when it was observed
+func (g *StatusGate) Status() (string, time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.status, g.observedAt
+}
+
+// allow refuses placing orders in maintenance and cancel_only mode, and
+// cancelling orders in maintenance mode. A nil gate allows everything.
+func (g *StatusGate) allow(method string) error {
+	if g == nil {
+		return nil
+	}
+	status, observedAt := g.Status()
+
+	refused := false
+	switch {
+	case isOrderPlacement(method):
+		refused = status == SystemStatusMaintenance || status == SystemStatusCancelOnly
+	case strings.HasPrefix(method, "Cancel"):
+		refused = status == SystemStatusMaintenance
+	}
+	if refused {
+		return &ExchangeDegradedError{Method: method, Status: status, ObservedAt: observedAt}
+	}
+	return nil
+}
+
+// isOrderPlacement reports whether the API method places or modifies orders
+func isOrderPlacement(method string) bool {
+	switch method {
+	case "AddOrder", "AddOrderBatch", "EditOrder":
+		return true
+	}
+	return false
+}
+
+// WithStatusGate makes the trading methods fail fast with an
+// *ExchangeDegradedError while the gate holds a degraded trading mode. The
+// gate is fed by SystemStatus calls and by WatchStatus.
+func (api *KrakenAPI) WithStatusGate(gate *StatusGate) *KrakenAPI {
+	api.statusGate = gate
+	return api
+}
+
+// StatusWatcher reports the trading mode transitions of the exchange. Its
+// methods are safe for concurrent use.
+type StatusWatcher struct {
+	// C receives the status whenever the trading mode changes, starting with
+	// the first observed one. A slow reader only gets the latest status:
+	// one not received yet is replaced by the next transition, so that
+	// polling never waits for the reader. C is closed when the context is
+	// done.
+	C <-chan SystemStatusResponse
+
+	mu  sync.Mutex
+	err error
+}
+
+// WatchStatus polls SystemStatus every interval, DefaultWatchInterval if not
+// positive, until ctx is done
+func (api *KrakenAPI) WatchStatus(ctx context.Context, interval time.Duration) *StatusWatcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ch := make(chan SystemStatusResponse, 1)
+	w := &StatusWatcher{C: ch}
+
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := ""
+		for {
+			status, err := api.SystemStatusWithContext(ctx)
+			w.mu.Lock()
+			w.err = err
+			w.mu.Unlock()
+
+			if err == nil && status.Status != last {
+				last = status.Status
+				chanutil.SendDropOldest(ch, *status)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return w
+}
+
+// Err returns the error of the last poll, nil if it succeeded
+func (w *StatusWatcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
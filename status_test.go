@@ -0,0 +1,139 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStatusGate(t *testing.T) {
+	status := SystemStatusCancelOnly
+	calls := make(map[string]int)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		calls[method]++
+		switch method {
+		case "SystemStatus":
+			return `{"error":[],"result":{"status":"` + status + `","timestamp":"2023-07-06T18:52:00Z"}}`
+		case "CancelOrder":
+			return `{"error":[],"result":{"count":1}}`
+		}
+		t.Fatalf("Unexpected call to %s", method)
+		return ""
+	}).WithStatusGate(NewStatusGate())
+
+	if _, err := api.SystemStatus(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := api.AddOrder("XBTUSD", "buy", OTMarket, "1", nil)
+	var degraded *ExchangeDegradedError
+	if !errors.As(err, &degraded) || degraded.Status != SystemStatusCancelOnly || degraded.ObservedAt.IsZero() {
+		t.Errorf("AddOrder should be refused in cancel_only mode, got %v", err)
+	}
+	if !errors.Is(err, ErrExchangeDegraded) {
+		t.Errorf("Refusal should match ErrExchangeDegraded")
+	}
+	if _, err := api.CancelOrder("O1"); err != nil {
+		t.Errorf("CancelOrder should be allowed in cancel_only mode, got %v", err)
+	}
+
+	status = SystemStatusMaintenance
+	api.SystemStatus()
+	if _, err := api.CancelOrder("O1"); !errors.Is(err, ErrExchangeDegraded) {
+		t.Errorf("CancelOrder should be refused in maintenance mode, got %v", err)
+	}
+	if calls["CancelOrder"] != 1 || calls["AddOrder"] != 0 {
+		t.Errorf("Refused requests should not reach Kraken, got %v", calls)
+	}
+}
+
+// statusFeed serves the SystemStatus polls with the statuses sent to it
+func statusFeed() (chan string, *KrakenAPI) {
+	statuses := make(chan string)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		select {
+		case status := <-statuses:
+			return `{"error":[],"result":{"status":"` + status + `","timestamp":"2023-07-06T18:52:00Z"}}`
+		case <-req.Context().Done():
+			return `{"error":["EService:Unavailable"]}`
+		}
+	})
+	return statuses, api
+}
+
+func TestWatchStatus(t *testing.T) {
+	statuses, api := statusFeed()
+	ctx, cancel := context.WithCancel(context.Background())
+	w := api.WatchStatus(ctx, time.Millisecond)
+
+	statuses <- SystemStatusOnline
+	if got := <-w.C; got.Status != SystemStatusOnline {
+		t.Errorf("First status should be reported, got %s", got.Status)
+	}
+	statuses <- SystemStatusOnline
+	statuses <- SystemStatusMaintenance
+	if got := <-w.C; got.Status != SystemStatusMaintenance {
+		t.Errorf("Only transitions should be reported, got %s", got.Status)
+	}
+	cancel()
+	for range w.C {
+	}
+}
+
+func TestWatchStatusSlowReader(t *testing.T) {
+	statuses, api := statusFeed()
+	ctx, cancel := context.WithCancel(context.Background())
+	w := api.WatchStatus(ctx, time.Millisecond)
+
+	// Polling goes on while nobody reads
+	for _, status := range []string{SystemStatusOnline, SystemStatusMaintenance, SystemStatusCancelOnly, SystemStatusCancelOnly} {
+		select {
+		case statuses <- status:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Polling stalled before %s", status)
+		}
+	}
+	if got := <-w.C; got.Status != SystemStatusCancelOnly {
+		t.Errorf("A slow reader should get the latest status, got %s", got.Status)
+	}
+	select {
+	case got := <-w.C:
+		t.Errorf("Older statuses should be dropped, got %s", got.Status)
+	default:
+	}
+	cancel()
+	for range w.C {
+	}
+}
+
+func TestSystemStatusUnknownMode(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":[],"result":{"status":"reduce_only","timestamp":"2023-07-06T18:52:00Z"}}`
+	})
+
+	status, err := api.SystemStatus()
+	if err != nil {
+		t.Fatalf("Unknown trading modes should be decoded, got %s", err)
+	}
+	if status.Status != "reduce_only" || status.Known() || status.AcceptsOrders() {
+		t.Errorf("Unknown trading modes should be kept and not accept orders, got %+v", status)
+	}
+	if !status.Timestamp.Equal(time.Date(2023, 7, 6, 18, 52, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected timestamp %s", status.Timestamp)
+	}
+
+	if _, err := api.WithEnumPolicy(EnumStrict).SystemStatus(); !errors.Is(err, ErrUnknownEnumValue) {
+		t.Errorf("Unknown trading modes should be rejected in strict mode, got %v", err)
+	}
+
+	for _, mode := range []string{SystemStatusOnline, SystemStatusPostOnly} {
+		if s := (SystemStatusResponse{Status: mode}); !s.Known() || !s.AcceptsOrders() {
+			t.Errorf("%s should accept orders", mode)
+		}
+	}
+	if s := (SystemStatusResponse{Status: SystemStatusCancelOnly}); s.AcceptsOrders() {
+		t.Errorf("cancel_only should not accept orders")
+	}
+}
@@ -0,0 +1,160 @@
+package krakenapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// resultStream decodes the map shaped result of a response one entry at a
+// time, handing each key and the decoder positioned on its value to entry
+type resultStream struct {
+	ctx   context.Context
+	entry func(key string, dec *json.Decoder) error
+}
+
+// decode reads the response envelope from r, streaming the entries of the
+// result. It stops at the first error returned by entry or when ctx is done.
+func (s *resultStream) decode(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	var apiErrors []string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("Could not execute request! #6 (%s)", err.Error())
+		}
+		switch key {
+		case "error":
+			err = dec.Decode(&apiErrors)
+		case "result":
+			err = s.decodeResult(dec)
+		default:
+			err = dec.Decode(&json.RawMessage{})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(apiErrors) > 0 {
+		return &APIError{Errors: apiErrors}
+	}
+	return nil
+}
+
+func (s *resultStream) decodeResult(dec *json.Decoder) error {
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+		token, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("Could not execute request! #6 (%s)", err.Error())
+		}
+		key, _ := token.(string)
+		if err := s.entry(key, dec); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// expectDelim reads the next token of dec, which must be delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("Could not execute request! #6 (%s)", err.Error())
+	}
+	if token != delim {
+		return fmt.Errorf("Could not execute request! #6 (expected %s, got %v)", delim, token)
+	}
+	return nil
+}
+
+// fileResult copies a result Kraken sends as a file instead of a JSON
+// envelope, e.g. the ZIP archive of RetrieveExport, to w
+type fileResult struct {
+	w io.Writer
+}
+
+// fileMimeTypes are the content types of the files Kraken sends
+var fileMimeTypes = []string{"application/zip", "application/octet-stream", "application/x-zip-compressed"}
+
+// fileErrorPreview bounds the part of an unexpected body quoted in errors
+const fileErrorPreview = 256
+
+// decode copies the body of a successful resp of a file type to w. A JSON
+// body carries the errors of the request; any other body, e.g. the error
+// page of a proxy, fails with its status and beginning.
+func (f *fileResult) decode(resp *http.Response) error {
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if resp.StatusCode == http.StatusOK && isStringInSlice(mimeType, fileMimeTypes) {
+		if _, err := io.Copy(f.w, resp.Body); err != nil {
+			return fmt.Errorf("Could not execute request! #3 (%s)", err.Error())
+		}
+		return nil
+	}
+
+	if mimeType == "application/json" {
+		var jsonData KrakenResponse
+		if err := json.NewDecoder(resp.Body).Decode(&jsonData); err != nil {
+			return fmt.Errorf("Could not execute request! #6 (%s)", err.Error())
+		}
+		if len(jsonData.Error) > 0 {
+			return &APIError{Errors: jsonData.Error}
+		}
+	}
+
+	preview, _ := io.ReadAll(io.LimitReader(resp.Body, fileErrorPreview))
+	return fmt.Errorf("Could not execute request #5! (HTTP %d with Content-Type '%s' instead of a file: %q)", resp.StatusCode, mimeType, preview)
+}
+
+// streamPublic queries a public method and streams the entries of its map
+// shaped result. It is not retried, since entries may already have been
+// handed to the caller when a request fails.
+func (api *KrakenAPI) streamPublic(ctx context.Context, method string, entry func(key string, dec *json.Decoder) error) error {
+	reqURL, err := api.endpointURL(Endpoint{Name: method})
+	if err != nil {
+		return err
+	}
+	_, err = api.doGet(ctx, reqURL, nil, nil, &resultStream{ctx: ctx, entry: entry})
+	return err
+}
+
+// AssetPairsStream decodes the asset pairs one at a time and calls fn with
+// each, without holding the whole catalogue in memory. It stops with the
+// error of fn or of ctx.
+func (api *KrakenAPI) AssetPairsStream(ctx context.Context, fn func(name string, info AssetPairInfo) error) error {
+	return api.streamPublic(ctx, "AssetPairs", func(key string, dec *json.Decoder) error {
+		var info AssetPairInfo
+		if err := dec.Decode(&info); err != nil {
+			return fmt.Errorf("Could not execute request! #6 (%s)", err.Error())
+		}
+		if err := api.checkEnums(info); err != nil {
+			return err
+		}
+		return fn(key, info)
+	})
+}
+
+// AssetsStream decodes the assets one at a time and calls fn with each. It
+// stops with the error of fn or of ctx.
+func (api *KrakenAPI) AssetsStream(ctx context.Context, fn func(name string, info AssetInfo) error) error {
+	return api.streamPublic(ctx, "Assets", func(key string, dec *json.Decoder) error {
+		var info AssetInfo
+		if err := dec.Decode(&info); err != nil {
+			return fmt.Errorf("Could not execute request! #6 (%s)", err.Error())
+		}
+		return fn(key, info)
+	})
+}
@@ -0,0 +1,141 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// pairsPayload generates an AssetPairs response of n pairs without holding it in memory
+type pairsPayload struct {
+	n, i int
+	buf  strings.Reader
+}
+
+func (p *pairsPayload) Read(b []byte) (int, error) {
+	for p.buf.Len() == 0 {
+		switch {
+		case p.i == 0:
+			p.buf.Reset(`{"error":[],"result":{`)
+		case p.i <= p.n:
+			sep := ","
+			if p.i == p.n {
+				sep = ""
+			}
+			p.buf.Reset(fmt.Sprintf(`"PAIR%[1]d":{"altname":"P%[1]d","wsname":"P/%[1]d","base":"B%[1]d","quote":"ZUSD",`+
+				`"pair_decimals":5,"lot_decimals":8,"fees":[[0,0.26],[50000,0.24],[100000,0.22]],`+
+				`"fees_maker":[[0,0.16],[50000,0.14],[100000,0.12]],"ordermin":"0.0001","status":"online"}%s`, p.i, sep))
+		case p.i == p.n+1:
+			p.buf.Reset(`}}`)
+		default:
+			return 0, io.EOF
+		}
+		p.i++
+	}
+	return p.buf.Read(b)
+}
+
+func newPairsAPI(n int) *KrakenAPI {
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(&pairsPayload{n: n}),
+			Request:    req,
+		}, nil
+	})
+	return NewWithClient("key", "c2VjcmV0", &http.Client{Transport: transport})
+}
+
+// heapInUse returns the live heap after a garbage collection
+func heapInUse() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func TestAssetPairsStream(t *testing.T) {
+	const n = 5000
+	api := newPairsAPI(n)
+
+	base := heapInUse()
+	var streamPeak uint64
+	count := 0
+	err := api.AssetPairsStream(context.Background(), func(name string, info AssetPairInfo) error {
+		count++
+		if name != fmt.Sprintf("PAIR%d", count) || info.Altname != fmt.Sprintf("P%d", count) {
+			return fmt.Errorf("unexpected pair %s %+v", name, info)
+		}
+		if count%1000 == 0 {
+			if heap := heapInUse(); heap > streamPeak {
+				streamPeak = heap
+			}
+		}
+		return nil
+	})
+	if err != nil || count != n {
+		t.Fatalf("AssetPairsStream() should visit %d pairs, got %d, %v", n, count, err)
+	}
+
+	base = minUint64(base, heapInUse())
+	pairs, err := api.AssetPairs()
+	if err != nil || len(*pairs) != n {
+		t.Fatalf("AssetPairs() should return %d pairs, got %v", n, err)
+	}
+	mapHeap := heapInUse()
+	runtime.KeepAlive(pairs)
+
+	if streamPeak > base && mapHeap > base && (streamPeak-base)*4 > mapHeap-base {
+		t.Errorf("Streaming should use a fraction of the memory of AssetPairs(), got %d and %d bytes",
+			streamPeak-base, mapHeap-base)
+	}
+}
+
+func minUint64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func TestAssetPairsStreamAborts(t *testing.T) {
+	api := newPairsAPI(100)
+
+	stop := errors.New("stop")
+	count := 0
+	err := api.AssetPairsStream(context.Background(), func(name string, info AssetPairInfo) error {
+		count++
+		if count == 10 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || count != 10 {
+		t.Errorf("AssetPairsStream() should stop with the callback's error, got %v after %d pairs", err, count)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count = 0
+	err = api.AssetPairsStream(ctx, func(name string, info AssetPairInfo) error {
+		count++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || count != 1 {
+		t.Errorf("AssetPairsStream() should stop when ctx is done, got %v after %d pairs", err, count)
+	}
+
+	api = newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":["EGeneral:Temporary lockout"]}`
+	})
+	err = api.AssetsStream(context.Background(), func(name string, info AssetInfo) error { return nil })
+	if !hasErrorCode(err, "EGeneral:Temporary lockout") {
+		t.Errorf("AssetsStream() should return Kraken's error, got %v", err)
+	}
+}
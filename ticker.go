@@ -0,0 +1,163 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TickerStats holds display ready figures computed from a pair's ticker.
+//
+// Kraken's opening price ("o") is the price at today's 00:00 UTC, not 24 hours
+// ago, so ChangeTodayPercent is the change since the UTC day started. The
+// ticker carries no price from exactly 24 hours ago; ChangeVs24hVWAPPercent
+// compares the last price to the rolling 24 hour volume weighted average
+// instead, which is the closest rolling figure a single Ticker call provides.
+type TickerStats struct {
+	Pair                   string
+	Last                   float64   // Last trade price
+	Bid                    float64   // Best bid price
+	Ask                    float64   // Best ask price
+	OpenToday              float64   // Price at today's 00:00 UTC
+	ChangeTodayPercent     float64   // Change of Last against OpenToday in percent
+	VWAP24h                float64   // Volume weighted average price of the last 24 hours
+	ChangeVs24hVWAPPercent float64   // Change of Last against VWAP24h in percent
+	Volume24h              float64   // Volume of the last 24 hours (base currency)
+	QuoteVolume24h         float64   // Volume24h multiplied by VWAP24h (quote currency)
+	SpreadBps              float64   // Ask minus bid relative to the mid price, in basis points
+	Time                   time.Time // When the ticker was fetched
+}
+
+// NewTickerStats computes TickerStats from the ticker of pair fetched at t
+func NewTickerStats(pair string, info PairTickerInfo, t time.Time) (TickerStats, error) {
+	stats := TickerStats{Pair: pair, OpenToday: info.OpeningPrice, Time: t}
+
+	fields := []struct {
+		name  string
+		list  []string
+		index int
+		dest  *float64
+	}{
+		{"last", info.Close, 0, &stats.Last},
+		{"bid", info.Bid, 0, &stats.Bid},
+		{"ask", info.Ask, 0, &stats.Ask},
+	}
+	for _, field := range fields {
+		if len(field.list) <= field.index {
+			return stats, fmt.Errorf("ticker of %s has no %s", pair, field.name)
+		}
+		value, err := strconv.ParseFloat(field.list[field.index], 64)
+		if err != nil {
+			return stats, fmt.Errorf("ticker of %s has invalid %s: %s", pair, field.name, err)
+		}
+		*field.dest = value
+	}
+
+	vwap, err := info.VWAPWindows()
+	if err != nil {
+		return stats, fmt.Errorf("%s: %s", pair, err)
+	}
+	volume, err := info.VolumeWindows()
+	if err != nil {
+		return stats, fmt.Errorf("%s: %s", pair, err)
+	}
+	stats.VWAP24h, stats.Volume24h = vwap.Last24h, volume.Last24h
+
+	stats.ChangeTodayPercent = percentChange(stats.OpenToday, stats.Last)
+	stats.ChangeVs24hVWAPPercent = percentChange(stats.VWAP24h, stats.Last)
+	stats.QuoteVolume24h = stats.Volume24h * stats.VWAP24h
+	if mid := (stats.Bid + stats.Ask) / 2; mid > 0 {
+		stats.SpreadBps = (stats.Ask - stats.Bid) / mid * 10000
+	}
+
+	return stats, nil
+}
+
+// TickerSummary fetches the tickers of pairs with a single request and
+// returns their TickerStats indexed by the pair names Kraken answered with
+func (api *KrakenAPI) TickerSummary(pairs []string) (map[string]TickerStats, error) {
+	return api.TickerSummaryWithContext(context.Background(), pairs)
+}
+
+// TickerSummaryWithContext fetches the tickers of pairs with a single request
+// and returns their TickerStats indexed by the pair names Kraken answered with
+func (api *KrakenAPI) TickerSummaryWithContext(ctx context.Context, pairs []string) (map[string]TickerStats, error) {
+	resp, err := api.TickerWithContext(ctx, pairs...)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	summary := make(map[string]TickerStats, len(*resp))
+	for pair, info := range *resp {
+		stats, err := NewTickerStats(pair, info, now)
+		if err != nil {
+			return nil, err
+		}
+		summary[pair] = stats
+	}
+
+	return summary, nil
+}
+
+// percentChange returns the change from base to value in percent, or 0 without a base
+func percentChange(base, value float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return (value - base) / base * 100
+}
+
+// TickerWindow holds a ticker figure over the two windows Kraken reports:
+// since today's 00:00 UTC and over the rolling last 24 hours
+type TickerWindow[T any] struct {
+	Today   T
+	Last24h T
+}
+
+// VolumeWindows returns the traded volume in the base currency
+func (info PairTickerInfo) VolumeWindows() (TickerWindow[float64], error) {
+	return decimalWindows("volume", info.Volume)
+}
+
+// VWAPWindows returns the volume weighted average price
+func (info PairTickerInfo) VWAPWindows() (TickerWindow[float64], error) {
+	return decimalWindows("vwap", info.VolumeAveragePrice)
+}
+
+// TradesWindows returns the number of trades
+func (info PairTickerInfo) TradesWindows() (TickerWindow[int], error) {
+	if len(info.Trades) != 2 {
+		return TickerWindow[int]{}, fmt.Errorf("ticker trades should hold 2 values, got %d", len(info.Trades))
+	}
+	return TickerWindow[int]{Today: info.Trades[0], Last24h: info.Trades[1]}, nil
+}
+
+// LowWindows returns the lowest price
+func (info PairTickerInfo) LowWindows() (TickerWindow[float64], error) {
+	return decimalWindows("low", info.Low)
+}
+
+// HighWindows returns the highest price
+func (info PairTickerInfo) HighWindows() (TickerWindow[float64], error) {
+	return decimalWindows("high", info.High)
+}
+
+// decimalWindows decodes a [today, last 24 hours] array of decimal strings
+func decimalWindows(name string, list []string) (TickerWindow[float64], error) {
+	var window TickerWindow[float64]
+	if len(list) != 2 {
+		return window, fmt.Errorf("ticker %s should hold 2 values, got %d", name, len(list))
+	}
+	today, err := strconv.ParseFloat(list[0], 64)
+	if err != nil {
+		return window, fmt.Errorf("ticker has invalid %s: %s", name, err)
+	}
+	last24h, err := strconv.ParseFloat(list[1], 64)
+	if err != nil {
+		return window, fmt.Errorf("ticker has invalid %s: %s", name, err)
+	}
+	window.Today, window.Last24h = today, last24h
+	return window, nil
+}
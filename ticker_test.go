@@ -0,0 +1,168 @@
+package krakenapi
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTickerSummary(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":[],"result":{"XXBTZUSD":{
+			"a":["30010.0","1","1.000"],"b":["29990.0","2","2.000"],"c":["30000.0","0.1"],
+			"v":["100.0","200.0"],"p":["29000.0","29500.0"],"t":[10,20],
+			"l":["28000.0","27000.0"],"h":["31000.0","32000.0"],"o":"25000.0"}}}`
+	})
+
+	summary, err := api.TickerSummary([]string{"XBTUSD"})
+	if err != nil {
+		t.Fatalf("TickerSummary() should not return an error, got %s", err)
+	}
+
+	stats, ok := summary["XXBTZUSD"]
+	if !ok {
+		t.Fatalf("TickerSummary() should index by Kraken's pair name, got %v", summary)
+	}
+	if stats.Last != 30000 || stats.ChangeTodayPercent != 20 {
+		t.Errorf("Expected last 30000 and +20%% since UTC open, got %+v", stats)
+	}
+	if stats.QuoteVolume24h != 200*29500 {
+		t.Errorf("Quote volume should use the 24h figures, got %f", stats.QuoteVolume24h)
+	}
+	if math.Abs(stats.SpreadBps-20/30000.0*10000) > 1e-9 {
+		t.Errorf("Unexpected spread %f bps", stats.SpreadBps)
+	}
+	if math.Abs(stats.ChangeVs24hVWAPPercent-500/29500.0*100) > 1e-9 {
+		t.Errorf("Unexpected change vs VWAP %f", stats.ChangeVs24hVWAPPercent)
+	}
+}
+
+func TestNewTickerStatsIncomplete(t *testing.T) {
+	if _, err := NewTickerStats("XXBTZUSD", PairTickerInfo{}, time.Time{}); err == nil {
+		t.Errorf("NewTickerStats() should reject an empty ticker")
+	}
+}
+
+func TestPairTickerInfoWindows(t *testing.T) {
+	info := PairTickerInfo{
+		Volume: []string{"100.0", "200.0"}, VolumeAveragePrice: []string{"29000.0", "29500.0"}, Trades: []int{10, 20},
+		Low: []string{"28000.0", "27000.0"}, High: []string{"31000.0", "32000.0"},
+	}
+	volume, err := info.VolumeWindows()
+	if err != nil || volume != (TickerWindow[float64]{Today: 100, Last24h: 200}) {
+		t.Errorf("Unexpected volume %+v, %v", volume, err)
+	}
+	vwap, err := info.VWAPWindows()
+	if err != nil || vwap.Today != 29000 || vwap.Last24h != 29500 {
+		t.Errorf("Unexpected vwap %+v, %v", vwap, err)
+	}
+	trades, err := info.TradesWindows()
+	if err != nil || trades != (TickerWindow[int]{Today: 10, Last24h: 20}) {
+		t.Errorf("Unexpected trades %+v, %v", trades, err)
+	}
+	low, _ := info.LowWindows()
+	high, _ := info.HighWindows()
+	if low.Last24h != 27000 || high.Today != 31000 {
+		t.Errorf("Unexpected low %+v or high %+v", low, high)
+	}
+
+	info.Volume = []string{"100.0"}
+	if _, err := info.VolumeWindows(); err == nil {
+		t.Errorf("A single value should be rejected rather than read as either window")
+	}
+	info.Trades = []int{1, 2, 3}
+	if _, err := info.TradesWindows(); err == nil {
+		t.Errorf("Three values should be rejected")
+	}
+	info.High = []string{"31000.0", "x"}
+	if _, err := info.HighWindows(); err == nil {
+		t.Errorf("Invalid decimals should be rejected")
+	}
+}
+
+func TestTickerMultiplePairs(t *testing.T) {
+	var requested []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		requested = req.URL.Query()["pair"]
+		return `{"error":[],"result":{
+			"XXBTZUSD":{"a":["30300.1","1","1.000"],"b":["30300.0","1","1.000"],"c":["30300.0","0.01"]},
+			"XETHZUSD":{"a":["1870.1","1","1.000"],"b":["1870.0","1","1.000"],"c":["1870.0","0.5"]},
+			"DOTUSD":{"a":["5.1","1","1.000"],"b":["5.0","1","1.000"],"c":["5.0","10"]}}}`
+	})
+
+	pairs := []string{"XBTUSD", "ETHUSD", "DOTUSD"}
+	resp, err := api.Ticker(pairs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requested) != 1 || requested[0] != "XBTUSD,ETHUSD,DOTUSD" {
+		t.Errorf("Expected one comma joined pair parameter, got %v", requested)
+	}
+
+	assetPairs := AssetPairsResponse{
+		"XXBTZUSD": {Altname: "XBTUSD", WSName: "XBT/USD"},
+		"XETHZUSD": {Altname: "ETHUSD", WSName: "ETH/USD"},
+		"DOTUSD":   {Altname: "DOTUSD", WSName: "DOT/USD"},
+	}
+	if len(*resp) != len(pairs) {
+		t.Errorf("Expected a ticker per pair, got %d", len(*resp))
+	}
+	for _, pair := range pairs {
+		names, ok := assetPairs.FindPair(pair)
+		if !ok || resp.GetPairTickerInfo(names.Name).Close == nil {
+			t.Errorf("Missing the ticker of %s", pair)
+		}
+	}
+
+	requested = nil
+	if _, err := api.Ticker("XBTUSD", ""); err == nil || requested != nil {
+		t.Errorf("Empty pair names should fail before sending, got %v", err)
+	}
+}
+
+// testAllTickers returns a Ticker result holding n pairs
+func testAllTickers(n int) string {
+	tickers := make([]string, n)
+	for i := range tickers {
+		tickers[i] = fmt.Sprintf(`"PAIR%dUSD":{"a":["30300.10000","1","1.000"],"b":["30300.00000","2","2.000"],`+
+			`"c":["30300.00000","0.00100000"],"v":["1234.56789012","2345.67890123"],"p":["30250.12345","30200.54321"],`+
+			`"t":[12345,23456],"l":["30000.00000","29900.00000"],"h":["30500.00000","30600.00000"],"o":"30100.00000"}`, i)
+	}
+	return `{"error":[],"result":{` + strings.Join(tickers, ",") + `}}`
+}
+
+func TestTickerAllPairs(t *testing.T) {
+	var query url.Values
+	body := testAllTickers(700)
+	api := newTestAPI(func(method string, req *http.Request) string {
+		query = req.URL.Query()
+		return body
+	})
+
+	resp, err := api.Ticker()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query.Has("pair") {
+		t.Errorf("The pair parameter should be left out, got %v", query)
+	}
+	if len(*resp) != 700 || resp.GetPairTickerInfo("PAIR699USD").Trades[1] != 23456 {
+		t.Errorf("Expected every ticker decoded, got %d", len(*resp))
+	}
+}
+
+func BenchmarkTickerAllPairs(b *testing.B) {
+	body := testAllTickers(700)
+	api := newTestAPI(func(method string, req *http.Request) string { return body })
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := api.Ticker(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
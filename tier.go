@@ -0,0 +1,129 @@
+package krakenapi
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Tier is the verification tier of a Kraken account, which determines its
+// rate limits and withdrawal limits
+type Tier int
+
+// Verification tiers
+const (
+	TierUnknown Tier = iota
+	TierStarter
+	TierIntermediate
+	TierPro
+)
+
+// String returns the name Kraken uses for the tier
+func (t Tier) String() string {
+	switch t {
+	case TierStarter:
+		return "Starter"
+	case TierIntermediate:
+		return "Intermediate"
+	case TierPro:
+		return "Pro"
+	}
+	return "Unknown"
+}
+
+// TierLimits are the limits Kraken documents for a verification tier
+type TierLimits struct {
+	MaxCounter         float64 // Ceiling of the private API call counter
+	DecayPerSecond     float64 // Decrease of the call counter per second
+	DailyWithdrawalUSD float64 // Daily withdrawal limit in USD, may differ by region
+}
+
+// tierLimits holds the limits of the known tiers
+var tierLimits = map[Tier]TierLimits{
+	TierStarter:      {MaxCounter: 15, DecayPerSecond: 0.33, DailyWithdrawalUSD: 5000},
+	TierIntermediate: {MaxCounter: 20, DecayPerSecond: 0.5, DailyWithdrawalUSD: 500000},
+	TierPro:          {MaxCounter: 20, DecayPerSecond: 1, DailyWithdrawalUSD: 10000000},
+}
+
+// Limits returns the documented limits of the tier, or zero limits for an
+// unknown tier
+func (t Tier) Limits() TierLimits {
+	return tierLimits[t]
+}
+
+// tierWindow is the period over which call rates are compared with the tier
+const tierWindow = time.Minute
+
+// sustainedCost returns the call counter cost the tier can spend in a window
+func (l TierLimits) sustainedCost() float64 {
+	return l.MaxCounter + l.DecayPerSecond*tierWindow.Seconds()
+}
+
+// tierUsage sums the cost of the private calls made in the current window
+type tierUsage struct {
+	mu     sync.Mutex
+	start  time.Time
+	cost   float64
+	warned bool
+}
+
+// add records a call and returns the cost of the window if it is the first
+// call of the window to exceed budget, or zero
+func (u *tierUsage) add(cost, budget float64, now time.Time) float64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if now.Sub(u.start) >= tierWindow {
+		u.start, u.cost, u.warned = now, 0, false
+	}
+	u.cost += cost
+	if u.cost <= budget || u.warned {
+		return 0
+	}
+	u.warned = true
+	return u.cost
+}
+
+// WithAccountTier configures the client for an account of the given tier. It
+// installs a rate limiter using the limits of the tier unless one was set
+// with WithRateLimiter, fills WithdrawInfoResponse.DailyLimitUSD, and
+// reports through Hooks.OnWarning when private calls are made faster than
+// the tier can sustain. Tiers without documented limits leave the rate
+// limiter alone.
+func (api *KrakenAPI) WithAccountTier(tier Tier) *KrakenAPI {
+	limits := tier.Limits()
+	api.tier = tier
+	api.tierUsage = &tierUsage{}
+	if api.limiter != nil && !api.tierLimiter {
+		return api
+	}
+	if limits.MaxCounter > 0 && limits.DecayPerSecond > 0 {
+		api.limiter = NewRateLimiter(limits.MaxCounter, limits.DecayPerSecond)
+		api.tierLimiter = true
+	} else if api.tierLimiter {
+		api.limiter, api.tierLimiter = nil, false
+	}
+	return api
+}
+
+// observeTierUsage warns through the hooks once per window in which the
+// private calls cost more than the configured tier can sustain
+func (api *KrakenAPI) observeTierUsage(method string, cost float64) {
+	if api.tier == TierUnknown || api.hooks.OnWarning == nil {
+		return
+	}
+	limits := api.tier.Limits()
+	used := api.tierUsage.add(cost, limits.sustainedCost(), time.Now())
+	if used == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("private calls cost %g in the last minute, more than a %s account sustains (%g); requests are being delayed",
+		used, api.tier, limits.sustainedCost())
+	for tier := api.tier + 1; tier <= TierPro; tier++ {
+		if used <= tier.Limits().sustainedCost() {
+			message += fmt.Sprintf("; this rate needs a %s account", tier)
+			break
+		}
+	}
+	api.hooks.OnWarning(WarningEvent{Method: method, Message: message})
+}
@@ -0,0 +1,66 @@
+package krakenapi
+
+import (
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestWithAccountTier(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method == "WithdrawInfo" {
+			return `{"error":[],"result":{"method":"Bitcoin","limit":"1","amount":"0.5","fee":"0.0001"}}`
+		}
+		return `{"error":[],"result":{"ZUSD":"1.0"}}`
+	})
+	var warnings []WarningEvent
+	api.WithHooks(Hooks{OnWarning: func(e WarningEvent) { warnings = append(warnings, e) }}).WithAccountTier(TierStarter)
+
+	if api.limiter == nil || api.limiter.max != 15 || api.limiter.decay != 0.33 {
+		t.Fatalf("WithAccountTier should configure the rate limiter of the tier, got %+v", api.limiter)
+	}
+
+	info, err := api.WithdrawInfo("XBT", "wallet", big.NewFloat(0.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DailyLimitUSD != TierStarter.Limits().DailyWithdrawalUSD {
+		t.Errorf("WithdrawInfo should carry the daily limit of the tier, got %v", info.DailyLimitUSD)
+	}
+
+	// Keep the call counter but do not wait, to simulate a burst
+	api.WithRateLimiter(nil)
+	for i := 0; i < 40; i++ {
+		if _, err := api.Balance(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("Expected one warning per window, got %v", warnings)
+	}
+	if !strings.Contains(warnings[0].Message, "Starter") || !strings.Contains(warnings[0].Message, "Intermediate account") {
+		t.Errorf("The warning should name the configured and the needed tier, got %q", warnings[0].Message)
+	}
+}
+
+func TestWithAccountTierLimiter(t *testing.T) {
+	api := New("key", "c2VjcmV0").WithAccountTier(TierUnknown)
+	if api.limiter != nil {
+		t.Errorf("An unknown tier should not install a rate limiter, got %+v", api.limiter)
+	}
+
+	shared := NewRateLimiter(10, 2)
+	api.WithRateLimiter(shared).WithAccountTier(TierPro)
+	if api.limiter != shared {
+		t.Errorf("A rate limiter set explicitly should be kept")
+	}
+
+	api = New("key", "c2VjcmV0").WithAccountTier(TierStarter).WithAccountTier(TierPro)
+	if api.limiter == nil || api.limiter.max != 20 || api.limiter.decay != 1 {
+		t.Errorf("A new tier should replace the limiter of the previous one, got %+v", api.limiter)
+	}
+	if api.WithAccountTier(TierUnknown).limiter != nil {
+		t.Errorf("An unknown tier should remove the limiter of the previous tier")
+	}
+}
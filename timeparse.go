@@ -0,0 +1,111 @@
+package krakenapi
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rfc1123Layouts are the layouts of TimeResponse.Rfc1123, which Kraken sends
+// with a two digit year and a numeric zone, e.g. "Thu, 06 Jul 23 18:50:48 +0000"
+var rfc1123Layouts = []string{
+	"Mon, 02 Jan 06 15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+var errInvalidTime = errors.New("invalid Kraken timestamp")
+
+// ParseKrakenTime converts the timestamp shapes found in Kraken responses
+// into a time.Time: integer or float unix seconds, decimal strings of unix
+// seconds with up to nanosecond precision, json.Number, and RFC 1123 strings.
+//
+// Float seconds carry about microsecond precision at current dates, so their
+// fraction is rounded to the microsecond. Decimal strings are parsed exactly
+// and always use a dot as decimal separator, whatever the locale.
+func ParseKrakenTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case int64:
+		return time.Unix(t, 0), nil
+	case int:
+		return time.Unix(int64(t), 0), nil
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return time.Time{}, errInvalidTime
+		}
+		return unixFloat(t), nil
+	case json.Number:
+		return parseTimeString(string(t))
+	case string:
+		return parseTimeString(t)
+	}
+	return time.Time{}, errInvalidTime
+}
+
+// optionalTime converts unix seconds decoded from JSON through
+// ParseKrakenTime, zero for a zero timestamp. JSON numbers are never NaN or
+// infinite, which ParseKrakenTime rejects.
+func optionalTime(seconds float64) time.Time {
+	if seconds == 0 {
+		return time.Time{}
+	}
+	t, _ := ParseKrakenTime(seconds)
+	return t
+}
+
+// unixFloat converts Kraken's fractional unix seconds, rounding to the microsecond
+func unixFloat(t float64) time.Time {
+	sec, frac := math.Modf(t)
+	usec := math.Round(frac * 1e6)
+	return time.Unix(int64(sec), int64(usec)*int64(time.Microsecond))
+}
+
+// parseTimeString parses decimal unix seconds exactly, or an RFC 1123 time
+func parseTimeString(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errInvalidTime
+	}
+	if c := s[0]; c < '0' || c > '9' {
+		if c != '-' {
+			return parseRFC1123(s)
+		}
+	}
+
+	whole, fraction := s, ""
+	if dot := strings.IndexByte(s, '.'); dot >= 0 {
+		whole, fraction = s[:dot], s[dot+1:]
+	}
+	sec, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || len(fraction) > 9 {
+		return time.Time{}, errInvalidTime
+	}
+	var nsec int64
+	for i := 0; i < 9; i++ {
+		nsec *= 10
+		if i < len(fraction) {
+			digit := fraction[i]
+			if digit < '0' || digit > '9' {
+				return time.Time{}, errInvalidTime
+			}
+			nsec += int64(digit - '0')
+		}
+	}
+	if strings.HasPrefix(whole, "-") {
+		nsec = -nsec
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+func parseRFC1123(s string) (time.Time, error) {
+	for _, layout := range rfc1123Layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errInvalidTime
+}
@@ -0,0 +1,89 @@
+package krakenapi
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseKrakenTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		input interface{}
+		want  time.Time
+	}{
+		{"int", 1688669448, time.Unix(1688669448, 0)},
+		{"int64", int64(1688669448), time.Unix(1688669448, 0)},
+		{"float", 1688669448.0, time.Unix(1688669448, 0)},
+		{"float fraction", 1688669448.1234, time.Unix(1688669448, 123400000)},
+		{"float microseconds", 1688669448.123456, time.Unix(1688669448, 123456000)},
+		{"string", "1688669448", time.Unix(1688669448, 0)},
+		{"string fraction", "1688669448.1234", time.Unix(1688669448, 123400000)},
+		{"string nanoseconds", "1688669448.123456789", time.Unix(1688669448, 123456789)},
+		{"string trailing dot", "1688669448.", time.Unix(1688669448, 0)},
+		{"json.Number", json.Number("1688669448.5"), time.Unix(1688669448, 500000000)},
+		{"rfc1123 kraken", "Thu, 06 Jul 23 18:50:48 +0000", time.Unix(1688669448, 0)},
+		{"rfc1123", "Thu, 06 Jul 2023 18:50:48 GMT", time.Unix(1688669448, 0)},
+		{"rfc1123z", "Thu, 06 Jul 2023 20:50:48 +0200", time.Unix(1688669448, 0)},
+		{"time", time.Unix(1, 2), time.Unix(1, 2)},
+	}
+	for _, test := range tests {
+		got, err := ParseKrakenTime(test.input)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", test.name, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("%s: expected %s, got %s", test.name, test.want.Format(time.RFC3339Nano), got.Format(time.RFC3339Nano))
+		}
+	}
+
+	invalid := []interface{}{nil, "", "1688669448,5", "1688669448.1234567891", "1688669448.12a", "abc", math.NaN(), math.Inf(1), []int{1}}
+	for _, input := range invalid {
+		if _, err := ParseKrakenTime(input); err == nil {
+			t.Errorf("ParseKrakenTime(%#v) should fail", input)
+		}
+	}
+}
+
+func TestDecodedTimes(t *testing.T) {
+	var order Order
+	if err := json.Unmarshal([]byte(`{"opentm":1688666559.8974,"closetm":1688666560.1234,"starttm":0,"expiretm":0}`), &order); err != nil {
+		t.Fatal(err)
+	}
+	if !order.OpenTimestamp().Equal(time.Unix(1688666559, 897400000)) || !order.CloseTimestamp().Equal(time.Unix(1688666560, 123400000)) {
+		t.Errorf("Unexpected order times %v %v", order.OpenTimestamp(), order.CloseTimestamp())
+	}
+	if !order.StartTimestamp().IsZero() || !order.ExpireTimestamp().IsZero() {
+		t.Errorf("Unset order times should be zero, got %v %v", order.StartTimestamp(), order.ExpireTimestamp())
+	}
+
+	trade := TradeHistoryInfo{Time: 1688667796.8802}
+	ledger := LedgerInfo{Time: 1688667796.8802}
+	if want := time.Unix(1688667796, 880200000); !trade.Timestamp().Equal(want) || !ledger.Timestamp().Equal(want) {
+		t.Errorf("Unexpected trade and ledger times %v %v", trade.Timestamp(), ledger.Timestamp())
+	}
+
+	if _, err := NewOHLC([]interface{}{"yesterday", "1", "1", "1", "1", "1", "1", 1.0}); err == nil {
+		t.Errorf("NewOHLC should report an invalid time")
+	}
+}
+
+func BenchmarkParseKrakenTime(b *testing.B) {
+	inputs := map[string]interface{}{
+		"float":   1688669448.1234,
+		"string":  "1688669448.123456789",
+		"rfc1123": "Thu, 06 Jul 23 18:50:48 +0000",
+	}
+	for name, input := range inputs {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ParseKrakenTime(input); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
-	"reflect"
 	"strconv"
 	"time"
 )
@@ -31,33 +30,47 @@ type TimeResponse struct {
 	Rfc1123 string
 }
 
+// SystemStatusResponse represents the trading mode of the exchange
+type SystemStatusResponse struct {
+	Status    string    `json:"status" enum:"system_status"` // One of the SystemStatus constants
+	Timestamp time.Time `json:"timestamp"`                   // Server time of the status
+}
+
 // AssetPairsResponse includes asset pair informations
 type AssetPairsResponse map[string]AssetPairInfo
 
 // AssetPairInfo represents asset pair information
 type AssetPairInfo struct {
-	Altname            string      `json:"altname"`              // Alternate pair name
-	AssetClassBase     string      `json:"aclass_base"`          // Asset class of base component
-	Base               string      `json:"base"`                 // Asset ID of base component
-	AssetClassQuote    string      `json:"aclass_quote"`         // Asset class of quote component
-	Quote              string      `json:"quote"`                // Asset ID of quote component
-	PairDecimals       int         `json:"pair_decimals"`        // Scaling decimal places for pair
-	CostDecimals       int         `json:"cost_decimals"`        // Scaling decimal places for cost
-	LotDecimals        int         `json:"lot_decimals"`         // Scaling decimal places for volume
-	LotMultiplier      int         `json:"lot_multiplier"`       // Amount to multiply lot volume by to get currency volume
-	LeverageBuy        []float64   `json:"leverage_buy"`         // Array of leverage amounts available when buying
-	LeverageSell       []float64   `json:"leverage_sell"`        // Array of leverage amounts available when selling
-	Fees               [][]float64 `json:"fees"`                 // Fee schedule array in [<volume>, <percent fee>] tuples
-	FeesMaker          [][]float64 `json:"fees_maker"`           // Maker fee schedule array in [<volume>, <percent fee>] tuples (if on maker/taker)
-	FeeVolumeCurrency  string      `json:"fee_volume_currency"`  // Volume discount currency
-	MarginCall         int         `json:"margin_call"`          // Margin call level
-	MarginStop         int         `json:"margin_stop"`          // Stop-out/liquidation margin level
-	OrderMin           string      `json:"ordermin"`             // Minimum order size (in terms of base currency)
-	CostMin            string      `json:"costmin"`              // Minimum order cost (in terms of quote currency)
-	TickSize           string      `json:"tick_size"`            // Minimum increment between valid price levels
-	Status             string      `json:"status"`               // Status of asset. Possible values: online, cancel_only, post_only, limit_only, reduce_only.
-	LongPositionLimit  int         `json:"long_position_limit"`  // Maximum long margin position size (in terms of base currency)
-	ShortPositionLimit int         `json:"short_position_limit"` // Maximum short margin position size (in terms of base currency)
+	Altname            string      `json:"altname"`                   // Alternate pair name
+	WSName             string      `json:"wsname"`                    // WebSocket pair name (if available)
+	AssetClassBase     string      `json:"aclass_base"`               // Asset class of base component
+	Base               string      `json:"base"`                      // Asset ID of base component
+	AssetClassQuote    string      `json:"aclass_quote"`              // Asset class of quote component
+	Quote              string      `json:"quote"`                     // Asset ID of quote component
+	PairDecimals       int         `json:"pair_decimals"`             // Scaling decimal places for pair
+	CostDecimals       int         `json:"cost_decimals"`             // Scaling decimal places for cost
+	LotDecimals        int         `json:"lot_decimals"`              // Scaling decimal places for volume
+	LotMultiplier      int         `json:"lot_multiplier"`            // Amount to multiply lot volume by to get currency volume
+	LeverageBuy        []float64   `json:"leverage_buy"`              // Array of leverage amounts available when buying
+	LeverageSell       []float64   `json:"leverage_sell"`             // Array of leverage amounts available when selling
+	Fees               [][]float64 `json:"fees"`                      // Fee schedule array in [<volume>, <percent fee>] tuples
+	FeesMaker          [][]float64 `json:"fees_maker"`                // Maker fee schedule array in [<volume>, <percent fee>] tuples (if on maker/taker)
+	FeeVolumeCurrency  string      `json:"fee_volume_currency"`       // Volume discount currency
+	MarginCall         int         `json:"margin_call"`               // Margin call level
+	MarginStop         int         `json:"margin_stop"`               // Stop-out/liquidation margin level
+	OrderMin           string      `json:"ordermin"`                  // Minimum order size (in terms of base currency)
+	CostMin            string      `json:"costmin"`                   // Minimum order cost (in terms of quote currency)
+	TickSize           string      `json:"tick_size"`                 // Minimum increment between valid price levels
+	Status             string      `json:"status" enum:"pair_status"` // Status of asset. Possible values: online, cancel_only, post_only, limit_only, reduce_only.
+	LongPositionLimit  int         `json:"long_position_limit"`       // Maximum long margin position size (in terms of base currency)
+	ShortPositionLimit int         `json:"short_position_limit"`      // Maximum short margin position size (in terms of base currency)
+}
+
+// AssetsOpts selects the assets returned by Assets. The zero AssetsOpts
+// returns every asset.
+type AssetsOpts struct {
+	Assets []string // Asset codes to return, all of them if empty
+	AClass string   // Asset class, e.g. "currency"; Kraken's default if empty
 }
 
 // AssetsResponse includes asset informations
@@ -78,7 +91,24 @@ type AssetInfo struct {
 // BalanceResponse represents the account's balances (list of currencies)
 type BalanceResponse map[string]string
 
-// TradeBalanceResponse struct used as the response for the TradeBalance method
+// BalanceExResponse represents the account's extended balances indexed by asset
+type BalanceExResponse map[string]ExtendedBalance
+
+// ExtendedBalance represents the balance of an asset including credit and holds
+type ExtendedBalance struct {
+	Balance    float64 `json:"balance,string"`     // Total balance
+	Credit     float64 `json:"credit,string"`      // Credit available
+	CreditUsed float64 `json:"credit_used,string"` // Credit used
+	HoldTrade  float64 `json:"hold_trade,string"`  // Amount held by open orders
+}
+
+// Available returns the amount that can be used for new orders
+func (b ExtendedBalance) Available() float64 {
+	return b.Balance + b.Credit - b.CreditUsed - b.HoldTrade
+}
+
+// TradeBalanceResponse struct used as the response for the TradeBalance method.
+// Fields Kraken leaves out, e.g. MarginLevel without open positions, are zero.
 type TradeBalanceResponse struct {
 	EquivalentBalance         float64 `json:"eb,string"`
 	TradeBalance              float64 `json:"tb,string"`
@@ -89,6 +119,28 @@ type TradeBalanceResponse struct {
 	Equity                    float64 `json:"e,string"`
 	FreeMargin                float64 `json:"mf,string"`
 	MarginLevel               float64 `json:"ml,string"`
+	UnexecutedValue           float64 `json:"uv,string"` // Value of the unfilled and partially filled orders
+}
+
+// PositionInfo represents an open margin position
+type PositionInfo struct {
+	OrderTxID    string  `json:"ordertxid"` // Order that opened the position
+	PosStatus    string  `json:"posstatus"`
+	Pair         string  `json:"pair"`
+	Time         float64 `json:"time"` // Unix timestamp of the opening trade
+	Type         string  `json:"type"` // Direction of the opening order, "buy" or "sell"
+	OrderType    string  `json:"ordertype"`
+	Cost         float64 `json:"cost,string"` // Opening cost in the quote currency
+	Fee          float64 `json:"fee,string"`
+	Volume       float64 `json:"vol,string"`
+	VolumeClosed float64 `json:"vol_closed,string"`
+	Margin       float64 `json:"margin,string"`
+	Value        float64 `json:"value,string"` // Current value of the remaining position, zero without docalcs
+	Net          float64 `json:"net,string"`   // Unrealized profit or loss, zero without docalcs
+	Terms        string  `json:"terms"`        // Rollover terms, e.g. "0.0100% per 4 hours"
+	RolloverTime int64   `json:"rollovertm,string"`
+	Misc         string  `json:"misc"`
+	OFlags       string  `json:"oflags"`
 }
 
 // Fees includes fees information for different currencies
@@ -122,6 +174,395 @@ type DepositAddressesResponse []struct {
 	New      bool   `json:"new,omitempty"`
 }
 
+// DepositMethodInfo is a way of depositing an asset, as returned by DepositMethods
+type DepositMethodInfo struct {
+	Method           string       `json:"method"` // Name to pass to DepositAddresses and DepositStatus
+	Limit            DepositLimit `json:"limit"`
+	Fee              float64      `json:"fee,string"`
+	AddressSetupCost float64      `json:"address-setup-cost,string"` // Cost of generating a new address, if any
+	GenAddress       bool         `json:"gen-address"`               // Whether new addresses can be generated
+	Minimum          float64      `json:"minimum,string"`            // Minimum net amount that can be deposited
+}
+
+// DepositLimit is the maximum net amount that can be deposited right now
+type DepositLimit struct {
+	Limited bool // False when Kraken reports no limit
+	Amount  float64
+}
+
+// UnmarshalJSON decodes the limit, which Kraken reports as false when there
+// is none and as a decimal string otherwise
+func (l *DepositLimit) UnmarshalJSON(data []byte) error {
+	if s := string(data); s == "false" || s == "null" {
+		*l = DepositLimit{}
+		return nil
+	}
+	var amount json.Number
+	if err := json.Unmarshal(data, &amount); err != nil {
+		var text string
+		if json.Unmarshal(data, &text) != nil {
+			return fmt.Errorf("unexpected deposit limit %s", data)
+		}
+		amount = json.Number(text)
+	}
+	v, err := amount.Float64()
+	if err != nil {
+		return fmt.Errorf("unexpected deposit limit %s", data)
+	}
+	*l = DepositLimit{Limited: true, Amount: v}
+	return nil
+}
+
+// DepositStatusInfo is a recent deposit, as returned by DepositStatus
+type DepositStatusInfo struct {
+	Method     string            `json:"method"`
+	AClass     string            `json:"aclass"`
+	Asset      string            `json:"asset"`
+	RefID      string            `json:"refid"`
+	TxID       string            `json:"txid"` // Transaction id on the network
+	Info       string            `json:"info"` // Address or other method specific details
+	Amount     big.Float         `json:"amount"`
+	Fee        big.Float         `json:"fee"`
+	Time       time.Time         `json:"-"`
+	Status     FundingStatus     `json:"status" enum:"funding_status"`
+	StatusProp FundingStatusProp `json:"status-prop" enum:"funding_status_prop"` // Empty, or e.g. "return" or "onhold"
+}
+
+// UnmarshalJSON decodes the deposit, whose time Kraken sends as unix seconds
+func (d *DepositStatusInfo) UnmarshalJSON(data []byte) error {
+	type deposit DepositStatusInfo
+	raw := struct {
+		*deposit
+		Time json.Number `json:"time"`
+	}{deposit: (*deposit)(d)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	d.Time = time.Time{}
+	if raw.Time != "" {
+		seconds, err := raw.Time.Float64()
+		if err != nil {
+			return fmt.Errorf("unexpected deposit time %s", raw.Time)
+		}
+		d.Time = unixFloat(seconds)
+	}
+	return nil
+}
+
+// State returns the status of the deposit with its modifier
+func (d DepositStatusInfo) State() FundingState {
+	return FundingState{Status: d.Status, Prop: d.StatusProp}
+}
+
+// WithdrawMethodInfo is a way of withdrawing an asset, as returned by WithdrawMethods
+type WithdrawMethodInfo struct {
+	Asset    string  `json:"asset"`
+	MethodID string  `json:"method_id"`
+	Method   string  `json:"method"`  // Name WithdrawInfo reports for withdrawals with this method
+	Network  string  `json:"network"` // Network the funds are sent on, e.g. "Tron" or "Ethereum"
+	Minimum  float64 `json:"minimum,string"`
+}
+
+// WithdrawAddressesArgs filters the addresses returned by WithdrawAddresses.
+// Empty fields do not filter.
+type WithdrawAddressesArgs struct {
+	Asset    string
+	AClass   string // Asset class, e.g. "currency"
+	Method   string // Withdrawal method, e.g. "Bitcoin"
+	Key      string // Name of the withdrawal key
+	Verified *bool  // Only verified or only unverified addresses, nil for both
+}
+
+// WithdrawAddressInfo is a withdrawal address, as returned by WithdrawAddresses
+type WithdrawAddressInfo struct {
+	Address  string `json:"address"`
+	Asset    string `json:"asset"`
+	Method   string `json:"method"`
+	Key      string `json:"key"` // Name to pass to WithdrawInfo and Withdraw
+	Verified bool   `json:"verified"`
+}
+
+// StakeResponse is the response type of a Stake query to the Kraken API.
+type StakeResponse struct {
+	RefID string `json:"refid"`
+}
+
+// UnstakeResponse is the response type of an Unstake query to the Kraken API.
+type UnstakeResponse struct {
+	RefID string `json:"refid"`
+}
+
+// StakingAssetInfo is a stakeable asset, as returned by StakingAssets
+type StakingAssetInfo struct {
+	Method         string          `json:"method"`        // Staking method to pass to StakeAsset
+	Asset          string          `json:"asset"`         // Asset staked, e.g. "DOT"
+	StakingAsset   string          `json:"staking_asset"` // Asset the staked funds are held in, e.g. "DOT.S"
+	Rewards        StakingRewards  `json:"rewards"`
+	OnChain        bool            `json:"on_chain"` // Whether the funds are staked on chain
+	CanStake       bool            `json:"can_stake"`
+	CanUnstake     bool            `json:"can_unstake"`
+	MinimumAmount  StakingMinimums `json:"minimum_amount"`
+	Lock           StakingLocks    `json:"lock"`
+	EnabledForUser bool            `json:"enabled_for_user"`
+	Disabled       bool            `json:"disabled"`
+}
+
+// StakingRewards is the reward paid for staking an asset
+type StakingRewards struct {
+	Reward string `json:"reward"` // Yearly reward, e.g. "4.5", or a range such as "4.5-7"
+	Type   string `json:"type"`   // Unit of Reward, e.g. "percentage"
+}
+
+// StakingMinimums are the smallest amounts that can be staked and unstaked
+type StakingMinimums struct {
+	Staking   float64 `json:"staking,string"`
+	Unstaking float64 `json:"unstaking,string"`
+}
+
+// StakingLocks are the periods staked funds are locked for
+type StakingLocks struct {
+	Staking   []StakingLockPeriod `json:"staking"`   // Before staked funds earn rewards
+	Unstaking []StakingLockPeriod `json:"unstaking"` // Before unstaked funds become available
+	Lockup    []StakingLockPeriod `json:"lockup"`    // During which staked funds cannot be unstaked
+}
+
+// StakingLockPeriod locks a percentage of the funds for a number of days
+type StakingLockPeriod struct {
+	Days       float64 `json:"days"`
+	Percentage float64 `json:"percentage"`
+}
+
+// StakingTransaction is a staking, unstaking or reward, as returned by
+// StakingPendingTransactions and StakingTransactions
+type StakingTransaction struct {
+	Method string                 `json:"method"`
+	AClass string                 `json:"aclass"`
+	Asset  string                 `json:"asset"`
+	RefID  string                 `json:"refid"`
+	Amount big.Float              `json:"amount"`
+	Fee    big.Float              `json:"fee"`
+	Time   time.Time              `json:"-"`
+	Status FundingStatus          `json:"status" enum:"funding_status"`
+	Type   StakingTransactionType `json:"type"`
+
+	// BondStart and BondEnd delimit the bonding or unbonding period. Kraken
+	// sends them for some transactions only; they are zero otherwise.
+	BondStart time.Time `json:"-"`
+	BondEnd   time.Time `json:"-"`
+}
+
+// UnmarshalJSON decodes the transaction, whose times Kraken sends as unix seconds
+func (t *StakingTransaction) UnmarshalJSON(data []byte) error {
+	type transaction StakingTransaction
+	raw := struct {
+		*transaction
+		Time      json.Number `json:"time"`
+		BondStart json.Number `json:"bond_start"`
+		BondEnd   json.Number `json:"bond_end"`
+	}{transaction: (*transaction)(t)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		value json.Number
+		dest  *time.Time
+	}{{raw.Time, &t.Time}, {raw.BondStart, &t.BondStart}, {raw.BondEnd, &t.BondEnd}} {
+		*field.dest = time.Time{}
+		if field.value == "" {
+			continue
+		}
+		seconds, err := field.value.Float64()
+		if err != nil {
+			return fmt.Errorf("unexpected staking transaction time %s", field.value)
+		}
+		*field.dest = unixFloat(seconds)
+	}
+	return nil
+}
+
+// EarnOperationStatus is the state of the last allocation or deallocation
+// of a strategy, as returned by EarnAllocateStatus
+type EarnOperationStatus struct {
+	Pending bool `json:"pending"` // Whether the operation is still being processed
+}
+
+// EarnStrategiesArgs filters and pages the strategies returned by
+// EarnStrategies. Empty fields do not filter.
+type EarnStrategiesArgs struct {
+	Asset     string
+	LockTypes []EarnLockKind
+	Cursor    string // NextCursor of the previous page, empty for the first page
+	Limit     int    // Maximum number of strategies per page, zero for Kraken's default
+	Ascending bool   // Sort the strategies in ascending order
+}
+
+// EarnStrategiesResponse is a page of Earn strategies
+type EarnStrategiesResponse struct {
+	Items      []EarnStrategy `json:"items"`
+	NextCursor string         `json:"next_cursor"` // Cursor of the next page, empty on the last page
+}
+
+// EarnStrategy is a way of earning rewards on an asset
+type EarnStrategy struct {
+	ID                string          `json:"id"` // Strategy id to pass to EarnAllocate
+	Asset             string          `json:"asset"`
+	LockType          EarnLockType    `json:"lock_type"`
+	APREstimate       EarnAPREstimate `json:"apr_estimate"`
+	UserMinAllocation float64         `json:"user_min_allocation,string"`
+	UserCap           float64         `json:"user_cap,string"` // Maximum allocation, zero if unlimited
+	AllocationFee     float64         `json:"allocation_fee,string"`
+	DeallocationFee   float64         `json:"deallocation_fee,string"`
+	CanAllocate       bool            `json:"can_allocate"`
+	CanDeallocate     bool            `json:"can_deallocate"`
+}
+
+// EarnLockType describes how long funds allocated to a strategy are locked.
+// Periods are in seconds.
+type EarnLockType struct {
+	Type            EarnLockKind `json:"type"`
+	BondingPeriod   int64        `json:"bonding_period"`    // Before allocated funds earn rewards
+	UnbondingPeriod int64        `json:"unbonding_period"`  // Before deallocated funds become available
+	ExitQueuePeriod int64        `json:"exit_queue_period"` // Before deallocated funds start unbonding
+	PayoutFrequency int64        `json:"payout_frequency"`  // Between reward payouts
+}
+
+// EarnAPREstimate is the estimated yearly yield of a strategy in percent
+type EarnAPREstimate struct {
+	Low  float64 `json:"low,string"`
+	High float64 `json:"high,string"`
+}
+
+// EarnAllocationsResponse is the response type of an EarnAllocations query
+type EarnAllocationsResponse struct {
+	ConvertedAsset string           `json:"converted_asset"`
+	TotalAllocated float64          `json:"total_allocated,string"` // In ConvertedAsset
+	TotalRewarded  float64          `json:"total_rewarded,string"`  // In ConvertedAsset
+	Items          []EarnAllocation `json:"items"`
+}
+
+// EarnAllocation holds the funds allocated to an Earn strategy
+type EarnAllocation struct {
+	StrategyID      string               `json:"strategy_id"`
+	NativeAsset     string               `json:"native_asset"`
+	AmountAllocated EarnAllocatedAmounts `json:"amount_allocated"`
+	TotalRewarded   EarnAmount           `json:"total_rewarded"`
+}
+
+// EarnAllocatedAmounts breaks the funds allocated to a strategy down by
+// state. Total includes every state; states the allocation has no funds in
+// are zero.
+type EarnAllocatedAmounts struct {
+	Total     EarnAmount          `json:"total"`
+	Bonding   EarnAllocationState `json:"bonding"`    // Not earning rewards yet
+	ExitQueue EarnAllocationState `json:"exit_queue"` // Waiting to start unbonding
+	Unbonding EarnAllocationState `json:"unbonding"`  // Being deallocated
+	Pending   EarnAmount          `json:"pending"`    // Allocation or deallocation in progress
+}
+
+// EarnAllocationState holds the funds of an allocation in one state
+type EarnAllocationState struct {
+	EarnAmount
+	AllocationCount int        `json:"allocation_count"`
+	Holds           []EarnHold `json:"holds"`
+}
+
+// EarnHold is an amount held in a state until Expires
+type EarnHold struct {
+	EarnAmount
+	CreatedAt time.Time `json:"created_at"`
+	Expires   time.Time `json:"expires"`
+}
+
+// EarnAmount is an amount of an Earn allocation in its native asset and
+// converted to the requested asset
+type EarnAmount struct {
+	Native    float64 `json:"native,string"`
+	Converted float64 `json:"converted,string"`
+}
+
+// AddExportResponse is the response type of an AddExport query
+type AddExportResponse struct {
+	ID string `json:"id"` // Export id to pass to ExportStatus, RetrieveExport and RemoveExport
+}
+
+// RemoveExportResponse is the response type of a RemoveExport query
+type RemoveExportResponse struct {
+	Cancel bool `json:"cancel"` // The export was canceled
+	Delete bool `json:"delete"` // The export was deleted
+}
+
+// ExportStatusInfo is an export of a report, as returned by ExportStatus.
+// Times Kraken has not set yet are zero.
+type ExportStatusInfo struct {
+	ID            string      `json:"id"`
+	Description   string      `json:"descr"`
+	Format        string      `json:"format"`
+	Report        string      `json:"report"`
+	Subtype       string      `json:"subtype"`
+	Status        ExportState `json:"status"`
+	Flags         string      `json:"flags"`
+	Fields        string      `json:"fields"` // Comma separated fields, or "all"
+	AClass        string      `json:"aclass"`
+	Asset         string      `json:"asset"`
+	CreatedTime   time.Time   `json:"-"`
+	ExpireTime    time.Time   `json:"-"`
+	StartTime     time.Time   `json:"-"` // When Kraken started processing the export
+	CompletedTime time.Time   `json:"-"`
+	DataStartTime time.Time   `json:"-"` // Start of the exported period
+	DataEndTime   time.Time   `json:"-"` // End of the exported period
+}
+
+// UnmarshalJSON decodes the export, whose times Kraken sends as strings of
+// unix seconds
+func (e *ExportStatusInfo) UnmarshalJSON(data []byte) error {
+	type export ExportStatusInfo
+	raw := struct {
+		*export
+		CreatedTime   string `json:"createdtm"`
+		ExpireTime    string `json:"expiretm"`
+		StartTime     string `json:"starttm"`
+		CompletedTime string `json:"completedtm"`
+		DataStartTime string `json:"datastarttm"`
+		DataEndTime   string `json:"dataendtm"`
+	}{export: (*export)(e)}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for _, field := range []struct {
+		raw string
+		t   *time.Time
+	}{
+		{raw.CreatedTime, &e.CreatedTime},
+		{raw.ExpireTime, &e.ExpireTime},
+		{raw.StartTime, &e.StartTime},
+		{raw.CompletedTime, &e.CompletedTime},
+		{raw.DataStartTime, &e.DataStartTime},
+		{raw.DataEndTime, &e.DataEndTime},
+	} {
+		*field.t = time.Time{}
+		if field.raw == "" || field.raw == "0" {
+			continue
+		}
+		t, err := parseTimeString(field.raw)
+		if err != nil {
+			return fmt.Errorf("unexpected export time %s", field.raw)
+		}
+		*field.t = t
+	}
+	return nil
+}
+
+// AccountTransferResponse is the response type of an AccountTransfer query
+type AccountTransferResponse struct {
+	TransferID string `json:"transfer_id"`
+	Status     string `json:"status"` // e.g. "complete" or "pending"
+}
+
+// WalletTransferResponse is the response type of a WalletTransfer query to the Kraken API.
+type WalletTransferResponse struct {
+	RefID string `json:"refid"`
+}
+
 // WithdrawResponse is the response type of a Withdraw query to the Kraken API.
 type WithdrawResponse struct {
 	RefID string `json:"refid"`
@@ -133,14 +574,17 @@ type WithdrawInfoResponse struct {
 	Limit  big.Float `json:"limit"`
 	Amount big.Float `json:"amount"`
 	Fee    big.Float `json:"fee"`
+
+	DailyLimitUSD float64 `json:"-"` // Daily withdrawal limit of the tier set with WithAccountTier, zero if unknown
 }
 
-// GetPairTickerInfo is a helper method that returns given `pair`'s `PairTickerInfo`
+// GetPairTickerInfo is a helper method that returns given `pair`'s `PairTickerInfo`,
+// or an empty PairTickerInfo if the response holds no such pair
 func (v *TickerResponse) GetPairTickerInfo(pair string) PairTickerInfo {
-	r := reflect.ValueOf(v)
-	f := reflect.Indirect(r).FieldByName(pair)
-
-	return f.Interface().(PairTickerInfo)
+	if v == nil {
+		return PairTickerInfo{}
+	}
+	return (*v)[pair]
 }
 
 // PairTickerInfo represents ticker information for a pair
@@ -151,15 +595,15 @@ type PairTickerInfo struct {
 	Bid []string `json:"b"`
 	// Last trade closed array(<price>, <lot volume>)
 	Close []string `json:"c"`
-	// Volume array(<today>, <last 24 hours>)
+	// Volume array(<today>, <last 24 hours>), see VolumeWindows
 	Volume []string `json:"v"`
-	// Volume weighted average price array(<today>, <last 24 hours>)
+	// Volume weighted average price array(<today>, <last 24 hours>), see VWAPWindows
 	VolumeAveragePrice []string `json:"p"`
-	// Number of trades array(<today>, <last 24 hours>)
+	// Number of trades array(<today>, <last 24 hours>), see TradesWindows
 	Trades []int `json:"t"`
-	// Low array(<today>, <last 24 hours>)
+	// Low array(<today>, <last 24 hours>), see LowWindows
 	Low []string `json:"l"`
-	// High array(<today>, <last 24 hours>)
+	// High array(<today>, <last 24 hours>), see HighWindows
 	High []string `json:"h"`
 	// Today's opening price
 	OpeningPrice float64 `json:"o,string"`
@@ -167,8 +611,10 @@ type PairTickerInfo struct {
 
 // TradesResponse represents a list of the last trades
 type TradesResponse struct {
-	Last   int64
-	Trades []TradeInfo
+	Pair       string // Pair name Kraken answered with, e.g. "XXBTZUSD"
+	Last       int64
+	LastCursor string // Last as sent by Kraken, to pass on as since
+	Trades     []TradeInfo
 }
 
 // TradesHistoryResponse represents a list of executed trade
@@ -179,18 +625,24 @@ type TradesHistoryResponse struct {
 
 // TradeHistoryInfo represents a transaction
 type TradeHistoryInfo struct {
-	TransactionID string  `json:"ordertxid"`
-	PostxID       string  `json:"postxid"`
-	AssetPair     string  `json:"pair"`
-	Time          float64 `json:"time"`
-	Type          string  `json:"type"`
-	OrderType     string  `json:"ordertype"`
-	Price         float64 `json:"price,string"`
-	Cost          float64 `json:"cost,string"`
-	Fee           float64 `json:"fee,string"`
-	Volume        float64 `json:"vol,string"`
-	Margin        float64 `json:"margin,string"`
-	Misc          string  `json:"misc"`
+	TransactionID string   `json:"ordertxid"`
+	PostxID       string   `json:"postxid"`
+	AssetPair     string   `json:"pair"`
+	Time          float64  `json:"time"`
+	Type          string   `json:"type" enum:"side"`
+	OrderType     string   `json:"ordertype" enum:"order_type"`
+	Price         float64  `json:"price,string"`
+	Cost          float64  `json:"cost,string"`
+	Fee           float64  `json:"fee,string"`
+	Volume        float64  `json:"vol,string"`
+	Margin        float64  `json:"margin,string"`
+	Misc          string   `json:"misc"`
+	Ledgers       []string `json:"ledgers,omitempty"` // Ledger ids of the trade (if requested with ledgers=true)
+}
+
+// Timestamp returns the time of the trade
+func (t TradeHistoryInfo) Timestamp() time.Time {
+	return optionalTime(t.Time)
 }
 
 // TradeInfo represents a trades information
@@ -200,23 +652,27 @@ type TradeInfo struct {
 	Volume        string
 	VolumeFloat   float64
 	Time          int64
+	Timestamp     time.Time // Time with sub-second precision
 	Buy           bool
 	Sell          bool
 	Market        bool
 	Limit         bool
 	Miscellaneous string
+	TradeID       int64 // Zero when Kraken does not number the trade
 }
 
 // LedgersResponse represents an associative array of ledgers infos
 type LedgersResponse struct {
 	Ledger map[string]LedgerInfo `json:"ledger"`
+	Count  int                   `json:"count"`
 }
 
 // LedgerInfo Represents the ledger informations
 type LedgerInfo struct {
 	RefID   string    `json:"refid"`
 	Time    float64   `json:"time"`
-	Type    string    `json:"type"`
+	Type    string    `json:"type" enum:"ledger_type"`
+	Subtype string    `json:"subtype"`
 	Aclass  string    `json:"aclass"`
 	Asset   string    `json:"asset"`
 	Amount  big.Float `json:"amount"`
@@ -224,6 +680,11 @@ type LedgerInfo struct {
 	Balance big.Float `json:"balance"`
 }
 
+// Timestamp returns the time of the ledger entry
+func (l LedgerInfo) Timestamp() time.Time {
+	return optionalTime(l.Time)
+}
+
 // OrderTypes for AddOrder
 const (
 	OTMarket              = "market"
@@ -242,34 +703,60 @@ const (
 
 // OrderDescription represents an order description
 type OrderDescription struct {
-	Pair      string  `json:"pair"`          // Asset pair
-	Type      string  `json:"type"`          // "buy" or "sell"
-	OrderType string  `json:"ordertype"`     // "market" or "limit" or "stop-loss" or "take-profit" or "stop-loss-limit" or "take-profit-limit" or "trailing-stop" or "trailing-stop-limit" or "settle-position"
-	Price     float64 `json:"price,string"`  // Limit price for "limit" orders. Trigger price for "stop-loss", "stop-loss-limit", "take-profit", "take-profit-limit", "trailing-stop" and "trailing-stop-limit orders"
-	Price2    float64 `json:"price2,string"` // Limit price for "stop-loss-limit", "take-profit-limit" and "trailing-stop-limit orders"
-	Leverage  string  `json:"leverage"`      // Amount of leverage
-	Order     string  `json:"order"`         // Order description
-	Close     string  `json:"close"`         // Conditional close order description (if conditional close set)
+	Pair      string  `json:"pair"`                        // Asset pair
+	Type      string  `json:"type" enum:"side"`            // "buy" or "sell"
+	OrderType string  `json:"ordertype" enum:"order_type"` // "market" or "limit" or "stop-loss" or "take-profit" or "stop-loss-limit" or "take-profit-limit" or "trailing-stop" or "trailing-stop-limit" or "settle-position"
+	Price     float64 `json:"price,string"`                // Limit price for "limit" orders. Trigger price for "stop-loss", "stop-loss-limit", "take-profit", "take-profit-limit", "trailing-stop" and "trailing-stop-limit orders"
+	Price2    float64 `json:"price2,string"`               // Limit price for "stop-loss-limit", "take-profit-limit" and "trailing-stop-limit orders"
+	Leverage  string  `json:"leverage"`                    // Amount of leverage, e.g. "2:1" or "none" (see ParsedLeverage)
+	Order     string  `json:"order"`                       // Order description
+	Close     string  `json:"close"`                       // Conditional close order description (if conditional close set)
 }
 
 // Order represents a single order
 type Order struct {
-	ReferenceID    string           `json:"refid"`             // Referral order transaction ID that created this order
-	UserRef        int              `json:"userref"`           // User reference id
-	Status         string           `json:"status"`            // "pending" or "open" or "closed" or "canceled" or "expired"
-	OpenTime       float64          `json:"opentm"`            // Unix timestamp of when order was placed
-	StartTime      float64          `json:"starttm"`           // Unix timestamp of order start time (or 0 if not set)
-	ExpireTime     float64          `json:"expiretm"`          // Unix timestamp of order end time (or 0 if not set)
-	Description    OrderDescription `json:"descr"`             // Order description info
-	Volume         float64          `json:"vol,string"`        // Volume of order (base currency)
-	VolumeExecuted float64          `json:"vol_exec,string"`   // Volume executed (base currency)
-	Cost           float64          `json:"cost,string"`       // Total cost (quote currency unless)
-	Fee            float64          `json:"fee,string"`        // Total fee (quote currency)
-	Price          float64          `json:"price,string"`      // Average price (quote currency)
-	StopPrice      float64          `json:"stopprice.string"`  // Stop price (quote currency)
-	LimitPrice     float64          `json:"limitprice,string"` // Triggered limit price (quote currency, when limit based order type triggered)
-	Misc           string           `json:"misc"`              // Comma delimited list of miscellaneous info
-	OrderFlags     string           `json:"oflags"`            // Comma delimited list of order flags
+	ReferenceID    string           `json:"refid"`                      // Referral order transaction ID that created this order
+	UserRef        int              `json:"userref"`                    // User reference id
+	ClientOrderID  string           `json:"cl_ord_id"`                  // Client order id (if set when the order was placed)
+	Status         string           `json:"status" enum:"order_status"` // "pending" or "open" or "closed" or "canceled" or "expired"
+	Reason         string           `json:"reason"`                     // Additional info on status (if any)
+	OpenTime       float64          `json:"opentm"`                     // Unix timestamp of when order was placed
+	CloseTime      float64          `json:"closetm"`                    // Unix timestamp of when order was closed (closed orders only)
+	StartTime      float64          `json:"starttm"`                    // Unix timestamp of order start time (or 0 if not set)
+	ExpireTime     float64          `json:"expiretm"`                   // Unix timestamp of order end time (or 0 if not set)
+	Description    OrderDescription `json:"descr"`                      // Order description info
+	Volume         float64          `json:"vol,string"`                 // Volume of order (base currency)
+	VolumeExecuted float64          `json:"vol_exec,string"`            // Volume executed (base currency)
+	Cost           float64          `json:"cost,string"`                // Total cost (quote currency unless)
+	Fee            float64          `json:"fee,string"`                 // Total fee (quote currency)
+	Price          float64          `json:"price,string"`               // Average price (quote currency)
+	StopPrice      float64          `json:"stopprice,string"`           // Stop price (quote currency)
+	LimitPrice     float64          `json:"limitprice,string"`          // Triggered limit price (quote currency, when limit based order type triggered)
+	Trigger        string           `json:"trigger"`                    // Price signal triggering stop and take profit orders: "last" or "index"
+	Margin         bool             `json:"margin"`                     // Whether the order is funded on margin
+	Misc           string           `json:"misc"`                       // Comma delimited list of miscellaneous info
+	OrderFlags     string           `json:"oflags"`                     // Comma delimited list of order flags
+	Trades         []string         `json:"trades"`                     // List of trade IDs related to order (if trades info requested and data available)
+}
+
+// OpenTimestamp returns when the order was placed
+func (o Order) OpenTimestamp() time.Time {
+	return optionalTime(o.OpenTime)
+}
+
+// CloseTimestamp returns when the order was closed, zero if it is not
+func (o Order) CloseTimestamp() time.Time {
+	return optionalTime(o.CloseTime)
+}
+
+// StartTimestamp returns the start time of the order, zero if not set
+func (o Order) StartTimestamp() time.Time {
+	return optionalTime(o.StartTime)
+}
+
+// ExpireTimestamp returns the end time of the order, zero if not set
+func (o Order) ExpireTimestamp() time.Time {
+	return optionalTime(o.ExpireTime)
 }
 
 // ClosedOrdersResponse represents a list of closed orders, indexed by id
@@ -319,6 +806,67 @@ type OrderBook struct {
 	Bids []OrderBookItem
 }
 
+// SpreadItem is the best bid and ask of a pair at a point in time
+type SpreadItem struct {
+	Time time.Time
+	Bid  float64
+	Ask  float64
+}
+
+// UnmarshalJSON takes a json array from kraken and converts it into a SpreadItem.
+func (s *SpreadItem) UnmarshalJSON(data []byte) error {
+	var ts json.Number
+	var bid, ask string
+	if err := json.Unmarshal(data, &[]interface{}{&ts, &bid, &ask}); err != nil {
+		return err
+	}
+
+	var err error
+	if s.Time, err = ParseKrakenTime(ts); err != nil {
+		return err
+	}
+	if s.Bid, err = strconv.ParseFloat(bid, 64); err != nil {
+		return err
+	}
+	s.Ask, err = strconv.ParseFloat(ask, 64)
+	return err
+}
+
+// SpreadResponse holds the recent spreads of a pair
+type SpreadResponse struct {
+	Pair    string // Pair name Kraken answered with, e.g. "XXBTZUSD"
+	Last    int64  // Id to pass as since to get the spreads recorded after these
+	Spreads []SpreadItem
+}
+
+// UnmarshalJSON decodes the result of Spread, which holds the spreads under
+// the pair name next to the last id
+func (s *SpreadResponse) UnmarshalJSON(data []byte) error {
+	var v map[string]json.RawMessage
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*s = SpreadResponse{}
+	for key, raw := range v {
+		if key == "last" {
+			var last json.Number
+			if err := json.Unmarshal(raw, &last); err != nil {
+				return err
+			}
+			var err error
+			if s.Last, err = last.Int64(); err != nil {
+				return fmt.Errorf("unexpected spread last %s", last)
+			}
+			continue
+		}
+		s.Pair = key
+		if err := json.Unmarshal(raw, &s.Spreads); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // OpenOrdersResponse response when opening an order
 type OpenOrdersResponse struct {
 	Open map[string]Order `json:"open"`
@@ -332,12 +880,97 @@ type AddOrderResponse struct {
 	TxId []string `json:"txid"`
 }
 
+// AddOrderRequest is an order of an AddOrderBatch call. Args takes the keys
+// of the AddOrder arguments: "price", "price2", "leverage", "oflags",
+// "starttm", "expiretm", "timeinforce", "userref", "cl_ord_id",
+// "close_order_type", "close_price" and "close_price2".
+type AddOrderRequest struct {
+	Direction string // "buy" or "sell"
+	OrderType string
+	Volume    string
+	Leverage  Leverage // Zero for a spot order; takes precedence over Args["leverage"]
+	Args      map[string]string
+}
+
+// AddOrderBatchResult is the outcome of an order of an AddOrderBatch call:
+// either its txid and description, or the error Kraken rejected it with
+type AddOrderBatchResult struct {
+	TxID        string `json:"txid"`
+	Description struct {
+		Order string `json:"order"`
+		Close string `json:"close"`
+	} `json:"descr"`
+	Error string `json:"error"`
+}
+
+// AddOrderBatchResponse is the response of AddOrderBatch
+type AddOrderBatchResponse struct {
+	Orders []AddOrderBatchResult `json:"orders"`
+}
+
+// EditOrderArgs are the amendments of an open order. Zero values keep the
+// current value of the order.
+type EditOrderArgs struct {
+	Volume         float64   // New volume, formatted with the lot decimals of the pair
+	Price          float64   // New price, formatted with the price decimals of the pair
+	Price2         float64   // New secondary price, formatted like Price
+	OFlags         string    // Comma delimited list of order flags, e.g. "post"
+	UserRef        int       // New user reference id
+	Deadline       time.Time // Reject the amendment if it cannot be matched before
+	CancelResponse bool      // Cancel the original order if the amended one cannot be placed
+	Validate       bool      // Only validate the amendment
+}
+
+// EditOrderResponse response when amending an order
+type EditOrderResponse struct {
+	Status          string  `json:"status"`           // "ok" or "err"
+	ErrorMessage    string  `json:"error_message"`    // Why the amendment failed, if Status is "err"
+	TxID            string  `json:"txid"`             // Txid of the amended order
+	OriginalTxID    string  `json:"originaltxid"`     // Txid of the order that was amended
+	Volume          float64 `json:"volume,string"`    // Volume of the amended order
+	Price           float64 `json:"price,string"`     // Price of the amended order
+	Price2          float64 `json:"price2,string"`    // Secondary price of the amended order
+	OrdersCancelled int     `json:"orders_cancelled"` // Number of orders cancelled, e.g. the original
+	Description     struct {
+		Order string `json:"order"`
+	} `json:"descr"`
+}
+
 // CancelOrderResponse response when cancelling and order
 type CancelOrderResponse struct {
 	Count   int  `json:"count"`
 	Pending bool `json:"pending"`
 }
 
+// CancelAllOrdersAfterResponse response when arming the dead man's switch
+type CancelAllOrdersAfterResponse struct {
+	CurrentTime time.Time `json:"currentTime"` // Server time of the request
+	TriggerTime time.Time `json:"triggerTime"` // When the open orders get cancelled, zero if disarmed
+}
+
+// UnmarshalJSON decodes the response, in which Kraken reports a disarmed
+// timer with a trigger time of "0"
+func (r *CancelAllOrdersAfterResponse) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		CurrentTime time.Time `json:"currentTime"`
+		TriggerTime string    `json:"triggerTime"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	r.CurrentTime = raw.CurrentTime
+	r.TriggerTime = time.Time{}
+	if raw.TriggerTime == "" || raw.TriggerTime == "0" {
+		return nil
+	}
+	trigger, err := time.Parse(time.RFC3339, raw.TriggerTime)
+	if err != nil {
+		return err
+	}
+	r.TriggerTime = trigger
+	return nil
+}
+
 // QueryOrdersResponse response when checking all orders
 type QueryOrdersResponse map[string]Order
 
@@ -348,7 +981,10 @@ func NewOHLC(input []interface{}) (*OHLC, error) {
 	}
 
 	tmp := new(OHLC)
-	tmp.Time = time.Unix(int64(input[0].(float64)), 0)
+	var err error
+	if tmp.Time, err = ParseKrakenTime(input[0]); err != nil {
+		return nil, fmt.Errorf("time %v: %w", input[0], err)
+	}
 	tmp.Open, _ = strconv.ParseFloat(input[1].(string), 64)
 	tmp.High, _ = strconv.ParseFloat(input[2].(string), 64)
 	tmp.Low, _ = strconv.ParseFloat(input[3].(string), 64)
@@ -372,9 +1008,53 @@ type OHLC struct {
 	Count  int       `json:"count"`
 }
 
+// NewOHLCExact constructs an OHLCExact from the same input as NewOHLC,
+// keeping prices and volume as Kraken sent them
+func NewOHLCExact(input []interface{}) (*OHLCExact, error) {
+	if len(input) != 8 {
+		return nil, fmt.Errorf("the length is not 8 but %d", len(input))
+	}
+
+	tmp := new(OHLCExact)
+	fields := []*string{&tmp.Open, &tmp.High, &tmp.Low, &tmp.Close, &tmp.Vwap, &tmp.Volume}
+	for i, field := range fields {
+		value, ok := input[i+1].(string)
+		if !ok {
+			return nil, fmt.Errorf("field %d is not a string but %T", i+1, input[i+1])
+		}
+		*field = value
+	}
+	unixtime, errTime := ParseKrakenTime(input[0])
+	count, okCount := input[7].(float64)
+	if errTime != nil || !okCount {
+		return nil, fmt.Errorf("time and count must be numbers")
+	}
+	tmp.Time = unixtime
+	tmp.Count = int(count)
+
+	return tmp, nil
+}
+
+// OHLCExact is an OHLC entry whose prices and volume keep the exact decimal
+// representation sent by Kraken, e.g. for backtests on high precision pairs
+type OHLCExact struct {
+	Time   time.Time `json:"time"`
+	Open   string    `json:"open"`
+	High   string    `json:"high"`
+	Low    string    `json:"low"`
+	Close  string    `json:"close"`
+	Vwap   string    `json:"vwap"`
+	Volume string    `json:"volume"`
+	Count  int       `json:"count"`
+}
+
 // OHLCResponse represents the OHLC's response
 type OHLCResponse struct {
-	Pair string  `json:"pair"`
-	OHLC []*OHLC `json:"OHLC"`
-	Last float64 `json:"last"`
+	Pair       string  `json:"pair"`
+	OHLC       []*OHLC `json:"OHLC"`
+	Last       float64 `json:"last"`
+	LastCursor string  `json:"last_cursor,omitempty"` // Last as sent by Kraken, to pass on as OHLCArgs.Since
+	// Exact holds the same candles as OHLC with exact prices, only when the
+	// client was created with WithExactOHLC
+	Exact []*OHLCExact `json:"exact,omitempty"`
 }
@@ -0,0 +1,132 @@
+package krakenapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sergey-lipin/kraken-go-api-client/krakentest"
+)
+
+// decodeStrict decodes a Kraken response fixture into typ and fails on any
+// field the Go types do not know about.
+func decodeStrict(t *testing.T, fixture string, typ interface{}) {
+	t.Helper()
+	data, err := krakentest.Fixture(fixture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jsonData := KrakenResponse{Result: typ}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&jsonData); err != nil {
+		t.Fatalf("%s should decode without unknown fields, got %s", fixture, err)
+	}
+}
+
+func TestClosedOrdersStrictDecode(t *testing.T) {
+	var resp ClosedOrdersResponse
+	decodeStrict(t, "ClosedOrders", &resp)
+
+	if resp.Count != 2 {
+		t.Errorf("Expected 2 closed orders, got %d", resp.Count)
+	}
+
+	order := resp.Closed["O6YDQ5-LOMWU-37YKEE"]
+	if !order.Margin || len(order.Trades) != 2 {
+		t.Errorf("Margin order should expose margin flag and trades, got %+v", order)
+	}
+	if order.Cost != 27526.2 || order.Fee != 26.2 || order.CloseTime != 1688148493.7708 {
+		t.Errorf("Unexpected cost breakdown, got %+v", order)
+	}
+
+	canceled := resp.Closed["O37652-RJWRT-IMO74O"]
+	if canceled.Reason != "User requested" || canceled.ClientOrderID == "" {
+		t.Errorf("Canceled order should expose reason and cl_ord_id, got %+v", canceled)
+	}
+}
+
+func TestQueryOrdersStrictDecode(t *testing.T) {
+	var resp QueryOrdersResponse
+	decodeStrict(t, "QueryOrders", &resp)
+
+	order, ok := resp["OBCMZD-JIEE7-77TH3F"]
+	if !ok {
+		t.Fatalf("Expected order OBCMZD-JIEE7-77TH3F, got %+v", resp)
+	}
+	if order.StopPrice != 26500 {
+		t.Errorf("Expected stop price 26500, got %f", order.StopPrice)
+	}
+}
+
+func TestTradesWithCount(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if req.URL.Query().Get("count") != "2" {
+			t.Errorf("count should be sent, got %q", req.URL.Query().Get("count"))
+		}
+		return string(krakentest.MustFixture("Trades"))
+	})
+
+	resp, err := api.TradesWithCount("XBTEUR", 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Pair != "XXBTZEUR" || len(resp.Trades) != 2 {
+		t.Errorf("Trades should be decoded under the pair Kraken answered with, got %+v", resp)
+	}
+	if resp.LastCursor != "1688671284567800000" {
+		t.Errorf("Raw cursor should be kept, got %s", resp.LastCursor)
+	}
+
+	for _, count := range []int{-1, 1001} {
+		if _, err := api.TradesWithCount("XBTEUR", 0, count); err == nil {
+			t.Errorf("A count of %d should be rejected", count)
+		}
+	}
+}
+
+func TestTradesWithCountPaging(t *testing.T) {
+	start := time.Unix(1688671200, 123456789)
+	h := newFakeTradeHistory(start, 5, 5)
+	var cursors []string
+	api := newTestAPI(func(method string, req *http.Request) string {
+		cursors = append(cursors, req.URL.Query().Get("since"))
+		if count := req.URL.Query().Get("count"); count != "2" {
+			t.Errorf("Expected count 2 to be sent, got %q", count)
+		}
+		return h.serve(method, req)
+	})
+
+	var ids []int64
+	since := start.UnixNano()
+	for page := 0; page < 4; page++ {
+		resp, err := api.TradesWithCountWithContext(context.Background(), "XBTUSD", since, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Trades) == 0 {
+			break
+		}
+		for _, trade := range resp.Trades {
+			ids = append(ids, trade.TradeID)
+		}
+		if want := h.trades[len(ids)-1].Timestamp.UnixNano(); resp.LastCursor != strconv.FormatInt(want, 10) {
+			t.Errorf("Expected cursor %d, got %s", want, resp.LastCursor)
+		}
+		since = resp.Last
+	}
+
+	if fmt.Sprint(ids) != "[1 2 3 4 5]" {
+		t.Errorf("Pages should stitch without gaps or duplicates, got %v", ids)
+	}
+	if len(cursors) != 4 || cursors[1] != "1688671202123456789" {
+		t.Errorf("Cursors should be passed on exactly, got %v", cursors)
+	}
+
+}
@@ -0,0 +1,172 @@
+package krakenapi
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval is the polling interval of WatchBestQuotes,
+// WatchStatus and WatchOpenOrders when given an interval that is not
+// positive
+const DefaultWatchInterval = time.Second
+
+// BestQuote is the top of the book of a pair
+type BestQuote struct {
+	Pair string    // Pair as passed to BestQuoteWatcher
+	Bid  float64   // Best bid price
+	Ask  float64   // Best ask price
+	Time time.Time // When the change was observed
+}
+
+// BestQuoteOptions configures BestQuoteWatcher
+type BestQuoteOptions struct {
+	// Ticks is the number of tick sizes either side has to move before a
+	// change is reported. Defaults to 1; pairs without a known tick size
+	// report every change.
+	Ticks int
+}
+
+// BestQuoteWatcher polls the best bid and ask of several pairs and reports
+// only the changes. Its methods are safe for concurrent use.
+type BestQuoteWatcher struct {
+	// C receives a BestQuote whenever a side of a pair moved by at least the
+	// configured threshold. It is closed when the context is done.
+	C <-chan BestQuote
+
+	mu         sync.Mutex
+	last       map[string]BestQuote
+	lastChange map[string]time.Time
+	lastPoll   time.Time
+	err        error
+}
+
+// WatchBestQuotes polls the Ticker of all pairs with a single request every
+// interval, DefaultWatchInterval if not positive, until ctx is done. The
+// first poll reports every pair.
+func (api *KrakenAPI) WatchBestQuotes(ctx context.Context, pairs []string, interval time.Duration, opts BestQuoteOptions) *BestQuoteWatcher {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ch := make(chan BestQuote, len(pairs))
+	w := &BestQuoteWatcher{
+		C:          ch,
+		last:       make(map[string]BestQuote),
+		lastChange: make(map[string]time.Time),
+	}
+	if opts.Ticks <= 0 {
+		opts.Ticks = 1
+	}
+
+	go func() {
+		defer close(ch)
+
+		// Thresholds and the names Kraken answers with, per requested pair
+		thresholds := make(map[string]float64)
+		canonical := make(map[string]string)
+		if info, err := api.assetPairs(ctx); err == nil {
+			for _, pair := range pairs {
+				if names, ok := info.FindPair(pair); ok {
+					canonical[names.Name] = pair
+					tick, _ := strconv.ParseFloat((*info)[names.Name].TickSize, 64)
+					thresholds[pair] = tick * float64(opts.Ticks)
+				}
+			}
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			for _, quote := range w.poll(ctx, api, pairs, canonical, thresholds) {
+				select {
+				case ch <- quote:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return w
+}
+
+// poll fetches the tickers and returns the quotes that changed enough
+func (w *BestQuoteWatcher) poll(ctx context.Context, api *KrakenAPI, pairs []string, canonical map[string]string, thresholds map[string]float64) []BestQuote {
+	resp, err := api.TickerWithContext(ctx, pairs...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	w.lastPoll = now
+	var changed []BestQuote
+	for name, info := range *resp {
+		pair, ok := canonical[name]
+		if !ok {
+			pair = name
+		}
+		if len(info.Bid) == 0 || len(info.Ask) == 0 {
+			continue
+		}
+		bid, errBid := strconv.ParseFloat(info.Bid[0], 64)
+		ask, errAsk := strconv.ParseFloat(info.Ask[0], 64)
+		if errBid != nil || errAsk != nil {
+			continue
+		}
+
+		quote := BestQuote{Pair: pair, Bid: bid, Ask: ask, Time: now}
+		previous, seen := w.last[pair]
+		threshold := thresholds[pair]
+		if seen && !movedBy(previous.Bid, bid, threshold) && !movedBy(previous.Ask, ask, threshold) {
+			continue
+		}
+		w.last[pair] = quote
+		w.lastChange[pair] = now
+		changed = append(changed, quote)
+	}
+	return changed
+}
+
+// movedBy reports whether the price moved by at least threshold, or at all without threshold
+func movedBy(previous, current, threshold float64) bool {
+	diff := math.Abs(current - previous)
+	if threshold <= 0 {
+		return diff > 0
+	}
+	// Allow for float noise when the move is exactly the threshold
+	return diff >= threshold*(1-1e-9)
+}
+
+// LastChange returns when the pair last moved by at least the threshold
+func (w *BestQuoteWatcher) LastChange(pair string) (time.Time, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	t, ok := w.lastChange[pair]
+	return t, ok
+}
+
+// LastPoll returns when the last successful poll happened, for staleness checks
+func (w *BestQuoteWatcher) LastPoll() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastPoll
+}
+
+// Err returns the error of the last poll, nil if it succeeded
+func (w *BestQuoteWatcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
@@ -0,0 +1,83 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchBestQuotes(t *testing.T) {
+	bids := []string{"30000.0", "30000.05", "30000.2", "30000.2"}
+	var mu sync.Mutex
+	polls := 0
+	api := newTestAPI(func(method string, req *http.Request) string {
+		if method == "AssetPairs" {
+			return `{"error":[],"result":{"XXBTZUSD":{"altname":"XBTUSD","wsname":"XBT/USD","tick_size":"0.1"}}}`
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if req.URL.Query().Get("pair") != "XBTUSD" {
+			t.Errorf("All pairs should be polled with one request, got %s", req.URL.Query().Get("pair"))
+		}
+		bid := bids[len(bids)-1]
+		if polls < len(bids) {
+			bid = bids[polls]
+		}
+		polls++
+		return fmt.Sprintf(`{"error":[],"result":{"XXBTZUSD":{"a":["30010.0","1","1"],"b":["%s","1","1"]}}}`, bid)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	w := api.WatchBestQuotes(ctx, []string{"XBTUSD"}, time.Millisecond, BestQuoteOptions{})
+
+	first := <-w.C
+	if first.Pair != "XBTUSD" || first.Bid != 30000 {
+		t.Errorf("First poll should report the pair by its requested name, got %+v", first)
+	}
+	second := <-w.C
+	if second.Bid != 30000.2 {
+		t.Errorf("Moves below one tick should be suppressed, got %+v", second)
+	}
+	if changed, ok := w.LastChange("XBTUSD"); !ok || !changed.Equal(second.Time) {
+		t.Errorf("LastChange() should track the last reported change, got %v", changed)
+	}
+
+	cancel()
+	for range w.C {
+	}
+}
+
+func TestWatchersDefaultInterval(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		switch method {
+		case "AssetPairs":
+			return testAssetPairs
+		case "SystemStatus":
+			return `{"error":[],"result":{"status":"online","timestamp":"2023-07-06T18:52:00Z"}}`
+		case "Ticker":
+			return `{"error":[],"result":{"XXBTZUSD":{"a":["30000.2","1","1.0"],"b":["30000.1","1","1.0"]}}}`
+		}
+		return `{"error":[],"result":{"open":{}}}`
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if _, ok := <-api.WatchStatus(ctx, interval).C; !ok {
+			t.Errorf("WatchStatus with interval %v should poll", interval)
+		}
+		if _, ok := <-api.WatchBestQuotes(ctx, []string{"XBTUSD"}, interval, BestQuoteOptions{}).C; !ok {
+			t.Errorf("WatchBestQuotes with interval %v should poll", interval)
+		}
+		cache := api.WatchOpenOrders(ctx, interval, time.Minute)
+		for cache.SyncedAt().IsZero() && ctx.Err() == nil {
+			time.Sleep(time.Millisecond)
+		}
+		if cache.SyncedAt().IsZero() {
+			t.Errorf("WatchOpenOrders with interval %v should poll", interval)
+		}
+	}
+}
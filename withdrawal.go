@@ -0,0 +1,240 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+)
+
+// WithdrawalBasis tells which amount of a withdrawal is compared to the
+// remaining withdrawal limit
+type WithdrawalBasis string
+
+// Bases of WithdrawalOptions.LimitBasis
+const (
+	// WithdrawLimitNet compares the amount the recipient receives, which is
+	// what Kraken documents the limit of WithdrawInfo to bound
+	WithdrawLimitNet WithdrawalBasis = "net"
+	// WithdrawLimitGross compares the amount leaving the balance, fee included
+	WithdrawLimitGross WithdrawalBasis = "gross"
+)
+
+// WithdrawalRule names a check a withdrawal failed
+type WithdrawalRule string
+
+// Rules of WithdrawalViolation
+const (
+	WithdrawalNotPositive      WithdrawalRule = "not_positive"       // The amount is zero or negative
+	WithdrawalTooPrecise       WithdrawalRule = "too_precise"        // The amount has more decimals than the asset
+	WithdrawalBelowMinimum     WithdrawalRule = "below_minimum"      // The amount is below the minimum of the withdrawal method
+	WithdrawalAboveLimit       WithdrawalRule = "above_limit"        // The amount exceeds the remaining withdrawal limit
+	WithdrawalFeeExceedsAmount WithdrawalRule = "fee_exceeds_amount" // Nothing is left for the recipient after the fee
+)
+
+// WithdrawalViolation is a check a withdrawal failed
+type WithdrawalViolation struct {
+	Rule   WithdrawalRule
+	Detail string
+}
+
+// ErrWithdrawalInvalid is matched by every *WithdrawalValidationError
+var ErrWithdrawalInvalid = errors.New("invalid withdrawal")
+
+// WithdrawalValidationError lists every check a withdrawal failed
+type WithdrawalValidationError struct {
+	Asset      string
+	Key        string
+	Violations []WithdrawalViolation
+}
+
+func (e *WithdrawalValidationError) Error() string {
+	details := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		details[i] = v.Detail
+	}
+	return fmt.Sprintf("invalid withdrawal of %s to %s: %s", e.Asset, e.Key, strings.Join(details, "; "))
+}
+
+// Unwrap makes errors.Is(err, ErrWithdrawalInvalid) hold
+func (e *WithdrawalValidationError) Unwrap() error {
+	return ErrWithdrawalInvalid
+}
+
+// WithdrawalOptions tunes WithdrawChecked
+type WithdrawalOptions struct {
+	// LimitBasis selects the amount compared to the remaining limit.
+	// Defaults to WithdrawLimitNet.
+	LimitBasis WithdrawalBasis
+	// DryRun validates the withdrawal without executing it
+	DryRun bool
+}
+
+// WithdrawalCheck is the outcome of validating a withdrawal. Amounts are
+// exact decimals as sent to and returned by Kraken.
+type WithdrawalCheck struct {
+	Asset    string
+	Key      string
+	Method   string   // Withdrawal method of the key, from WithdrawInfo
+	Decimals int      // Decimals of the asset
+	Amount   *big.Rat // Requested amount
+	Fee      *big.Rat
+	Net      *big.Rat // Amount the recipient receives
+	Gross    *big.Rat // Amount leaving the balance, Net plus Fee
+	// FeeIncluded reports whether Kraken deducts the fee from the requested
+	// amount, so that Gross equals Amount, rather than adding it on top
+	FeeIncluded bool
+	Minimum     *big.Rat // Minimum of the method, nil if WithdrawMethods does not list it
+	Limit       *big.Rat // Remaining withdrawal limit
+	LimitBasis  WithdrawalBasis
+	RefID       string // Reference of the executed withdrawal, empty on a dry run or failure
+}
+
+// WithdrawChecked validates a withdrawal before executing it. The amount
+// must be positive, have no more decimals than the asset, reach the minimum
+// of the key's withdrawal method and stay within the remaining limit, with
+// the fee accounted for as selected by opts.LimitBasis. Failed checks are
+// returned together as a *WithdrawalValidationError, and the withdrawal is
+// only executed if all pass and opts.DryRun is not set. The returned check
+// holds the net amount the recipient receives whenever Kraken could compute
+// it, even alongside a validation error.
+func (api *KrakenAPI) WithdrawChecked(ctx context.Context, asset, key string, amount *big.Float, opts WithdrawalOptions) (*WithdrawalCheck, error) {
+	if amount == nil {
+		return nil, errors.New("no withdrawal amount")
+	}
+	basis := opts.LimitBasis
+	if basis == "" {
+		basis = WithdrawLimitNet
+	}
+	if basis != WithdrawLimitNet && basis != WithdrawLimitGross {
+		return nil, fmt.Errorf("invalid limit basis %q", basis)
+	}
+
+	assets, err := api.assets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, ok := findAsset(*assets, asset)
+	if !ok {
+		return nil, fmt.Errorf("unknown asset %s", asset)
+	}
+
+	text := amount.Text('f', -1)
+	check := &WithdrawalCheck{
+		Asset:      asset,
+		Key:        key,
+		Decimals:   info.Decimals,
+		Amount:     exactDecimal(text),
+		LimitBasis: basis,
+	}
+	invalid := &WithdrawalValidationError{Asset: asset, Key: key}
+	violate := func(rule WithdrawalRule, format string, args ...interface{}) {
+		invalid.Violations = append(invalid.Violations, WithdrawalViolation{Rule: rule, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	if i := strings.IndexByte(text, '.'); i >= 0 && len(text)-i-1 > info.Decimals {
+		violate(WithdrawalTooPrecise, "amount %s has more than the %d decimals of %s", text, info.Decimals, asset)
+	}
+	if check.Amount.Sign() <= 0 {
+		// Kraken computes no fee nor limit for such an amount
+		violate(WithdrawalNotPositive, "amount %s is not positive", text)
+		return check, invalid
+	}
+
+	withdrawInfo, err := api.WithdrawInfoWithContext(ctx, asset, key, amount)
+	if err != nil {
+		return nil, err
+	}
+	check.Method = withdrawInfo.Method
+	check.Fee = exactDecimal(withdrawInfo.Fee.Text('f', -1))
+	check.Net = exactDecimal(withdrawInfo.Amount.Text('f', -1))
+	check.Gross = new(big.Rat).Add(check.Net, check.Fee)
+	check.FeeIncluded = check.Gross.Cmp(check.Amount) == 0
+	check.Limit = exactDecimal(withdrawInfo.Limit.Text('f', -1))
+
+	minimum, err := api.withdrawMinimum(ctx, asset, withdrawInfo.Method)
+	if err != nil {
+		return nil, err
+	}
+	check.Minimum = minimum
+
+	if check.Net.Sign() <= 0 {
+		violate(WithdrawalFeeExceedsAmount, "fee %s leaves nothing of %s", check.formatAmount(check.Fee), text)
+	}
+	if minimum != nil && check.Amount.Cmp(minimum) < 0 {
+		violate(WithdrawalBelowMinimum, "amount %s is below the minimum %s of %s", text, check.formatAmount(minimum), withdrawInfo.Method)
+	}
+	limited := check.Net
+	if basis == WithdrawLimitGross {
+		limited = check.Gross
+	}
+	if limited.Cmp(check.Limit) > 0 {
+		violate(WithdrawalAboveLimit, "%s amount %s exceeds the remaining limit %s", basis, check.formatAmount(limited), check.formatAmount(check.Limit))
+	}
+
+	if len(invalid.Violations) > 0 {
+		return check, invalid
+	}
+	if opts.DryRun {
+		return check, nil
+	}
+
+	resp, err := api.WithdrawWithContext(ctx, asset, key, amount)
+	if err != nil {
+		return check, err
+	}
+	check.RefID = resp.RefID
+	return check, nil
+}
+
+// withdrawMinimum returns the minimum amount of the withdrawal method of
+// asset, or nil if Kraken does not list one
+func (api *KrakenAPI) withdrawMinimum(ctx context.Context, asset, method string) (*big.Rat, error) {
+	// Decoded as sent, the float64 of WithdrawMethodInfo may be off the
+	// exact minimum
+	var methods []struct {
+		Method  string `json:"method"`
+		Minimum string `json:"minimum"`
+	}
+	if _, err := api.queryPrivateContext(ctx, "WithdrawMethods", url.Values{"asset": {asset}}, &methods); err != nil {
+		return nil, err
+	}
+	for _, m := range methods {
+		if m.Method != method {
+			continue
+		}
+		if minimum := exactDecimal(m.Minimum); minimum.Sign() != 0 {
+			return minimum, nil
+		}
+	}
+	return nil, nil
+}
+
+// findAsset returns the asset of the response named name or having name as altname
+func findAsset(assets AssetsResponse, name string) (AssetInfo, bool) {
+	if info, ok := assets[name]; ok {
+		return info, true
+	}
+	for _, info := range assets {
+		if info.Altname == name {
+			return info, true
+		}
+	}
+	return AssetInfo{}, false
+}
+
+// exactDecimal parses a decimal returned by Kraken, reading malformed ones as zero
+func exactDecimal(s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return new(big.Rat)
+	}
+	return r
+}
+
+// formatAmount renders an amount with the decimals of the asset
+func (c *WithdrawalCheck) formatAmount(r *big.Rat) string {
+	return trimZeros(r.FloatString(c.Decimals))
+}
@@ -0,0 +1,111 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+)
+
+func newWithdrawalTestAPI(withdrawInfo string, calls map[string]int) *KrakenAPI {
+	return newTestAPI(func(method string, req *http.Request) string {
+		calls[method]++
+		switch method {
+		case "Assets":
+			return `{"error":[],"result":{"XXBT":{"aclass":"currency","altname":"XBT","decimals":8,"display_decimals":5}}}`
+		case "WithdrawInfo":
+			return withdrawInfo
+		case "WithdrawMethods":
+			return `{"error":[],"result":[{"asset":"XXBT","method":"Bitcoin","network":"Bitcoin","minimum":"0.0005"},
+				{"asset":"XXBT","method":"Bitcoin Lightning","network":"Lightning","minimum":"0.00001"}]}`
+		case "Withdraw":
+			return `{"error":[],"result":{"refid":"FTQcuak-V6Za8qrWnhzTx67yYHz8Tg"}}`
+		}
+		return `{"error":["EGeneral:Unknown method"]}`
+	})
+}
+
+func TestWithdrawChecked(t *testing.T) {
+	calls := make(map[string]int)
+	// The fee is deducted from the amount
+	api := newWithdrawalTestAPI(`{"error":[],"result":{"method":"Bitcoin","limit":"0.1","amount":"0.0999","fee":"0.0001"}}`, calls)
+	ctx := context.Background()
+
+	check, err := api.WithdrawChecked(ctx, "XBT", "wallet", big.NewFloat(0.1), WithdrawalOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Net.RatString() != "999/10000" || !check.FeeIncluded || check.Gross.Cmp(check.Amount) != 0 {
+		t.Errorf("Unexpected net amount %s, fee included %v", check.Net.FloatString(8), check.FeeIncluded)
+	}
+	if check.Minimum == nil || check.Minimum.FloatString(4) != "0.0005" {
+		t.Errorf("The minimum of the method of the key should be used, got %v", check.Minimum)
+	}
+	if calls["Withdraw"] != 0 || check.RefID != "" {
+		t.Errorf("A dry run should not withdraw")
+	}
+
+	// Gross, the same withdrawal exceeds the limit
+	_, err = api.WithdrawChecked(ctx, "XBT", "wallet", big.NewFloat(0.1), WithdrawalOptions{LimitBasis: WithdrawLimitGross})
+	if err != nil {
+		t.Errorf("The gross amount equals the limit and should pass, got %v", err)
+	}
+	if calls["Withdraw"] != 1 {
+		t.Errorf("A valid withdrawal should be executed once, got %d", calls["Withdraw"])
+	}
+}
+
+func TestWithdrawCheckedViolations(t *testing.T) {
+	calls := make(map[string]int)
+	// The fee is added on top of the amount, which is below the minimum
+	api := newWithdrawalTestAPI(`{"error":[],"result":{"method":"Bitcoin","limit":"0.0004","amount":"0.000400001","fee":"0.0001"}}`, calls)
+
+	check, err := api.WithdrawChecked(context.Background(), "XXBT", "wallet", big.NewFloat(0.000400001), WithdrawalOptions{LimitBasis: WithdrawLimitGross})
+	var invalid *WithdrawalValidationError
+	if !errors.As(err, &invalid) || !errors.Is(err, ErrWithdrawalInvalid) {
+		t.Fatalf("Expected a *WithdrawalValidationError, got %v", err)
+	}
+	var rules []WithdrawalRule
+	for _, v := range invalid.Violations {
+		rules = append(rules, v.Rule)
+	}
+	want := []WithdrawalRule{WithdrawalTooPrecise, WithdrawalBelowMinimum, WithdrawalAboveLimit}
+	if len(rules) != len(want) {
+		t.Fatalf("Expected violations %v, got %v", want, rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("Expected violations %v, got %v", want, rules)
+		}
+	}
+	if check == nil || check.FeeIncluded || check.Gross.FloatString(9) != "0.000500001" {
+		t.Errorf("The check should carry the amounts computed by Kraken, got %+v", check)
+	}
+	if calls["Withdraw"] != 0 {
+		t.Errorf("An invalid withdrawal should not be executed")
+	}
+
+	_, err = api.WithdrawChecked(context.Background(), "XBT", "wallet", big.NewFloat(0), WithdrawalOptions{})
+	if !errors.As(err, &invalid) || invalid.Violations[0].Rule != WithdrawalNotPositive {
+		t.Errorf("A zero amount should be rejected, got %v", err)
+	}
+	if calls["WithdrawInfo"] != 1 {
+		t.Errorf("A zero amount should not reach WithdrawInfo")
+	}
+}
+
+func TestWithdrawMinimumExact(t *testing.T) {
+	api := newTestAPI(func(method string, req *http.Request) string {
+		return `{"error":[],"result":[{"asset":"XXBT","method":"Bitcoin","minimum":"0.100000000000000001"},
+			{"asset":"XXBT","method":"Bitcoin Lightning","minimum":"0"}]}`
+	})
+
+	minimum, err := api.withdrawMinimum(context.Background(), "XBT", "Bitcoin")
+	if err != nil || minimum == nil || minimum.Cmp(exactDecimal("0.100000000000000001")) != 0 {
+		t.Errorf("The minimum should be kept as Kraken sent it, got %v, %v", minimum, err)
+	}
+	if minimum, err := api.withdrawMinimum(context.Background(), "XBT", "Bitcoin Lightning"); err != nil || minimum != nil {
+		t.Errorf("A zero minimum should be ignored, got %v, %v", minimum, err)
+	}
+}
@@ -0,0 +1,202 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+// bookDepths are the depths Kraken accepts for book subscriptions
+var bookDepths = []int{10, 25, 100, 500, 1000}
+
+// Book is the order book of a pair maintained from the book channel, asks
+// by ascending and bids by descending price. Its methods are safe for
+// concurrent use.
+type Book struct {
+	Pair  string
+	Depth int
+
+	mu      sync.RWMutex
+	book    krakenapi.OrderBook
+	updated time.Time
+}
+
+// newBook returns the empty book of pair, holding depth levels per side
+func newBook(pair string, depth int) *Book {
+	return &Book{Pair: pair, Depth: depth}
+}
+
+// Snapshot returns a copy of the book
+func (b *Book) Snapshot() krakenapi.OrderBook {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return krakenapi.OrderBook{
+		Asks: append([]krakenapi.OrderBookItem(nil), b.book.Asks...),
+		Bids: append([]krakenapi.OrderBookItem(nil), b.book.Bids...),
+	}
+}
+
+// Updated returns the time of the latest level applied to the book, zero
+// before the snapshot
+func (b *Book) Updated() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.updated
+}
+
+// bookPayload is a payload of a book message: a snapshot with as and bs,
+// or an update with a, b or both. Levels are [price, volume, timestamp],
+// updates may append "r" to levels republished after a truncation.
+type bookPayload struct {
+	As [][]string `json:"as"`
+	Bs [][]string `json:"bs"`
+	A  [][]string `json:"a"`
+	B  [][]string `json:"b"`
+}
+
+// apply applies a snapshot or update payload to the book
+func (b *Book) apply(p bookPayload) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if p.As != nil || p.Bs != nil {
+		b.book = krakenapi.OrderBook{}
+		if err := b.applySide(&b.book.Asks, p.As, false); err != nil {
+			return err
+		}
+		return b.applySide(&b.book.Bids, p.Bs, true)
+	}
+	if err := b.applySide(&b.book.Asks, p.A, false); err != nil {
+		return err
+	}
+	return b.applySide(&b.book.Bids, p.B, true)
+}
+
+// applySide inserts, replaces or, for a zero volume, removes the levels of
+// a side, then truncates it to the depth of the book
+func (b *Book) applySide(side *[]krakenapi.OrderBookItem, levels [][]string, descending bool) error {
+	for _, level := range levels {
+		item, ts, err := parseLevel(level)
+		if err != nil {
+			return fmt.Errorf("book of %s: %w", b.Pair, err)
+		}
+		if ts.After(b.updated) {
+			b.updated = ts
+		}
+
+		levels := *side
+		i := sort.Search(len(levels), func(i int) bool {
+			if descending {
+				return levels[i].Price <= item.Price
+			}
+			return levels[i].Price >= item.Price
+		})
+		found := i < len(levels) && levels[i].Price == item.Price
+		switch {
+		case item.Amount == 0 && found:
+			levels = append(levels[:i], levels[i+1:]...)
+		case item.Amount == 0:
+		case found:
+			levels[i] = item
+		default:
+			levels = append(levels, krakenapi.OrderBookItem{})
+			copy(levels[i+1:], levels[i:])
+			levels[i] = item
+		}
+		*side = levels
+	}
+	if b.Depth > 0 && len(*side) > b.Depth {
+		*side = (*side)[:b.Depth]
+	}
+	return nil
+}
+
+// parseLevel parses [price, volume, timestamp] or its republished form
+// [price, volume, timestamp, "r"]
+func parseLevel(level []string) (krakenapi.OrderBookItem, time.Time, error) {
+	if len(level) < 3 {
+		return krakenapi.OrderBookItem{}, time.Time{}, fmt.Errorf("unexpected level %v", level)
+	}
+	price, err := strconv.ParseFloat(level[0], 64)
+	if err != nil {
+		return krakenapi.OrderBookItem{}, time.Time{}, err
+	}
+	volume, err := strconv.ParseFloat(level[1], 64)
+	if err != nil {
+		return krakenapi.OrderBookItem{}, time.Time{}, err
+	}
+	ts, err := krakenapi.ParseKrakenTime(level[2])
+	if err != nil {
+		return krakenapi.OrderBookItem{}, time.Time{}, err
+	}
+	return krakenapi.OrderBookItem{Price: price, Amount: volume, Ts: ts.Unix()}, ts, nil
+}
+
+// BookSubscription maintains the order books of its pairs
+type BookSubscription struct {
+	*Subscription
+	books map[string]*Book
+}
+
+// Book returns the book of pair, nil if it is not subscribed
+func (s *BookSubscription) Book(pair string) *Book {
+	return s.books[pair]
+}
+
+// SubscribeBook subscribes to the order books of pairs, given as WebSocket
+// pair names, and maintains them locally at depth, one of 10, 25, 100, 500
+// or 1000 levels per side. onUpdate, if not nil, is called after every
+// message applied to a book, snapshots included; it runs on the read loop
+// and must not block.
+func (c *Client) SubscribeBook(ctx context.Context, depth int, onUpdate func(*Book), pairs ...string) (*BookSubscription, error) {
+	if len(pairs) == 0 {
+		return nil, errNoPairs
+	}
+	valid := false
+	for _, d := range bookDepths {
+		valid = valid || depth == d
+	}
+	if !valid {
+		return nil, fmt.Errorf("unsupported book depth %d, Kraken accepts %v", depth, bookDepths)
+	}
+
+	books := make(map[string]*Book, len(pairs))
+	for _, pair := range pairs {
+		books[pair] = newBook(pair, depth)
+	}
+	handle := func(ctx context.Context, msg []json.RawMessage) error {
+		pair, payloads, err := publicMessage(msg)
+		if err != nil {
+			return err
+		}
+		book, ok := books[pair]
+		if !ok {
+			return fmt.Errorf("book update of unexpected pair %s", pair)
+		}
+		for _, payload := range payloads {
+			var p bookPayload
+			if err := json.Unmarshal(payload, &p); err != nil {
+				return err
+			}
+			if err := book.apply(p); err != nil {
+				return err
+			}
+		}
+		if onUpdate != nil {
+			onUpdate(book)
+		}
+		return nil
+	}
+
+	s, err := c.subscribe(ctx, c.env.WebSocketURL, pairs, subscriptionSpec{Name: "book", Depth: depth}, handle, func() {})
+	if err != nil {
+		return nil, err
+	}
+	return &BookSubscription{Subscription: s, books: books}, nil
+}
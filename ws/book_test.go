@@ -0,0 +1,157 @@
+package ws
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+func TestBookApply(t *testing.T) {
+	snapshot := bookPayload{
+		As: [][]string{{"101.0", "1.0", "1534614248.123678"}, {"102.0", "2.0", "1534614248.123678"}, {"103.0", "3.0", "1534614248.123678"}},
+		Bs: [][]string{{"100.0", "1.0", "1534614248.123678"}, {"99.0", "2.0", "1534614248.123678"}, {"98.0", "3.0", "1534614248.123678"}},
+	}
+	level := func(price, amount float64) krakenapi.OrderBookItem {
+		return krakenapi.OrderBookItem{Price: price, Amount: amount, Ts: 1534614248}
+	}
+	initial := krakenapi.OrderBook{
+		Asks: []krakenapi.OrderBookItem{level(101, 1), level(102, 2), level(103, 3)},
+		Bids: []krakenapi.OrderBookItem{level(100, 1), level(99, 2), level(98, 3)},
+	}
+
+	for _, test := range []struct {
+		name   string
+		update bookPayload
+		asks   []krakenapi.OrderBookItem
+		bids   []krakenapi.OrderBookItem
+	}{
+		{
+			name:   "Replace a level",
+			update: bookPayload{A: [][]string{{"102.0", "5.0", "1534614249.000000"}}},
+			asks:   []krakenapi.OrderBookItem{level(101, 1), {Price: 102, Amount: 5, Ts: 1534614249}, level(103, 3)},
+			bids:   initial.Bids,
+		},
+		{
+			name:   "Insert a level",
+			update: bookPayload{B: [][]string{{"99.5", "4.0", "1534614248.5"}}},
+			asks:   initial.Asks,
+			bids:   []krakenapi.OrderBookItem{level(100, 1), level(99.5, 4), level(99, 2)},
+		},
+		{
+			name:   "Insert a level at the top",
+			update: bookPayload{A: [][]string{{"100.5", "4.0", "1534614248.5"}}},
+			asks:   []krakenapi.OrderBookItem{level(100.5, 4), level(101, 1), level(102, 2)},
+			bids:   initial.Bids,
+		},
+		{
+			name:   "Insert a level beyond the depth",
+			update: bookPayload{B: [][]string{{"97.0", "4.0", "1534614248.5"}}},
+			asks:   initial.Asks,
+			bids:   initial.Bids,
+		},
+		{
+			name:   "Remove a level",
+			update: bookPayload{A: [][]string{{"101.0", "0.00000000", "1534614248.5"}}},
+			asks:   []krakenapi.OrderBookItem{level(102, 2), level(103, 3)},
+			bids:   initial.Bids,
+		},
+		{
+			name:   "Remove an unknown level",
+			update: bookPayload{B: [][]string{{"99.5", "0.00000000", "1534614248.5"}}},
+			asks:   initial.Asks,
+			bids:   initial.Bids,
+		},
+		{
+			name: "Remove a level and republish the next one",
+			update: bookPayload{B: [][]string{
+				{"100.0", "0.00000000", "1534614248.5"},
+				{"97.0", "4.0", "1534614248.5", "r"},
+			}},
+			asks: initial.Asks,
+			bids: []krakenapi.OrderBookItem{level(99, 2), level(98, 3), level(97, 4)},
+		},
+		{
+			name: "Update both sides",
+			update: bookPayload{
+				A: [][]string{{"103.0", "0.00000000", "1534614248.5"}},
+				B: [][]string{{"98.0", "6.0", "1534614248.5"}},
+			},
+			asks: []krakenapi.OrderBookItem{level(101, 1), level(102, 2)},
+			bids: []krakenapi.OrderBookItem{level(100, 1), level(99, 2), level(98, 6)},
+		},
+		{
+			name:   "Replace the book with a new snapshot",
+			update: bookPayload{As: [][]string{{"110.0", "1.0", "1534614248.5"}}, Bs: [][]string{}},
+			asks:   []krakenapi.OrderBookItem{level(110, 1)},
+			bids:   []krakenapi.OrderBookItem{},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			book := newBook("XBT/USD", 3)
+			if err := book.apply(snapshot); err != nil {
+				t.Fatal(err)
+			}
+			if got := book.Snapshot(); !reflect.DeepEqual(got, initial) {
+				t.Fatalf("Unexpected snapshot %+v", got)
+			}
+			if err := book.apply(test.update); err != nil {
+				t.Fatal(err)
+			}
+			got := book.Snapshot()
+			if len(got.Asks) != len(test.asks) || len(got.Bids) != len(test.bids) ||
+				(len(test.asks) > 0 && !reflect.DeepEqual(got.Asks, test.asks)) || (len(test.bids) > 0 && !reflect.DeepEqual(got.Bids, test.bids)) {
+				t.Errorf("Expected asks %v and bids %v, got %+v", test.asks, test.bids, got)
+			}
+		})
+	}
+
+	book := newBook("XBT/USD", 10)
+	if err := book.apply(bookPayload{A: [][]string{{"101.0", "x", "1534614248.5"}}}); err == nil {
+		t.Errorf("Malformed levels should fail")
+	}
+}
+
+func TestSubscribeBook(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		if req := c.confirm(t); req["subscription"].(map[string]interface{})["depth"] != 10.0 {
+			t.Errorf("Unexpected request %v", req)
+		}
+		c.write(`[1,{"as":[["5541.30000","2.50700000","1534614248.123678"],["5541.80000","0.33000000","1534614098.345543"]],` +
+			`"bs":[["5541.20000","1.52900000","1534614248.765567"]]},"book-10","XBT/USD"]`)
+		c.write(`[1,{"a":[["5541.30000","0.00000000","1534614335.345903"]]},{"b":[["5541.25000","1.00000000","1534614335.345903"]],"c":"974942666"},"book-10","XBT/USD"]`)
+	}()
+
+	updates := make(chan krakenapi.OrderBook, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := server.client().SubscribeBook(ctx, 10, func(b *Book) { updates <- b.Snapshot() }, "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if snapshot := <-updates; len(snapshot.Asks) != 2 || len(snapshot.Bids) != 1 {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+	update := <-updates
+	if len(update.Asks) != 1 || update.Asks[0].Price != 5541.8 || len(update.Bids) != 2 || update.Bids[0].Price != 5541.25 {
+		t.Errorf("Unexpected book after the update %+v", update)
+	}
+	if book := sub.Book("XBT/USD"); book.Updated() != time.Unix(1534614335, 345903000) {
+		t.Errorf("Unexpected update time %v", book.Updated())
+	}
+
+	cancel()
+	<-sub.Done()
+	if sub.Err() != nil {
+		t.Errorf("A cancelled subscription should not report an error, got %v", sub.Err())
+	}
+
+	if _, err := server.client().SubscribeBook(context.Background(), 50, nil, "XBT/USD"); err == nil {
+		t.Errorf("Unsupported depths should be refused")
+	}
+}
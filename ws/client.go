@@ -0,0 +1,351 @@
+// Package ws subscribes to the feeds of Kraken's WebSocket API (version 1).
+// Every subscription opens its own connection, closed along with the output
+// channel of the subscription when its context is done.
+package ws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+// DefaultHeartbeatTimeout is how long a subscription waits for a message
+// before failing with ErrHeartbeatTimeout. Kraken sends a heartbeat every
+// second when there is no other traffic.
+const DefaultHeartbeatTimeout = 5 * time.Second
+
+// Client subscribes to the WebSocket feeds of a Kraken environment
+type Client struct {
+	env              krakenapi.Environment
+	reqID            int64
+	heartbeatTimeout time.Duration
+	stats            *krakenapi.KrakenAPI
+	recorder         Recorder
+	// replay opens the transport of a replay client, nil to connect to Kraken
+	replay func(ctx context.Context) (transport, error)
+}
+
+// New returns a client of the production WebSocket API
+func New() *Client {
+	return NewWithEnvironment(krakenapi.Production)
+}
+
+// NewWithEnvironment returns a client of the WebSocket API of env
+func NewWithEnvironment(env krakenapi.Environment) *Client {
+	return &Client{env: env}
+}
+
+// WithHeartbeatTimeout sets how long the subscriptions of c wait for a
+// message, heartbeats included, before failing with ErrHeartbeatTimeout.
+// Zero restores DefaultHeartbeatTimeout.
+func (c *Client) WithHeartbeatTimeout(timeout time.Duration) *Client {
+	c.heartbeatTimeout = timeout
+	return c
+}
+
+// WithStats reports the connections of the subscriptions of c, and the time
+// of their last message, to the Stats of api
+func (c *Client) WithStats(api *krakenapi.KrakenAPI) *Client {
+	c.stats = api
+	return c
+}
+
+// Recorder receives every message of a subscription as it is read, e.g. a
+// *krakenapi.FrameRecorder
+type Recorder interface {
+	Record(t time.Time, data []byte) error
+}
+
+var _ Recorder = (*krakenapi.FrameRecorder)(nil)
+
+// WithRecorder passes every message read by the subscriptions of c, events
+// and heartbeats included, to r. Their frames are interleaved in r, so a
+// frame log meant for NewReplayClient records a single subscription.
+// Errors of r are ignored, so that a failing log does not stop the feeds.
+func (c *Client) WithRecorder(r Recorder) *Client {
+	c.recorder = r
+	return c
+}
+
+// SubscriptionError is returned when Kraken rejects a subscription
+type SubscriptionError struct {
+	Channel string
+	Pair    string // Empty for subscriptions without pair
+	Message string
+}
+
+func (e *SubscriptionError) Error() string {
+	if e.Pair == "" {
+		return fmt.Sprintf("subscription to %s rejected: %s", e.Channel, e.Message)
+	}
+	return fmt.Sprintf("subscription to %s of %s rejected: %s", e.Channel, e.Pair, e.Message)
+}
+
+// subscriptionSpec is the subscription object of subscribe requests
+type subscriptionSpec struct {
+	Name     string `json:"name"`
+	Depth    int    `json:"depth,omitempty"`
+	Interval int    `json:"interval,omitempty"`
+	Token    string `json:"token,omitempty"`
+	Snapshot *bool  `json:"snapshot,omitempty"`
+}
+
+// request is a subscribe or unsubscribe request
+type request struct {
+	Event        string           `json:"event"`
+	ReqID        int64            `json:"reqid,omitempty"`
+	Pair         []string         `json:"pair,omitempty"`
+	Subscription subscriptionSpec `json:"subscription"`
+}
+
+// event is a message of the API other than channel data
+type event struct {
+	Event        string `json:"event"`
+	Status       string `json:"status"`
+	Pair         string `json:"pair"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// Subscription is the connection of a subscription. Its methods are safe for
+// concurrent use.
+type Subscription struct {
+	conn     transport
+	req      request
+	done     chan struct{}
+	stats    *krakenapi.KrakenAPI // Nil if not reported
+	recorder Recorder             // Nil if not recorded
+
+	mu  sync.Mutex
+	err error
+}
+
+// Err returns why the subscription stopped, nil while it runs or if its context was done
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Done returns a channel closed once the subscription stopped
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// recordConnection reports the connection opened or closed to the stats
+func (s *Subscription) recordConnection(open bool) {
+	if s.stats != nil {
+		s.stats.RecordWebSocketConnection(s.req.Subscription.Name, open)
+	}
+}
+
+// readMessage reads the next message of the connection, reporting it to the
+// stats and the recorder
+func (s *Subscription) readMessage() ([]byte, error) {
+	message, err := s.conn.readMessage()
+	if err != nil {
+		return nil, err
+	}
+	if s.stats != nil {
+		s.stats.RecordWebSocketMessage(s.req.Subscription.Name)
+	}
+	if s.recorder != nil {
+		s.recorder.Record(time.Now(), message)
+	}
+	return message, nil
+}
+
+func (s *Subscription) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// handler processes a data message of a subscription, split into its
+// elements. It returns an error to end the subscription.
+type handler func(ctx context.Context, msg []json.RawMessage) error
+
+// subscribe connects to url and sends a subscribe request for spec and
+// pairs, returning once Kraken accepted it for every pair. Data messages are
+// then passed to handle until ctx is done, which unsubscribes, or the
+// connection fails. finish is called at the end, e.g. to close the output
+// channel.
+func (c *Client) subscribe(ctx context.Context, url string, pairs []string, spec subscriptionSpec, handle handler, finish func()) (*Subscription, error) {
+	conn, err := c.connect(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	s := &Subscription{
+		conn:     conn,
+		req:      request{Event: "subscribe", ReqID: atomic.AddInt64(&c.reqID, 1), Pair: pairs, Subscription: spec},
+		done:     make(chan struct{}),
+		stats:    c.stats,
+		recorder: c.recorder,
+	}
+	s.recordConnection(true)
+	go s.watch(ctx)
+
+	pending, err := s.confirm()
+	if err != nil {
+		close(s.done)
+		conn.close()
+		s.recordConnection(false)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	go s.run(ctx, pending, handle, finish)
+	return s, nil
+}
+
+// connect opens the transport of a subscription to url
+func (c *Client) connect(ctx context.Context, url string) (transport, error) {
+	if c.replay != nil {
+		return c.replay(ctx)
+	}
+	conn, err := dial(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	conn.readTimeout = c.heartbeatTimeout
+	if conn.readTimeout <= 0 {
+		conn.readTimeout = DefaultHeartbeatTimeout
+	}
+	return conn, nil
+}
+
+// watch unsubscribes and closes the connection once ctx is done
+func (s *Subscription) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		unsubscribe := s.req
+		unsubscribe.Event = "unsubscribe"
+		unsubscribe.ReqID = 0
+		if data, err := json.Marshal(unsubscribe); err == nil {
+			s.conn.writeMessage(data)
+		}
+		s.conn.close()
+	case <-s.done:
+	}
+}
+
+// confirm sends the subscribe request and waits for its status for every
+// pair, returning the data messages received meanwhile
+func (s *Subscription) confirm() ([][]byte, error) {
+	data, err := json.Marshal(s.req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.conn.writeMessage(data); err != nil {
+		return nil, err
+	}
+
+	var pending [][]byte
+	waiting := len(s.req.Pair)
+	if waiting == 0 {
+		waiting = 1
+	}
+	for waiting > 0 {
+		message, err := s.readMessage()
+		if err != nil {
+			return nil, err
+		}
+		if isData(message) {
+			pending = append(pending, message)
+			continue
+		}
+
+		var e event
+		if err := json.Unmarshal(message, &e); err != nil {
+			return nil, err
+		}
+		switch {
+		case e.Event == "error":
+			return nil, &SubscriptionError{Channel: s.req.Subscription.Name, Message: e.ErrorMessage}
+		case e.Event != "subscriptionStatus":
+		case e.Status == "error":
+			return nil, &SubscriptionError{Channel: s.req.Subscription.Name, Pair: e.Pair, Message: e.ErrorMessage}
+		case e.Status == "subscribed":
+			waiting--
+		}
+	}
+	return pending, nil
+}
+
+// run passes the data messages to handle until ctx is done or the connection
+// fails, e.g. with ErrHeartbeatTimeout once Kraken went silent
+func (s *Subscription) run(ctx context.Context, pending [][]byte, handle handler, finish func()) {
+	defer close(s.done)
+	defer s.recordConnection(false)
+	defer s.conn.close()
+	defer finish()
+
+	for {
+		var message []byte
+		if len(pending) > 0 {
+			message, pending = pending[0], pending[1:]
+		} else {
+			var err error
+			if message, err = s.readMessage(); err != nil {
+				if ctx.Err() == nil {
+					s.fail(err)
+				}
+				return
+			}
+		}
+		if !isData(message) {
+			continue
+		}
+
+		var msg []json.RawMessage
+		err := json.Unmarshal(message, &msg)
+		if err == nil {
+			err = handle(ctx, msg)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				s.fail(err)
+			}
+			return
+		}
+	}
+}
+
+// isData tells channel data, sent as arrays, from events, sent as objects
+func isData(message []byte) bool {
+	message = bytes.TrimSpace(message)
+	return len(message) > 0 && message[0] == '['
+}
+
+// publicMessage splits a message of a public channel, [channelID, payload...,
+// channelName, pair], into its pair and payloads
+func publicMessage(msg []json.RawMessage) (string, []json.RawMessage, error) {
+	if len(msg) < 4 {
+		return "", nil, fmt.Errorf("unexpected message of %d elements", len(msg))
+	}
+	var pair string
+	if err := json.Unmarshal(msg[len(msg)-1], &pair); err != nil {
+		return "", nil, err
+	}
+	return pair, msg[1 : len(msg)-2], nil
+}
+
+// send delivers v to out unless ctx is done first
+func send[T any](ctx context.Context, out chan<- T, v T) error {
+	select {
+	case out <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// errNoPairs is returned by the subscriptions of public channels called without pairs
+var errNoPairs = errors.New("no pairs to subscribe to")
@@ -0,0 +1,230 @@
+package ws
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+// fakeServer accepts WebSocket connections and hands them to the test
+type fakeServer struct {
+	*httptest.Server
+	conns chan *serverConn
+}
+
+// serverConn is the server side of a connection. It reads the masked frames
+// of the client through conn and writes unmasked frames.
+type serverConn struct {
+	*conn
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{conns: make(chan *serverConn, 4)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Upgrade") != "websocket" || req.Header.Get("Sec-WebSocket-Version") != "13" {
+			http.Error(w, "not a WebSocket handshake", http.StatusBadRequest)
+			return
+		}
+		nc, brw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		fmt.Fprintf(nc, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			acceptKey(req.Header.Get("Sec-WebSocket-Key")))
+		s.conns <- &serverConn{&conn{nc: nc, br: brw.Reader}}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// client returns a client of the server, for public and private feeds
+func (s *fakeServer) client() *Client {
+	url := "ws" + strings.TrimPrefix(s.URL, "http")
+	return NewWithEnvironment(krakenapi.Environment{Name: "test", WebSocketURL: url, AuthWebSocketURL: url})
+}
+
+// accept returns the next connection of the client
+func (s *fakeServer) accept(t *testing.T) *serverConn {
+	t.Helper()
+	select {
+	case c := <-s.conns:
+		t.Cleanup(func() { c.nc.Close() })
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("The client did not connect")
+		return nil
+	}
+}
+
+// read returns the next message of the client
+func (c *serverConn) read(t *testing.T) map[string]interface{} {
+	t.Helper()
+	c.nc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	data, err := c.readMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg map[string]interface{}
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// write sends message in a single unmasked text frame
+func (c *serverConn) write(message string) {
+	c.writeServerFrame(true, opText, []byte(message))
+}
+
+func (c *serverConn) writeServerFrame(fin bool, opcode byte, payload []byte) {
+	header := []byte{opcode, 0}
+	if fin {
+		header[0] |= 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	c.nc.Write(append(header, payload...))
+}
+
+// confirm reads a subscribe request and accepts it for every pair,
+// returning the request
+func (c *serverConn) confirm(t *testing.T) map[string]interface{} {
+	t.Helper()
+	req := c.read(t)
+	if req["event"] != "subscribe" {
+		t.Fatalf("Expected a subscribe request, got %v", req)
+	}
+	subscription, _ := json.Marshal(req["subscription"])
+	pairs, _ := req["pair"].([]interface{})
+	if len(pairs) == 0 {
+		pairs = []interface{}{nil}
+	}
+	for i, pair := range pairs {
+		status := map[string]interface{}{"channelID": i + 1, "event": "subscriptionStatus", "status": "subscribed", "reqid": req["reqid"]}
+		if pair != nil {
+			status["pair"] = pair
+		}
+		data, _ := json.Marshal(status)
+		c.write(strings.TrimSuffix(string(data), "}") + `,"subscription":` + string(subscription) + "}")
+	}
+	return req
+}
+
+func TestSubscriptionRejected(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		c.read(t)
+		c.write(`{"event":"systemStatus","status":"online","version":"1.9.0"}`)
+		c.write(`{"channelID":1,"event":"subscriptionStatus","pair":"XBT/USD","status":"subscribed","subscription":{"name":"ticker"}}`)
+		c.write(`{"errorMessage":"Currency pair not supported XBT/USDX","event":"subscriptionStatus","pair":"XBT/USDX","status":"error","subscription":{"name":"ticker"}}`)
+	}()
+
+	_, err := server.client().SubscribeTicker(context.Background(), "XBT/USD", "XBT/USDX")
+	var rejected *SubscriptionError
+	if !errors.As(err, &rejected) || rejected.Pair != "XBT/USDX" || rejected.Channel != "ticker" {
+		t.Errorf("Expected the rejection of XBT/USDX, got %v", err)
+	}
+
+	if _, err := server.client().SubscribeTicker(context.Background()); err == nil {
+		t.Errorf("Subscribing without pairs should fail")
+	}
+}
+
+func TestSubscriptionConnectionLost(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		c.confirm(t)
+		c.nc.Close()
+	}()
+
+	sub, err := server.client().SubscribeTicker(context.Background(), "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.C {
+	}
+	if !errors.Is(sub.Err(), ErrClosed) {
+		t.Errorf("Expected the connection to be reported closed, got %v", sub.Err())
+	}
+}
+
+func TestSubscriptionHeartbeatTimeout(t *testing.T) {
+	server := newFakeServer(t)
+	silent := make(chan struct{})
+	go func() {
+		c := server.accept(t)
+		c.confirm(t)
+		for i := 0; i < 10; i++ {
+			time.Sleep(20 * time.Millisecond)
+			c.write(`{"event":"heartbeat"}`)
+		}
+		close(silent)
+	}()
+
+	sub, err := server.client().WithHeartbeatTimeout(100*time.Millisecond).SubscribeTicker(context.Background(), "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-sub.Done():
+		t.Fatalf("Heartbeats should keep the subscription alive, got %v", sub.Err())
+	case <-silent:
+	}
+	select {
+	case <-sub.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("The subscription should stop once Kraken went silent")
+	}
+	for range sub.C {
+	}
+	if !errors.Is(sub.Err(), ErrHeartbeatTimeout) {
+		t.Errorf("Expected a heartbeat timeout, got %v", sub.Err())
+	}
+}
+
+func TestSubscriptionStats(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		c.confirm(t)
+		c.write(`[0,[["5541.20000","0.15850568","1534614057.321597","s","l",""]],"trade","XBT/USD"]`)
+	}()
+
+	api := krakenapi.New("key", "c2VjcmV0")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := server.client().WithStats(api).SubscribeTrade(ctx, "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-sub.C
+	if state := api.Stats().WebSockets["trade"]; state.Connections != 1 || state.LastMessage.IsZero() {
+		t.Errorf("Expected an open connection with messages, got %+v", state)
+	}
+
+	cancel()
+	<-sub.Done()
+	if state := api.Stats().WebSockets["trade"]; state.Connections != 0 {
+		t.Errorf("Expected the connection reported closed, got %+v", state)
+	}
+}
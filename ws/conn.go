@@ -0,0 +1,325 @@
+package ws
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// maxMessageSize bounds the size of a message, book snapshots of the
+// deepest subscriptions included
+const maxMessageSize = 16 << 20
+
+// acceptGUID is appended to the handshake key by RFC 6455
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes of RFC 6455
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// ErrClosed is returned by reads of a connection closed by either side
+var ErrClosed = errors.New("websocket connection closed")
+
+// ErrHeartbeatTimeout is returned by reads once the server sent nothing, not
+// even a heartbeat, for longer than the read timeout of the connection
+var ErrHeartbeatTimeout = errors.New("websocket heartbeat timeout")
+
+// transport carries the messages of a subscription: a WebSocket connection,
+// or a frame log replayed by a replay client
+type transport interface {
+	readMessage() ([]byte, error)
+	writeMessage(data []byte) error
+	close() error
+}
+
+// conn is a client WebSocket connection. Reads must come from a single
+// goroutine, writes are safe for concurrent use.
+type conn struct {
+	nc net.Conn
+	br *bufio.Reader
+	// readTimeout bounds the wait for every frame, none if zero
+	readTimeout time.Duration
+
+	mu     sync.Mutex // Serializes writes
+	closed bool
+}
+
+// dial opens a WebSocket connection to rawURL, a ws:// or wss:// URL
+func dial(ctx context.Context, rawURL string) (*conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	switch u.Scheme {
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported WebSocket URL %s", rawURL)
+	}
+
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		tc := tls.Client(nc, &tls.Config{ServerName: u.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			nc.Close()
+			return nil, err
+		}
+		nc = tc
+	}
+
+	// The handshake is bound to ctx, the connection is not
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			nc.Close()
+		case <-stop:
+		}
+	}()
+
+	c, err := handshake(nc, u)
+	if err != nil {
+		nc.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// handshake upgrades nc to a WebSocket connection
+func handshake(nc net.Conn, u *url.URL) (*conn, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &url.URL{Scheme: "http", Host: u.Host, Path: u.Path, RawQuery: u.RawQuery},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+		Host: u.Host,
+	}
+	if err := req.Write(nc); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(nc)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("WebSocket handshake failed with HTTP %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, errors.New("WebSocket handshake failed: invalid Sec-WebSocket-Accept")
+	}
+	return &conn{nc: nc, br: br}, nil
+}
+
+// acceptKey returns the Sec-WebSocket-Accept answering key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// readMessage returns the next text or binary message, answering pings on
+// the way. It returns ErrClosed once the server closed the connection.
+func (c *conn) readMessage() ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		if c.readTimeout > 0 {
+			c.nc.SetReadDeadline(time.Now().Add(c.readTimeout))
+		}
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.close()
+			return nil, ErrClosed
+		case opText, opBinary:
+			if started {
+				return nil, errors.New("WebSocket message interrupted by a new message")
+			}
+			started = true
+			message = payload
+		case opContinuation:
+			if !started {
+				return nil, errors.New("WebSocket continuation without a message")
+			}
+			if len(message)+len(payload) > maxMessageSize {
+				return nil, fmt.Errorf("WebSocket message larger than %d bytes", maxMessageSize)
+			}
+			message = append(message, payload...)
+		default:
+			return nil, fmt.Errorf("unknown WebSocket opcode %#x", opcode)
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readFrame reads a frame, which servers send unmasked
+func (c *conn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		return false, 0, nil, c.readErr(err)
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0f
+	masked := header[1]&0x80 != 0
+
+	size := uint64(header[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, c.readErr(err)
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, c.readErr(err)
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if size > maxMessageSize {
+		return false, 0, nil, fmt.Errorf("WebSocket frame larger than %d bytes", maxMessageSize)
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, c.readErr(err)
+		}
+	}
+	payload = make([]byte, size)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, c.readErr(err)
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// readErr reports reads failing after close as ErrClosed, and reads timing
+// out as ErrHeartbeatTimeout
+func (c *conn) readErr(err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || errors.Is(err, io.EOF) {
+		return ErrClosed
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: nothing received for %s", ErrHeartbeatTimeout, c.readTimeout)
+	}
+	return err
+}
+
+// writeMessage sends data as a text message
+func (c *conn) writeMessage(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// writeFrame sends a single masked frame, as clients must
+func (c *conn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, 14+len(payload))
+	frame = append(frame, 0x80|opcode)
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	_, err := c.nc.Write(frame)
+	return err
+}
+
+// close sends a close frame and closes the connection, unblocking readMessage
+func (c *conn) close() error {
+	c.writeFrame(opClose, []byte{0x03, 0xe8}) // 1000, normal closure
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.nc.Close()
+}
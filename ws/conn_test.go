@@ -0,0 +1,50 @@
+package ws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConnFrames(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		c.writeServerFrame(true, opPing, []byte("hello"))
+		c.writeServerFrame(false, opText, []byte(`[1,"frag`))
+		c.writeServerFrame(true, opPong, nil)
+		c.writeServerFrame(true, opContinuation, []byte(`mented"]`))
+		c.write(strings.Repeat("x", 70000))
+		c.writeServerFrame(true, opClose, []byte{0x03, 0xe8})
+	}()
+
+	client, err := dial(context.Background(), server.client().env.WebSocketURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.close()
+
+	if message, err := client.readMessage(); err != nil || string(message) != `[1,"fragmented"]` {
+		t.Errorf("Expected the fragments joined around the control frames, got %q and %v", message, err)
+	}
+	if message, err := client.readMessage(); err != nil || len(message) != 70000 {
+		t.Errorf("Expected a message of 70000 bytes, got %d and %v", len(message), err)
+	}
+	if _, err := client.readMessage(); err != ErrClosed {
+		t.Errorf("Expected the close to be reported, got %v", err)
+	}
+}
+
+func TestDialRejected(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, err := dial(context.Background(), server.URL); err == nil {
+		t.Errorf("HTTP URLs should be refused")
+	}
+	if _, err := dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http")); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected the failed handshake to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+// Candle is an update of the ohlc channel. Kraken sends the candle in
+// progress on every trade; its values are final once Closed is set.
+type Candle struct {
+	Pair           string // WebSocket pair name, e.g. "XBT/USD"
+	Interval       krakenapi.OHLCInterval
+	krakenapi.OHLC           // Time is the start of the candle, as in the REST OHLC
+	End            time.Time // End of the candle
+	Updated        time.Time // Time of the update
+	// Closed marks the last update of a candle, delivered again when the
+	// first update of the next candle arrives
+	Closed bool
+}
+
+// OHLCSubscription delivers the candles of its pairs
+type OHLCSubscription struct {
+	// C receives every update of the candles. It is closed when the context
+	// is done or the subscription fails, see Err.
+	C <-chan Candle
+	*Subscription
+}
+
+// SubscribeOHLC subscribes to the candles of pairs, given as WebSocket pair
+// names, e.g. "XBT/USD". Zero interval is one minute candles.
+func (c *Client) SubscribeOHLC(ctx context.Context, interval krakenapi.OHLCInterval, pairs ...string) (*OHLCSubscription, error) {
+	if len(pairs) == 0 {
+		return nil, errNoPairs
+	}
+	if interval == 0 {
+		interval = krakenapi.OHLCInterval1m
+	}
+	if err := interval.Validate(); err != nil {
+		return nil, err
+	}
+
+	out := make(chan Candle)
+	latest := make(map[string]Candle, len(pairs))
+	handle := func(ctx context.Context, msg []json.RawMessage) error {
+		pair, payloads, err := publicMessage(msg)
+		if err != nil {
+			return err
+		}
+		var fields []json.RawMessage
+		if err := json.Unmarshal(payloads[0], &fields); err != nil {
+			return err
+		}
+		candle, err := decodeCandle(rawStrings(fields), interval)
+		if err != nil {
+			return err
+		}
+		candle.Pair = pair
+
+		if previous, ok := latest[pair]; ok && candle.End.After(previous.End) {
+			previous.Closed = true
+			if err := send(ctx, out, previous); err != nil {
+				return err
+			}
+		}
+		latest[pair] = candle
+		return send(ctx, out, candle)
+	}
+
+	s, err := c.subscribe(ctx, c.env.WebSocketURL, pairs, subscriptionSpec{Name: "ohlc", Interval: int(interval)}, handle, func() { close(out) })
+	if err != nil {
+		return nil, err
+	}
+	return &OHLCSubscription{C: out, Subscription: s}, nil
+}
+
+// decodeCandle decodes [time, etime, open, high, low, close, vwap, volume, count]
+func decodeCandle(fields []string, interval krakenapi.OHLCInterval) (Candle, error) {
+	if len(fields) < 9 {
+		return Candle{}, fmt.Errorf("unexpected candle %v", fields)
+	}
+	updated, err := krakenapi.ParseKrakenTime(fields[0])
+	if err != nil {
+		return Candle{}, err
+	}
+	end, err := krakenapi.ParseKrakenTime(fields[1])
+	if err != nil {
+		return Candle{}, err
+	}
+
+	candle := Candle{Interval: interval, End: end, Updated: updated}
+	candle.Time = end.Add(-time.Duration(interval) * time.Minute)
+	values := []*float64{&candle.Open, &candle.High, &candle.Low, &candle.Close, &candle.Vwap, &candle.Volume}
+	for i, value := range values {
+		if *value, err = strconv.ParseFloat(fields[i+2], 64); err != nil {
+			return Candle{}, err
+		}
+	}
+	if candle.Count, err = strconv.Atoi(fields[8]); err != nil {
+		return Candle{}, err
+	}
+	return candle, nil
+}
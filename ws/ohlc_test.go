@@ -0,0 +1,48 @@
+package ws
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubscribeOHLC(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		if req := c.confirm(t); req["subscription"].(map[string]interface{})["interval"] != 5.0 {
+			t.Errorf("Unexpected request %v", req)
+		}
+		c.write(`[42,["1542057314.748456","1542057600.000000","3586.70000","3586.70000","3586.60000","3586.60000","3586.68894","0.03373000",2],"ohlc-5","XBT/USD"]`)
+		c.write(`[42,["1542057321.100000","1542057600.000000","3586.70000","3587.00000","3586.60000","3587.00000","3586.80000","0.04373000",3],"ohlc-5","XBT/USD"]`)
+		c.write(`[42,["1542057602.500000","1542057900.000000","3587.10000","3587.10000","3587.10000","3587.10000","3587.10000","0.01000000",1],"ohlc-5","XBT/USD"]`)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := server.client().SubscribeOHLC(ctx, 5, "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := <-sub.C
+	if first.Pair != "XBT/USD" || first.Closed || first.Count != 2 || first.Vwap != 3586.68894 {
+		t.Errorf("Unexpected candle %+v", first)
+	}
+	if !first.Time.Equal(time.Unix(1542057300, 0)) || !first.End.Equal(time.Unix(1542057600, 0)) {
+		t.Errorf("Expected the candle to span 5 minutes, got %v to %v", first.Time, first.End)
+	}
+	if update := <-sub.C; update.Closed || update.High != 3587 {
+		t.Errorf("Unexpected update %+v", update)
+	}
+	if closed := <-sub.C; !closed.Closed || closed.Count != 3 || closed.Close != 3587 {
+		t.Errorf("Expected the last update of the candle marked closed, got %+v", closed)
+	}
+	if next := <-sub.C; next.Closed || !next.Time.Equal(time.Unix(1542057600, 0)) {
+		t.Errorf("Unexpected next candle %+v", next)
+	}
+
+	if _, err := server.client().SubscribeOHLC(ctx, 7, "XBT/USD"); err == nil {
+		t.Errorf("Unsupported intervals should be refused")
+	}
+}
@@ -0,0 +1,118 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+// TokenSource provides the token authenticating private subscriptions, e.g.
+// a *krakenapi.WSTokenSource
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+var _ TokenSource = (*krakenapi.WSTokenSource)(nil)
+
+// OwnTrade is a trade of the account from the ownTrades channel
+type OwnTrade struct {
+	TradeID string // Transaction id of the trade
+	krakenapi.TradeHistoryInfo
+	// Sequence numbers the messages of the channel, shared by the trades of
+	// a message. A jump of more than one means messages were missed.
+	Sequence int64
+}
+
+// OwnTradesArgs tunes SubscribeOwnTrades
+type OwnTradesArgs struct {
+	// SkipSnapshot leaves out the last 50 trades Kraken otherwise sends on
+	// subscription, e.g. when resubscribing after a reconnect
+	SkipSnapshot bool
+}
+
+// OwnTradesSubscription delivers the trades of the account
+type OwnTradesSubscription struct {
+	// C receives every trade. It is closed when the context is done or the
+	// subscription fails, see Err.
+	C <-chan OwnTrade
+	*Subscription
+}
+
+// SubscribeOwnTrades subscribes to the trades of the account on the private
+// WebSocket API, authenticated with a token of tokens
+func (c *Client) SubscribeOwnTrades(ctx context.Context, tokens TokenSource, args OwnTradesArgs) (*OwnTradesSubscription, error) {
+	token, err := tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	spec := subscriptionSpec{Name: "ownTrades", Token: token}
+	if args.SkipSnapshot {
+		snapshot := false
+		spec.Snapshot = &snapshot
+	}
+
+	out := make(chan OwnTrade)
+	handle := func(ctx context.Context, msg []json.RawMessage) error {
+		trades, err := decodeOwnTrades(msg)
+		if err != nil {
+			return err
+		}
+		for _, trade := range trades {
+			if err := send(ctx, out, trade); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s, err := c.subscribe(ctx, c.env.AuthWebSocketURL, nil, spec, handle, func() { close(out) })
+	if err != nil {
+		return nil, err
+	}
+	return &OwnTradesSubscription{C: out, Subscription: s}, nil
+}
+
+// decodeOwnTrades decodes [[{txid: trade}...], "ownTrades", {"sequence": n}],
+// keeping the order of the trades
+func decodeOwnTrades(msg []json.RawMessage) ([]OwnTrade, error) {
+	if len(msg) < 3 {
+		return nil, fmt.Errorf("unexpected message of %d elements", len(msg))
+	}
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(msg[0], &entries); err != nil {
+		return nil, err
+	}
+	var meta struct {
+		Sequence int64 `json:"sequence"`
+	}
+	if err := json.Unmarshal(msg[2], &meta); err != nil {
+		return nil, err
+	}
+
+	var trades []OwnTrade
+	for _, entry := range entries {
+		for txid, data := range entry {
+			// Unlike the REST TradesHistory, the time is sent as a string
+			var trade struct {
+				krakenapi.TradeHistoryInfo
+				Time string `json:"time"`
+			}
+			if err := json.Unmarshal(data, &trade); err != nil {
+				return nil, fmt.Errorf("trade %s: %w", txid, err)
+			}
+			info := trade.TradeHistoryInfo
+			if trade.Time != "" {
+				t, err := strconv.ParseFloat(trade.Time, 64)
+				if err != nil {
+					return nil, fmt.Errorf("trade %s: invalid time %q", txid, trade.Time)
+				}
+				info.Time = t
+			}
+			trades = append(trades, OwnTrade{TradeID: txid, TradeHistoryInfo: info, Sequence: meta.Sequence})
+		}
+	}
+	return trades, nil
+}
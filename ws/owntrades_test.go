@@ -0,0 +1,54 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type staticToken string
+
+func (t staticToken) Token(ctx context.Context) (string, error) {
+	if t == "" {
+		return "", errors.New("no token")
+	}
+	return string(t), nil
+}
+
+func TestSubscribeOwnTrades(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		req := c.confirm(t)
+		subscription := req["subscription"].(map[string]interface{})
+		if subscription["name"] != "ownTrades" || subscription["token"] != "WW91ciBhdXRoZW50aWNhdGlvbiB0b2tlbiBnb2VzIGhlcmUu" ||
+			subscription["snapshot"] != false || req["pair"] != nil {
+			t.Errorf("Unexpected request %v", req)
+		}
+		c.write(`[[{"TDLH43-DVQXD-2KHVYY":{"cost":"1000000.00000","fee":"1600.00000","margin":"0.00000","ordertxid":"OGTT3Y-C6I3P-XRI6HX",` +
+			`"ordertype":"limit","pair":"XBT/EUR","postxid":"TKH2SE-M7IF5-CFI7LT","price":"100000.00000","time":"1560516023.070651","type":"sell","vol":"10.00000000"}},` +
+			`{"TDLH43-DVQXD-2KHVYZ":{"cost":"50.00000","fee":"0.08000","margin":"0.00000","ordertxid":"OQCLML-BW3P3-BUCMWZ",` +
+			`"ordertype":"market","pair":"XBT/EUR","postxid":"TKH2SE-M7IF5-CFI7LT","price":"50000.00000","time":"1560516024.5","type":"buy","vol":"0.00100000"}}],` +
+			`"ownTrades",{"sequence":2948}]`)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := server.client().SubscribeOwnTrades(ctx, staticToken("WW91ciBhdXRoZW50aWNhdGlvbiB0b2tlbiBnb2VzIGhlcmUu"), OwnTradesArgs{SkipSnapshot: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := <-sub.C, <-sub.C
+	if first.TradeID != "TDLH43-DVQXD-2KHVYY" || first.TransactionID != "OGTT3Y-C6I3P-XRI6HX" || first.Type != "sell" || first.OrderType != "limit" ||
+		first.Price != 100000 || first.Cost != 1000000 || first.Fee != 1600 || first.Volume != 10 || first.Time != 1560516023.070651 {
+		t.Errorf("Unexpected trade %+v", first)
+	}
+	if second.TradeID != "TDLH43-DVQXD-2KHVYZ" || second.Type != "buy" || first.Sequence != 2948 || second.Sequence != 2948 {
+		t.Errorf("Unexpected trade %+v", second)
+	}
+
+	if _, err := server.client().SubscribeOwnTrades(ctx, staticToken(""), OwnTradesArgs{}); err == nil {
+		t.Errorf("A failed token fetch should be reported")
+	}
+}
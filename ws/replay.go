@@ -0,0 +1,85 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+// NewReplayClient returns a client whose subscriptions read a frame log
+// written through WithRecorder from r instead of connecting to Kraken. The
+// frames go through the same decoding, and book maintenance for books, as
+// live ones. The log holds the single subscription it was recorded from,
+// which must be made again with the same channel and pairs; subscribing
+// again fails. The subscription ends with Err io.EOF once the log is
+// exhausted.
+func NewReplayClient(r io.Reader, opts krakenapi.ReplayOptions) *Client {
+	c := &Client{}
+	var mu sync.Mutex
+	replayed := false
+	c.replay = func(ctx context.Context) (transport, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if replayed {
+			return nil, errors.New("frame log already replayed")
+		}
+		replayed = true
+		return replay(ctx, r, opts), nil
+	}
+	return c
+}
+
+// replayConn is the transport of a replay client. Writes are discarded.
+type replayConn struct {
+	frames chan []byte
+	stop   context.CancelFunc
+	done   chan struct{}
+	err    error // Why the replay ended, set before done is closed
+}
+
+// replay starts reading the frame log of r until ctx is done or the
+// transport is closed
+func replay(ctx context.Context, r io.Reader, opts krakenapi.ReplayOptions) *replayConn {
+	ctx, stop := context.WithCancel(ctx)
+	c := &replayConn{frames: make(chan []byte), stop: stop, done: make(chan struct{})}
+	go func() {
+		err := krakenapi.ReplayFrames(ctx, r, opts, func(f krakenapi.Frame) error {
+			select {
+			case c.frames <- f.Data:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		switch {
+		case ctx.Err() != nil:
+			err = ErrClosed
+		case err == nil:
+			err = io.EOF
+		}
+		c.err = err
+		close(c.done)
+	}()
+	return c
+}
+
+func (c *replayConn) readMessage() ([]byte, error) {
+	select {
+	case data := <-c.frames:
+		return data, nil
+	case <-c.done:
+		return nil, c.err
+	}
+}
+
+func (c *replayConn) writeMessage(data []byte) error {
+	return nil
+}
+
+func (c *replayConn) close() error {
+	c.stop()
+	return nil
+}
@@ -0,0 +1,53 @@
+package ws
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+func TestReplayBook(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		c.confirm(t)
+		c.write(`{"event":"heartbeat"}`)
+		c.write(`[1,{"as":[["5541.30000","2.50700000","1534614248.123678"],["5541.80000","0.33000000","1534614098.345543"]],` +
+			`"bs":[["5541.20000","1.52900000","1534614248.765567"]]},"book-10","XBT/USD"]`)
+		c.write(`[1,{"a":[["5541.30000","0.00000000","1534614335.345903"]]},{"b":[["5541.25000","1.00000000","1534614335.345903"]],"c":"974942666"},"book-10","XBT/USD"]`)
+	}()
+
+	var log bytes.Buffer
+	updates := make(chan krakenapi.OrderBook, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	sub, err := server.client().WithRecorder(krakenapi.NewFrameRecorder(&log)).SubscribeBook(ctx, 10, func(b *Book) { updates <- b.Snapshot() }, "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-updates
+	live := <-updates
+	cancel()
+	<-sub.Done()
+
+	client := NewReplayClient(bytes.NewReader(log.Bytes()), krakenapi.ReplayOptions{})
+	var replayed []krakenapi.OrderBook
+	sub, err = client.SubscribeBook(context.Background(), 10, func(b *Book) { replayed = append(replayed, b.Snapshot()) }, "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-sub.Done()
+	if sub.Err() != io.EOF {
+		t.Errorf("The replay should end with the log, got %v", sub.Err())
+	}
+	if len(replayed) != 2 || !reflect.DeepEqual(replayed[1], live) {
+		t.Errorf("The replayed book should match the live one %+v, got %+v", live, replayed)
+	}
+
+	if _, err := client.SubscribeBook(context.Background(), 10, nil, "XBT/USD"); err == nil {
+		t.Errorf("A log should be replayed once")
+	}
+}
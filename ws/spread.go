@@ -0,0 +1,102 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+	"github.com/sergey-lipin/kraken-go-api-client/internal/chanutil"
+)
+
+// DefaultSpreadBuffer is the number of spread updates buffered for a slow
+// consumer when SubscribeSpread is given no buffer size
+const DefaultSpreadBuffer = 64
+
+// Spread is an update of the spread channel: the best bid and ask of a pair
+type Spread struct {
+	Pair string // WebSocket pair name, e.g. "XBT/USD"
+	krakenapi.SpreadItem
+	BidVolume float64
+	AskVolume float64
+}
+
+// SpreadSubscription delivers the best bid and ask of its pairs
+type SpreadSubscription struct {
+	// C receives the updates. It is closed when the context is done or the
+	// subscription fails, see Err.
+	C <-chan Spread
+	*Subscription
+
+	dropped int64
+}
+
+// Dropped returns the number of updates dropped because C was full
+func (s *SpreadSubscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// SubscribeSpread subscribes to the best bid and ask of pairs, given as
+// WebSocket pair names, e.g. "XBT/USD". C buffers up to buffer updates,
+// DefaultSpreadBuffer if zero; once a slow consumer lets it fill up, the
+// oldest update is dropped for every new one instead of stalling the
+// connection, see Dropped.
+func (c *Client) SubscribeSpread(ctx context.Context, buffer int, pairs ...string) (*SpreadSubscription, error) {
+	if len(pairs) == 0 {
+		return nil, errNoPairs
+	}
+	if buffer <= 0 {
+		buffer = DefaultSpreadBuffer
+	}
+
+	out := make(chan Spread, buffer)
+	sub := &SpreadSubscription{C: out}
+	handle := func(ctx context.Context, msg []json.RawMessage) error {
+		pair, payloads, err := publicMessage(msg)
+		if err != nil {
+			return err
+		}
+		var fields []json.RawMessage
+		if err := json.Unmarshal(payloads[0], &fields); err != nil {
+			return err
+		}
+		spread, err := decodeSpread(rawStrings(fields))
+		if err != nil {
+			return err
+		}
+		spread.Pair = pair
+		atomic.AddInt64(&sub.dropped, int64(chanutil.SendDropOldest(out, spread)))
+		return nil
+	}
+
+	s, err := c.subscribe(ctx, c.env.WebSocketURL, pairs, subscriptionSpec{Name: "spread"}, handle, func() { close(out) })
+	if err != nil {
+		return nil, err
+	}
+	sub.Subscription = s
+	return sub, nil
+}
+
+// decodeSpread decodes [bid, ask, timestamp, bidVolume, askVolume]
+func decodeSpread(fields []string) (Spread, error) {
+	if len(fields) < 5 {
+		return Spread{}, fmt.Errorf("unexpected spread %v", fields)
+	}
+	var spread Spread
+	var err error
+	values := []*float64{&spread.Bid, &spread.Ask, nil, &spread.BidVolume, &spread.AskVolume}
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		if *value, err = strconv.ParseFloat(fields[i], 64); err != nil {
+			return Spread{}, err
+		}
+	}
+	if spread.Time, err = krakenapi.ParseKrakenTime(fields[2]); err != nil {
+		return Spread{}, err
+	}
+	return spread, nil
+}
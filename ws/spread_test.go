@@ -0,0 +1,51 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSubscribeSpread(t *testing.T) {
+	server := newFakeServer(t)
+	sent := make(chan struct{})
+	go func() {
+		c := server.accept(t)
+		c.confirm(t)
+		for i := 0; i < 5; i++ {
+			c.write(fmt.Sprintf(`[0,["5698.%d0000","5700.00000","1542057299.545897","1.01234567","0.98765432"],"spread","XBT/USD"]`, i))
+		}
+		c.write(`{"event":"heartbeat"}`)
+		c.write(`{"event":"heartbeat"}`)
+		close(sent)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := server.client().SubscribeSpread(ctx, 2, "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-sent
+	deadline := time.Now().Add(5 * time.Second)
+	for sub.Dropped() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if sub.Dropped() != 3 {
+		t.Fatalf("Expected the 3 oldest updates dropped, got %d", sub.Dropped())
+	}
+
+	first, second := <-sub.C, <-sub.C
+	if first.Pair != "XBT/USD" || first.Bid != 5698.3 || second.Bid != 5698.4 || first.Ask != 5700 {
+		t.Errorf("Expected the newest updates kept, got %+v and %+v", first, second)
+	}
+	if first.BidVolume != 1.01234567 || first.AskVolume != 0.98765432 || !first.Time.Equal(time.Unix(1542057299, 545897000)) {
+		t.Errorf("Unexpected spread %+v", first)
+	}
+
+	cancel()
+	for range sub.C {
+	}
+}
@@ -0,0 +1,106 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+// Ticker is an update of the ticker channel, with the fields of the REST
+// Ticker. OpeningPrice is today's, OpeningPrice24h the one of 24 hours ago.
+type Ticker struct {
+	Pair string // WebSocket pair name, e.g. "XBT/USD"
+	krakenapi.PairTickerInfo
+	OpeningPrice24h float64
+}
+
+// TickerSubscription delivers the ticker updates of its pairs
+type TickerSubscription struct {
+	// C receives every update. It is closed when the context is done or the
+	// subscription fails, see Err.
+	C <-chan Ticker
+	*Subscription
+}
+
+// SubscribeTicker subscribes to the ticker of pairs, given as WebSocket pair
+// names, e.g. "XBT/USD"
+func (c *Client) SubscribeTicker(ctx context.Context, pairs ...string) (*TickerSubscription, error) {
+	if len(pairs) == 0 {
+		return nil, errNoPairs
+	}
+	out := make(chan Ticker)
+	handle := func(ctx context.Context, msg []json.RawMessage) error {
+		pair, payloads, err := publicMessage(msg)
+		if err != nil {
+			return err
+		}
+		ticker, err := decodeTicker(payloads[0])
+		if err != nil {
+			return err
+		}
+		ticker.Pair = pair
+		return send(ctx, out, ticker)
+	}
+
+	s, err := c.subscribe(ctx, c.env.WebSocketURL, pairs, subscriptionSpec{Name: "ticker"}, handle, func() { close(out) })
+	if err != nil {
+		return nil, err
+	}
+	return &TickerSubscription{C: out, Subscription: s}, nil
+}
+
+// decodeTicker decodes a ticker payload. Unlike the REST Ticker it sends
+// whole lot volumes as numbers and the opening prices of both windows.
+func decodeTicker(payload json.RawMessage) (Ticker, error) {
+	var fields map[string][]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return Ticker{}, err
+	}
+
+	var ticker Ticker
+	ticker.Ask = rawStrings(fields["a"])
+	ticker.Bid = rawStrings(fields["b"])
+	ticker.Close = rawStrings(fields["c"])
+	ticker.Volume = rawStrings(fields["v"])
+	ticker.VolumeAveragePrice = rawStrings(fields["p"])
+	ticker.Low = rawStrings(fields["l"])
+	ticker.High = rawStrings(fields["h"])
+	for _, count := range rawStrings(fields["t"]) {
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return Ticker{}, fmt.Errorf("invalid trade count %q", count)
+		}
+		ticker.Trades = append(ticker.Trades, n)
+	}
+
+	open := rawStrings(fields["o"])
+	var err error
+	if len(open) > 0 {
+		if ticker.OpeningPrice, err = strconv.ParseFloat(open[0], 64); err != nil {
+			return Ticker{}, err
+		}
+	}
+	if len(open) > 1 {
+		if ticker.OpeningPrice24h, err = strconv.ParseFloat(open[1], 64); err != nil {
+			return Ticker{}, err
+		}
+	}
+	return ticker, nil
+}
+
+// rawStrings returns JSON strings and numbers as their text
+func rawStrings(values []json.RawMessage) []string {
+	if values == nil {
+		return nil
+	}
+	texts := make([]string, len(values))
+	for i, value := range values {
+		if err := json.Unmarshal(value, &texts[i]); err != nil {
+			texts[i] = string(value)
+		}
+	}
+	return texts
+}
@@ -0,0 +1,51 @@
+package ws
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestSubscribeTicker(t *testing.T) {
+	server := newFakeServer(t)
+	unsubscribed := make(chan map[string]interface{}, 1)
+	go func() {
+		c := server.accept(t)
+		req := c.confirm(t)
+		if pairs := req["pair"].([]interface{}); len(pairs) != 2 || req["subscription"].(map[string]interface{})["name"] != "ticker" {
+			t.Errorf("Unexpected request %v", req)
+		}
+		c.write(`{"event":"heartbeat"}`)
+		c.write(`[1,{"a":["5525.40000",1,"1.000"],"b":["5525.10000",1,"1.000"],"c":["5525.10000","0.00398963"],` +
+			`"v":["2634.11501494","3591.17907851"],"p":["5631.44067","5653.78939"],"t":[11493,16267],` +
+			`"l":["5505.00000","5505.00000"],"h":["5783.00000","5783.00000"],"o":["5760.70000","5763.40000"]},"ticker","XBT/USD"]`)
+		unsubscribed <- c.read(t)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := server.client().SubscribeTicker(ctx, "XBT/USD", "ETH/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ticker := <-sub.C
+	if ticker.Pair != "XBT/USD" || !reflect.DeepEqual(ticker.Ask, []string{"5525.40000", "1", "1.000"}) ||
+		!reflect.DeepEqual(ticker.Trades, []int{11493, 16267}) || ticker.High[1] != "5783.00000" {
+		t.Errorf("Unexpected ticker %+v", ticker)
+	}
+	if ticker.OpeningPrice != 5760.7 || ticker.OpeningPrice24h != 5763.4 {
+		t.Errorf("Unexpected opening prices %v and %v", ticker.OpeningPrice, ticker.OpeningPrice24h)
+	}
+
+	cancel()
+	if req := <-unsubscribed; req["event"] != "unsubscribe" || len(req["pair"].([]interface{})) != 2 {
+		t.Errorf("Expected an unsubscribe request, got %v", req)
+	}
+	if _, open := <-sub.C; open {
+		t.Errorf("The channel should be closed once the context is done")
+	}
+	if sub.Err() != nil {
+		t.Errorf("A cancelled subscription should not report an error, got %v", sub.Err())
+	}
+}
@@ -0,0 +1,137 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	krakenapi "github.com/sergey-lipin/kraken-go-api-client"
+)
+
+// Trade is a trade of the trade channel
+type Trade struct {
+	Pair string // WebSocket pair name, e.g. "XBT/USD"
+	krakenapi.TradeInfo
+}
+
+// TradeSubscription delivers the trades of its pairs as they happen
+type TradeSubscription struct {
+	// C receives every trade, in the order of the exchange. It is closed
+	// when the context is done or the subscription fails, see Err.
+	C <-chan Trade
+	*Subscription
+}
+
+// SubscribeTrade subscribes to the trades of pairs, given as WebSocket pair
+// names, e.g. "XBT/USD"
+func (c *Client) SubscribeTrade(ctx context.Context, pairs ...string) (*TradeSubscription, error) {
+	if len(pairs) == 0 {
+		return nil, errNoPairs
+	}
+	out := make(chan Trade)
+	handle := func(ctx context.Context, msg []json.RawMessage) error {
+		pair, payloads, err := publicMessage(msg)
+		if err != nil {
+			return err
+		}
+		var trades [][]json.RawMessage
+		if err := json.Unmarshal(payloads[0], &trades); err != nil {
+			return err
+		}
+		for _, fields := range trades {
+			trade, err := decodeTrade(rawStrings(fields))
+			if err != nil {
+				return err
+			}
+			if err := send(ctx, out, Trade{Pair: pair, TradeInfo: trade}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	s, err := c.subscribe(ctx, c.env.WebSocketURL, pairs, subscriptionSpec{Name: "trade"}, handle, func() { close(out) })
+	if err != nil {
+		return nil, err
+	}
+	return &TradeSubscription{C: out, Subscription: s}, nil
+}
+
+// SubscribeTrades delivers the trades of a single pair, so that the client
+// can serve as the live feed of KrakenAPI.BackfillAndFollow. The channel is
+// closed when ctx is done or the subscription fails; SubscribeTradeFeed
+// also tells why.
+func (c *Client) SubscribeTrades(ctx context.Context, pair string) (<-chan krakenapi.TradeInfo, error) {
+	feed, err := c.SubscribeTradeFeed(ctx, pair)
+	if err != nil {
+		return nil, err
+	}
+	return feed.Trades(), nil
+}
+
+// tradeFeed is the krakenapi.LiveTradeFeed of a trade subscription
+type tradeFeed struct {
+	trades <-chan krakenapi.TradeInfo
+	*Subscription
+}
+
+func (f *tradeFeed) Trades() <-chan krakenapi.TradeInfo {
+	return f.trades
+}
+
+// SubscribeTradeFeed delivers the trades of a single pair like
+// SubscribeTrades, along with the error of the subscription, which
+// KrakenAPI.BackfillAndFollow wraps into the error of its follower
+func (c *Client) SubscribeTradeFeed(ctx context.Context, pair string) (krakenapi.LiveTradeFeed, error) {
+	sub, err := c.SubscribeTrade(ctx, pair)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan krakenapi.TradeInfo)
+	go func() {
+		defer close(out)
+		for trade := range sub.C {
+			if send(ctx, out, trade.TradeInfo) != nil {
+				return
+			}
+		}
+	}()
+	return &tradeFeed{trades: out, Subscription: sub.Subscription}, nil
+}
+
+var _ krakenapi.LiveTradeFeedSource = (*Client)(nil)
+
+// decodeTrade decodes [price, volume, time, side, orderType, misc], with
+// the side and order type encoded as by the REST Trades
+func decodeTrade(fields []string) (krakenapi.TradeInfo, error) {
+	if len(fields) < 6 {
+		return krakenapi.TradeInfo{}, fmt.Errorf("unexpected trade %v", fields)
+	}
+	price, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return krakenapi.TradeInfo{}, err
+	}
+	volume, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return krakenapi.TradeInfo{}, err
+	}
+	timestamp, err := krakenapi.ParseKrakenTime(fields[2])
+	if err != nil {
+		return krakenapi.TradeInfo{}, err
+	}
+
+	return krakenapi.TradeInfo{
+		Price:         fields[0],
+		PriceFloat:    price,
+		Volume:        fields[1],
+		VolumeFloat:   volume,
+		Time:          timestamp.Unix(),
+		Timestamp:     timestamp,
+		Buy:           fields[3] == krakenapi.BUY,
+		Sell:          fields[3] == krakenapi.SELL,
+		Market:        fields[4] == krakenapi.MARKET,
+		Limit:         fields[4] == krakenapi.LIMIT,
+		Miscellaneous: fields[5],
+	}, nil
+}
@@ -0,0 +1,84 @@
+package ws
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubscribeTrade(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		if req := c.confirm(t); req["subscription"].(map[string]interface{})["name"] != "trade" {
+			t.Errorf("Unexpected request %v", req)
+		}
+		c.write(`[0,[["5541.20000","0.15850568","1534614057.321597","s","l",""],` +
+			`["6060.00000","0.02455000","1534614057.324998","b","m","x"]],"trade","XBT/USD"]`)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub, err := server.client().SubscribeTrade(ctx, "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := <-sub.C, <-sub.C
+	if first.Pair != "XBT/USD" || first.Price != "5541.20000" || first.VolumeFloat != 0.15850568 || !first.Sell || !first.Limit || first.Buy || first.Market {
+		t.Errorf("Unexpected trade %+v", first)
+	}
+	if first.Time != 1534614057 || !first.Timestamp.Equal(time.Unix(1534614057, 321597000)) {
+		t.Errorf("Expected the time to the microsecond, got %v", first.Timestamp)
+	}
+	if !second.Buy || !second.Market || second.Miscellaneous != "x" {
+		t.Errorf("Unexpected trade %+v", second)
+	}
+
+	cancel()
+	if _, open := <-sub.C; open {
+		t.Errorf("The channel should be closed once the context is done")
+	}
+}
+
+func TestSubscribeTradesLiveSource(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		c.confirm(t)
+		c.write(`[0,[["5541.20000","0.15850568","1534614057.321597","s","l",""]],"trade","XBT/USD"]`)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trades, err := server.client().SubscribeTrades(ctx, "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trade := <-trades; trade.PriceFloat != 5541.2 {
+		t.Errorf("Unexpected trade %+v", trade)
+	}
+	cancel()
+	for range trades {
+	}
+}
+
+func TestSubscribeTradeFeedError(t *testing.T) {
+	server := newFakeServer(t)
+	go func() {
+		c := server.accept(t)
+		c.confirm(t)
+		c.nc.Close()
+	}()
+
+	feed, err := server.client().SubscribeTradeFeed(context.Background(), "XBT/USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range feed.Trades() {
+	}
+	if !errors.Is(feed.Err(), ErrClosed) {
+		t.Errorf("Expected the connection to be reported closed, got %v", feed.Err())
+	}
+}
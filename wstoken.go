@@ -0,0 +1,139 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultWSTokenMaxAge is the age after which a WSTokenSource fetches a new
+// token. Kraken expires tokens not used to connect within 15 minutes.
+const DefaultWSTokenMaxAge = 10 * time.Minute
+
+// WebSocketsTokenResponse holds an authentication token for the private WebSocket feeds
+type WebSocketsTokenResponse struct {
+	Token   string `json:"token"`
+	Expires int    `json:"expires"` // Seconds until the token expires unless used to connect
+}
+
+// GetWebSocketsToken returns a token to authenticate private WebSocket subscriptions
+func (api *KrakenAPI) GetWebSocketsToken() (*WebSocketsTokenResponse, error) {
+	return api.GetWebSocketsTokenWithContext(context.Background())
+}
+
+// GetWebSocketsTokenWithContext returns a token to authenticate private WebSocket subscriptions
+func (api *KrakenAPI) GetWebSocketsTokenWithContext(ctx context.Context) (*WebSocketsTokenResponse, error) {
+	resp, err := api.queryPrivateContext(ctx, "GetWebSocketsToken", url.Values{}, &WebSocketsTokenResponse{})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*WebSocketsTokenResponse), nil
+}
+
+// TokenRefreshError reports a failed attempt to obtain a WebSocket token.
+// WebSocket connections deliver it as an event instead of failing their
+// resubscriptions silently.
+type TokenRefreshError struct {
+	Err error
+	At  time.Time // When the refresh was attempted
+}
+
+func (e *TokenRefreshError) Error() string {
+	return fmt.Sprintf("WebSocket token refresh failed: %s", e.Err)
+}
+
+// Unwrap returns the error of GetWebSocketsToken
+func (e *TokenRefreshError) Unwrap() error {
+	return e.Err
+}
+
+// WSTokenSource caches the WebSocket token of a client and coordinates its
+// refreshes. Concurrent callers share a single GetWebSocketsToken call.
+type WSTokenSource struct {
+	api         *KrakenAPI
+	maxAge      time.Duration
+	callTimeout time.Duration // Of the shared calls, sharedCallTimeout if zero
+
+	mu        sync.Mutex
+	token     string
+	fetchedAt time.Time
+	inflight  *tokenCall
+}
+
+// tokenCall is a GetWebSocketsToken call shared by every waiting caller
+type tokenCall struct {
+	done  chan struct{}
+	token string
+	err   error
+}
+
+// NewWSTokenSource returns a token source fetching a new token once the
+// cached one is older than maxAge; zero uses DefaultWSTokenMaxAge
+func (api *KrakenAPI) NewWSTokenSource(maxAge time.Duration) *WSTokenSource {
+	if maxAge <= 0 {
+		maxAge = DefaultWSTokenMaxAge
+	}
+	return &WSTokenSource{api: api, maxAge: maxAge}
+}
+
+// Token returns the cached token, fetching a new one when it is missing or older than maxAge
+func (s *WSTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	if s.token != "" && time.Since(s.fetchedAt) < s.maxAge {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+	return s.fetch(ctx)
+}
+
+// Refresh discards the cached token and fetches a new one. Call it when a
+// reconnect failed to authenticate.
+func (s *WSTokenSource) Refresh(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	s.token = ""
+	return s.fetch(ctx)
+}
+
+// fetch joins the in-flight call or starts one; s.mu must be held and is released
+func (s *WSTokenSource) fetch(ctx context.Context) (string, error) {
+	call := s.inflight
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		s.inflight = call
+		go s.run(call)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// run performs the shared call. It is not bound to the context of the caller
+// that started it, so that one cancelled caller does not fail the others,
+// but times out so that a hung request does not block the source.
+func (s *WSTokenSource) run(call *tokenCall) {
+	ctx, cancel := sharedCallContext(s.callTimeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := s.api.GetWebSocketsTokenWithContext(ctx)
+
+	s.mu.Lock()
+	if err != nil {
+		call.err = &TokenRefreshError{Err: err, At: start}
+	} else {
+		call.token = resp.Token
+		s.token = resp.Token
+		s.fetchedAt = start
+	}
+	s.inflight = nil
+	s.mu.Unlock()
+	close(call.done)
+}
@@ -0,0 +1,76 @@
+package krakenapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWSTokenSource(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	api := newTestAPI(func(method string, req *http.Request) string {
+		n := atomic.AddInt32(&calls, 1)
+		<-release
+		if n == 3 {
+			return `{"error":["EGeneral:Temporary lockout"]}`
+		}
+		return fmt.Sprintf(`{"error":[],"result":{"token":"token-%d","expires":900}}`, n)
+	})
+	source := api.NewWSTokenSource(time.Hour)
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], _ = source.Token(context.Background())
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Concurrent callers should share one call, got %d", calls)
+	}
+	for _, token := range tokens {
+		if token != "token-1" {
+			t.Errorf("Every caller should get the shared token, got %q", token)
+		}
+	}
+	if token, _ := source.Token(context.Background()); token != "token-1" {
+		t.Errorf("Fresh token should be cached, got %q", token)
+	}
+
+	if token, _ := source.Refresh(context.Background()); token != "token-2" {
+		t.Errorf("Refresh should fetch a new token, got %q", token)
+	}
+
+	_, err := source.Refresh(context.Background())
+	var refreshErr *TokenRefreshError
+	if !errors.As(err, &refreshErr) {
+		t.Errorf("Failed refresh should return a TokenRefreshError, got %v", err)
+	}
+}
+
+func TestWSTokenSourceHungCall(t *testing.T) {
+	api := newTestAPI(hangFirst("GetWebSocketsToken", func(string, *http.Request) string {
+		return `{"error":[],"result":{"token":"token","expires":900}}`
+	}))
+	source := api.NewWSTokenSource(time.Hour)
+	source.callTimeout = 20 * time.Millisecond
+
+	if _, err := source.Token(context.Background()); err == nil {
+		t.Fatal("The hung call should time out")
+	}
+	if token, err := source.Token(context.Background()); err != nil || token != "token" {
+		t.Errorf("A new call should follow the hung one, got %q %v", token, err)
+	}
+}
@@ -0,0 +1,86 @@
+package krakenapi
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// valueTypes lists the response and helper types whose methods must not
+// panic on zero values, nil maps, nil slices or nil pointer receivers
+var valueTypes = []interface{}{
+	AccountTransferResponse{}, AddExportResponse{}, APIError{}, AssetCodes{}, AssetPairsOpts{}, AssetsOpts{}, AddOrderBatchResponse{}, AddOrderBatchResult{}, AddOrderRequest{}, AddOrderResponse{}, BookDiff{}, BookReport{}, AssetInfo{}, AssetPairInfo{}, AssetPairsResponse{}, AssetsResponse{},
+	BalanceExResponse{}, BalanceResponse{}, InconsistentReadError{}, BatchCancelError{}, BestQuote{}, CacheStats{}, CancelAllOrdersAfterResponse{}, ClientStats{}, CancelOrderResponse{},
+	CancelPairResult{}, CancelPairsError{}, Candles{}, ComponentTimes{}, ClientSetBalances{}, ClosedOrdersResponse{}, ConversionStep{}, DepositAddressesResponse{}, DepositLimit{}, DepositMethodInfo{}, DepositStatusInfo{}, DepthChange{},
+	DepthResponse{}, EarnAPREstimate{}, EarnAllocatedAmounts{}, EarnAllocation{}, EarnAllocationState{}, EarnAllocationsResponse{}, EarnAmount{}, EarnHold{}, EarnLockKind(""), EarnLockType{}, EarnOperationStatus{}, EarnStrategiesArgs{}, EarnStrategiesResponse{}, EarnStrategy{}, EditOrderArgs{}, ExportState(""), ExportStatusInfo{}, EditOrderResponse{}, Endpoint{}, EndpointUnavailableError{}, Environment{}, ExchangeDegradedError{}, ExtendedBalance{}, FeeInfo{}, Fees{}, FlattenReport{},
+	FundingState{}, FundingStatus(""), FundingStatusProp(""), HistoricalPrice{}, Idempotency(0), InsufficientFundsError{}, Tier(0), WarningEvent{},
+	KrakenResponse{}, LedgerInfo{}, LedgersResponse{}, LevelChange{}, Leverage{}, LiquidityReport{}, MiscFlag(""), MiscFlags{},
+	OHLC{}, OHLCAnomaly{}, OHLCArgs{}, OHLCExact{}, OHLCInterval(0), OHLCGap{}, OHLCMultiResult{}, OHLCReport{}, OHLCResponse{}, OHLCSeries{}, OHLCStretch{}, OpenOrdersResponse{}, Order{}, OrderAudit{},
+	OrderAuditEvent{}, OrderExecution{},
+	OrderBook{}, OrderBookItem{}, PositionInfo{}, OrderDescription{}, OrderNotFoundError{}, PairNames{}, PairTickerInfo{}, ResolvedPrice{}, PricePoint{}, RateLimitStats{},
+	Param{}, QueryOrdersResponse{}, RemoveExportResponse{}, RewardHistoryResponse{}, RewardTotal{}, RolloverDiscrepancy{}, RolloverPrediction{}, RolloverTerms{}, SizingDecision{}, SpreadItem{}, SpreadResponse{},
+	SideDiff{}, SkewError{}, StaleError{}, SuspectDataError{}, SystemStatusResponse{}, TickerResponse{}, TickerStats{}, TickerWindow[float64]{}, TimeResponse{}, TokenRefreshError{}, WalletTransferResponse{},
+	TradeBalanceResponse{}, TradeEvent{}, TradeHistoryInfo{}, TradeInfo{}, TradeVolumeResponse{}, TradesHistoryResponse{},
+	TradesResponse{}, UnknownAssetError{}, UnknownEnumValueError{}, UnstakeResponse{}, StakeResponse{}, StakingAssetInfo{}, StakingLockPeriod{}, StakingLocks{}, StakingMinimums{}, StakingRewards{}, StakingTransaction{}, StakingTransactionType(""), WebSocketsTokenResponse{}, WithdrawAddressInfo{}, WithdrawAddressesArgs{}, WithdrawInfoResponse{}, WithdrawMethodInfo{}, WithdrawResponse{},
+	WithdrawalCheck{}, WithdrawalOptions{}, WithdrawalValidationError{}, WithdrawalViolation{},
+}
+
+// zeroArgs returns zero values for the parameters of method, with a usable context
+func zeroArgs(method reflect.Type, skip int) []reflect.Value {
+	args := make([]reflect.Value, 0, method.NumIn())
+	for i := skip; i < method.NumIn(); i++ {
+		in := method.In(i)
+		if in == reflect.TypeOf((*context.Context)(nil)).Elem() {
+			args = append(args, reflect.ValueOf(context.Background()))
+			continue
+		}
+		args = append(args, reflect.Zero(in))
+	}
+	return args
+}
+
+// callSafely calls fn and returns the panic it raised, if any
+func callSafely(fn func()) (panicked interface{}) {
+	defer func() { panicked = recover() }()
+	fn()
+	return nil
+}
+
+func TestZeroValueMethodsDoNotPanic(t *testing.T) {
+	for _, v := range valueTypes {
+		typ := reflect.TypeOf(v)
+		ptrType := reflect.PtrTo(typ)
+
+		for i := 0; i < ptrType.NumMethod(); i++ {
+			method := ptrType.Method(i)
+			name := fmt.Sprintf("%s.%s", typ.Name(), method.Name)
+
+			zero := reflect.New(typ)
+			args := append([]reflect.Value{zero}, zeroArgs(method.Type, 1)...)
+			if p := callSafely(func() { method.Func.Call(args) }); p != nil {
+				t.Errorf("%s panics on a zero value: %v", name, p)
+			}
+
+			// Methods with value receivers cannot be called through a nil pointer,
+			// and a nil error pointer is never returned as an error
+			if _, valueReceiver := typ.MethodByName(method.Name); valueReceiver || method.Name == "Error" || method.Name == "Unwrap" {
+				continue
+			}
+			args[0] = reflect.Zero(ptrType)
+			if p := callSafely(func() { method.Func.Call(args) }); p != nil {
+				t.Errorf("%s panics on a nil receiver: %v", name, p)
+			}
+		}
+	}
+}
+
+func TestGetPairTickerInfo(t *testing.T) {
+	resp := TickerResponse{"XXBTZUSD": {OpeningPrice: 30000}}
+	if info := resp.GetPairTickerInfo("XXBTZUSD"); info.OpeningPrice != 30000 {
+		t.Errorf("Expected the ticker of XXBTZUSD, got %+v", info)
+	}
+	if info := resp.GetPairTickerInfo("XETHZUSD"); info.Ask != nil {
+		t.Errorf("Expected an empty ticker for an unknown pair, got %+v", info)
+	}
+}